@@ -3,6 +3,7 @@ package app
 import (
 	grpcapp "auth-service/internal/app/grpc"
 	"auth-service/internal/data/storage"
+	"auth-service/internal/services/address"
 	"auth-service/internal/services/auth"
 	"auth-service/internal/services/user_info"
 	"log/slog"
@@ -30,13 +31,20 @@ func New(
 		panic(err)
 	}
 
+	addressStorage, err := storage.NewAddressStorage(dsn)
+	if err != nil {
+		panic(err)
+	}
+
 	// auth service setup
 	authService := auth.New(log, tokenTTL, authStorage)
 
 	userInfoService := user_info.New(log, userInfoStorage, tokenTTL)
 
+	addressBook := address.New(log, addressStorage)
+
 	// grpc app setup
-	grpcApp := grpcapp.New(log, authService, userInfoService, grpcPort)
+	grpcApp := grpcapp.New(log, authService, userInfoService, addressBook, grpcPort)
 
 	go authStorage.CheckTokens()
 	return &App{
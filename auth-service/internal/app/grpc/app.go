@@ -1,6 +1,7 @@
 package grpcapp
 
 import (
+	addressGrpc "auth-service/internal/grpc/address"
 	authGrpc "auth-service/internal/grpc/auth"
 	"auth-service/internal/grpc/user_info"
 	_ "auth-service/internal/services/auth"
@@ -27,6 +28,7 @@ func New(
 	log *slog.Logger,
 	authService authGrpc.Auth,
 	userInfoService user_info.UserInfo,
+	addressBook addressGrpc.AddressBook,
 	port int,
 ) *App {
 	loggingOpts := []logging.Option{
@@ -50,6 +52,7 @@ func New(
 
 	authGrpc.Register(gRPCServer, authService)
 	user_info.Register(gRPCServer, userInfoService)
+	addressGrpc.Register(gRPCServer, addressBook)
 
 	return &App{
 		log:        log,
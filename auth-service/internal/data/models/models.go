@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type User struct {
 	ID           int64
 	Username     string
@@ -19,3 +21,20 @@ type App struct {
 	Name   string
 	Secret string
 }
+
+type Address struct {
+	ID         int64
+	UserID     int64
+	Label      string
+	Recipient  string
+	Line1      string
+	Line2      string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string
+	Phone      string
+	IsDefault  bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
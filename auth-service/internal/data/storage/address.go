@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"auth-service/internal/data/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the Postgres error code of unique_violation.
+const uniqueViolation = "23505"
+
+type AddressStorage struct {
+	db *sql.DB
+}
+
+const addressColumns = `id, user_id, label, recipient, line1, line2, city, region, postal_code, country, phone, is_default, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAddress(row rowScanner) (*models.Address, error) {
+	var a models.Address
+	err := row.Scan(
+		&a.ID,
+		&a.UserID,
+		&a.Label,
+		&a.Recipient,
+		&a.Line1,
+		&a.Line2,
+		&a.City,
+		&a.Region,
+		&a.PostalCode,
+		&a.Country,
+		&a.Phone,
+		&a.IsDefault,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// SaveAddress adds the address to the address book of its user. The first
+// address of a user becomes the default one.
+func (s *AddressStorage) SaveAddress(ctx context.Context, address *models.Address) (*models.Address, error) {
+	const op = "data.storage.SaveAddress"
+
+	saved, err := retryDefault(func() (*models.Address, error) {
+		return s.saveAddress(ctx, address)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+func (s *AddressStorage) saveAddress(ctx context.Context, address *models.Address) (*models.Address, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if address.IsDefault {
+		if err = clearDefaultAddress(ctx, tx, address.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	row := tx.QueryRowContext(ctx, `
+								INSERT INTO auth.addresses (user_id, label, recipient, line1, line2, city, region, postal_code, country, phone, is_default)
+								VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+									$11 OR NOT EXISTS (SELECT 1 FROM auth.addresses WHERE user_id = $1 AND is_default))
+								RETURNING `+addressColumns,
+		address.UserID,
+		address.Label,
+		address.Recipient,
+		address.Line1,
+		address.Line2,
+		address.City,
+		address.Region,
+		address.PostalCode,
+		address.Country,
+		address.Phone,
+		address.IsDefault,
+	)
+	saved, err := scanAddress(row)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// ListAddresses returns the address book of the user, default address first.
+func (s *AddressStorage) ListAddresses(ctx context.Context, userId int64) ([]*models.Address, error) {
+	const op = "data.storage.ListAddresses"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %w", op, e)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+								SELECT `+addressColumns+`
+								FROM auth.addresses
+								WHERE user_id = $1
+								ORDER BY is_default DESC, id`, userId)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var addresses []*models.Address
+	for rows.Next() {
+		a, err := scanAddress(rows)
+		if err != nil {
+			return nil, fail(err)
+		}
+
+		addresses = append(addresses, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return addresses, nil
+}
+
+func (s *AddressStorage) GetAddress(ctx context.Context, userId int64, id int64) (*models.Address, error) {
+	const op = "data.storage.GetAddress"
+
+	row := s.db.QueryRowContext(ctx, `
+								SELECT `+addressColumns+`
+								FROM auth.addresses
+								WHERE id = $1 AND user_id = $2`, id, userId)
+	a, err := scanAddress(row)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrAddressNotFound
+		default:
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return a, nil
+}
+
+// UpdateAddress replaces the fields of an address of the user. Making it the
+// default address unsets the previous default one; unsetting it leaves the
+// user without a default address.
+func (s *AddressStorage) UpdateAddress(ctx context.Context, address *models.Address) (*models.Address, error) {
+	const op = "data.storage.UpdateAddress"
+
+	updated, err := retryDefault(func() (*models.Address, error) {
+		return s.updateAddress(ctx, address)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrAddressNotFound
+		default:
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return updated, nil
+}
+
+func (s *AddressStorage) updateAddress(ctx context.Context, address *models.Address) (*models.Address, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if address.IsDefault {
+		if err = clearDefaultAddress(ctx, tx, address.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	row := tx.QueryRowContext(ctx, `
+								UPDATE auth.addresses
+								SET label = $3, recipient = $4, line1 = $5, line2 = $6, city = $7, region = $8,
+									postal_code = $9, country = $10, phone = $11, is_default = $12, updated_at = NOW()
+								WHERE id = $1 AND user_id = $2
+								RETURNING `+addressColumns,
+		address.ID,
+		address.UserID,
+		address.Label,
+		address.Recipient,
+		address.Line1,
+		address.Line2,
+		address.City,
+		address.Region,
+		address.PostalCode,
+		address.Country,
+		address.Phone,
+		address.IsDefault,
+	)
+	updated, err := scanAddress(row)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// DeleteAddress removes an address of the user. When it was the default
+// address the oldest remaining address becomes the default one.
+func (s *AddressStorage) DeleteAddress(ctx context.Context, userId int64, id int64) error {
+	const op = "data.storage.DeleteAddress"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %w", op, e)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback()
+
+	var wasDefault bool
+	err = tx.QueryRowContext(ctx, `
+								DELETE FROM auth.addresses
+								WHERE id = $1 AND user_id = $2
+								RETURNING is_default`, id, userId).Scan(&wasDefault)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrAddressNotFound
+		default:
+			return fail(err)
+		}
+	}
+
+	if wasDefault {
+		_, err = tx.ExecContext(ctx, `
+								UPDATE auth.addresses SET is_default = TRUE, updated_at = NOW()
+								WHERE id = (SELECT id FROM auth.addresses WHERE user_id = $1 ORDER BY id LIMIT 1)`, userId)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// retryDefault runs save once more when it lost the default address of the
+// user to a concurrent save: addresses_default_idx keeps a user to one default
+// address, and the second run sees the address saved by the other one.
+func retryDefault(save func() (*models.Address, error)) (*models.Address, error) {
+	address, err := save()
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == "addresses_default_idx" {
+		address, err = save()
+	}
+
+	return address, err
+}
+
+func clearDefaultAddress(ctx context.Context, tx *sql.Tx, userId int64) error {
+	_, err := tx.ExecContext(ctx, `
+								UPDATE auth.addresses SET is_default = FALSE, updated_at = NOW()
+								WHERE user_id = $1 AND is_default`, userId)
+
+	return err
+}
@@ -11,6 +11,8 @@ var (
 	ErrUserNotFound  = errors.New("user not found")
 	ErrAppNotFound   = errors.New("app not found")
 	ErrTokenNotSaved = errors.New("token not saved")
+
+	ErrAddressNotFound = errors.New("address not found")
 )
 
 func NewAuthStorage(dsn string) (*AuthStorage, error) {
@@ -35,6 +37,17 @@ func NewUserInfoStorage(dsn string) (*UserInfoStorage, error) {
 	return &UserInfoStorage{db: db}, nil
 }
 
+func NewAddressStorage(dsn string) (*AddressStorage, error) {
+	const op = "data.storage.NewAddressStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &AddressStorage{db: db}, nil
+}
+
 func (s *AuthStorage) Stop(db *sql.DB) error {
 	return s.db.Close()
 }
@@ -0,0 +1,175 @@
+package address
+
+import (
+	"auth-service/internal/data/models"
+	"auth-service/internal/services/address"
+	"auth-service/internal/services/auth"
+	"context"
+	"errors"
+	authp "github.com/sntabq/proto-gen/gen/go/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type AddressBook interface {
+	CreateAddress(ctx context.Context, token string, address *models.Address) (*models.Address, error)
+	ListAddresses(ctx context.Context, token string) ([]*models.Address, error)
+	GetAddress(ctx context.Context, token string, id int64) (*models.Address, error)
+	UpdateAddress(ctx context.Context, token string, address *models.Address) (*models.Address, error)
+	DeleteAddress(ctx context.Context, token string, id int64) error
+}
+
+type serverAPI struct {
+	authp.UnimplementedAddressBookServer
+	addressBook AddressBook
+}
+
+func Register(gRPCServer *grpc.Server, addressBook AddressBook) {
+	authp.RegisterAddressBookServer(gRPCServer, &serverAPI{addressBook: addressBook})
+}
+
+func (s *serverAPI) CreateAddress(ctx context.Context, req *authp.CreateAddressRequest) (*authp.CreateAddressResponse, error) {
+	token, err := tokenFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetAddress() == nil {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+
+	saved, err := s.addressBook.CreateAddress(ctx, token, toModel(req.GetAddress()))
+	if err != nil {
+		return nil, statusError(err, "failed to create address")
+	}
+
+	return &authp.CreateAddressResponse{Address: toProto(saved)}, nil
+}
+
+func (s *serverAPI) ListAddresses(ctx context.Context, req *authp.ListAddressesRequest) (*authp.ListAddressesResponse, error) {
+	token, err := tokenFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := s.addressBook.ListAddresses(ctx, token)
+	if err != nil {
+		return nil, statusError(err, "failed to list addresses")
+	}
+
+	var response []*authp.Address
+	for _, a := range addresses {
+		response = append(response, toProto(a))
+	}
+
+	return &authp.ListAddressesResponse{Addresses: response}, nil
+}
+
+func (s *serverAPI) GetAddress(ctx context.Context, req *authp.GetAddressRequest) (*authp.GetAddressResponse, error) {
+	token, err := tokenFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	a, err := s.addressBook.GetAddress(ctx, token, req.GetId())
+	if err != nil {
+		return nil, statusError(err, "failed to get address")
+	}
+
+	return &authp.GetAddressResponse{Address: toProto(a)}, nil
+}
+
+func (s *serverAPI) UpdateAddress(ctx context.Context, req *authp.UpdateAddressRequest) (*authp.UpdateAddressResponse, error) {
+	token, err := tokenFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetAddress().GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "address id is required")
+	}
+
+	updated, err := s.addressBook.UpdateAddress(ctx, token, toModel(req.GetAddress()))
+	if err != nil {
+		return nil, statusError(err, "failed to update address")
+	}
+
+	return &authp.UpdateAddressResponse{Address: toProto(updated)}, nil
+}
+
+func (s *serverAPI) DeleteAddress(ctx context.Context, req *authp.DeleteAddressRequest) (*authp.DeleteAddressResponse, error) {
+	token, err := tokenFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	if err := s.addressBook.DeleteAddress(ctx, token, req.GetId()); err != nil {
+		return nil, statusError(err, "failed to delete address")
+	}
+
+	return &authp.DeleteAddressResponse{}, nil
+}
+
+// tokenFromContext returns the token the gateway forwards from the
+// Authorization header.
+func tokenFromContext(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	tkn := md.Get("authorization")
+	if len(tkn) == 0 || tkn[0] == "" {
+		return "", status.Error(codes.Unauthenticated, "authentication is required")
+	}
+
+	return tkn[0], nil
+}
+
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, auth.ErrNotValidJwt):
+		return status.Error(codes.Unauthenticated, "unknown user")
+	case errors.Is(err, address.ErrInvalidAddress):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, address.ErrAddressNotFound):
+		return status.Error(codes.NotFound, "address not found")
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
+
+func toModel(a *authp.Address) *models.Address {
+	return &models.Address{
+		ID:         a.GetId(),
+		Label:      a.GetLabel(),
+		Recipient:  a.GetRecipient(),
+		Line1:      a.GetLine1(),
+		Line2:      a.GetLine2(),
+		City:       a.GetCity(),
+		Region:     a.GetRegion(),
+		PostalCode: a.GetPostalCode(),
+		Country:    a.GetCountry(),
+		Phone:      a.GetPhone(),
+		IsDefault:  a.GetIsDefault(),
+	}
+}
+
+func toProto(a *models.Address) *authp.Address {
+	return &authp.Address{
+		Id:         a.ID,
+		UserId:     a.UserID,
+		Label:      a.Label,
+		Recipient:  a.Recipient,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		Region:     a.Region,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		Phone:      a.Phone,
+		IsDefault:  a.IsDefault,
+	}
+}
@@ -0,0 +1,202 @@
+package address
+
+import (
+	"auth-service/internal/data/models"
+	"auth-service/internal/data/storage"
+	"auth-service/internal/services/auth"
+	"auth-service/internal/sl"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+var (
+	ErrInvalidAddress  = errors.New("invalid address")
+	ErrAddressNotFound = errors.New("address not found")
+)
+
+type AddressProvider interface {
+	SaveAddress(ctx context.Context, address *models.Address) (*models.Address, error)
+	ListAddresses(ctx context.Context, userId int64) ([]*models.Address, error)
+	GetAddress(ctx context.Context, userId int64, id int64) (*models.Address, error)
+	UpdateAddress(ctx context.Context, address *models.Address) (*models.Address, error)
+	DeleteAddress(ctx context.Context, userId int64, id int64) error
+}
+
+// AddressBook manages the shipping addresses of the user behind a token.
+type AddressBook struct {
+	log             *slog.Logger
+	addressProvider AddressProvider
+}
+
+func New(log *slog.Logger, addressProvider AddressProvider) *AddressBook {
+	return &AddressBook{
+		log:             log,
+		addressProvider: addressProvider,
+	}
+}
+
+func (ab *AddressBook) CreateAddress(ctx context.Context, token string, address *models.Address) (*models.Address, error) {
+	const op = "AddressBook.CreateAddress"
+
+	log := ab.log.With(
+		slog.String("op", op),
+	)
+
+	userId, err := userIdFromToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validate(address); err != nil {
+		return nil, err
+	}
+
+	log.Info("attempting to save address")
+
+	address.UserID = userId
+	saved, err := ab.addressProvider.SaveAddress(ctx, address)
+	if err != nil {
+		log.Error("failed to save address", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+func (ab *AddressBook) ListAddresses(ctx context.Context, token string) ([]*models.Address, error) {
+	const op = "AddressBook.ListAddresses"
+
+	userId, err := userIdFromToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := ab.addressProvider.ListAddresses(ctx, userId)
+	if err != nil {
+		ab.log.Error("failed to list addresses", slog.String("op", op), sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return addresses, nil
+}
+
+func (ab *AddressBook) GetAddress(ctx context.Context, token string, id int64) (*models.Address, error) {
+	const op = "AddressBook.GetAddress"
+
+	userId, err := userIdFromToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := ab.addressProvider.GetAddress(ctx, userId, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrAddressNotFound) {
+			return nil, ErrAddressNotFound
+		}
+		ab.log.Error("failed to get address", slog.String("op", op), sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return address, nil
+}
+
+func (ab *AddressBook) UpdateAddress(ctx context.Context, token string, address *models.Address) (*models.Address, error) {
+	const op = "AddressBook.UpdateAddress"
+
+	userId, err := userIdFromToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validate(address); err != nil {
+		return nil, err
+	}
+
+	address.UserID = userId
+	updated, err := ab.addressProvider.UpdateAddress(ctx, address)
+	if err != nil {
+		if errors.Is(err, storage.ErrAddressNotFound) {
+			return nil, ErrAddressNotFound
+		}
+		ab.log.Error("failed to update address", slog.String("op", op), sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return updated, nil
+}
+
+func (ab *AddressBook) DeleteAddress(ctx context.Context, token string, id int64) error {
+	const op = "AddressBook.DeleteAddress"
+
+	userId, err := userIdFromToken(token)
+	if err != nil {
+		return err
+	}
+
+	if err := ab.addressProvider.DeleteAddress(ctx, userId, id); err != nil {
+		if errors.Is(err, storage.ErrAddressNotFound) {
+			return ErrAddressNotFound
+		}
+		ab.log.Error("failed to delete address", slog.String("op", op), sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func userIdFromToken(token string) (int64, error) {
+	claims, err := auth.DecodeToken(token)
+	if err != nil {
+		return 0, auth.ErrNotValidJwt
+	}
+
+	return int64(claims.UID), nil
+}
+
+// validate trims the fields of the address and checks that the ones a carrier
+// needs are present.
+func validate(a *models.Address) error {
+	for _, field := range []*string{&a.Label, &a.Recipient, &a.Line1, &a.Line2, &a.City, &a.Region, &a.PostalCode, &a.Country, &a.Phone} {
+		*field = strings.TrimSpace(*field)
+	}
+	a.Country = strings.ToUpper(a.Country)
+
+	switch {
+	case a.Recipient == "":
+		return fmt.Errorf("%w: recipient is required", ErrInvalidAddress)
+	case a.Line1 == "":
+		return fmt.Errorf("%w: line1 is required", ErrInvalidAddress)
+	case a.City == "":
+		return fmt.Errorf("%w: city is required", ErrInvalidAddress)
+	case a.PostalCode == "":
+		return fmt.Errorf("%w: postal_code is required", ErrInvalidAddress)
+	case len(a.Country) != 2:
+		return fmt.Errorf("%w: country must be a two letter ISO code", ErrInvalidAddress)
+	}
+
+	// The limits match the sizes of the columns.
+	limits := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"label", a.Label, 64},
+		{"recipient", a.Recipient, 255},
+		{"line1", a.Line1, 255},
+		{"line2", a.Line2, 255},
+		{"city", a.City, 128},
+		{"region", a.Region, 128},
+		{"postal_code", a.PostalCode, 32},
+		{"phone", a.Phone, 32},
+	}
+	for _, l := range limits {
+		if len(l.value) > l.max {
+			return fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidAddress, l.name, l.max)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS auth.addresses
+(
+    id          BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id     BIGINT                      NOT NULL REFERENCES auth.users ON DELETE CASCADE,
+    label       VARCHAR(64)                 NOT NULL DEFAULT '',
+    recipient   VARCHAR(255)                NOT NULL,
+    line1       VARCHAR(255)                NOT NULL,
+    line2       VARCHAR(255)                NOT NULL DEFAULT '',
+    city        VARCHAR(128)                NOT NULL,
+    region      VARCHAR(128)                NOT NULL DEFAULT '',
+    postal_code VARCHAR(32)                 NOT NULL,
+    country     CHAR(2)                     NOT NULL,
+    phone       VARCHAR(32)                 NOT NULL DEFAULT '',
+    is_default  BOOLEAN                     NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS addresses_user_id_idx ON auth.addresses (user_id);
+
+-- A user has at most one default address.
+CREATE UNIQUE INDEX IF NOT EXISTS addresses_default_idx ON auth.addresses (user_id) WHERE is_default;
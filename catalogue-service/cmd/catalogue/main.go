@@ -4,9 +4,11 @@ import (
 	"catalogue-service/config"
 	"catalogue-service/internal/app"
 	"catalogue-service/internal/sl"
+	"context"
 	"log/slog"
 	"os"
 	"os/signal"
+	"shared/broker"
 	"syscall"
 )
 
@@ -19,18 +21,26 @@ const (
 func main() {
 	cfg := config.LoadConfig()
 	log := setupLogger(cfg.Env)
-	application := app.New(log, cfg.GRPC.Port, cfg.StoragePath, cfg.TokenTtl)
+
+	publisher := broker.NewRabbitMQ(cfg.RabbitMQ.URL)
+	defer publisher.Close()
+
+	application := app.New(log, cfg.GRPC.Port, cfg.ServiceToken, cfg.StoragePath, cfg.TokenTtl, publisher, cfg.Outbox.Interval, cfg.Outbox.BatchSize, cfg.Outbox.Lease)
 
 	go func() {
 		application.GRPCServer.MustRun()
 	}()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	go application.OutboxRelay.Run(ctx)
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 
 	<-stop
 
 	application.GRPCServer.Stop()
+	cancel()
 	log.Info("Catalogue service gracefully stopped")
 }
 
@@ -8,8 +8,11 @@ require (
 	github.com/ilyakaznacheev/cleanenv v1.5.0
 	github.com/jinzhu/copier v0.4.0
 	github.com/lib/pq v1.10.9
-	github.com/sntabq/proto-gen v0.0.0-20240604204705-5b85fce0a239
+	github.com/rabbitmq/amqp091-go v1.10.0
+	github.com/sntabq/proto-gen v0.0.0-00010101000000-000000000000
 	google.golang.org/grpc v1.64.0
+	google.golang.org/protobuf v1.34.1
+	shared v0.0.0-00010101000000-000000000000
 )
 
 require (
@@ -25,7 +28,11 @@ require (
 	golang.org/x/text v0.16.0 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20240604185151-ef581f913117 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240528184218-531527333157 // indirect
-	google.golang.org/protobuf v1.34.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	olympos.io/encoding/edn v0.0.0-20201019073823-d3554ca0b0a3 // indirect
 )
+
+replace (
+	github.com/sntabq/proto-gen => ../gateway/third_party/protos
+	shared => ../shared
+)
@@ -4,19 +4,30 @@ import (
 	grpcapp "catalogue-service/internal/app/grpc"
 	"catalogue-service/internal/data"
 	"catalogue-service/internal/services/catalogue"
+	"catalogue-service/internal/services/review"
+	"catalogue-service/internal/services/stock"
+	"catalogue-service/internal/services/wishlist"
 	"log/slog"
+	"shared/broker"
+	"shared/outbox"
 	"time"
 )
 
 type App struct {
-	GRPCServer *grpcapp.App
+	GRPCServer  *grpcapp.App
+	OutboxRelay *outbox.Relay
 }
 
 func New(
 	log *slog.Logger,
 	grpcPort int,
+	serviceToken string,
 	dsn string,
 	tokenTTL time.Duration,
+	publisher broker.Publisher,
+	outboxInterval time.Duration,
+	outboxBatchSize int,
+	outboxLease time.Duration,
 ) *App {
 	// TODO: database setup
 	itemRepo, err := data.New(dsn)
@@ -24,11 +35,40 @@ func New(
 		panic(err)
 	}
 
+	reviewRepo, err := data.NewReviewRepo(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	stockRepo, err := data.NewStockRepo(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	wishlistRepo, err := data.NewWishlistRepo(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	outboxRepo, err := data.NewOutboxRepo(dsn)
+	if err != nil {
+		panic(err)
+	}
+
 	// TODO: catalogue service setup in services/catalogue
 	catalogueService := catalogue.New(log, itemRepo, tokenTTL)
 
+	reviewService := review.New(log, reviewRepo)
+
+	stockService := stock.New(log, stockRepo)
+
+	wishlistService := wishlist.New(log, wishlistRepo)
+
 	// TODO: grpc app setup
-	grpcApp := grpcapp.New(log, catalogueService, grpcPort)
+	grpcApp := grpcapp.New(log, catalogueService, reviewService, stockService, wishlistService, grpcPort, serviceToken)
 
-	return &App{GRPCServer: grpcApp}
+	return &App{
+		GRPCServer:  grpcApp,
+		OutboxRelay: outbox.New(log, outboxRepo, publisher, outboxInterval, outboxBatchSize, outboxLease),
+	}
 }
@@ -2,6 +2,9 @@ package grpcapp
 
 import (
 	catalogueGrpc "catalogue-service/internal/grpc/catalogue"
+	reviewGrpc "catalogue-service/internal/grpc/review"
+	stockGrpc "catalogue-service/internal/grpc/stock"
+	wishlistGrpc "catalogue-service/internal/grpc/wishlist"
 	"fmt"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
@@ -29,7 +32,11 @@ var OrderServiceClient orderp.OrderServiceClient
 func New(
 	log *slog.Logger,
 	catalogueService catalogueGrpc.Catalogue,
+	reviewService reviewGrpc.Review,
+	stockService stockGrpc.Stock,
+	wishlistService wishlistGrpc.Wishlist,
 	port int,
+	serviceToken string,
 ) *App {
 	loggingOpts := []logging.Option{
 		logging.WithLogOnEvents(
@@ -50,9 +57,16 @@ func New(
 	gRPCServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
 		recovery.UnaryServerInterceptor(recoveryOpts...),
 		logging.UnaryServerInterceptor(InterceptorLogger(log), loggingOpts...),
+		InterceptorReviewAuthor,
+		AdminInterceptorModerateReviews,
+		InterceptorWishlist,
+		AdminInterceptorStock(serviceToken),
 	), grpc.UnaryInterceptor(AuthInterceptor))
 
 	catalogueGrpc.Register(gRPCServer, catalogueService)
+	reviewGrpc.Register(gRPCServer, reviewService)
+	stockGrpc.Register(gRPCServer, stockService)
+	wishlistGrpc.Register(gRPCServer, wishlistService)
 
 	ConnectToOrderService()
 	ConnectToSsoService()
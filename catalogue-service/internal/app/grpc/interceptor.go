@@ -2,9 +2,11 @@ package grpcapp
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	authp "github.com/sntabq/proto-gen/gen/go/auth"
+	cataloguep "github.com/sntabq/proto-gen/gen/go/catalogue"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -62,3 +64,146 @@ func AuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServe
 
 	return handler(ctx, req)
 }
+
+// InterceptorReviewAuthor makes sure reviews and helpful votes are submitted
+// by the authenticated user only.
+func InterceptorReviewAuthor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var userId int32
+	switch r := req.(type) {
+	case *cataloguep.CreateReviewRequest:
+		userId = r.GetReview().GetUserId()
+	case *cataloguep.VoteReviewHelpfulRequest:
+		userId = r.GetUserId()
+	default:
+		return handler(ctx, req)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user.Id != userId {
+		return nil, status.Errorf(codes.PermissionDenied, "you can not review on behalf of others")
+	}
+
+	return handler(ctx, req)
+}
+
+// AdminInterceptorModerateReviews restricts review moderation, and listing of
+// reviews that are not approved yet, to admins.
+func AdminInterceptorModerateReviews(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	switch info.FullMethod {
+	case "/catalogue.ReviewService/ModerateReview",
+		"/catalogue.ReviewService/HideReview",
+		"/catalogue.ReviewService/RestoreReview":
+	case "/catalogue.ReviewService/ListReviews":
+		reviewStatus := req.(*cataloguep.ListReviewsRequest).GetStatus()
+		if reviewStatus == "" || reviewStatus == "approved" {
+			return handler(ctx, req)
+		}
+	default:
+		return handler(ctx, req)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireAdmin(ctx, user.Id); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// serviceTokenHeader is the metadata key other services send the service
+// token in.
+const serviceTokenHeader = "x-service-token"
+
+// AdminInterceptorStock restricts StockService, which reserves, releases and
+// restocks items, to the order service, which sends serviceToken, and to
+// admins. An empty serviceToken admits admins only.
+func AdminInterceptorStock(serviceToken string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, "/catalogue.StockService/") {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		if tkn := md[serviceTokenHeader]; serviceToken != "" && len(tkn) > 0 &&
+			subtle.ConstantTimeCompare([]byte(tkn[0]), []byte(serviceToken)) == 1 {
+			return handler(ctx, req)
+		}
+
+		user, err := userFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := requireAdmin(ctx, user.Id); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+type userKey struct{}
+
+// UserFromContext returns the user stored by InterceptorWishlist.
+func UserFromContext(ctx context.Context) (*authp.User, bool) {
+	user, ok := ctx.Value(userKey{}).(*authp.User)
+	return user, ok
+}
+
+// InterceptorWishlist authenticates the callers of WishlistService, which only
+// ever act on their own wishlist and alerts.
+func InterceptorWishlist(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, "/catalogue.WishlistService/") {
+		return handler(ctx, req)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(context.WithValue(ctx, userKey{}, user), req)
+}
+
+func userFromContext(ctx context.Context) (*authp.User, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		log.Printf("failed to get metadata from context")
+	}
+	tkn, found := md["authorization"]
+	if !found || len(tkn) == 0 || tkn[0] == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "authentication is required")
+	}
+
+	getUserInfoResponse, err := UserInfoServiceClient.GetUserInfo(ctx, &authp.GetUserInfoRequest{Token: tkn[0]})
+	if err != nil {
+		log.Printf("failed to get user info %v", err)
+		switch {
+		case strings.Contains(err.Error(), "unknown user"):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid user")
+		default:
+			return nil, status.Errorf(codes.Internal, "get user info failed")
+		}
+	}
+
+	return getUserInfoResponse.User, nil
+}
+
+func requireAdmin(ctx context.Context, userId int32) error {
+	isAdminResponse, err := AuthServiceClient.IsAdmin(ctx, &authp.IsAdminRequest{UserId: int64(userId)})
+	if err != nil {
+		log.Printf("permissions fail %v", err)
+		return status.Errorf(codes.PermissionDenied, "permission failed")
+	}
+
+	if !isAdminResponse.IsAdmin {
+		return status.Errorf(codes.PermissionDenied, "permission failed")
+	}
+
+	return nil
+}
@@ -18,15 +18,34 @@ type ItemRepo struct {
 var (
 	ErrRecordNotFound   = errors.New("record (row, entry) not found")
 	ErrItemAlreadyExist = errors.New("item already exists")
+	ErrUnknownSortKey   = errors.New("unknown sort key")
 )
 
+// selectItemQuery joins every item with the aggregate of its approved reviews.
+const selectItemQuery = `
+			SELECT i.id, i.name, i.price, i.description, i.quantity, i.image_url, i.category,
+			       COALESCE(r.rating, 0), COALESCE(r.review_count, 0)
+			FROM catalogue.item_info i
+			LEFT JOIN (
+				SELECT item_id, AVG(rating)::REAL AS rating, COUNT(*) AS review_count
+				FROM catalogue.reviews
+				WHERE status = 'approved'
+				GROUP BY item_id
+			) r ON r.item_id = i.id`
+
+// itemOrderings maps the sort keys accepted by ListItems to ORDER BY clauses.
+var itemOrderings = map[string]string{
+	"":       "i.id",
+	"rating": "COALESCE(r.rating, 0) DESC, COALESCE(r.review_count, 0) DESC, i.id",
+}
+
 func (ir *ItemRepo) SaveItem(ctx context.Context, item *models.Item) (int32, error) {
 	const op = "data.SaveItem"
 	fail := func(e error) error {
 		return fmt.Errorf("%s: %v", op, e)
 	}
-	query := `INSERT INTO catalogue.item_info (name, price, description, quantity, image_url)
-			VALUES ($1, $2, $3, $4, $5)
+	query := `INSERT INTO catalogue.item_info (name, price, description, quantity, image_url, category)
+			VALUES ($1, $2, $3, $4, $5, $6)
 			RETURNING id`
 	args := []interface{}{
 		item.Name,
@@ -34,6 +53,7 @@ func (ir *ItemRepo) SaveItem(ctx context.Context, item *models.Item) (int32, err
 		item.Description,
 		item.Quantity,
 		item.ImageURL,
+		item.Category,
 	}
 
 	tx, err := ir.DB.BeginTx(ctx, nil)
@@ -67,8 +87,8 @@ func (ir *ItemRepo) GetItemById(ctx context.Context, id int) (*models.Item, erro
 		return fmt.Errorf("%s: %v", op, e)
 	}
 	var item models.Item
-	query := `SELECT * FROM catalogue.item_info
-			WHERE id = $1`
+	query := selectItemQuery + `
+			WHERE i.id = $1`
 	err := ir.DB.QueryRowContext(ctx, query, id).Scan(
 		&item.ID,
 		&item.Name,
@@ -76,6 +96,9 @@ func (ir *ItemRepo) GetItemById(ctx context.Context, id int) (*models.Item, erro
 		&item.Description,
 		&item.Quantity,
 		&item.ImageURL,
+		&item.Category,
+		&item.Rating,
+		&item.ReviewCount,
 	)
 	if err != nil {
 		switch {
@@ -88,12 +111,17 @@ func (ir *ItemRepo) GetItemById(ctx context.Context, id int) (*models.Item, erro
 	return &item, nil
 }
 
-func (ir *ItemRepo) GetAllItems(ctx context.Context) ([]*models.Item, error) {
+func (ir *ItemRepo) GetAllItems(ctx context.Context, sortBy string) ([]*models.Item, error) {
 	const op = "data.GetAllItems"
 	fail := func(e error) error {
 		return fmt.Errorf("%s, %v", op, e)
 	}
-	query := `SELECT * FROM catalogue.item_info`
+	ordering, ok := itemOrderings[sortBy]
+	if !ok {
+		return nil, ErrUnknownSortKey
+	}
+	query := selectItemQuery + `
+			ORDER BY ` + ordering
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	rows, err := ir.DB.QueryContext(ctx, query)
@@ -111,6 +139,9 @@ func (ir *ItemRepo) GetAllItems(ctx context.Context) ([]*models.Item, error) {
 			&item.Description,
 			&item.Quantity,
 			&item.ImageURL,
+			&item.Category,
+			&item.Rating,
+			&item.ReviewCount,
 		)
 		if err != nil {
 			return nil, fail(err)
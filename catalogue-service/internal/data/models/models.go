@@ -1,10 +1,49 @@
 package models
 
+import "time"
+
 type Item struct {
-	ID          int32  `json:"id,omitempty"`
-	Name        string `json:"name,omitempty"`
-	Price       int32  `json:"price,omitempty"`
-	Description string `json:"description,omitempty"`
-	Quantity    int32  `json:"quantity,omitempty"`
-	ImageURL    string `json:"image_url"`
+	ID          int32   `json:"id,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	Price       int32   `json:"price,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Quantity    int32   `json:"quantity,omitempty"`
+	ImageURL    string  `json:"image_url"`
+	Category    string  `json:"category"`
+	Rating      float32 `json:"rating"`
+	ReviewCount int32   `json:"review_count"`
+}
+
+type Review struct {
+	ID           int64     `json:"id,omitempty"`
+	ItemId       int32     `json:"item_id"`
+	UserId       int32     `json:"user_id"`
+	Rating       int32     `json:"rating"`
+	Body         string    `json:"body"`
+	Status       string    `json:"status"`
+	HelpfulCount int32     `json:"helpful_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type StockLine struct {
+	ItemId   int32 `json:"item_id"`
+	Quantity int32 `json:"quantity"`
+}
+
+// WishlistEntry is an item a user saved for later.
+type WishlistEntry struct {
+	Item    Item      `json:"item"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// StockAlert is the subscription of a user to be told when an out of stock
+// item is back. It is sent once; subscribing again re-arms it.
+type StockAlert struct {
+	UserId     int32      `json:"user_id"`
+	ItemId     int32      `json:"item_id"`
+	Email      string     `json:"email"`
+	Username   string     `json:"username"`
+	CreatedAt  time.Time  `json:"created_at"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
 }
@@ -0,0 +1,32 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"shared/events"
+)
+
+// EventItemBackInStock is published when an item that was out of stock can be
+// ordered again, for the users who asked to be told.
+const EventItemBackInStock = "item.back_in_stock"
+
+// producer is the name the catalogue service signs its events with.
+const producer = "catalogue-service"
+
+// insertOutboxEvent stores payload wrapped in an event envelope. The events of
+// an item are correlated by the item.
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, eventType string, aggregateId int32, payload proto.Message) error {
+	body, err := events.Marshal(producer, eventType, fmt.Sprintf("item-%d", aggregateId), payload)
+	if err != nil {
+		return err
+	}
+
+	query := `
+			INSERT INTO catalogue.outbox (aggregate_id, event_type, payload)
+			VALUES ($1, $2, $3)`
+	_, err = tx.ExecContext(ctx, query, aggregateId, eventType, body)
+
+	return err
+}
@@ -0,0 +1,230 @@
+package data
+
+import (
+	"catalogue-service/internal/data/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strings"
+)
+
+type ReviewRepo struct {
+	DB *sql.DB
+}
+
+var (
+	ErrReviewAlreadyExist = errors.New("review already exists")
+	ErrAlreadyVoted       = errors.New("review already voted by user")
+)
+
+const selectReviewQuery = `
+			SELECT id, item_id, user_id, rating, body, status, helpful_count, created_at, updated_at
+			FROM catalogue.reviews`
+
+func scanReview(row interface{ Scan(...any) error }, review *models.Review) error {
+	return row.Scan(
+		&review.ID,
+		&review.ItemId,
+		&review.UserId,
+		&review.Rating,
+		&review.Body,
+		&review.Status,
+		&review.HelpfulCount,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+	)
+}
+
+// HasOrderedItem reports whether the user has bought the item: an order
+// containing it was paid for and not refunded before it was delivered. Orders
+// refunded after delivery, such as by a return, still count, as the user had
+// the item in hand.
+func (rr *ReviewRepo) HasOrderedItem(ctx context.Context, userId int32, itemId int32) (bool, error) {
+	const op = "data.HasOrderedItem"
+	query := `
+			SELECT EXISTS(
+				SELECT 1 FROM order_service.order_lines l
+				JOIN order_service.orders o ON o.id = l.order_id
+				WHERE o.user_id = $1 AND l.item_id = $2 AND (
+					o.status IN ('paid', 'shipped', 'delivered') OR
+					(o.status = 'refunded' AND EXISTS(
+						SELECT 1 FROM order_service.order_status_history h
+						WHERE h.order_id = o.id AND h.to_status = 'delivered'
+					))
+				)
+			)`
+
+	var ordered bool
+	err := rr.DB.QueryRowContext(ctx, query, userId, itemId).Scan(&ordered)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return ordered, nil
+}
+
+func (rr *ReviewRepo) SaveReview(ctx context.Context, review *models.Review) (*models.Review, error) {
+	const op = "data.SaveReview"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+	query := `INSERT INTO catalogue.reviews (item_id, user_id, rating, body, status)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, helpful_count, created_at, updated_at`
+	args := []interface{}{
+		review.ItemId,
+		review.UserId,
+		review.Rating,
+		review.Body,
+		review.Status,
+	}
+
+	err := rr.DB.QueryRowContext(ctx, query, args...).Scan(
+		&review.ID,
+		&review.HelpfulCount,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23505" && strings.Contains(pqErr.Message, "reviews_item_id_user_id_key"):
+			return nil, ErrReviewAlreadyExist
+		case errors.As(err, &pqErr) && pqErr.Code == "23503":
+			return nil, ErrRecordNotFound
+		default:
+			return nil, fail(err)
+		}
+	}
+
+	return review, nil
+}
+
+func (rr *ReviewRepo) GetReviewById(ctx context.Context, id int64) (*models.Review, error) {
+	const op = "data.GetReviewById"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+	query := selectReviewQuery + `
+			WHERE id = $1`
+
+	var review models.Review
+	err := scanReview(rr.DB.QueryRowContext(ctx, query, id), &review)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, fail(err)
+		}
+	}
+
+	return &review, nil
+}
+
+func (rr *ReviewRepo) GetReviewsByItemId(ctx context.Context, itemId int32, status string) ([]*models.Review, error) {
+	const op = "data.GetReviewsByItemId"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+	query := selectReviewQuery + `
+			WHERE item_id = $1 AND status = $2
+			ORDER BY helpful_count DESC, created_at DESC`
+
+	rows, err := rr.DB.QueryContext(ctx, query, itemId, status)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var reviews []*models.Review
+	for rows.Next() {
+		var review models.Review
+		if err := scanReview(rows, &review); err != nil {
+			return nil, fail(err)
+		}
+
+		reviews = append(reviews, &review)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return reviews, nil
+}
+
+// UpdateReviewStatus moves the review to status only if it is currently in
+// one of the from states, so concurrent moderators cannot skip a transition.
+func (rr *ReviewRepo) UpdateReviewStatus(ctx context.Context, id int64, from []string, status string) (*models.Review, error) {
+	const op = "data.UpdateReviewStatus"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+	query := `
+			UPDATE catalogue.reviews
+			SET status = $1, updated_at = NOW()
+			WHERE id = $2 AND status = ANY($3)
+			RETURNING id, item_id, user_id, rating, body, status, helpful_count, created_at, updated_at`
+
+	var review models.Review
+	err := scanReview(rr.DB.QueryRowContext(ctx, query, status, id, pq.Array(from)), &review)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, fail(err)
+		}
+	}
+
+	return &review, nil
+}
+
+// VoteHelpful records a helpful vote of the user and bumps the counter of the
+// review in the same transaction. A user can vote for a review only once.
+func (rr *ReviewRepo) VoteHelpful(ctx context.Context, reviewId int64, userId int32) (int32, error) {
+	const op = "data.VoteHelpful"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := rr.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fail(err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+			INSERT INTO catalogue.review_votes (review_id, user_id)
+			VALUES ($1, $2)`, reviewId, userId)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23505":
+			return 0, ErrAlreadyVoted
+		case errors.As(err, &pqErr) && pqErr.Code == "23503":
+			return 0, ErrRecordNotFound
+		default:
+			return 0, fail(err)
+		}
+	}
+
+	var helpfulCount int32
+	err = tx.QueryRowContext(ctx, `
+			UPDATE catalogue.reviews
+			SET helpful_count = helpful_count + 1
+			WHERE id = $1
+			RETURNING helpful_count`, reviewId).Scan(&helpfulCount)
+	if err != nil {
+		return 0, fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fail(err)
+	}
+
+	return helpfulCount, nil
+}
@@ -0,0 +1,270 @@
+package data
+
+import (
+	"catalogue-service/internal/data/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	eventsp "github.com/sntabq/proto-gen/gen/go/events"
+	"sort"
+)
+
+type StockRepo struct {
+	DB *sql.DB
+}
+
+var ErrOutOfStock = errors.New("not enough items in stock")
+
+// ReserveStock takes the quantities of all lines out of stock in a single
+// transaction and records them under reservationId. Items are locked in id
+// order so concurrent reservations cannot deadlock. The reservation id is
+// claimed first in the same transaction, so a reservation id that is already
+// known, or being reserved by a concurrent retry, is left untouched, which
+// makes retries safe.
+func (sr *StockRepo) ReserveStock(ctx context.Context, reservationId string, lines []models.StockLine) error {
+	const op = "data.ReserveStock"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := sr.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+			INSERT INTO catalogue.reservation_ids (reservation_id)
+			VALUES ($1)
+			ON CONFLICT (reservation_id) DO NOTHING`, reservationId)
+	if err != nil {
+		return fail(err)
+	}
+	claimed, err := res.RowsAffected()
+	if err != nil {
+		return fail(err)
+	}
+	if claimed == 0 {
+		return nil
+	}
+
+	sorted := make([]models.StockLine, len(lines))
+	copy(sorted, lines)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ItemId < sorted[j].ItemId })
+
+	for _, line := range sorted {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE catalogue.item_info
+			SET quantity = quantity - $2
+			WHERE id = $1 AND quantity >= $2`, line.ItemId, line.Quantity)
+		if err != nil {
+			return fail(err)
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return fmt.Errorf("%w: item %d", ErrOutOfStock, line.ItemId)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO catalogue.stock_reservations (reservation_id, item_id, quantity)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (reservation_id, item_id)
+			DO UPDATE SET quantity = catalogue.stock_reservations.quantity + EXCLUDED.quantity`,
+			reservationId, line.ItemId, line.Quantity)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// ReleaseStock puts the items of a reservation back into stock. Releasing an
+// unknown or already released reservation does nothing.
+func (sr *StockRepo) ReleaseStock(ctx context.Context, reservationId string) error {
+	const op = "data.ReleaseStock"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+	query := `
+			WITH released AS (
+				UPDATE catalogue.stock_reservations
+				SET status = 'released', released_at = NOW()
+				WHERE reservation_id = $1 AND status = 'reserved'
+				RETURNING item_id, quantity
+			)
+			UPDATE catalogue.item_info i
+			SET quantity = i.quantity + r.quantity
+			FROM released r
+			WHERE i.id = r.item_id
+			RETURNING i.id, i.quantity - r.quantity`
+
+	tx, err := sr.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, reservationId)
+	if err != nil {
+		return fail(err)
+	}
+
+	var restocked []int32
+	for rows.Next() {
+		var itemId, before int32
+		if err := rows.Scan(&itemId, &before); err != nil {
+			rows.Close()
+			return fail(err)
+		}
+		if before == 0 {
+			restocked = append(restocked, itemId)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fail(err)
+	}
+
+	for _, itemId := range restocked {
+		if err = notifyBackInStock(ctx, tx, itemId); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// RestockItems puts returned items back into stock and records them under
+// restockId. A restock id that is already known is left untouched, which
+// makes retries safe.
+func (sr *StockRepo) RestockItems(ctx context.Context, restockId string, lines []models.StockLine) error {
+	const op = "data.RestockItems"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := sr.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback()
+
+	sorted := make([]models.StockLine, len(lines))
+	copy(sorted, lines)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ItemId < sorted[j].ItemId })
+
+	for _, line := range sorted {
+		var before int32
+		err := tx.QueryRowContext(ctx, `
+			WITH restocked AS (
+				INSERT INTO catalogue.stock_restocks (restock_id, item_id, quantity)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (restock_id, item_id) DO NOTHING
+				RETURNING item_id, quantity
+			)
+			UPDATE catalogue.item_info i
+			SET quantity = i.quantity + r.quantity
+			FROM restocked r
+			WHERE i.id = r.item_id
+			RETURNING i.quantity - r.quantity`, restockId, line.ItemId, line.Quantity).Scan(&before)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			continue
+		case err != nil:
+			return fail(err)
+		}
+
+		if before == 0 {
+			if err = notifyBackInStock(ctx, tx, line.ItemId); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// notifyBackInStock stores the event telling the users waiting for the item
+// that it is back, and marks their alerts as sent in the same transaction, so
+// that every alert is sent once per restock.
+func notifyBackInStock(ctx context.Context, tx *sql.Tx, itemId int32) error {
+	rows, err := tx.QueryContext(ctx, `
+			UPDATE catalogue.stock_alerts
+			SET notified_at = NOW()
+			WHERE item_id = $1 AND notified_at IS NULL
+			RETURNING user_id, item_id, email, username, created_at, notified_at`, itemId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var subscribers []*eventsp.Subscriber
+	for rows.Next() {
+		var alert models.StockAlert
+		err := rows.Scan(
+			&alert.UserId,
+			&alert.ItemId,
+			&alert.Email,
+			&alert.Username,
+			&alert.CreatedAt,
+			&alert.NotifiedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		subscribers = append(subscribers, &eventsp.Subscriber{
+			UserId:   alert.UserId,
+			Email:    alert.Email,
+			Username: alert.Username,
+		})
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(subscribers) == 0 {
+		return nil
+	}
+
+	var item models.Item
+	err = tx.QueryRowContext(ctx, `
+			SELECT id, name, price, description, quantity, image_url, category
+			FROM catalogue.item_info
+			WHERE id = $1`, itemId).Scan(
+		&item.ID,
+		&item.Name,
+		&item.Price,
+		&item.Description,
+		&item.Quantity,
+		&item.ImageURL,
+		&item.Category,
+	)
+	if err != nil {
+		return err
+	}
+
+	return insertOutboxEvent(ctx, tx, EventItemBackInStock, itemId, &eventsp.BackInStockEvent{
+		Item: &eventsp.Item{
+			Id:          item.ID,
+			Name:        item.Name,
+			Price:       item.Price,
+			Description: item.Description,
+			ImageUrl:    item.ImageURL,
+			Category:    item.Category,
+		},
+		Subscribers: subscribers,
+	})
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"database/sql"
 	"fmt"
+	"shared/outbox"
 )
 
 func New(dsn string) (*ItemRepo, error) {
@@ -15,3 +16,49 @@ func New(dsn string) (*ItemRepo, error) {
 
 	return &ItemRepo{DB: db}, nil
 }
+
+func NewReviewRepo(dsn string) (*ReviewRepo, error) {
+	const op = "data.NewReviewRepo"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &ReviewRepo{DB: db}, nil
+}
+
+func NewStockRepo(dsn string) (*StockRepo, error) {
+	const op = "data.NewStockRepo"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &StockRepo{DB: db}, nil
+}
+
+func NewWishlistRepo(dsn string) (*WishlistRepo, error) {
+	const op = "data.NewWishlistRepo"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &WishlistRepo{DB: db}, nil
+}
+
+// NewOutboxRepo returns the outbox the events of the catalogue service are
+// stored in until they are published.
+func NewOutboxRepo(dsn string) (*outbox.Store, error) {
+	const op = "data.NewOutboxRepo"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return outbox.NewStore(db, "catalogue.outbox"), nil
+}
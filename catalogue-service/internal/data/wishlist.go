@@ -0,0 +1,222 @@
+package data
+
+import (
+	"catalogue-service/internal/data/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+type WishlistRepo struct {
+	DB *sql.DB
+}
+
+var ErrItemInStock = errors.New("item is in stock")
+
+// AddToWishlist saves the item for the user. Adding an item twice keeps the
+// first entry.
+func (wr *WishlistRepo) AddToWishlist(ctx context.Context, userId int32, itemId int32) error {
+	const op = "data.AddToWishlist"
+
+	_, err := wr.DB.ExecContext(ctx, `
+			INSERT INTO catalogue.wishlist_items (user_id, item_id)
+			VALUES ($1, $2)
+			ON CONFLICT (user_id, item_id) DO NOTHING`, userId, itemId)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23503":
+			return ErrRecordNotFound
+		default:
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return nil
+}
+
+func (wr *WishlistRepo) RemoveFromWishlist(ctx context.Context, userId int32, itemId int32) error {
+	const op = "data.RemoveFromWishlist"
+
+	res, err := wr.DB.ExecContext(ctx, `
+			DELETE FROM catalogue.wishlist_items WHERE user_id = $1 AND item_id = $2`, userId, itemId)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetWishlist returns the items saved by the user, most recent first.
+func (wr *WishlistRepo) GetWishlist(ctx context.Context, userId int32) ([]*models.WishlistEntry, error) {
+	const op = "data.GetWishlist"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+	query := `
+			SELECT i.id, i.name, i.price, i.description, i.quantity, i.image_url, i.category,
+			       COALESCE(r.rating, 0), COALESCE(r.review_count, 0), w.created_at
+			FROM catalogue.wishlist_items w
+			JOIN catalogue.item_info i ON i.id = w.item_id
+			LEFT JOIN (
+				SELECT item_id, AVG(rating)::REAL AS rating, COUNT(*) AS review_count
+				FROM catalogue.reviews
+				WHERE status = 'approved'
+				GROUP BY item_id
+			) r ON r.item_id = i.id
+			WHERE w.user_id = $1
+			ORDER BY w.created_at DESC, i.id`
+
+	rows, err := wr.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var entries []*models.WishlistEntry
+	for rows.Next() {
+		var entry models.WishlistEntry
+		err := rows.Scan(
+			&entry.Item.ID,
+			&entry.Item.Name,
+			&entry.Item.Price,
+			&entry.Item.Description,
+			&entry.Item.Quantity,
+			&entry.Item.ImageURL,
+			&entry.Item.Category,
+			&entry.Item.Rating,
+			&entry.Item.ReviewCount,
+			&entry.AddedAt,
+		)
+		if err != nil {
+			return nil, fail(err)
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return entries, nil
+}
+
+// SubscribeStockAlert asks for the user to be told when the item, which has to
+// be out of stock, is back. Subscribing again re-arms an alert that was sent.
+func (wr *WishlistRepo) SubscribeStockAlert(ctx context.Context, alert *models.StockAlert) (*models.StockAlert, error) {
+	const op = "data.SubscribeStockAlert"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := wr.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer tx.Rollback()
+
+	// The row lock orders the subscription against concurrent restocks, so it
+	// is either seen by the restock or made after it and refused.
+	var quantity int32
+	err = tx.QueryRowContext(ctx, `
+			SELECT quantity FROM catalogue.item_info WHERE id = $1 FOR SHARE`, alert.ItemId).Scan(&quantity)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, fail(err)
+		}
+	}
+	if quantity > 0 {
+		return nil, ErrItemInStock
+	}
+
+	var saved models.StockAlert
+	err = tx.QueryRowContext(ctx, `
+			INSERT INTO catalogue.stock_alerts (user_id, item_id, email, username)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, item_id)
+			DO UPDATE SET email = EXCLUDED.email, username = EXCLUDED.username, created_at = NOW(), notified_at = NULL
+			RETURNING user_id, item_id, email, username, created_at, notified_at`,
+		alert.UserId, alert.ItemId, alert.Email, alert.Username).Scan(
+		&saved.UserId,
+		&saved.ItemId,
+		&saved.Email,
+		&saved.Username,
+		&saved.CreatedAt,
+		&saved.NotifiedAt,
+	)
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fail(err)
+	}
+
+	return &saved, nil
+}
+
+func (wr *WishlistRepo) UnsubscribeStockAlert(ctx context.Context, userId int32, itemId int32) error {
+	const op = "data.UnsubscribeStockAlert"
+
+	res, err := wr.DB.ExecContext(ctx, `
+			DELETE FROM catalogue.stock_alerts WHERE user_id = $1 AND item_id = $2`, userId, itemId)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ListStockAlerts returns the alerts of the user that were not sent yet.
+func (wr *WishlistRepo) ListStockAlerts(ctx context.Context, userId int32) ([]*models.StockAlert, error) {
+	const op = "data.ListStockAlerts"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := wr.DB.QueryContext(ctx, `
+			SELECT user_id, item_id, email, username, created_at, notified_at
+			FROM catalogue.stock_alerts
+			WHERE user_id = $1 AND notified_at IS NULL
+			ORDER BY created_at DESC, item_id`, userId)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var alerts []*models.StockAlert
+	for rows.Next() {
+		var alert models.StockAlert
+		err := rows.Scan(
+			&alert.UserId,
+			&alert.ItemId,
+			&alert.Email,
+			&alert.Username,
+			&alert.CreatedAt,
+			&alert.NotifiedAt,
+		)
+		if err != nil {
+			return nil, fail(err)
+		}
+
+		alerts = append(alerts, &alert)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return alerts, nil
+}
@@ -23,7 +23,8 @@ type Catalogue interface {
 		item *models.Item,
 	) (int32, error)
 	ListItems(
-		context.Context,
+		ctx context.Context,
+		sortBy string,
 	) ([]*models.Item, error)
 	GetItem(
 		context.Context,
@@ -81,8 +82,12 @@ func (cs *catalogueService) CreateItem(ctx context.Context, req *cataloguep.Crea
 func (cs *catalogueService) ListItems(ctx context.Context, req *cataloguep.ListItemsRequest) (*cataloguep.ListItemsResponse, error) {
 	var responseItems []*cataloguep.Item
 
-	items, err := cs.catalogue.ListItems(ctx)
+	items, err := cs.catalogue.ListItems(ctx, req.GetSortBy())
 	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUnknownSortKey):
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("unknown sort key '%s'", req.GetSortBy()))
+		}
 		return nil, err
 	}
 
@@ -117,6 +122,9 @@ func (cs *catalogueService) GetItem(ctx context.Context, req *cataloguep.GetItem
 		Description: item.Description,
 		Price:       item.Price,
 		Quantity:    item.Quantity,
+		Rating:      item.Rating,
+		ReviewCount: item.ReviewCount,
+		Category:    item.Category,
 	}
 	return &cataloguep.GetItemResponse{Item: itemResponse}, nil
 }
@@ -0,0 +1,145 @@
+package reviewGrpc
+
+import (
+	"catalogue-service/internal/data"
+	"catalogue-service/internal/data/models"
+	"catalogue-service/internal/services/review"
+	"context"
+	"errors"
+	cataloguep "github.com/sntabq/proto-gen/gen/go/catalogue"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Review interface {
+	CreateReview(ctx context.Context, review *models.Review) (*models.Review, error)
+	ListReviews(ctx context.Context, itemId int32, status string) ([]*models.Review, error)
+	VoteHelpful(ctx context.Context, reviewId int64, userId int32) (int32, error)
+	ModerateReview(ctx context.Context, reviewId int64, status string) (*models.Review, error)
+	HideReview(ctx context.Context, reviewId int64) (*models.Review, error)
+	RestoreReview(ctx context.Context, reviewId int64) (*models.Review, error)
+}
+
+type reviewService struct {
+	cataloguep.UnimplementedReviewServiceServer
+	review Review
+}
+
+// Register - for registering gRPC server
+func Register(gRPCServer *grpc.Server, review Review) {
+	cataloguep.RegisterReviewServiceServer(gRPCServer, &reviewService{review: review})
+}
+
+func (rs *reviewService) CreateReview(ctx context.Context, req *cataloguep.CreateReviewRequest) (*cataloguep.CreateReviewResponse, error) {
+	if req.GetReview() == nil {
+		return nil, status.Error(codes.InvalidArgument, "review is required")
+	}
+	if req.Review.ItemId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "item_id is required")
+	}
+
+	created, err := rs.review.CreateReview(ctx, &models.Review{
+		ItemId: req.Review.ItemId,
+		UserId: req.Review.UserId,
+		Rating: req.Review.Rating,
+		Body:   req.Review.Body,
+	})
+	if err != nil {
+		return nil, toStatus(err, "error with create review")
+	}
+
+	return &cataloguep.CreateReviewResponse{Review: toProto(created)}, nil
+}
+
+func (rs *reviewService) ListReviews(ctx context.Context, req *cataloguep.ListReviewsRequest) (*cataloguep.ListReviewsResponse, error) {
+	if req.GetItemId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "item_id is required")
+	}
+
+	reviews, err := rs.review.ListReviews(ctx, req.GetItemId(), req.GetStatus())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get reviews")
+	}
+
+	var responseReviews []*cataloguep.Review
+	for _, r := range reviews {
+		responseReviews = append(responseReviews, toProto(r))
+	}
+
+	return &cataloguep.ListReviewsResponse{Reviews: responseReviews}, nil
+}
+
+func (rs *reviewService) VoteReviewHelpful(ctx context.Context, req *cataloguep.VoteReviewHelpfulRequest) (*cataloguep.VoteReviewHelpfulResponse, error) {
+	if req.GetReviewId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "review_id is required")
+	}
+
+	helpfulCount, err := rs.review.VoteHelpful(ctx, req.GetReviewId(), req.GetUserId())
+	if err != nil {
+		return nil, toStatus(err, "error with vote for review")
+	}
+
+	return &cataloguep.VoteReviewHelpfulResponse{HelpfulCount: helpfulCount}, nil
+}
+
+func (rs *reviewService) ModerateReview(ctx context.Context, req *cataloguep.ModerateReviewRequest) (*cataloguep.ModerateReviewResponse, error) {
+	moderated, err := rs.review.ModerateReview(ctx, req.GetReviewId(), req.GetStatus())
+	if err != nil {
+		return nil, toStatus(err, "error with moderate review")
+	}
+
+	return &cataloguep.ModerateReviewResponse{Review: toProto(moderated)}, nil
+}
+
+func (rs *reviewService) HideReview(ctx context.Context, req *cataloguep.HideReviewRequest) (*cataloguep.HideReviewResponse, error) {
+	hidden, err := rs.review.HideReview(ctx, req.GetReviewId())
+	if err != nil {
+		return nil, toStatus(err, "error with hide review")
+	}
+
+	return &cataloguep.HideReviewResponse{Review: toProto(hidden)}, nil
+}
+
+func (rs *reviewService) RestoreReview(ctx context.Context, req *cataloguep.RestoreReviewRequest) (*cataloguep.RestoreReviewResponse, error) {
+	restored, err := rs.review.RestoreReview(ctx, req.GetReviewId())
+	if err != nil {
+		return nil, toStatus(err, "error with restore review")
+	}
+
+	return &cataloguep.RestoreReviewResponse{Review: toProto(restored)}, nil
+}
+
+func toStatus(err error, msg string) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "review or item not found")
+	case errors.Is(err, data.ErrReviewAlreadyExist):
+		return status.Error(codes.AlreadyExists, "item is already reviewed by user")
+	case errors.Is(err, data.ErrAlreadyVoted):
+		return status.Error(codes.AlreadyExists, "review is already voted by user")
+	case errors.Is(err, review.ErrInvalidRating), errors.Is(err, review.ErrOwnReview):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, review.ErrItemNotOrdered):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, review.ErrInvalidTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
+
+func toProto(r *models.Review) *cataloguep.Review {
+	return &cataloguep.Review{
+		Id:           r.ID,
+		ItemId:       r.ItemId,
+		UserId:       r.UserId,
+		Rating:       r.Rating,
+		Body:         r.Body,
+		Status:       r.Status,
+		HelpfulCount: r.HelpfulCount,
+		CreatedAt:    timestamppb.New(r.CreatedAt),
+		UpdatedAt:    timestamppb.New(r.UpdatedAt),
+	}
+}
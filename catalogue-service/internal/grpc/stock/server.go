@@ -0,0 +1,83 @@
+package stockGrpc
+
+import (
+	"catalogue-service/internal/data"
+	"catalogue-service/internal/data/models"
+	"catalogue-service/internal/services/stock"
+	"context"
+	"errors"
+	cataloguep "github.com/sntabq/proto-gen/gen/go/catalogue"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Stock interface {
+	ReserveStock(ctx context.Context, reservationId string, lines []models.StockLine) error
+	ReleaseStock(ctx context.Context, reservationId string) error
+	RestockItems(ctx context.Context, restockId string, lines []models.StockLine) error
+}
+
+type stockService struct {
+	cataloguep.UnimplementedStockServiceServer
+	stock Stock
+}
+
+// Register - for registering gRPC server
+func Register(gRPCServer *grpc.Server, stock Stock) {
+	cataloguep.RegisterStockServiceServer(gRPCServer, &stockService{stock: stock})
+}
+
+func (ss *stockService) ReserveStock(ctx context.Context, req *cataloguep.ReserveStockRequest) (*cataloguep.ReserveStockResponse, error) {
+	lines := make([]models.StockLine, 0, len(req.GetLines()))
+	for _, line := range req.GetLines() {
+		lines = append(lines, models.StockLine{ItemId: line.ItemId, Quantity: line.Quantity})
+	}
+
+	err := ss.stock.ReserveStock(ctx, req.GetReservationId(), lines)
+	if err != nil {
+		switch {
+		case errors.Is(err, stock.ErrInvalidReservation):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, data.ErrOutOfStock):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		default:
+			return nil, status.Error(codes.Internal, "failed to reserve stock")
+		}
+	}
+
+	return &cataloguep.ReserveStockResponse{}, nil
+}
+
+func (ss *stockService) ReleaseStock(ctx context.Context, req *cataloguep.ReleaseStockRequest) (*cataloguep.ReleaseStockResponse, error) {
+	err := ss.stock.ReleaseStock(ctx, req.GetReservationId())
+	if err != nil {
+		switch {
+		case errors.Is(err, stock.ErrInvalidReservation):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		default:
+			return nil, status.Error(codes.Internal, "failed to release stock")
+		}
+	}
+
+	return &cataloguep.ReleaseStockResponse{}, nil
+}
+
+func (ss *stockService) RestockItems(ctx context.Context, req *cataloguep.RestockItemsRequest) (*cataloguep.RestockItemsResponse, error) {
+	lines := make([]models.StockLine, 0, len(req.GetLines()))
+	for _, line := range req.GetLines() {
+		lines = append(lines, models.StockLine{ItemId: line.ItemId, Quantity: line.Quantity})
+	}
+
+	err := ss.stock.RestockItems(ctx, req.GetRestockId(), lines)
+	if err != nil {
+		switch {
+		case errors.Is(err, stock.ErrInvalidReservation):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		default:
+			return nil, status.Error(codes.Internal, "failed to restock items")
+		}
+	}
+
+	return &cataloguep.RestockItemsResponse{}, nil
+}
@@ -0,0 +1,139 @@
+package wishlistGrpc
+
+import (
+	"catalogue-service/internal/data"
+	"catalogue-service/internal/data/models"
+	"context"
+	"errors"
+	cataloguep "github.com/sntabq/proto-gen/gen/go/catalogue"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Wishlist interface {
+	AddToWishlist(ctx context.Context, itemId int32) error
+	RemoveFromWishlist(ctx context.Context, itemId int32) error
+	GetWishlist(ctx context.Context) ([]*models.WishlistEntry, error)
+	SubscribeStockAlert(ctx context.Context, itemId int32) (*models.StockAlert, error)
+	UnsubscribeStockAlert(ctx context.Context, itemId int32) error
+	ListStockAlerts(ctx context.Context) ([]*models.StockAlert, error)
+}
+
+type wishlistService struct {
+	cataloguep.UnimplementedWishlistServiceServer
+	wishlist Wishlist
+}
+
+// Register - for registering gRPC server
+func Register(gRPCServer *grpc.Server, wishlist Wishlist) {
+	cataloguep.RegisterWishlistServiceServer(gRPCServer, &wishlistService{wishlist: wishlist})
+}
+
+func (ws *wishlistService) AddToWishlist(ctx context.Context, req *cataloguep.AddToWishlistRequest) (*cataloguep.AddToWishlistResponse, error) {
+	if req.GetItemId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "item_id is required")
+	}
+
+	if err := ws.wishlist.AddToWishlist(ctx, req.GetItemId()); err != nil {
+		return nil, toStatus(err, "error with add to wishlist")
+	}
+
+	return &cataloguep.AddToWishlistResponse{}, nil
+}
+
+func (ws *wishlistService) RemoveFromWishlist(ctx context.Context, req *cataloguep.RemoveFromWishlistRequest) (*cataloguep.RemoveFromWishlistResponse, error) {
+	if req.GetItemId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "item_id is required")
+	}
+
+	if err := ws.wishlist.RemoveFromWishlist(ctx, req.GetItemId()); err != nil {
+		return nil, toStatus(err, "error with remove from wishlist")
+	}
+
+	return &cataloguep.RemoveFromWishlistResponse{}, nil
+}
+
+func (ws *wishlistService) GetWishlist(ctx context.Context, req *cataloguep.GetWishlistRequest) (*cataloguep.GetWishlistResponse, error) {
+	entries, err := ws.wishlist.GetWishlist(ctx)
+	if err != nil {
+		return nil, toStatus(err, "error with get wishlist")
+	}
+
+	response := make([]*cataloguep.WishlistEntry, 0, len(entries))
+	for _, e := range entries {
+		response = append(response, &cataloguep.WishlistEntry{
+			Item: &cataloguep.Item{
+				Id:          e.Item.ID,
+				Name:        e.Item.Name,
+				Price:       e.Item.Price,
+				Description: e.Item.Description,
+				Quantity:    e.Item.Quantity,
+				Rating:      e.Item.Rating,
+				ReviewCount: e.Item.ReviewCount,
+				Category:    e.Item.Category,
+			},
+			AddedAt: timestamppb.New(e.AddedAt),
+		})
+	}
+
+	return &cataloguep.GetWishlistResponse{Entries: response}, nil
+}
+
+func (ws *wishlistService) SubscribeStockAlert(ctx context.Context, req *cataloguep.SubscribeStockAlertRequest) (*cataloguep.SubscribeStockAlertResponse, error) {
+	if req.GetItemId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "item_id is required")
+	}
+
+	alert, err := ws.wishlist.SubscribeStockAlert(ctx, req.GetItemId())
+	if err != nil {
+		return nil, toStatus(err, "error with subscribe to stock alert")
+	}
+
+	return &cataloguep.SubscribeStockAlertResponse{Alert: toProtoAlert(alert)}, nil
+}
+
+func (ws *wishlistService) UnsubscribeStockAlert(ctx context.Context, req *cataloguep.UnsubscribeStockAlertRequest) (*cataloguep.UnsubscribeStockAlertResponse, error) {
+	if req.GetItemId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "item_id is required")
+	}
+
+	if err := ws.wishlist.UnsubscribeStockAlert(ctx, req.GetItemId()); err != nil {
+		return nil, toStatus(err, "error with unsubscribe from stock alert")
+	}
+
+	return &cataloguep.UnsubscribeStockAlertResponse{}, nil
+}
+
+func (ws *wishlistService) ListStockAlerts(ctx context.Context, req *cataloguep.ListStockAlertsRequest) (*cataloguep.ListStockAlertsResponse, error) {
+	alerts, err := ws.wishlist.ListStockAlerts(ctx)
+	if err != nil {
+		return nil, toStatus(err, "error with list stock alerts")
+	}
+
+	response := make([]*cataloguep.StockAlert, 0, len(alerts))
+	for _, a := range alerts {
+		response = append(response, toProtoAlert(a))
+	}
+
+	return &cataloguep.ListStockAlertsResponse{Alerts: response}, nil
+}
+
+func toStatus(err error, msg string) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "item not found")
+	case errors.Is(err, data.ErrItemInStock):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
+
+func toProtoAlert(a *models.StockAlert) *cataloguep.StockAlert {
+	return &cataloguep.StockAlert{
+		ItemId:    a.ItemId,
+		CreatedAt: timestamppb.New(a.CreatedAt),
+	}
+}
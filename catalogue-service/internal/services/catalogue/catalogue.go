@@ -35,7 +35,8 @@ type CatalogueProvider interface {
 		item *models.Item,
 	) (int32, error)
 	GetAllItems(
-		context.Context,
+		ctx context.Context,
+		sortBy string,
 	) ([]*models.Item, error)
 	GetItemById(
 		context.Context,
@@ -68,15 +69,16 @@ func (c *Catalogue) CreateItem(ctx context.Context, item *models.Item) (int32, e
 	return id, nil
 }
 
-func (c *Catalogue) ListItems(ctx context.Context) ([]*models.Item, error) {
+func (c *Catalogue) ListItems(ctx context.Context, sortBy string) ([]*models.Item, error) {
 	const op = "Catalogue.ListItems"
 	log := c.log.With(
 		slog.String("op", op),
+		slog.String("sort by", sortBy),
 	)
 
 	log.Info("attempting to get all items")
 
-	items, err := c.catalogueProvider.GetAllItems(ctx)
+	items, err := c.catalogueProvider.GetAllItems(ctx, sortBy)
 	if err != nil {
 		c.log.Warn("failed to get all items", sl.Err(err))
 		return nil, err
@@ -0,0 +1,188 @@
+package review
+
+import (
+	"catalogue-service/internal/data"
+	"catalogue-service/internal/data/models"
+	"catalogue-service/internal/sl"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusHidden   = "hidden"
+)
+
+var (
+	ErrInvalidRating     = errors.New("rating must be between 1 and 5")
+	ErrItemNotOrdered    = errors.New("only customers who ordered the item can review it")
+	ErrInvalidTransition = errors.New("review status transition is not allowed")
+	ErrOwnReview         = errors.New("cannot vote for own review")
+)
+
+// moderationTransitions lists, for every status a moderator can set, the
+// statuses a review may be moved from.
+var moderationTransitions = map[string][]string{
+	StatusApproved: {StatusPending, StatusRejected},
+	StatusRejected: {StatusPending, StatusApproved},
+}
+
+type Review struct {
+	log            *slog.Logger
+	reviewProvider ReviewProvider
+}
+
+func New(
+	log *slog.Logger,
+	reviewProvider ReviewProvider,
+) *Review {
+	return &Review{
+		log:            log,
+		reviewProvider: reviewProvider,
+	}
+}
+
+type ReviewProvider interface {
+	HasOrderedItem(ctx context.Context, userId int32, itemId int32) (bool, error)
+	SaveReview(ctx context.Context, review *models.Review) (*models.Review, error)
+	GetReviewById(ctx context.Context, id int64) (*models.Review, error)
+	GetReviewsByItemId(ctx context.Context, itemId int32, status string) ([]*models.Review, error)
+	UpdateReviewStatus(ctx context.Context, id int64, from []string, status string) (*models.Review, error)
+	VoteHelpful(ctx context.Context, reviewId int64, userId int32) (int32, error)
+}
+
+func (r *Review) CreateReview(ctx context.Context, review *models.Review) (*models.Review, error) {
+	const op = "Review.CreateReview"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.Int("item id", int(review.ItemId)),
+		slog.Int("user id", int(review.UserId)),
+	)
+
+	log.Info("attempting to create review")
+
+	if review.Rating < 1 || review.Rating > 5 {
+		return nil, ErrInvalidRating
+	}
+
+	ordered, err := r.reviewProvider.HasOrderedItem(ctx, review.UserId, review.ItemId)
+	if err != nil {
+		log.Warn("failed to check orders of user", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !ordered {
+		return nil, ErrItemNotOrdered
+	}
+
+	review.Status = StatusPending
+	review, err = r.reviewProvider.SaveReview(ctx, review)
+	if err != nil {
+		log.Warn("failed to save review", sl.Err(err))
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func (r *Review) ListReviews(ctx context.Context, itemId int32, status string) ([]*models.Review, error) {
+	const op = "Review.ListReviews"
+	log := r.log.With(
+		slog.String("op", op),
+		slog.Int("item id", int(itemId)),
+	)
+
+	log.Info("attempting to get reviews of item")
+
+	if status == "" {
+		status = StatusApproved
+	}
+
+	reviews, err := r.reviewProvider.GetReviewsByItemId(ctx, itemId, status)
+	if err != nil {
+		log.Warn("failed to get reviews", sl.Err(err))
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+func (r *Review) VoteHelpful(ctx context.Context, reviewId int64, userId int32) (int32, error) {
+	const op = "Review.VoteHelpful"
+	log := r.log.With(
+		slog.String("op", op),
+		slog.Int64("review id", reviewId),
+	)
+
+	log.Info("attempting to vote for review")
+
+	review, err := r.reviewProvider.GetReviewById(ctx, reviewId)
+	if err != nil {
+		log.Warn("failed to get review", sl.Err(err))
+		return 0, err
+	}
+	if review.Status != StatusApproved {
+		return 0, data.ErrRecordNotFound
+	}
+	if review.UserId == userId {
+		return 0, ErrOwnReview
+	}
+
+	helpfulCount, err := r.reviewProvider.VoteHelpful(ctx, reviewId, userId)
+	if err != nil {
+		log.Warn("failed to vote for review", sl.Err(err))
+		return 0, err
+	}
+
+	return helpfulCount, nil
+}
+
+// ModerateReview approves or rejects a review.
+func (r *Review) ModerateReview(ctx context.Context, reviewId int64, status string) (*models.Review, error) {
+	from, ok := moderationTransitions[status]
+	if !ok {
+		return nil, ErrInvalidTransition
+	}
+
+	return r.changeStatus(ctx, reviewId, from, status)
+}
+
+// HideReview takes an approved review out of the storefront.
+func (r *Review) HideReview(ctx context.Context, reviewId int64) (*models.Review, error) {
+	return r.changeStatus(ctx, reviewId, []string{StatusApproved}, StatusHidden)
+}
+
+// RestoreReview brings a hidden review back to the storefront.
+func (r *Review) RestoreReview(ctx context.Context, reviewId int64) (*models.Review, error) {
+	return r.changeStatus(ctx, reviewId, []string{StatusHidden}, StatusApproved)
+}
+
+func (r *Review) changeStatus(ctx context.Context, reviewId int64, from []string, status string) (*models.Review, error) {
+	const op = "Review.changeStatus"
+	log := r.log.With(
+		slog.String("op", op),
+		slog.Int64("review id", reviewId),
+		slog.String("status", status),
+	)
+
+	log.Info("attempting to change review status")
+
+	review, err := r.reviewProvider.UpdateReviewStatus(ctx, reviewId, from, status)
+	if err != nil {
+		// The update matches no row either when the review does not exist or
+		// when it is not in one of the expected states.
+		if errors.Is(err, data.ErrRecordNotFound) {
+			if _, getErr := r.reviewProvider.GetReviewById(ctx, reviewId); getErr == nil {
+				return nil, ErrInvalidTransition
+			}
+		}
+		log.Warn("failed to change review status", sl.Err(err))
+		return nil, err
+	}
+
+	return review, nil
+}
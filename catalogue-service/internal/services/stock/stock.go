@@ -0,0 +1,105 @@
+package stock
+
+import (
+	"catalogue-service/internal/data/models"
+	"catalogue-service/internal/sl"
+	"context"
+	"errors"
+	"log/slog"
+)
+
+var ErrInvalidReservation = errors.New("reservation needs an id and lines with positive quantities")
+
+type Stock struct {
+	log           *slog.Logger
+	stockProvider StockProvider
+}
+
+func New(
+	log *slog.Logger,
+	stockProvider StockProvider,
+) *Stock {
+	return &Stock{
+		log:           log,
+		stockProvider: stockProvider,
+	}
+}
+
+type StockProvider interface {
+	ReserveStock(ctx context.Context, reservationId string, lines []models.StockLine) error
+	ReleaseStock(ctx context.Context, reservationId string) error
+	RestockItems(ctx context.Context, restockId string, lines []models.StockLine) error
+}
+
+func (s *Stock) ReserveStock(ctx context.Context, reservationId string, lines []models.StockLine) error {
+	const op = "Stock.ReserveStock"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("reservation id", reservationId),
+	)
+
+	log.Info("attempting to reserve stock")
+
+	if reservationId == "" || len(lines) == 0 {
+		return ErrInvalidReservation
+	}
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return ErrInvalidReservation
+		}
+	}
+
+	if err := s.stockProvider.ReserveStock(ctx, reservationId, lines); err != nil {
+		log.Warn("failed to reserve stock", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *Stock) ReleaseStock(ctx context.Context, reservationId string) error {
+	const op = "Stock.ReleaseStock"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("reservation id", reservationId),
+	)
+
+	log.Info("attempting to release stock")
+
+	if reservationId == "" {
+		return ErrInvalidReservation
+	}
+
+	if err := s.stockProvider.ReleaseStock(ctx, reservationId); err != nil {
+		log.Warn("failed to release stock", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *Stock) RestockItems(ctx context.Context, restockId string, lines []models.StockLine) error {
+	const op = "Stock.RestockItems"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("restock id", restockId),
+	)
+
+	log.Info("attempting to restock items")
+
+	if restockId == "" || len(lines) == 0 {
+		return ErrInvalidReservation
+	}
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return ErrInvalidReservation
+		}
+	}
+
+	if err := s.stockProvider.RestockItems(ctx, restockId, lines); err != nil {
+		log.Warn("failed to restock items", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package wishlist
+
+import (
+	grpcapp "catalogue-service/internal/app/grpc"
+	"catalogue-service/internal/data/models"
+	"catalogue-service/internal/sl"
+	"context"
+	"errors"
+	"log/slog"
+)
+
+var ErrUnauthenticated = errors.New("caller is not authenticated")
+
+type Wishlist struct {
+	log              *slog.Logger
+	wishlistProvider WishlistProvider
+}
+
+func New(
+	log *slog.Logger,
+	wishlistProvider WishlistProvider,
+) *Wishlist {
+	return &Wishlist{
+		log:              log,
+		wishlistProvider: wishlistProvider,
+	}
+}
+
+type WishlistProvider interface {
+	AddToWishlist(ctx context.Context, userId int32, itemId int32) error
+	RemoveFromWishlist(ctx context.Context, userId int32, itemId int32) error
+	GetWishlist(ctx context.Context, userId int32) ([]*models.WishlistEntry, error)
+	SubscribeStockAlert(ctx context.Context, alert *models.StockAlert) (*models.StockAlert, error)
+	UnsubscribeStockAlert(ctx context.Context, userId int32, itemId int32) error
+	ListStockAlerts(ctx context.Context, userId int32) ([]*models.StockAlert, error)
+}
+
+func (w *Wishlist) AddToWishlist(ctx context.Context, itemId int32) error {
+	const op = "Wishlist.AddToWishlist"
+
+	user, ok := grpcapp.UserFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	log := w.log.With(
+		slog.String("op", op),
+		slog.Int("user id", int(user.Id)),
+		slog.Int("item id", int(itemId)),
+	)
+
+	log.Info("attempting to add item to wishlist")
+
+	if err := w.wishlistProvider.AddToWishlist(ctx, user.Id, itemId); err != nil {
+		log.Warn("failed to add item to wishlist", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (w *Wishlist) RemoveFromWishlist(ctx context.Context, itemId int32) error {
+	const op = "Wishlist.RemoveFromWishlist"
+
+	user, ok := grpcapp.UserFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	log := w.log.With(
+		slog.String("op", op),
+		slog.Int("user id", int(user.Id)),
+		slog.Int("item id", int(itemId)),
+	)
+
+	log.Info("attempting to remove item from wishlist")
+
+	if err := w.wishlistProvider.RemoveFromWishlist(ctx, user.Id, itemId); err != nil {
+		log.Warn("failed to remove item from wishlist", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (w *Wishlist) GetWishlist(ctx context.Context) ([]*models.WishlistEntry, error) {
+	const op = "Wishlist.GetWishlist"
+
+	user, ok := grpcapp.UserFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	entries, err := w.wishlistProvider.GetWishlist(ctx, user.Id)
+	if err != nil {
+		w.log.Warn("failed to get wishlist", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// SubscribeStockAlert asks for the caller to be emailed when the item, which
+// has to be out of stock, is back.
+func (w *Wishlist) SubscribeStockAlert(ctx context.Context, itemId int32) (*models.StockAlert, error) {
+	const op = "Wishlist.SubscribeStockAlert"
+
+	user, ok := grpcapp.UserFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	log := w.log.With(
+		slog.String("op", op),
+		slog.Int("user id", int(user.Id)),
+		slog.Int("item id", int(itemId)),
+	)
+
+	log.Info("attempting to subscribe to stock alert")
+
+	alert, err := w.wishlistProvider.SubscribeStockAlert(ctx, &models.StockAlert{
+		UserId:   user.Id,
+		ItemId:   itemId,
+		Email:    user.Email,
+		Username: user.Username,
+	})
+	if err != nil {
+		log.Warn("failed to subscribe to stock alert", sl.Err(err))
+		return nil, err
+	}
+
+	return alert, nil
+}
+
+func (w *Wishlist) UnsubscribeStockAlert(ctx context.Context, itemId int32) error {
+	const op = "Wishlist.UnsubscribeStockAlert"
+
+	user, ok := grpcapp.UserFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	if err := w.wishlistProvider.UnsubscribeStockAlert(ctx, user.Id, itemId); err != nil {
+		w.log.Warn("failed to unsubscribe from stock alert", slog.String("op", op), sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (w *Wishlist) ListStockAlerts(ctx context.Context) ([]*models.StockAlert, error) {
+	const op = "Wishlist.ListStockAlerts"
+
+	user, ok := grpcapp.UserFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	alerts, err := w.wishlistProvider.ListStockAlerts(ctx, user.Id)
+	if err != nil {
+		w.log.Warn("failed to list stock alerts", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS catalogue.review_votes;
+DROP TABLE IF EXISTS catalogue.reviews;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS catalogue.reviews(
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY ,
+    item_id INTEGER NOT NULL REFERENCES catalogue.item_info(id) ON DELETE CASCADE ,
+    user_id BIGINT NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE ,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5) ,
+    body TEXT NOT NULL DEFAULT '' ,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' ,
+    helpful_count INTEGER NOT NULL DEFAULT 0 ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    CONSTRAINT reviews_item_id_user_id_key UNIQUE (item_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS reviews_item_id_status_idx ON catalogue.reviews (item_id, status);
+
+CREATE TABLE IF NOT EXISTS catalogue.review_votes(
+    review_id BIGINT NOT NULL REFERENCES catalogue.reviews(id) ON DELETE CASCADE ,
+    user_id BIGINT NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE ,
+    PRIMARY KEY (review_id, user_id)
+);
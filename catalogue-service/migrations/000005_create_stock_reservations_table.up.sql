@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS catalogue.stock_reservations(
+    reservation_id VARCHAR(64) NOT NULL ,
+    item_id INTEGER NOT NULL REFERENCES catalogue.item_info(id) ON DELETE CASCADE ,
+    quantity INTEGER NOT NULL CHECK (quantity > 0) ,
+    status VARCHAR(20) NOT NULL DEFAULT 'reserved' ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    released_at TIMESTAMP(0) WITH TIME ZONE ,
+    PRIMARY KEY (reservation_id, item_id)
+);
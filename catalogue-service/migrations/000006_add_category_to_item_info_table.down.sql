@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS catalogue.item_info
+    DROP COLUMN IF EXISTS category;
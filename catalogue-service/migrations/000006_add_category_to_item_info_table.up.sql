@@ -0,0 +1,4 @@
+ALTER TABLE IF EXISTS catalogue.item_info
+    ADD COLUMN IF NOT EXISTS category VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS item_info_category_idx ON catalogue.item_info (category);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS catalogue.stock_restocks(
+    restock_id VARCHAR(64) NOT NULL ,
+    item_id INTEGER NOT NULL REFERENCES catalogue.item_info(id) ON DELETE CASCADE ,
+    quantity INTEGER NOT NULL CHECK (quantity > 0) ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    PRIMARY KEY (restock_id, item_id)
+);
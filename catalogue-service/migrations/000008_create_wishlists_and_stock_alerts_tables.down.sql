@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS catalogue.outbox;
+DROP TABLE IF EXISTS catalogue.stock_alerts;
+DROP TABLE IF EXISTS catalogue.wishlist_items;
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS catalogue.wishlist_items(
+    user_id INTEGER NOT NULL ,
+    item_id INTEGER NOT NULL REFERENCES catalogue.item_info(id) ON DELETE CASCADE ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    PRIMARY KEY (user_id, item_id)
+);
+
+CREATE TABLE IF NOT EXISTS catalogue.stock_alerts(
+    user_id INTEGER NOT NULL ,
+    item_id INTEGER NOT NULL REFERENCES catalogue.item_info(id) ON DELETE CASCADE ,
+    email VARCHAR(255) NOT NULL ,
+    username VARCHAR(255) NOT NULL DEFAULT '' ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    notified_at TIMESTAMP(0) WITH TIME ZONE ,
+    PRIMARY KEY (user_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS stock_alerts_pending_idx ON catalogue.stock_alerts (item_id)
+    WHERE notified_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS catalogue.outbox(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    aggregate_id BIGINT NOT NULL ,
+    event_type VARCHAR(64) NOT NULL ,
+    payload JSONB NOT NULL ,
+    attempts INTEGER NOT NULL DEFAULT 0 ,
+    last_error TEXT NOT NULL DEFAULT '' ,
+    next_attempt_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    published_at TIMESTAMP(0) WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS outbox_unpublished_idx ON catalogue.outbox (next_attempt_at, id)
+    WHERE published_at IS NULL;
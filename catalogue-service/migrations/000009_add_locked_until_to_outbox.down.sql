@@ -0,0 +1 @@
+ALTER TABLE catalogue.outbox DROP COLUMN IF EXISTS locked_until;
@@ -0,0 +1 @@
+ALTER TABLE catalogue.outbox ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP(0) WITH TIME ZONE;
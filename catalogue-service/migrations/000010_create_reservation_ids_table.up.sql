@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS catalogue.reservation_ids(
+    reservation_id VARCHAR(64) PRIMARY KEY ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO catalogue.reservation_ids (reservation_id, created_at)
+SELECT reservation_id, MIN(created_at) FROM catalogue.stock_reservations GROUP BY reservation_id
+ON CONFLICT (reservation_id) DO NOTHING;
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"net/http"
+)
+
+// reasonKeyReused is the reason the order service marks the error of a
+// request reusing an idempotency key for a different order with.
+const reasonKeyReused = "IDEMPOTENCY_KEY_REUSED"
+
+// errorHandler answers errors like the default handler of the gateway, except
+// for the reuse of an idempotency key, which is answered with 422.
+func errorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	if s, ok := status.FromError(err); ok {
+		for _, d := range s.Details() {
+			if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetReason() == reasonKeyReused {
+				err = &runtime.HTTPStatusError{HTTPStatus: http.StatusUnprocessableEntity, Err: err}
+				break
+			}
+		}
+	}
+
+	runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+}
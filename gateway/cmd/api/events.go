@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	order "github.com/sntabq/proto-gen/gen/go/order"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// heartbeatInterval keeps idle event streams open through proxies.
+const heartbeatInterval = 15 * time.Second
+
+// reconnectDelay is how long browsers wait before they watch again once a
+// stream ended.
+const reconnectDelay = 3 * time.Second
+
+type statusStream interface {
+	grpc.ClientStream
+	Recv() (*order.OrderStatusChange, error)
+}
+
+// orderEventsHandler serves the status changes of an order as Server-Sent
+// Events.
+func orderEventsHandler(client order.OrderServiceClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		orderId, err := strconv.ParseInt(params["order_id"], 10, 32)
+		if err != nil {
+			http.Error(w, "invalid order id", http.StatusBadRequest)
+			return
+		}
+
+		stream, err := client.WatchOrder(eventsContext(r), &order.WatchOrderRequest{OrderId: int32(orderId)})
+		serveStatusEvents(w, r, stream, err)
+	}
+}
+
+// myOrderEventsHandler serves the status changes of all orders of the caller
+// as Server-Sent Events.
+func myOrderEventsHandler(client order.OrderServiceClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		stream, err := client.WatchMyOrders(eventsContext(r), &order.WatchMyOrdersRequest{})
+		serveStatusEvents(w, r, stream, err)
+	}
+}
+
+// eventsContext passes the token of the caller on. EventSource can not set
+// headers, so browsers may send it as the access_token query parameter.
+func eventsContext(r *http.Request) context.Context {
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		token = r.URL.Query().Get("access_token")
+	}
+
+	return metadata.AppendToOutgoingContext(r.Context(), "authorization", token)
+}
+
+func serveStatusEvents(w http.ResponseWriter, r *http.Request, stream statusStream, err error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		writeEventsError(w, err)
+		return
+	}
+
+	// The order service sends its headers once the caller was let in; a
+	// stream that ends without them was refused.
+	md, err := stream.Header()
+	if err == nil && md == nil {
+		_, err = stream.Recv()
+	}
+	if err != nil {
+		writeEventsError(w, err)
+		return
+	}
+
+	changes := make(chan *order.OrderStatusChange)
+	done := make(chan error, 1)
+	go func() {
+		for {
+			change, err := stream.Recv()
+			if err != nil {
+				done <- err
+				return
+			}
+			select {
+			case changes <- change:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay.Milliseconds())
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case err := <-done:
+			if status.Code(err) != codes.Unavailable && status.Code(err) != codes.Canceled {
+				log.Printf("order event stream failed: %v", err)
+			}
+			return
+		case change := <-changes:
+			data, err := protojson.Marshal(change)
+			if err != nil {
+				log.Printf("failed to encode order status change: %v", err)
+				return
+			}
+			fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEventsError(w http.ResponseWriter, err error) {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		http.Error(w, "authentication is required", http.StatusUnauthorized)
+	case codes.PermissionDenied:
+		http.Error(w, "permission denied", http.StatusForbidden)
+	case codes.NotFound:
+		http.Error(w, "order not found", http.StatusNotFound)
+	case codes.InvalidArgument:
+		http.Error(w, "invalid order id", http.StatusBadRequest)
+	default:
+		log.Printf("order event stream failed: %v", err)
+		http.Error(w, "failed to watch orders", http.StatusInternalServerError)
+	}
+}
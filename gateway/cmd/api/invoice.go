@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	order "github.com/sntabq/proto-gen/gen/go/order"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// invoiceHandler streams the PDF of the invoice of an order to the client as
+// it arrives from the order service.
+func invoiceHandler(client order.OrderServiceClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		orderId, err := strconv.ParseInt(params["order_id"], 10, 32)
+		if err != nil {
+			http.Error(w, "invalid order id", http.StatusBadRequest)
+			return
+		}
+
+		ctx := metadata.AppendToOutgoingContext(r.Context(), "authorization", r.Header.Get("Authorization"))
+		stream, err := client.GetInvoice(ctx, &order.GetInvoiceRequest{OrderId: int32(orderId)})
+		if err != nil {
+			writeInvoiceError(w, err)
+			return
+		}
+
+		// Errors are only reported until the first chunk, as the status
+		// line is sent with it.
+		chunk, err := stream.Recv()
+		if err != nil {
+			writeInvoiceError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", chunk.GetContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", chunk.GetFilename()))
+		for {
+			if _, err = w.Write(chunk.GetData()); err != nil {
+				return
+			}
+
+			chunk, err = stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				log.Printf("invoice stream of order %d failed: %v", orderId, err)
+				return
+			}
+		}
+	}
+}
+
+func writeInvoiceError(w http.ResponseWriter, err error) {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		http.Error(w, "authentication is required", http.StatusUnauthorized)
+	case codes.PermissionDenied:
+		http.Error(w, "permission denied", http.StatusForbidden)
+	case codes.NotFound:
+		http.Error(w, "order not found", http.StatusNotFound)
+	case codes.FailedPrecondition:
+		http.Error(w, "order has not been invoiced yet", http.StatusConflict)
+	case codes.InvalidArgument:
+		http.Error(w, "invalid order id", http.StatusBadRequest)
+	default:
+		log.Printf("invoice request failed: %v", err)
+		http.Error(w, "failed to get invoice", http.StatusInternalServerError)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/rs/cors"
 	auth "github.com/sntabq/proto-gen/gen/go/auth"
 	catalogue "github.com/sntabq/proto-gen/gen/go/catalogue"
+	notification "github.com/sntabq/proto-gen/gen/go/notification"
 	order "github.com/sntabq/proto-gen/gen/go/order"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -20,9 +21,13 @@ func main() {
 		runtime.WithMetadata(func(ctx context.Context, req *http.Request) metadata.MD {
 			// Extract headers and return as metadata
 			md := metadata.Pairs("authorization", req.Header.Get("Authorization"))
+			if key := req.Header.Get("Idempotency-Key"); key != "" {
+				md.Append("idempotency-key", key)
+			}
 			log.Printf("Extracted metadata: %v", md)
 			return md
 		}),
+		runtime.WithErrorHandler(errorHandler),
 	)
 
 	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
@@ -31,16 +36,132 @@ func main() {
 		panic(err)
 	}
 
+	err = auth.RegisterAddressBookHandlerFromEndpoint(context.Background(), mux, "localhost:44044", opts)
+	if err != nil {
+		panic(err)
+	}
+
 	err = catalogue.RegisterCatalogueServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44045", opts)
 	if err != nil {
 		panic(err)
 	}
 
+	err = catalogue.RegisterWishlistServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44045", opts)
+	if err != nil {
+		panic(err)
+	}
+
+	err = catalogue.RegisterReviewServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44045", opts)
+	if err != nil {
+		panic(err)
+	}
+
 	err = order.RegisterOrderServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44046", opts)
 	if err != nil {
 		panic(err)
 	}
 
+	err = order.RegisterCartServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44046", opts)
+	if err != nil {
+		panic(err)
+	}
+
+	err = order.RegisterPaymentServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44046", opts)
+	if err != nil {
+		panic(err)
+	}
+
+	err = order.RegisterCouponServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44046", opts)
+	if err != nil {
+		panic(err)
+	}
+
+	err = order.RegisterReturnServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44046", opts)
+	if err != nil {
+		panic(err)
+	}
+
+	err = order.RegisterReportServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44046", opts)
+	if err != nil {
+		panic(err)
+	}
+
+	err = notification.RegisterTemplateServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44047", opts)
+	if err != nil {
+		panic(err)
+	}
+
+	err = notification.RegisterInboxServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44047", opts)
+	if err != nil {
+		panic(err)
+	}
+
+	err = notification.RegisterPreferenceServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44047", opts)
+	if err != nil {
+		panic(err)
+	}
+
+	err = notification.RegisterDeliveryServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44047", opts)
+	if err != nil {
+		panic(err)
+	}
+
+	err = notification.RegisterWebhookServiceHandlerFromEndpoint(context.Background(), mux, "localhost:44047", opts)
+	if err != nil {
+		panic(err)
+	}
+
+	orderConn, err := grpc.Dial("localhost:44046", opts...)
+	if err != nil {
+		panic(err)
+	}
+	defer orderConn.Close()
+
+	err = mux.HandlePath("POST", "/webhooks/payments", paymentWebhookHandler(order.NewPaymentServiceClient(orderConn)))
+	if err != nil {
+		panic(err)
+	}
+
+	orderClient := order.NewOrderServiceClient(orderConn)
+
+	err = mux.HandlePath("GET", "/v1/orders/{order_id}/invoice", invoiceHandler(orderClient))
+	if err != nil {
+		panic(err)
+	}
+
+	err = mux.HandlePath("GET", "/v1/orders/{order_id}/events", orderEventsHandler(orderClient))
+	if err != nil {
+		panic(err)
+	}
+
+	err = mux.HandlePath("GET", "/v1/orders/events", myOrderEventsHandler(orderClient))
+	if err != nil {
+		panic(err)
+	}
+
+	err = mux.HandlePath("GET", "/v1/reports/{report}/csv", reportCSVHandler(order.NewReportServiceClient(orderConn)))
+	if err != nil {
+		panic(err)
+	}
+
+	notificationConn, err := grpc.Dial("localhost:44047", opts...)
+	if err != nil {
+		panic(err)
+	}
+	defer notificationConn.Close()
+
+	preferenceClient := notification.NewPreferenceServiceClient(notificationConn)
+
+	err = mux.HandlePath("GET", "/v1/unsubscribe", unsubscribeHandler(preferenceClient))
+	if err != nil {
+		panic(err)
+	}
+
+	err = mux.HandlePath("POST", "/v1/unsubscribe", unsubscribeHandler(preferenceClient))
+	if err != nil {
+		panic(err)
+	}
+
 	handler := cors.Default().Handler(mux)
 
 	err = http.ListenAndServe(":8080", handler)
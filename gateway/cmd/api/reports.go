@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	order "github.com/sntabq/proto-gen/gen/go/order"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// reportCSVHandler exports a sales report as CSV. The report is picked by the
+// report path parameter and filtered by the from, to and category query
+// parameters, with granularity for sales and limit for top items.
+func reportCSVHandler(client order.ReportServiceClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		query := r.URL.Query()
+		filter, err := reportFilter(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx := metadata.AppendToOutgoingContext(r.Context(), "authorization", r.Header.Get("Authorization"))
+
+		var records [][]string
+		switch params["report"] {
+		case "sales":
+			res, err := client.GetSalesReport(ctx, &order.GetSalesReportRequest{
+				Filter:      filter,
+				Granularity: query.Get("granularity"),
+			})
+			if err != nil {
+				writeReportError(w, err)
+				return
+			}
+
+			records = append(records, []string{"period_start", "orders", "units", "revenue", "average_order_value"})
+			for _, p := range res.GetPeriods() {
+				records = append(records, []string{
+					p.GetPeriodStart().AsTime().Format(time.DateOnly),
+					strconv.Itoa(int(p.GetOrders())),
+					strconv.Itoa(int(p.GetUnits())),
+					strconv.FormatInt(p.GetRevenue(), 10),
+					strconv.FormatInt(p.GetAverageOrderValue(), 10),
+				})
+			}
+		case "top-items":
+			limit, err := strconv.Atoi(query.Get("limit"))
+			if err != nil && query.Get("limit") != "" {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+
+			res, err := client.GetTopItems(ctx, &order.GetTopItemsRequest{Filter: filter, Limit: int32(limit)})
+			if err != nil {
+				writeReportError(w, err)
+				return
+			}
+
+			records = append(records, []string{"item_id", "name", "category", "units", "orders", "revenue"})
+			for _, item := range res.GetItems() {
+				records = append(records, []string{
+					strconv.Itoa(int(item.GetItemId())),
+					item.GetName(),
+					item.GetCategory(),
+					strconv.Itoa(int(item.GetUnits())),
+					strconv.Itoa(int(item.GetOrders())),
+					strconv.FormatInt(item.GetRevenue(), 10),
+				})
+			}
+		case "customers":
+			res, err := client.GetCustomerReport(ctx, &order.GetCustomerReportRequest{Filter: filter})
+			if err != nil {
+				writeReportError(w, err)
+				return
+			}
+
+			records = [][]string{
+				{"segment", "customers", "revenue"},
+				{"new", strconv.Itoa(int(res.GetNewCustomers())), strconv.FormatInt(res.GetNewRevenue(), 10)},
+				{"returning", strconv.Itoa(int(res.GetReturningCustomers())), strconv.FormatInt(res.GetReturningRevenue(), 10)},
+			}
+		case "conversion":
+			res, err := client.GetConversionReport(ctx, &order.GetConversionReportRequest{Filter: filter})
+			if err != nil {
+				writeReportError(w, err)
+				return
+			}
+
+			records = [][]string{
+				{"carts", "checked_out", "conversion_rate"},
+				{
+					strconv.Itoa(int(res.GetCarts())),
+					strconv.Itoa(int(res.GetCheckedOut())),
+					strconv.FormatFloat(res.GetConversionRate(), 'f', 4, 64),
+				},
+			}
+		default:
+			http.Error(w, "unknown report", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", params["report"]+".csv"))
+		if err = csv.NewWriter(w).WriteAll(records); err != nil {
+			log.Printf("writing %s report failed: %v", params["report"], err)
+		}
+	}
+}
+
+// reportFilter reads the filter of a report from the query. Dates are either
+// RFC 3339 timestamps or plain dates in UTC.
+func reportFilter(query url.Values) (*order.ReportFilter, error) {
+	filter := &order.ReportFilter{Category: query.Get("category")}
+	for _, bound := range []struct {
+		name string
+		dst  **timestamppb.Timestamp
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t, err = time.Parse(time.DateOnly, value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s date", bound.name)
+		}
+		*bound.dst = timestamppb.New(t)
+	}
+
+	return filter, nil
+}
+
+func writeReportError(w http.ResponseWriter, err error) {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		http.Error(w, "authentication is required", http.StatusUnauthorized)
+	case codes.PermissionDenied:
+		http.Error(w, "permission denied", http.StatusForbidden)
+	case codes.InvalidArgument:
+		http.Error(w, status.Convert(err).Message(), http.StatusBadRequest)
+	default:
+		log.Printf("report request failed: %v", err)
+		http.Error(w, "failed to get report", http.StatusInternalServerError)
+	}
+}
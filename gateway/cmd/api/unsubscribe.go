@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	notification "github.com/sntabq/proto-gen/gen/go/notification"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// unsubscribePage asks the user to confirm, so that link scanners opening the
+// link of an email do not unsubscribe them.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!doctype html>
+<html>
+<head><meta name="viewport" content="width=device-width" /><title>Unsubscribe</title></head>
+<body>
+{{ if .Done }}<p>You have been unsubscribed.</p>
+{{ else }}<form method="post" action="?token={{ .Token }}">
+<p>Stop receiving these emails?</p>
+<button type="submit">Unsubscribe</button>
+</form>{{ end }}
+</body>
+</html>
+`))
+
+// unsubscribeHandler serves the unsubscribe links of the emails. GET shows a
+// confirmation page, and POST unsubscribes, both from that page and as the
+// one-click unsubscribe of mail clients.
+func unsubscribeHandler(client notification.PreferenceServiceClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			http.Error(w, "token is required", http.StatusBadRequest)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			_, err := client.Unsubscribe(r.Context(), &notification.UnsubscribeRequest{Token: token})
+			if err != nil {
+				log.Printf("unsubscribe failed: %v", err)
+				switch status.Code(err) {
+				case codes.PermissionDenied, codes.InvalidArgument:
+					http.Error(w, "invalid unsubscribe link", http.StatusBadRequest)
+				default:
+					http.Error(w, "failed to unsubscribe", http.StatusInternalServerError)
+				}
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := unsubscribePage.Execute(w, struct {
+			Token string
+			Done  bool
+		}{Token: token, Done: r.Method == http.MethodPost})
+		if err != nil {
+			log.Printf("failed to render unsubscribe page: %v", err)
+		}
+	}
+}
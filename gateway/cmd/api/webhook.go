@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	order "github.com/sntabq/proto-gen/gen/go/order"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"log"
+	"net/http"
+)
+
+// maxWebhookBody bounds the size of a payment provider webhook.
+const maxWebhookBody = 64 << 10
+
+// paymentWebhookHandler passes payment provider webhooks to the order service
+// untouched, as the signature is computed over the raw body.
+func paymentWebhookHandler(client order.PaymentServiceClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		_, err = client.HandlePaymentWebhook(r.Context(), &order.HandlePaymentWebhookRequest{
+			Payload:   payload,
+			Signature: r.Header.Get("X-Payment-Signature"),
+		})
+		if err != nil {
+			log.Printf("payment webhook failed: %v", err)
+			switch status.Code(err) {
+			case codes.Unauthenticated:
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+			case codes.NotFound:
+				http.Error(w, "unknown payment", http.StatusNotFound)
+			case codes.InvalidArgument:
+				http.Error(w, "invalid payload", http.StatusBadRequest)
+			default:
+				http.Error(w, "failed to handle webhook", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -5,7 +5,7 @@ go 1.21
 require (
 	github.com/grpc-ecosystem/grpc-gateway/v2 v2.20.0 // indirect
 	github.com/rs/cors v1.11.0 // indirect
-	github.com/sntabq/proto-gen v0.0.0-20240604204705-5b85fce0a239 // indirect
+	github.com/sntabq/proto-gen v0.0.0-00010101000000-000000000000 // indirect
 	golang.org/x/net v0.23.0 // indirect
 	golang.org/x/sys v0.18.0 // indirect
 	golang.org/x/text v0.15.0 // indirect
@@ -14,3 +14,5 @@ require (
 	google.golang.org/grpc v1.64.0 // indirect
 	google.golang.org/protobuf v1.34.1 // indirect
 )
+
+replace github.com/sntabq/proto-gen => ../gateway/third_party/protos
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	eventsp "github.com/sntabq/proto-gen/gen/go/events"
+	"google.golang.org/protobuf/encoding/protojson"
+	"io"
+	"log/slog"
+	"notification-service/internal/channel"
+	"notification-service/internal/data"
+	"notification-service/internal/data/dto"
+	"notification-service/internal/events"
+	"notification-service/internal/followup"
+	"notification-service/internal/partner"
+	"notification-service/internal/scheduler"
+	"notification-service/internal/templates"
+	"notification-service/internal/unsubscribe"
+	"shared/broker"
+	sharedevents "shared/events"
+	"shared/outbox"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The flow tests store events in an outbox the way the order service does,
+// and run them through the outbox relay and one memory broker into the
+// handler of the service, with the databases and the email provider faked.
+
+func TestOrderCreatedFlow(t *testing.T) {
+	p := newPipeline(t)
+
+	event, eventId := orderCreated(t, 42)
+	p.store(event)
+	if err := p.wait(t); err != nil {
+		t.Fatalf("handling order.created: %v", err)
+	}
+
+	sent := p.email.notifications()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(sent))
+	}
+	n := sent[0]
+	if n.EventId != eventId || n.Email != "jane@example.com" || n.OrderId != 42 {
+		t.Errorf("sent %+v, want the order 42 email to jane@example.com", n)
+	}
+	if want := "Thank you for your order #42"; n.Message.Subject != want {
+		t.Errorf("subject = %q, want %q", n.Message.Subject, want)
+	}
+	if want := []string{"cart.abandoned/user:7"}; fmt.Sprint(p.jobs.cancelled()) != fmt.Sprint(want) {
+		t.Errorf("cancelled jobs %v, want %v", p.jobs.cancelled(), want)
+	}
+	if want := []string{eventId + "/order.created"}; fmt.Sprint(p.webhooks.enqueued()) != fmt.Sprint(want) {
+		t.Errorf("queued webhook deliveries %v, want %v", p.webhooks.enqueued(), want)
+	}
+
+	// The relay publishes at least once, so an event whose lease expired
+	// before it was marked published is published again. The duplicate is not
+	// sent again.
+	p.store(event)
+	if err := p.wait(t); err != nil {
+		t.Fatalf("handling the duplicate: %v", err)
+	}
+	if n := len(p.email.notifications()); n != 1 {
+		t.Errorf("sent %d emails after the duplicate, want 1", n)
+	}
+}
+
+func TestMalformedEventIsDeadLettered(t *testing.T) {
+	p := newPipeline(t)
+
+	p.publish(t, &broker.Message{
+		ID:          "garbage",
+		Type:        "order.created",
+		ContentType: events.ContentType,
+		Body:        []byte("not protobuf"),
+	})
+	if err := p.wait(t); !broker.IsPermanent(err) {
+		t.Fatalf("handling a malformed event failed with %v, want a permanent error", err)
+	}
+
+	dead := p.broker.DeadLetters()
+	if len(dead) != 1 || dead[0].ID != "garbage" {
+		t.Errorf("dead letters %v, want the malformed event", dead)
+	}
+	if n := len(p.email.notifications()); n != 0 {
+		t.Errorf("sent %d emails, want none", n)
+	}
+}
+
+type pipeline struct {
+	outbox   *outboxStore
+	broker   *broker.Memory
+	email    *recorder
+	jobs     *jobStore
+	webhooks *webhookStore
+	handled  chan error
+}
+
+// newPipeline runs the outbox relay of the order service and the handler of
+// the notification service on the same memory broker until the test ends.
+func newPipeline(t *testing.T) *pipeline {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	registry := templates.New(log, "", nil)
+	if err := registry.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &pipeline{
+		outbox:   &outboxStore{},
+		broker:   broker.NewMemory(2, 0, time.Millisecond),
+		email:    &recorder{},
+		jobs:     &jobStore{},
+		webhooks: &webhookStore{},
+		handled:  make(chan error, 1),
+	}
+
+	dispatcher := channel.NewDispatcher(log, preferences{}, &deliveryLog{}, p.email)
+	links := unsubscribe.New("secret", "http://localhost/v1/unsubscribe")
+	jobs := scheduler.New(log, p.jobs, time.Minute, 10, time.Minute, 1, time.Minute)
+	followUps := followup.New(log, jobs, dispatcher, registry, links, followUpStore{}, time.Hour, time.Hour, "")
+
+	partners := partner.NewDispatcher(log, p.webhooks, time.Second, time.Minute, 10, time.Minute, 1, time.Minute, time.Minute, time.Hour)
+
+	handle := handleMessage(dispatcher, registry, links, followUps, partners, invoiceStore{}, log)
+
+	relay := outbox.New(log, p.outbox, p.broker, time.Millisecond, 10, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{}, 2)
+	go func() {
+		defer func() { done <- struct{}{} }()
+		relay.Run(ctx)
+	}()
+	go func() {
+		defer func() { done <- struct{}{} }()
+		p.broker.Run(ctx, func(ctx context.Context, msg *broker.Message) error {
+			err := handle(ctx, msg)
+			p.handled <- err
+			return err
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		<-done
+	})
+
+	return p
+}
+
+// store adds an event to the outbox the relay publishes from.
+func (p *pipeline) store(event *outbox.Event) {
+	p.outbox.add(event)
+}
+
+func (p *pipeline) publish(t *testing.T, msg *broker.Message) {
+	t.Helper()
+
+	if err := p.broker.Publish(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wait returns the outcome of the next message handled.
+func (p *pipeline) wait(t *testing.T) error {
+	t.Helper()
+
+	select {
+	case err := <-p.handled:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message was handled")
+		return nil
+	}
+}
+
+// orderCreated returns an order.created event as the order service stores it
+// in its outbox, and the id of the event.
+func orderCreated(t *testing.T, orderId int32) (*outbox.Event, string) {
+	t.Helper()
+
+	payload, err := sharedevents.Marshal("order-service", "order.created", fmt.Sprintf("order-%d", orderId), &eventsp.OrderEvent{
+		User: &eventsp.User{Id: 7, Username: "Jane", Email: "jane@example.com"},
+		Order: &eventsp.Order{
+			Id:     orderId,
+			UserId: 7,
+			Status: "created",
+			Lines: []*eventsp.OrderLine{
+				{Item: &eventsp.Item{Id: 1, Name: "Mug", Price: 1200}, Quantity: 2, UnitPrice: 1200},
+			},
+			Subtotal: 2400,
+			Total:    2400,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var envelope eventsp.Envelope
+	if err = protojson.Unmarshal(payload, &envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	event := &outbox.Event{
+		AggregateId: orderId,
+		EventType:   "order.created",
+		Payload:     payload,
+		CreatedAt:   time.Now(),
+	}
+
+	return event, envelope.GetEventId()
+}
+
+// outboxStore is an outbox whose events are published once claimed.
+type outboxStore struct {
+	mu      sync.Mutex
+	lastId  int64
+	pending []*outbox.Event
+}
+
+func (s *outboxStore) add(event *outbox.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastId++
+	e := *event
+	e.ID = s.lastId
+	s.pending = append(s.pending, &e)
+}
+
+func (s *outboxStore) ClaimPending(_ context.Context, limit int, _ time.Duration) ([]*outbox.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claimed := s.pending[:min(limit, len(s.pending))]
+	s.pending = s.pending[len(claimed):]
+
+	return claimed, nil
+}
+
+func (s *outboxStore) MarkPublished(context.Context, *outbox.Event) error {
+	return nil
+}
+
+func (s *outboxStore) MarkFailed(context.Context, *outbox.Event, error) error {
+	return nil
+}
+
+// recorder is an email channel that keeps what it sends.
+type recorder struct {
+	mu   sync.Mutex
+	sent []*channel.Notification
+}
+
+func (r *recorder) Name() string {
+	return channel.Email
+}
+
+func (r *recorder) Send(_ context.Context, n *channel.Notification) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sent = append(r.sent, n)
+
+	return "", nil
+}
+
+func (r *recorder) notifications() []*channel.Notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*channel.Notification(nil), r.sent...)
+}
+
+// preferences subscribes every user to email only.
+type preferences struct{}
+
+func (preferences) IsSubscribed(context.Context, int32, string) (bool, error) {
+	return true, nil
+}
+
+func (preferences) GetChannels(context.Context, int32, string) ([]string, error) {
+	return []string{channel.Email}, nil
+}
+
+func (preferences) GetContacts(context.Context, int32) (*dto.ContactsDTO, error) {
+	return &dto.ContactsDTO{}, nil
+}
+
+// deliveryLog remembers the sent deliveries.
+type deliveryLog struct {
+	mu   sync.Mutex
+	sent map[string]bool
+}
+
+func (l *deliveryLog) IsDelivered(_ context.Context, eventId string, userId int32, channel string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.sent[deliveryKey(eventId, userId, channel)], nil
+}
+
+func (l *deliveryLog) RecordDelivery(_ context.Context, d *dto.DeliveryDTO) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if d.Status == channel.StatusSent {
+		if l.sent == nil {
+			l.sent = map[string]bool{}
+		}
+		l.sent[deliveryKey(d.EventId, d.UserId, d.Channel)] = true
+	}
+
+	return nil
+}
+
+func deliveryKey(eventId string, userId int32, channel string) string {
+	return fmt.Sprintf("%s/%d/%s", eventId, userId, channel)
+}
+
+// jobStore records the cancelled jobs and keeps no others.
+type jobStore struct {
+	mu     sync.Mutex
+	cancel []string
+}
+
+func (s *jobStore) SaveJob(context.Context, *scheduler.Job) error {
+	return nil
+}
+
+func (s *jobStore) EnsureJob(context.Context, *scheduler.Job) error {
+	return nil
+}
+
+func (s *jobStore) CancelJobs(_ context.Context, kind, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cancel = append(s.cancel, kind+"/"+key)
+
+	return false, nil
+}
+
+func (s *jobStore) ClaimJob(context.Context, time.Duration) (*scheduler.Job, error) {
+	return nil, nil
+}
+
+func (s *jobStore) RescheduleJob(context.Context, *scheduler.Job) error {
+	return nil
+}
+
+func (s *jobStore) FinishJob(context.Context, *scheduler.Job, string, string) error {
+	return nil
+}
+
+func (s *jobStore) cancelled() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.cancel...)
+}
+
+// followUpStore has no carts, items or users.
+type followUpStore struct{}
+
+func (followUpStore) HasOpenCart(context.Context, int32) (bool, error) {
+	return false, nil
+}
+
+func (followUpStore) GetLatestItemId(context.Context) (int32, error) {
+	return 0, nil
+}
+
+func (followUpStore) GetNewItems(context.Context, int32, int) ([]dto.ItemDTO, error) {
+	return nil, nil
+}
+
+func (followUpStore) GetDigestRecipients(context.Context, int32, int) ([]dto.UserDTO, error) {
+	return nil, nil
+}
+
+// invoiceStore has no invoices.
+type invoiceStore struct{}
+
+func (invoiceStore) GetInvoicePDF(context.Context, int32) ([]byte, error) {
+	return nil, data.ErrInvoiceNotFound
+}
+
+// webhookStore records the events queued for webhooks and has no deliveries
+// to post.
+type webhookStore struct {
+	mu    sync.Mutex
+	queue []string
+}
+
+func (s *webhookStore) EnqueueWebhookDeliveries(_ context.Context, eventId, eventType string, _ []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queue = append(s.queue, eventId+"/"+eventType)
+
+	return nil
+}
+
+func (s *webhookStore) ClaimWebhookDelivery(context.Context, time.Duration) (*partner.Attempt, error) {
+	return nil, nil
+}
+
+func (s *webhookStore) RecordWebhookSuccess(context.Context, *partner.Attempt, int) error {
+	return nil
+}
+
+func (s *webhookStore) RecordWebhookFailure(context.Context, *partner.Attempt, int, string, *time.Time) (time.Time, error) {
+	return time.Now(), nil
+}
+
+func (s *webhookStore) DisableWebhook(context.Context, int64, string) error {
+	return nil
+}
+
+func (s *webhookStore) enqueued() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.queue...)
+}
@@ -0,0 +1,156 @@
+// Command dlq inspects and replays the messages in the dead letter queue.
+//
+//	dlq list [-limit n]
+//	dlq replay [-limit n] [-id message-id]
+//
+// list prints the dead-lettered messages and leaves them in the queue. replay
+// publishes them back to the shop queue with their retry count reset, all of
+// them or only the one with the given message id.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"notification-service/config"
+	"notification-service/internal/consumer"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const confirmTimeout = 5 * time.Second
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	limit := flags.Int("limit", 100, "maximum number of messages to process")
+	id := flags.String("id", "", "only replay the message with this id")
+	_ = flags.Parse(os.Args[2:])
+
+	cfg := config.LoadConfig()
+	conn, err := amqp.Dial(cfg.RabbitMQ.URL)
+	failOnError(err, "failed to connect to RabbitMQ")
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	failOnError(err, "failed to open a channel")
+	defer ch.Close()
+
+	failOnError(consumer.Declare(ch, cfg.RabbitMQ.MaxRetries), "failed to declare queues")
+
+	switch os.Args[1] {
+	case "list":
+		failOnError(list(ch, *limit), "failed to list messages")
+	case "replay":
+		failOnError(ch.Confirm(false), "failed to enable confirms")
+		replayed, err := replay(ch, *limit, *id)
+		fmt.Printf("replayed %d message(s)\n", replayed)
+		failOnError(err, "failed to replay messages")
+	default:
+		usage()
+	}
+}
+
+// list prints up to limit dead-lettered messages. The messages are not
+// acknowledged, so the broker puts them back when the channel is closed.
+func list(ch *amqp.Channel, limit int) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MESSAGE ID\tTYPE\tPUBLISHED\tRETRIES\tERROR")
+
+	for i := 0; i < limit; i++ {
+		d, ok, err := ch.Get(consumer.DeadLetterQueue, false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		reason, _ := d.Headers[consumer.ErrorHeader].(string)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			d.MessageId,
+			d.Type,
+			d.Timestamp.Format(time.RFC3339),
+			consumer.RetryCount(d),
+			reason,
+		)
+	}
+
+	return w.Flush()
+}
+
+// replay moves up to limit dead-lettered messages, or only the one with id,
+// back to the shop queue. A message is only removed from the dead letter queue
+// once the broker confirmed its copy.
+func replay(ch *amqp.Channel, limit int, id string) (int, error) {
+	replayed := 0
+	for i := 0; i < limit; i++ {
+		d, ok, err := ch.Get(consumer.DeadLetterQueue, false)
+		if err != nil {
+			return replayed, err
+		}
+		if !ok {
+			break
+		}
+		if id != "" && d.MessageId != id {
+			continue
+		}
+
+		msg := consumer.Copy(d)
+		delete(msg.Headers, consumer.RetryCountHeader)
+		delete(msg.Headers, consumer.ErrorHeader)
+		delete(msg.Headers, "x-death")
+
+		if err = publish(ch, msg); err != nil {
+			_ = d.Nack(false, true)
+			return replayed, err
+		}
+		if err = d.Ack(false); err != nil {
+			return replayed, err
+		}
+		replayed++
+
+		if id != "" {
+			break
+		}
+	}
+
+	return replayed, nil
+}
+
+func publish(ch *amqp.Channel, msg amqp.Publishing) error {
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, "", consumer.QueueName, false, false, msg)
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return consumer.ErrNacked
+	}
+
+	return nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dlq list [-limit n] | dlq replay [-limit n] [-id message-id]")
+	os.Exit(2)
+}
+
+func failOnError(err error, msg string) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", msg, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,72 @@
+// Command smsstub is a stand-in SMS provider for local runs and tests of the
+// SMS channel. It logs the texts it accepts instead of sending them, and can
+// be told to fail so that retries can be tried out.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"notification-service/internal/channel"
+	"sync"
+)
+
+type stub struct {
+	apiKey string
+	fail   bool
+
+	mu  sync.Mutex
+	seq int
+}
+
+func main() {
+	addr := flag.String("addr", ":8091", "address to listen on")
+	apiKey := flag.String("api-key", "local-sms-key", "API key clients must send")
+	fail := flag.Bool("fail", false, "answer every message with 503")
+	flag.Parse()
+
+	s := &stub{apiKey: *apiKey, fail: *fail}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/messages", s.messages)
+
+	log.Printf("sms stub listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func (s *stub) messages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+		return
+	}
+	if s.fail {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req channel.SMSRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.To == "" || req.Text == "" {
+		http.Error(w, "to and text are required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.seq++
+	id := fmt.Sprintf("sms_%d", s.seq)
+	s.mu.Unlock()
+
+	log.Printf("%s from %s to %s: %s", id, req.From, req.To, req.Text)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(channel.SMSResponse{Id: id})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"notification-service/internal/channel"
+	"notification-service/internal/templates"
+	"testing"
+)
+
+// TestStubAcceptsMessages texts through the SMS channel the way the service
+// does in local runs.
+func TestStubAcceptsMessages(t *testing.T) {
+	provider := newProvider(t, &stub{apiKey: "key"})
+
+	sms := channel.NewSMSChannel(provider.URL, "key", "OnlineShop")
+	for _, want := range []string{"sms_1", "sms_2"} {
+		id, err := sms.Send(context.Background(), text("+4915112345678"))
+		if err != nil {
+			t.Fatalf("Send() = %v", err)
+		}
+		if id != want {
+			t.Errorf("Send() = %q, want %q", id, want)
+		}
+	}
+}
+
+func TestStubRejectsMessages(t *testing.T) {
+	for name, tc := range map[string]struct {
+		stub    *stub
+		apiKey  string
+		subject string
+	}{
+		"wrong api key": {stub: &stub{apiKey: "key"}, apiKey: "other", subject: "Shipped"},
+		"failing":       {stub: &stub{apiKey: "key", fail: true}, apiKey: "key", subject: "Shipped"},
+		"no text":       {stub: &stub{apiKey: "key"}, apiKey: "key"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			provider := newProvider(t, tc.stub)
+
+			sms := channel.NewSMSChannel(provider.URL, tc.apiKey, "OnlineShop")
+			n := text("+4915112345678")
+			n.Message.Subject = tc.subject
+			if _, err := sms.Send(context.Background(), n); err == nil {
+				t.Error("Send() succeeded, want it rejected")
+			}
+		})
+	}
+}
+
+func newProvider(t *testing.T, s *stub) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/messages", s.messages)
+
+	provider := httptest.NewServer(mux)
+	t.Cleanup(provider.Close)
+
+	return provider
+}
+
+func text(phone string) *channel.Notification {
+	return &channel.Notification{Phone: phone, Message: &templates.Message{Subject: "Your order #42 has shipped"}}
+}
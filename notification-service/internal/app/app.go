@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"notification-service/config"
+	grpcapp "notification-service/internal/app/grpc"
+	"notification-service/internal/channel"
+	"notification-service/internal/data"
+	"notification-service/internal/followup"
+	"notification-service/internal/partner"
+	"notification-service/internal/ratelimit"
+	"notification-service/internal/scheduler"
+	"notification-service/internal/services/delivery"
+	"notification-service/internal/services/inbox"
+	"notification-service/internal/services/preference"
+	"notification-service/internal/services/webhook"
+	"notification-service/internal/templates"
+	"notification-service/internal/unsubscribe"
+	"notification-service/mailer"
+)
+
+type App struct {
+	GRPCServer  *grpcapp.App
+	Templates   *templates.Registry
+	Dispatcher  *channel.Dispatcher
+	Unsubscribe *unsubscribe.Signer
+	Scheduler   *scheduler.Scheduler
+	FollowUps   *followup.FollowUps
+	Partners    *partner.Dispatcher
+	Invoices    *data.InvoiceStorage
+}
+
+// digestOff is the digest schedule that disables the digest.
+const digestOff = "off"
+
+func New(
+	log *slog.Logger,
+	cfg *config.Config,
+	m mailer.Mailer,
+) *App {
+	var store templates.Store
+	if cfg.Templates.Database {
+		templateStorage, err := data.NewTemplateStorage(cfg.StoragePath)
+		if err != nil {
+			panic(err)
+		}
+		store = templateStorage
+	}
+
+	registry := templates.New(log, cfg.Templates.Dir, store)
+	err := registry.Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	inboxStorage, err := data.NewInboxStorage(cfg.StoragePath)
+	if err != nil {
+		panic(err)
+	}
+
+	preferenceStorage, err := data.NewPreferenceStorage(cfg.StoragePath)
+	if err != nil {
+		panic(err)
+	}
+
+	channels := []channel.Channel{
+		channel.NewEmailChannel(log, m),
+		channel.NewWebhookChannel(cfg.Channels.WebhookTimeout, cfg.Channels.WebhookSecret),
+		channel.NewInAppChannel(inboxStorage),
+	}
+	if sms := cfg.Channels.SMS; sms.URL != "" {
+		channels = append(channels, channel.NewSMSChannel(sms.URL, sms.APIKey, sms.Sender))
+	}
+	for i, c := range channels {
+		if limits, ok := cfg.RateLimits[c.Name()]; ok {
+			channels[i] = channel.Limit(
+				c,
+				ratelimit.New(limits.Rate, limits.Burst),
+				ratelimit.NewDomains(limits.DomainRate, limits.DomainBurst, limits.Domains),
+			)
+		}
+	}
+	deliveryStorage, err := data.NewDeliveryStorage(cfg.StoragePath)
+	if err != nil {
+		panic(err)
+	}
+
+	dispatcher := channel.NewDispatcher(log, preferenceStorage, deliveryStorage, channels...)
+
+	signer := unsubscribe.New(cfg.Unsubscribe.Secret, cfg.Unsubscribe.URL)
+
+	jobStorage, err := data.NewJobStorage(cfg.StoragePath)
+	if err != nil {
+		panic(err)
+	}
+
+	followUpStorage, err := data.NewFollowUpStorage(cfg.StoragePath)
+	if err != nil {
+		panic(err)
+	}
+
+	jobs := scheduler.New(
+		log,
+		jobStorage,
+		cfg.Scheduler.PollInterval,
+		cfg.Scheduler.BatchSize,
+		cfg.Scheduler.Lease,
+		cfg.Scheduler.MaxAttempts,
+		cfg.Scheduler.RetryBaseDelay,
+	)
+
+	digestSchedule := cfg.Scheduler.DigestSchedule
+	if digestSchedule == digestOff {
+		digestSchedule = ""
+	}
+	followUps := followup.New(
+		log,
+		jobs,
+		dispatcher,
+		registry,
+		signer,
+		followUpStorage,
+		cfg.Scheduler.AbandonedCartDelay,
+		cfg.Scheduler.ReviewRequestDelay,
+		digestSchedule,
+	)
+	if err = followUps.Register(context.Background()); err != nil {
+		panic(err)
+	}
+
+	webhookStorage, err := data.NewWebhookStorage(cfg.StoragePath)
+	if err != nil {
+		panic(err)
+	}
+
+	partners := partner.NewDispatcher(
+		log,
+		webhookStorage,
+		cfg.Partners.Timeout,
+		cfg.Partners.PollInterval,
+		cfg.Partners.BatchSize,
+		cfg.Partners.Lease,
+		cfg.Partners.MaxAttempts,
+		cfg.Partners.RetryBaseDelay,
+		cfg.Partners.MaxRetryDelay,
+		cfg.Partners.DisableAfter,
+	)
+
+	invoiceStorage, err := data.NewInvoiceStorage(cfg.StoragePath)
+	if err != nil {
+		panic(err)
+	}
+
+	inboxService := inbox.New(log, inboxStorage)
+	preferenceService := preference.New(log, preferenceStorage, signer)
+	deliveryService := delivery.New(log, deliveryStorage, dispatcher)
+	webhookService := webhook.New(log, webhookStorage)
+
+	grpcApp := grpcapp.New(log, registry, inboxService, preferenceService, deliveryService, webhookService, cfg.Port)
+
+	return &App{
+		GRPCServer:  grpcApp,
+		Templates:   registry,
+		Dispatcher:  dispatcher,
+		Unsubscribe: signer,
+		Scheduler:   jobs,
+		FollowUps:   followUps,
+		Partners:    partners,
+		Invoices:    invoiceStorage,
+	}
+}
@@ -0,0 +1,116 @@
+package grpcapp
+
+import (
+	"fmt"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+	auth "github.com/sntabq/proto-gen/gen/go/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"log"
+	"log/slog"
+	"net"
+	deliveryGrpc "notification-service/internal/grpc/delivery"
+	inboxGrpc "notification-service/internal/grpc/inbox"
+	preferenceGrpc "notification-service/internal/grpc/preference"
+	templateGrpc "notification-service/internal/grpc/template"
+	webhookGrpc "notification-service/internal/grpc/webhook"
+)
+
+type App struct {
+	log        *slog.Logger
+	grpcServer *grpc.Server
+	port       int
+}
+
+var AuthServiceClient auth.AuthClient
+var UserInfoServiceClient auth.UserInfoClient
+
+func New(
+	log *slog.Logger,
+	templates templateGrpc.Templates,
+	inbox inboxGrpc.Inbox,
+	preferences preferenceGrpc.Preferences,
+	deliveries deliveryGrpc.Deliveries,
+	webhooks webhookGrpc.Webhooks,
+	port int,
+) *App {
+	loggingOpts := []logging.Option{
+		logging.WithLogOnEvents(
+			logging.PayloadReceived, logging.PayloadSent,
+		),
+	}
+
+	recoveryOpts := []recovery.Option{
+		recovery.WithRecoveryHandler(func(p interface{}) (err error) {
+			log.Error("Recovered from panic", slog.Any("panic", p))
+
+			return status.Errorf(codes.Internal, "internal error")
+		}),
+	}
+
+	gRPCServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		recovery.UnaryServerInterceptor(recoveryOpts...),
+		logging.UnaryServerInterceptor(InterceptorLogger(log), loggingOpts...),
+		AdminInterceptorTemplates,
+		InterceptorUser,
+	))
+
+	templateGrpc.Register(gRPCServer, templates)
+	inboxGrpc.Register(gRPCServer, inbox)
+	preferenceGrpc.Register(gRPCServer, preferences)
+	deliveryGrpc.Register(gRPCServer, deliveries)
+	webhookGrpc.Register(gRPCServer, webhooks)
+
+	return &App{
+		log:        log,
+		port:       port,
+		grpcServer: gRPCServer,
+	}
+}
+
+// MustRun runs gRPC server and panics if any error occurs.
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+// Run runs gRPC server.
+func (a *App) Run() error {
+	const op = "grpcapp.Run"
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.log.Info("grpc server started", slog.String("addr", l.Addr().String()))
+
+	if err := a.grpcServer.Serve(l); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Stop stops gRPC server.
+func (a *App) Stop() {
+	const op = "grpcapp.Stop"
+
+	a.log.With(slog.String("op", op)).
+		Info("stopping gRPC server", slog.Int("port", a.port))
+
+	a.grpcServer.GracefulStop()
+}
+
+func ConnectToSsoService() {
+	conn, err := grpc.NewClient("0.0.0.0:44044", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("failed to connect to auth service: %v", err)
+	}
+	AuthServiceClient = auth.NewAuthClient(conn)
+	UserInfoServiceClient = auth.NewUserInfoClient(conn)
+}
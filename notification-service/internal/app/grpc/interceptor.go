@@ -0,0 +1,116 @@
+package grpcapp
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	authp "github.com/sntabq/proto-gen/gen/go/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// InterceptorLogger adapts slog logger to interceptor logger.
+func InterceptorLogger(l *slog.Logger) logging.Logger {
+	return logging.LoggerFunc(func(ctx context.Context, lvl logging.Level, msg string, fields ...any) {
+		l.Log(ctx, slog.Level(lvl), msg, fields...)
+	})
+}
+
+type callerKey struct{}
+
+// Caller is the authenticated user on whose behalf a request is served.
+type Caller struct {
+	UserId  int32
+	IsAdmin bool
+}
+
+// WithCaller returns a copy of ctx carrying the caller.
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller stored by the auth interceptors.
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+// AdminInterceptorTemplates restricts the template management, the delivery
+// history and the webhooks of partners to admins.
+func AdminInterceptorTemplates(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, "/notification.TemplateService/") &&
+		!strings.HasPrefix(info.FullMethod, "/notification.DeliveryService/") &&
+		!strings.HasPrefix(info.FullMethod, "/notification.WebhookService/") {
+		return handler(ctx, req)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	isAdmin, err := isAdmin(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin {
+		return nil, status.Errorf(codes.PermissionDenied, "permission failed")
+	}
+
+	return handler(WithCaller(ctx, Caller{UserId: user.Id, IsAdmin: true}), req)
+}
+
+// InterceptorUser authenticates the calls to the inbox and the preferences,
+// which only ever concern the caller. Unsubscribing is authorized by the
+// signed token of the request instead.
+func InterceptorUser(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, "/notification.InboxService/") &&
+		!strings.HasPrefix(info.FullMethod, "/notification.PreferenceService/") ||
+		info.FullMethod == "/notification.PreferenceService/Unsubscribe" {
+		return handler(ctx, req)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(WithCaller(ctx, Caller{UserId: user.Id}), req)
+}
+
+func userFromContext(ctx context.Context) (*authp.User, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		log.Printf("failed to get metadata from context")
+	}
+	tkn, found := md["authorization"]
+	if !found || len(tkn) == 0 || tkn[0] == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "authentication is required")
+	}
+
+	userInfo, err := UserInfoServiceClient.GetUserInfo(ctx, &authp.GetUserInfoRequest{Token: tkn[0]})
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "unknown user"):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid user")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to get user info from sso service")
+		}
+	}
+
+	return userInfo.User, nil
+}
+
+func isAdmin(ctx context.Context, userId int32) (bool, error) {
+	isAdminResponse, err := AuthServiceClient.IsAdmin(ctx, &authp.IsAdminRequest{UserId: int64(userId)})
+	if err != nil {
+		log.Printf("permissions fail %v", err)
+		return false, status.Errorf(codes.Internal, "permission failed")
+	}
+
+	return isAdminResponse.IsAdmin, nil
+}
@@ -0,0 +1,55 @@
+package channel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Categories of notifications. Users can opt out of every category but the
+// transactional one.
+const (
+	CategoryTransactional = "transactional"
+	CategoryMarketing     = "marketing"
+	CategoryBackInStock   = "back_in_stock"
+)
+
+// Categories are the categories users have a preference for.
+var Categories = []string{CategoryTransactional, CategoryMarketing, CategoryBackInStock}
+
+// categories are the categories of the event types that are not
+// transactional.
+var categories = map[string]string{
+	"item.back_in_stock":   CategoryBackInStock,
+	"cart.abandoned":       CategoryMarketing,
+	"order.review_request": CategoryMarketing,
+	"digest.weekly":        CategoryMarketing,
+}
+
+// CategoryOf returns the category of the notifications about events of
+// eventType. Events about orders and returns are transactional.
+func CategoryOf(eventType string) string {
+	if category, ok := categories[eventType]; ok {
+		return category
+	}
+	if strings.HasPrefix(eventType, "marketing.") {
+		return CategoryMarketing
+	}
+
+	return CategoryTransactional
+}
+
+// ValidateCategory checks that users can set their preference for category
+// to subscribed.
+func ValidateCategory(category string, subscribed bool) error {
+	switch category {
+	case CategoryMarketing, CategoryBackInStock:
+		return nil
+	case CategoryTransactional:
+		if !subscribed {
+			return fmt.Errorf("%w: transactional notifications can not be turned off", ErrInvalidPreferences)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown category %q", ErrInvalidPreferences, category)
+	}
+}
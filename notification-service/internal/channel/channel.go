@@ -0,0 +1,120 @@
+// Package channel delivers rendered notifications to users over email, SMS,
+// webhooks and the in-app inbox, on the channels each user chose for the type
+// of the event.
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"notification-service/internal/outbound"
+	"notification-service/internal/templates"
+	"notification-service/mailer"
+	"regexp"
+)
+
+const (
+	Email   = "email"
+	SMS     = "sms"
+	Webhook = "webhook"
+	InApp   = "in_app"
+)
+
+// Default are the channels of the event types a user has no preference for.
+var Default = []string{Email, InApp}
+
+// known are the channels users can choose.
+var known = map[string]bool{Email: true, SMS: true, Webhook: true, InApp: true}
+
+var (
+	// ErrNoAddress is returned by a channel when the user can not be
+	// reached on it, for example SMS without a phone number.
+	ErrNoAddress            = errors.New("user has no address on this channel")
+	ErrInvalidPreferences   = errors.New("invalid channel preferences")
+	ErrNoUser               = errors.New("an authenticated user is required")
+	ErrDeliveryNotFound     = errors.New("delivery not found")
+	ErrNotFailed            = errors.New("only failed deliveries can be resent")
+	ErrInvalidDeliveryQuery = errors.New("invalid delivery query")
+)
+
+// Statuses of a delivery.
+const (
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Notification is a rendered template on its way to a user.
+type Notification struct {
+	EventId   string
+	EventType string
+	UserId    int32
+	OrderId   int32
+	// Template is the template the message was rendered from, for example
+	// order.created.en.
+	Template string
+
+	Email      string
+	Phone      string
+	WebhookURL string
+
+	// UnsubscribeURL unsubscribes the user from the category of the
+	// notification. Transactional notifications have none.
+	UnsubscribeURL string
+
+	Message     *templates.Message
+	Attachments []mailer.Attachment
+}
+
+// Channel sends notifications over one medium. Send returns the id the
+// provider gave the message, if any.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, n *Notification) (string, error)
+}
+
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// ValidateChannels checks that every channel is known.
+func ValidateChannels(channels []string) error {
+	for _, c := range channels {
+		if !known[c] {
+			return fmt.Errorf("%w: unknown channel %q", ErrInvalidPreferences, c)
+		}
+	}
+
+	return nil
+}
+
+// ValidateDeliveryQuery checks the status and channel deliveries are
+// filtered by. Empty values do not filter.
+func ValidateDeliveryQuery(status, channel string) error {
+	switch status {
+	case "", StatusSent, StatusFailed, StatusSkipped:
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidDeliveryQuery, status)
+	}
+
+	if channel != "" && !known[channel] {
+		return fmt.Errorf("%w: unknown channel %q", ErrInvalidDeliveryQuery, channel)
+	}
+
+	return nil
+}
+
+// ValidateContacts checks the phone number and webhook URL of a user. The
+// webhook URL must be an https URL of a public host. Empty values are valid
+// and unset the contact.
+func ValidateContacts(ctx context.Context, phone, webhookURL string) error {
+	if phone != "" && !phonePattern.MatchString(phone) {
+		return fmt.Errorf("%w: phone must be in international format", ErrInvalidPreferences)
+	}
+
+	if webhookURL != "" {
+		if err := outbound.ValidateURL(ctx, webhookURL); err != nil {
+			return fmt.Errorf("%w: webhook_url: %v", ErrInvalidPreferences, err)
+		}
+	}
+
+	return nil
+}
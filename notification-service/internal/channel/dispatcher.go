@@ -0,0 +1,233 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"notification-service/internal/data/dto"
+	"notification-service/internal/metrics"
+	"notification-service/internal/templates"
+	"notification-service/mailer"
+	"notification-service/sl"
+	"strconv"
+)
+
+// Preferences holds the categories, channels and contacts of the users.
+type Preferences interface {
+	IsSubscribed(ctx context.Context, userId int32, category string) (bool, error)
+	GetChannels(ctx context.Context, userId int32, eventType string) ([]string, error)
+	GetContacts(ctx context.Context, userId int32) (*dto.ContactsDTO, error)
+}
+
+// DeliveryLog records every attempt to send a notification.
+type DeliveryLog interface {
+	// IsDelivered reports whether the user was already notified about the
+	// event on the channel.
+	IsDelivered(ctx context.Context, eventId string, userId int32, channel string) (bool, error)
+	// RecordDelivery stores an attempt and sets the id and attempt count
+	// of d. A delivery with an id, or of an event the user was notified
+	// about on the channel before, is updated.
+	RecordDelivery(ctx context.Context, d *dto.DeliveryDTO) error
+}
+
+// Dispatcher sends a notification on the channels its user chose.
+type Dispatcher struct {
+	log         *slog.Logger
+	preferences Preferences
+	deliveries  DeliveryLog
+	channels    map[string]Channel
+}
+
+func NewDispatcher(log *slog.Logger, preferences Preferences, deliveries DeliveryLog, channels ...Channel) *Dispatcher {
+	d := &Dispatcher{
+		log:         log,
+		preferences: preferences,
+		deliveries:  deliveries,
+		channels:    make(map[string]Channel, len(channels)),
+	}
+	for _, c := range channels {
+		d.channels[c.Name()] = c
+	}
+
+	return d
+}
+
+// Send sends n on every channel the user chose for its event type, unless
+// the user unsubscribed from its category. Users without an id, such as those
+// of events published before users were known, get the default channels. A
+// channel the user was already notified on about the event, as on a retry, is
+// not sent to again. A user who can not be reached on a channel is skipped on
+// it; the other failures are returned together.
+func (d *Dispatcher) Send(ctx context.Context, n *Notification) error {
+	const op = "channel.Dispatcher.Send"
+	log := d.log.With(
+		slog.String("op", op),
+		slog.String("event type", n.EventType),
+		slog.Int("user id", int(n.UserId)),
+	)
+
+	channels := Default
+	if n.UserId != 0 {
+		if category := CategoryOf(n.EventType); category != CategoryTransactional {
+			subscribed, err := d.preferences.IsSubscribed(ctx, n.UserId, category)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			if !subscribed {
+				log.Debug("user unsubscribed", slog.String("category", category))
+				return nil
+			}
+		}
+
+		var err error
+		channels, err = d.preferences.GetChannels(ctx, n.UserId, n.EventType)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		contacts, err := d.preferences.GetContacts(ctx, n.UserId)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if contacts.Phone != "" {
+			n.Phone = contacts.Phone
+		}
+		n.WebhookURL = contacts.WebhookURL
+	}
+
+	var errs []error
+	for _, name := range channels {
+		c, ok := d.channels[name]
+		if !ok {
+			log.Debug("channel is not configured", slog.String("channel", name))
+			continue
+		}
+
+		if n.EventId != "" {
+			delivered, err := d.deliveries.IsDelivered(ctx, n.EventId, n.UserId, name)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			if delivered {
+				log.Debug("already notified", slog.String("channel", name))
+				continue
+			}
+		}
+
+		if err := d.send(ctx, c, n, toDelivery(n, name)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Resend sends a failed delivery again on its channel to the recipient it was
+// first sent to, and returns it updated.
+func (d *Dispatcher) Resend(ctx context.Context, delivery *dto.DeliveryDTO) (*dto.DeliveryDTO, error) {
+	if delivery.Status != StatusFailed {
+		return nil, ErrNotFailed
+	}
+
+	c, ok := d.channels[delivery.Channel]
+	if !ok {
+		return nil, fmt.Errorf("channel %s is not configured", delivery.Channel)
+	}
+
+	n := &Notification{
+		EventId:   delivery.EventId,
+		EventType: delivery.EventType,
+		UserId:    delivery.UserId,
+		OrderId:   delivery.OrderId,
+		Template:  delivery.Template,
+		Message: &templates.Message{
+			Subject:   delivery.Subject,
+			PlainBody: delivery.PlainBody,
+			HTMLBody:  delivery.HTMLBody,
+		},
+	}
+	switch delivery.Channel {
+	case Email:
+		n.Email = delivery.Recipient
+	case SMS:
+		n.Phone = delivery.Recipient
+	case Webhook:
+		n.WebhookURL = delivery.Recipient
+	}
+	for _, a := range delivery.Attachments {
+		n.Attachments = append(n.Attachments, mailer.Attachment{Filename: a.Filename, Data: a.Data})
+	}
+
+	// The error of the attempt is recorded on the delivery.
+	_ = d.send(ctx, c, n, delivery)
+
+	return delivery, nil
+}
+
+// send sends n on c and records the attempt in delivery. Failing to record an
+// attempt is logged and does not fail the notification.
+func (d *Dispatcher) send(ctx context.Context, c Channel, n *Notification, delivery *dto.DeliveryDTO) error {
+	log := d.log.With(
+		slog.String("channel", c.Name()),
+		slog.String("event type", n.EventType),
+		slog.Int("user id", int(n.UserId)),
+	)
+
+	providerId, err := c.Send(ctx, n)
+	delivery.ProviderMessageId = providerId
+	delivery.Error = ""
+	switch {
+	case err == nil:
+		delivery.Status = StatusSent
+		log.Debug("notification sent")
+	case errors.Is(err, ErrNoAddress):
+		delivery.Status = StatusSkipped
+		delivery.Error = err.Error()
+		log.Debug("user can not be reached")
+		err = nil
+	default:
+		delivery.Status = StatusFailed
+		delivery.Error = err.Error()
+		log.Warn("failed to send notification", sl.Err(err))
+	}
+
+	metrics.Notification(c.Name(), delivery.Status)
+
+	if recordErr := d.deliveries.RecordDelivery(ctx, delivery); recordErr != nil {
+		log.Error("failed to record delivery", sl.Err(recordErr))
+	}
+
+	return err
+}
+
+// toDelivery returns the delivery of n on channel.
+func toDelivery(n *Notification, channel string) *dto.DeliveryDTO {
+	delivery := &dto.DeliveryDTO{
+		EventId:   n.EventId,
+		EventType: n.EventType,
+		UserId:    n.UserId,
+		OrderId:   n.OrderId,
+		Channel:   channel,
+		Template:  n.Template,
+		Subject:   n.Message.Subject,
+		PlainBody: n.Message.PlainBody,
+		HTMLBody:  n.Message.HTMLBody,
+	}
+
+	switch channel {
+	case Email:
+		delivery.Recipient = n.Email
+		for _, a := range n.Attachments {
+			delivery.Attachments = append(delivery.Attachments, dto.AttachmentDTO{Filename: a.Filename, Data: a.Data})
+		}
+	case SMS:
+		delivery.Recipient = n.Phone
+	case Webhook:
+		delivery.Recipient = n.WebhookURL
+	case InApp:
+		delivery.Recipient = strconv.Itoa(int(n.UserId))
+	}
+
+	return delivery
+}
@@ -0,0 +1,39 @@
+package channel
+
+import (
+	"context"
+	"log/slog"
+	"notification-service/mailer"
+)
+
+// EmailChannel emails notifications with their attachments.
+type EmailChannel struct {
+	log    *slog.Logger
+	mailer mailer.Mailer
+}
+
+func NewEmailChannel(log *slog.Logger, m mailer.Mailer) *EmailChannel {
+	return &EmailChannel{log: log, mailer: m}
+}
+
+func (e *EmailChannel) Name() string {
+	return Email
+}
+
+func (e *EmailChannel) Send(ctx context.Context, n *Notification) (string, error) {
+	if n.Email == "" {
+		return "", ErrNoAddress
+	}
+
+	var headers map[string]string
+	if n.UnsubscribeURL != "" {
+		// One-click unsubscribe as of RFC 8058: mail clients POST to the
+		// link instead of opening it.
+		headers = map[string]string{
+			"List-Unsubscribe":      "<" + n.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
+
+	return e.mailer.Send(n.Email, n.Message, headers, e.log, n.Attachments...)
+}
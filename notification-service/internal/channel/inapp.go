@@ -0,0 +1,57 @@
+package channel
+
+import (
+	"context"
+	"notification-service/internal/data/dto"
+	"strconv"
+	"strings"
+)
+
+// Inbox stores the in-app notifications.
+type Inbox interface {
+	SaveNotification(ctx context.Context, n dto.InboxNotificationDTO) (int64, error)
+}
+
+// InAppChannel puts notifications in the inbox of the user in the storefront.
+type InAppChannel struct {
+	inbox Inbox
+}
+
+func NewInAppChannel(inbox Inbox) *InAppChannel {
+	return &InAppChannel{inbox: inbox}
+}
+
+func (i *InAppChannel) Name() string {
+	return InApp
+}
+
+func (i *InAppChannel) Send(ctx context.Context, n *Notification) (string, error) {
+	if n.UserId == 0 {
+		return "", ErrNoAddress
+	}
+
+	id, err := i.inbox.SaveNotification(ctx, dto.InboxNotificationDTO{
+		UserId:    n.UserId,
+		EventId:   n.EventId,
+		EventType: n.EventType,
+		Subject:   n.Message.Subject,
+		Body:      plainText(n.Message.PlainBody),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(id, 10), nil
+}
+
+// plainText drops the indentation and blank lines of a rendered plain body.
+func plainText(body string) string {
+	var lines []string
+	for _, l := range strings.Split(body, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,53 @@
+package channel
+
+import (
+	"context"
+	"net/url"
+	"notification-service/internal/ratelimit"
+	"strings"
+)
+
+// limitedChannel waits for its rate limits before sending a notification.
+type limitedChannel struct {
+	Channel
+	global  *ratelimit.Limiter
+	domains *ratelimit.Domains
+}
+
+// Limit returns c limited to the rate of global for all notifications and to
+// the rates of domains per recipient domain: the domain of an email address
+// or the host of a webhook. Nil limiters do not limit.
+func Limit(c Channel, global *ratelimit.Limiter, domains *ratelimit.Domains) Channel {
+	if global == nil && domains == nil {
+		return c
+	}
+
+	return &limitedChannel{Channel: c, global: global, domains: domains}
+}
+
+func (l *limitedChannel) Send(ctx context.Context, n *Notification) (string, error) {
+	if err := l.global.Wait(ctx); err != nil {
+		return "", err
+	}
+	if err := l.domains.Wait(ctx, domain(l.Name(), n)); err != nil {
+		return "", err
+	}
+
+	return l.Channel.Send(ctx, n)
+}
+
+// domain returns the domain n is sent to on channel, if it has one.
+func domain(channel string, n *Notification) string {
+	switch channel {
+	case Email:
+		if i := strings.LastIndex(n.Email, "@"); i >= 0 {
+			return n.Email[i+1:]
+		}
+	case Webhook:
+		if u, err := url.Parse(n.WebhookURL); err == nil {
+			return u.Hostname()
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,81 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SMSChannel texts the subject of notifications through an HTTP provider. The
+// smsstub command implements the provider API for local runs.
+type SMSChannel struct {
+	url    string
+	apiKey string
+	sender string
+	client *http.Client
+}
+
+func NewSMSChannel(url, apiKey, sender string) *SMSChannel {
+	return &SMSChannel{
+		url:    url,
+		apiKey: apiKey,
+		sender: sender,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SMSRequest is the message posted to the provider.
+type SMSRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+// SMSResponse is the answer of the provider to an accepted message.
+type SMSResponse struct {
+	Id string `json:"id"`
+}
+
+func (s *SMSChannel) Name() string {
+	return SMS
+}
+
+func (s *SMSChannel) Send(ctx context.Context, n *Notification) (string, error) {
+	const op = "channel.SMSChannel.Send"
+
+	if n.Phone == "" {
+		return "", ErrNoAddress
+	}
+
+	payload, err := json.Marshal(SMSRequest{From: s.sender, To: n.Phone, Text: n.Message.Subject})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url+"/messages", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s: provider answered %s", op, resp.Status)
+	}
+
+	var sent SMSResponse
+	if err = json.NewDecoder(resp.Body).Decode(&sent); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sent.Id, nil
+}
@@ -0,0 +1,81 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"notification-service/internal/outbound"
+	"notification-service/internal/partner"
+	"strconv"
+	"time"
+)
+
+// WebhookChannel posts notifications as JSON to the webhook URL of the user,
+// signed with secret the way the deliveries to partners are. Webhooks on
+// internal addresses are refused and redirects are not followed.
+type WebhookChannel struct {
+	client *http.Client
+	secret string
+}
+
+func NewWebhookChannel(timeout time.Duration, secret string) *WebhookChannel {
+	return &WebhookChannel{client: outbound.NewClient(timeout), secret: secret}
+}
+
+// WebhookPayload is the body posted to the webhook of a user.
+type WebhookPayload struct {
+	EventId   string    `json:"event_id,omitempty"`
+	EventType string    `json:"event_type"`
+	UserId    int32     `json:"user_id"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+func (w *WebhookChannel) Name() string {
+	return Webhook
+}
+
+func (w *WebhookChannel) Send(ctx context.Context, n *Notification) (string, error) {
+	const op = "channel.WebhookChannel.Send"
+
+	if n.WebhookURL == "" {
+		return "", ErrNoAddress
+	}
+
+	payload, err := json.Marshal(WebhookPayload{
+		EventId:   n.EventId,
+		EventType: n.EventType,
+		UserId:    n.UserId,
+		Subject:   n.Message.Subject,
+		Body:      n.Message.PlainBody,
+		SentAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(partner.HeaderId, n.EventId)
+	req.Header.Set(partner.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(partner.HeaderSignature, partner.Sign(w.secret, timestamp, payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s: webhook answered %s", op, resp.Status)
+	}
+
+	return "", nil
+}
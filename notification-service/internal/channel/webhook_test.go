@@ -0,0 +1,117 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"notification-service/internal/outbound"
+	"notification-service/internal/partner"
+	"notification-service/internal/templates"
+	"testing"
+	"time"
+)
+
+func TestValidateContacts(t *testing.T) {
+	for url, valid := range map[string]bool{
+		"":                               true,
+		"https://93.184.216.34/hooks":    true,
+		"http://93.184.216.34/hooks":     false,
+		"https://127.0.0.1:8080/hooks":   false,
+		"https://localhost/hooks":        false,
+		"https://10.1.2.3/hooks":         false,
+		"https://192.168.0.10/hooks":     false,
+		"https://169.254.169.254/latest": false,
+		"https://[::1]/hooks":            false,
+		"https://[fe80::1]/hooks":        false,
+		"ftp://93.184.216.34/hooks":      false,
+		"https:///hooks":                 false,
+	} {
+		err := ValidateContacts(context.Background(), "", url)
+		if valid && err != nil {
+			t.Errorf("ValidateContacts(%q) = %v, want it valid", url, err)
+		}
+		if !valid && !errors.Is(err, ErrInvalidPreferences) {
+			t.Errorf("ValidateContacts(%q) = %v, want %v", url, err, ErrInvalidPreferences)
+		}
+	}
+}
+
+// TestWebhookSignsPosts posts a notification to a receiver that verifies it
+// the way the deliveries to partners are verified.
+func TestWebhookSignsPosts(t *testing.T) {
+	const secret = "whsec_test"
+
+	var verifyErr error
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = partner.Verify(secret, r.Header, body, time.Now())
+	}))
+	defer receiver.Close()
+
+	w := NewWebhookChannel(time.Second, secret)
+	w.client = receiver.Client()
+
+	if _, err := w.Send(context.Background(), notification(receiver.URL)); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+	if verifyErr != nil {
+		t.Errorf("receiver rejected the notification: %v", verifyErr)
+	}
+}
+
+// TestWebhookRefusesRedirects fails a post that is answered with a redirect
+// instead of following it.
+func TestWebhookRefusesRedirects(t *testing.T) {
+	followed := false
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	w := NewWebhookChannel(time.Second, "s")
+	w.client.Transport = receiver.Client().Transport
+
+	if _, err := w.Send(context.Background(), notification(receiver.URL)); err == nil {
+		t.Error("Send() succeeded after a redirect, want it failed")
+	}
+	if followed {
+		t.Error("the redirect was followed")
+	}
+}
+
+// TestWebhookRefusesInternalAddresses posts to a webhook on the loopback
+// interface, which is refused when the connection is made.
+func TestWebhookRefusesInternalAddresses(t *testing.T) {
+	posted := false
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = true
+	}))
+	defer receiver.Close()
+
+	w := NewWebhookChannel(time.Second, "s")
+	// Trust the receiver, so that only the address can fail the post.
+	w.client.Transport.(*http.Transport).TLSClientConfig = receiver.Client().Transport.(*http.Transport).TLSClientConfig
+
+	if _, err := w.Send(context.Background(), notification(receiver.URL)); !errors.Is(err, outbound.ErrInternalAddress) {
+		t.Errorf("Send() to a loopback address = %v, want %v", err, outbound.ErrInternalAddress)
+	}
+	if posted {
+		t.Error("the notification was posted to a loopback address")
+	}
+}
+
+func notification(webhookURL string) *Notification {
+	return &Notification{
+		EventId:    "order-42-created",
+		EventType:  "order.created",
+		UserId:     7,
+		WebhookURL: webhookURL,
+		Message:    &templates.Message{Subject: "Order #42", PlainBody: "Thanks for your order."},
+	}
+}
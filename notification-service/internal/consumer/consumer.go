@@ -0,0 +1,403 @@
+// Package consumer is the RabbitMQ subscriber of the broker. It consumes the
+// events of the shop with manual acknowledgements. A message whose handler fails is retried after a growing
+// delay by parking it in a delay queue, and is dead-lettered once it has been
+// retried too often or can never succeed.
+//
+// Messages are handled by a pool of workers. The broker sends no more than
+// the prefetch count of unacknowledged messages, so a consumer whose workers
+// are all busy stops receiving until one of them settles its message.
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"log/slog"
+	"notification-service/internal/metrics"
+	"notification-service/sl"
+	"shared/broker"
+	"sync"
+	"time"
+)
+
+const (
+	// QueueName is the queue the producers publish events to.
+	QueueName = broker.QueueName
+	// DeadLetterExchange receives the messages that could not be handled,
+	// both from the consumer and from the broker when a message is rejected.
+	DeadLetterExchange = broker.DeadLetterExchange
+	// DeadLetterQueue keeps the dead-lettered messages for inspection and
+	// replay.
+	DeadLetterQueue = "shop.dead"
+
+	// RetryCountHeader counts how many times a message has been retried.
+	RetryCountHeader = "x-retry-count"
+	// ErrorHeader holds the error a message was dead-lettered for.
+	ErrorHeader = "x-error"
+)
+
+// depthInterval is how often the depth of the shop queue is checked.
+const depthInterval = 10 * time.Second
+
+// confirmTimeout bounds how long the consumer waits for the broker to confirm
+// a message moved to a retry or the dead letter queue.
+const confirmTimeout = 5 * time.Second
+
+var ErrNacked = broker.ErrNacked
+
+// QueueArgs are the arguments the shop queue is declared with. Producers
+// declare the queue too and must use the same arguments.
+var QueueArgs = broker.QueueArgs
+
+type Consumer struct {
+	log            *slog.Logger
+	url            string
+	workers        int
+	prefetch       int
+	maxRetries     int
+	retryBaseDelay time.Duration
+	reconnectDelay time.Duration
+}
+
+func New(
+	log *slog.Logger,
+	url string,
+	workers int,
+	prefetch int,
+	maxRetries int,
+	retryBaseDelay time.Duration,
+	reconnectDelay time.Duration,
+) *Consumer {
+	return &Consumer{
+		log:            log,
+		url:            url,
+		workers:        max(workers, 1),
+		prefetch:       max(prefetch, workers),
+		maxRetries:     maxRetries,
+		retryBaseDelay: retryBaseDelay,
+		reconnectDelay: reconnectDelay,
+	}
+}
+
+// Run consumes the shop queue until ctx is cancelled, reconnecting whenever
+// the connection to the broker is lost.
+func (c *Consumer) Run(ctx context.Context, handle broker.Handler) {
+	const op = "consumer.Run"
+	log := c.log.With(slog.String("op", op))
+
+	for {
+		err := c.consume(ctx, handle)
+		if ctx.Err() != nil {
+			log.Info("consumer stopped")
+			return
+		}
+		log.Warn("lost connection to broker, reconnecting", sl.Err(err), slog.Duration("delay", c.reconnectDelay))
+
+		select {
+		case <-ctx.Done():
+			log.Info("consumer stopped")
+			return
+		case <-time.After(c.reconnectDelay):
+		}
+	}
+}
+
+// consume handles deliveries until the connection or channel is closed.
+func (c *Consumer) consume(ctx context.Context, handle broker.Handler) error {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+
+	if err = Declare(ch, c.maxRetries); err != nil {
+		return err
+	}
+	if err = ch.Confirm(false); err != nil {
+		return err
+	}
+	if err = ch.Qos(c.prefetch, 0, false); err != nil {
+		return err
+	}
+
+	msgs, err := ch.Consume(
+		QueueName, // queue
+		"",        // consumer
+		false,     // auto-ack
+		false,     // exclusive
+		false,     // no-local
+		false,     // no-wait
+		nil,       // args
+	)
+	if err != nil {
+		return err
+	}
+
+	c.log.Info("waiting for messages", slog.String("queue", QueueName), slog.Int("workers", c.workers))
+
+	// The workers settle their messages before the connection is closed.
+	jobs := make(chan amqp.Delivery)
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range jobs {
+				metrics.InFlight.Add(1)
+				c.deliver(ctx, ch, d, handle)
+				metrics.InFlight.Add(-1)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(jobs)
+
+	depth := time.NewTicker(depthInterval)
+	defer depth.Stop()
+
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-closed:
+			return err
+		case <-depth.C:
+			c.checkDepth(ch)
+		case d, ok := <-msgs:
+			if !ok {
+				return errors.New("delivery channel closed")
+			}
+			metrics.Received.Add(1)
+			if !d.Timestamp.IsZero() {
+				metrics.QueueLag.Set(time.Since(d.Timestamp).Milliseconds())
+			}
+
+			select {
+			case jobs <- d:
+			case <-ctx.Done():
+				// The broker redelivers it to the next consumer.
+				_ = d.Nack(false, true)
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// checkDepth records how many messages wait in the shop queue.
+func (c *Consumer) checkDepth(ch *amqp.Channel) {
+	q, err := ch.QueueDeclarePassive(QueueName, true, false, false, false, QueueArgs)
+	if err != nil {
+		c.log.Warn("failed to check queue depth", sl.Err(err))
+		return
+	}
+
+	metrics.QueueDepth.Set(int64(q.Messages))
+}
+
+// deliver hands a message to its handler and settles it: the message is
+// acknowledged on success, and moved to a retry queue or dead-lettered on
+// failure.
+func (c *Consumer) deliver(ctx context.Context, ch *amqp.Channel, d amqp.Delivery, handle broker.Handler) {
+	log := c.log.With(
+		slog.String("message id", d.MessageId),
+		slog.String("type", d.Type),
+	)
+
+	err := handle(ctx, message(d))
+	if err == nil {
+		if err = d.Ack(false); err != nil {
+			log.Warn("failed to ack message", sl.Err(err))
+		}
+		metrics.Settle("acked")
+		return
+	}
+
+	if ctx.Err() != nil {
+		// The consumer is stopping; the message is handled again after
+		// the restart rather than counted as a failure.
+		log.Info("requeueing message", sl.Err(err))
+		if err = d.Nack(false, true); err != nil {
+			log.Warn("failed to requeue message", sl.Err(err))
+		}
+		metrics.Settle("requeued")
+		return
+	}
+
+	retries := RetryCount(d)
+	var outcome string
+	switch {
+	case broker.IsPermanent(err) || retries >= c.maxRetries:
+		log.Error("dead-lettering message", sl.Err(err), slog.Int("retries", retries))
+		outcome = "dead_lettered"
+		err = c.republish(ctx, ch, d, DeadLetterExchange, "", amqp.Table{ErrorHeader: err.Error()}, "")
+	default:
+		delay := c.retryBaseDelay << retries
+		log.Warn("retrying message", sl.Err(err), slog.Int("retry", retries+1), slog.Duration("delay", delay))
+		outcome = "retried"
+		err = c.republish(ctx, ch, d, "", RetryQueue(retries+1), amqp.Table{RetryCountHeader: int32(retries + 1)}, expiration(delay))
+	}
+
+	if err != nil {
+		// The broker dead-letters rejected messages of the shop queue,
+		// so the message is not lost even if it can not be moved.
+		log.Error("failed to move message, rejecting it", sl.Err(err))
+		if err = d.Nack(false, false); err != nil {
+			log.Warn("failed to reject message", sl.Err(err))
+		}
+		metrics.Settle("rejected")
+		return
+	}
+
+	if err = d.Ack(false); err != nil {
+		log.Warn("failed to ack message", sl.Err(err))
+	}
+	metrics.Settle(outcome)
+}
+
+// message returns the broker message of d.
+func message(d amqp.Delivery) *broker.Message {
+	return &broker.Message{
+		ID:            d.MessageId,
+		Type:          d.Type,
+		ContentType:   d.ContentType,
+		CorrelationId: d.CorrelationId,
+		Timestamp:     d.Timestamp,
+		Body:          d.Body,
+	}
+}
+
+// republish publishes a copy of d with headers added and waits for the broker
+// to confirm it.
+func (c *Consumer) republish(
+	ctx context.Context,
+	ch *amqp.Channel,
+	d amqp.Delivery,
+	exchange, key string,
+	headers amqp.Table,
+	expiration string,
+) error {
+	msg := Copy(d)
+	for k, v := range headers {
+		msg.Headers[k] = v
+	}
+	msg.Expiration = expiration
+
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
+
+	acked, err := confirmation.WaitContext(waitCtx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return ErrNacked
+	}
+
+	return nil
+}
+
+// Declare declares the shop queue, its dead letter exchange and queue, and one
+// delay queue per retry. A delay queue holds a message until it expires and
+// then dead-letters it back into the shop queue.
+func Declare(ch *amqp.Channel, maxRetries int) error {
+	const op = "consumer.Declare"
+
+	_, err := ch.QueueDeclare(
+		QueueName, // name
+		true,      // durable
+		false,     // delete when unused
+		false,     // exclusive
+		false,     // no-wait
+		QueueArgs, // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = ch.ExchangeDeclare(
+		DeadLetterExchange, // name
+		"fanout",           // type
+		true,               // durable
+		false,              // auto-deleted
+		false,              // internal
+		false,              // no-wait
+		nil,                // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = ch.QueueDeclare(DeadLetterQueue, true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = ch.QueueBind(DeadLetterQueue, "", DeadLetterExchange, false, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for retry := 1; retry <= maxRetries; retry++ {
+		_, err = ch.QueueDeclare(RetryQueue(retry), true, false, false, false, amqp.Table{
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": QueueName,
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// RetryQueue returns the delay queue of the n-th retry of a message. Every
+// retry has its own queue so that a message never waits behind one with a
+// longer delay.
+func RetryQueue(n int) string {
+	return fmt.Sprintf("%s.retry.%d", QueueName, n)
+}
+
+// RetryCount returns how many times d has been retried.
+func RetryCount(d amqp.Delivery) int {
+	switch n := d.Headers[RetryCountHeader].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	default:
+		return 0
+	}
+}
+
+// Copy returns a persistent message with the properties and body of d.
+func Copy(d amqp.Delivery) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	return amqp.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: d.CorrelationId,
+		MessageId:     d.MessageId,
+		Timestamp:     d.Timestamp,
+		Type:          d.Type,
+		Body:          d.Body,
+	}
+}
+
+func expiration(d time.Duration) string {
+	return fmt.Sprintf("%d", d.Milliseconds())
+}
@@ -0,0 +1,156 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"notification-service/internal/channel"
+	"notification-service/internal/data/dto"
+)
+
+type DeliveryStorage struct {
+	DB *sql.DB
+}
+
+// IsDelivered reports whether the user was notified about the event on the
+// channel.
+func (ds *DeliveryStorage) IsDelivered(ctx context.Context, eventId string, userId int32, ch string) (bool, error) {
+	const op = "data.IsDelivered"
+
+	var delivered bool
+	err := ds.DB.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM notification.deliveries
+				WHERE event_id = $1 AND user_id = $2 AND channel = $3 AND status = 'sent'
+			)`, eventId, userId, ch).Scan(&delivered)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return delivered, nil
+}
+
+// RecordDelivery stores an attempt. A delivery with an id, or of an event the
+// user was notified about on the channel before, is updated and its attempts
+// counted; the content is only stored by the first attempt.
+func (ds *DeliveryStorage) RecordDelivery(ctx context.Context, d *dto.DeliveryDTO) error {
+	const op = "data.RecordDelivery"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	if d.ID != 0 {
+		err := ds.DB.QueryRowContext(ctx, `
+				UPDATE notification.deliveries
+				SET status = $2, error = $3, provider_message_id = $4, attempts = attempts + 1, updated_at = now()
+				WHERE id = $1
+				RETURNING attempts, updated_at`, d.ID, d.Status, d.Error, d.ProviderMessageId).Scan(&d.Attempts, &d.UpdatedAt)
+		if err != nil {
+			return fail(err)
+		}
+
+		return nil
+	}
+
+	attachments, err := json.Marshal(d.Attachments)
+	if err != nil {
+		return fail(err)
+	}
+	if d.Attachments == nil {
+		attachments = []byte("[]")
+	}
+
+	err = ds.DB.QueryRowContext(ctx, `
+			INSERT INTO notification.deliveries (
+				event_id, event_type, user_id, order_id, channel, recipient, template, status, error,
+				provider_message_id, subject, plain_body, html_body, attachments
+			)
+			VALUES (NULLIF($1, ''), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			ON CONFLICT (event_id, user_id, channel)
+			DO UPDATE SET recipient = EXCLUDED.recipient, status = EXCLUDED.status, error = EXCLUDED.error,
+				provider_message_id = EXCLUDED.provider_message_id,
+				attempts = deliveries.attempts + 1, updated_at = now()
+			RETURNING id, attempts, created_at, updated_at`,
+		d.EventId, d.EventType, d.UserId, d.OrderId, d.Channel, d.Recipient, d.Template, d.Status, d.Error,
+		d.ProviderMessageId, d.Subject, d.PlainBody, d.HTMLBody, attachments,
+	).Scan(&d.ID, &d.Attempts, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// GetDelivery returns a delivery with its content.
+func (ds *DeliveryStorage) GetDelivery(ctx context.Context, id int64) (*dto.DeliveryDTO, error) {
+	const op = "data.GetDelivery"
+
+	var d dto.DeliveryDTO
+	var attachments []byte
+	err := ds.DB.QueryRowContext(ctx, `
+			SELECT `+deliveryColumns+`, plain_body, html_body, attachments
+			FROM notification.deliveries
+			WHERE id = $1`, id).Scan(append(deliveryFields(&d), &d.PlainBody, &d.HTMLBody, &attachments)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, channel.ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err = json.Unmarshal(attachments, &d.Attachments); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return &d, nil
+}
+
+// GetDeliveries returns the deliveries matching f, newest first, without their
+// content.
+func (ds *DeliveryStorage) GetDeliveries(ctx context.Context, f dto.DeliveryFilter) ([]*dto.DeliveryDTO, error) {
+	const op = "data.GetDeliveries"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := ds.DB.QueryContext(ctx, `
+			SELECT `+deliveryColumns+`
+			FROM notification.deliveries
+			WHERE ($1 = 0 OR user_id = $1)
+			  AND ($2 = 0 OR order_id = $2)
+			  AND ($3 = '' OR status = $3)
+			  AND ($4 = '' OR channel = $4)
+			  AND ($5::bigint = 0 OR id < $5)
+			ORDER BY id DESC
+			LIMIT $6`, f.UserId, f.OrderId, f.Status, f.Channel, f.BeforeId, f.Limit)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var deliveries []*dto.DeliveryDTO
+	for rows.Next() {
+		var d dto.DeliveryDTO
+		if err = rows.Scan(deliveryFields(&d)...); err != nil {
+			return nil, fail(err)
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return deliveries, nil
+}
+
+const deliveryColumns = `id, COALESCE(event_id, ''), event_type, user_id, order_id, channel, recipient, template,
+				status, error, attempts, provider_message_id, subject, created_at, updated_at`
+
+func deliveryFields(d *dto.DeliveryDTO) []any {
+	return []any{
+		&d.ID, &d.EventId, &d.EventType, &d.UserId, &d.OrderId, &d.Channel, &d.Recipient, &d.Template,
+		&d.Status, &d.Error, &d.Attempts, &d.ProviderMessageId, &d.Subject, &d.CreatedAt, &d.UpdatedAt,
+	}
+}
@@ -1,17 +1,49 @@
 package dto
 
+import "time"
+
 type UserDTO struct {
-	Id       int32  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Username string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
-	Email    string `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
-	Role     string `protobuf:"bytes,4,opt,name=role,proto3" json:"role,omitempty"`
+	Id       int32  `json:"id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Locale   string `json:"locale,omitempty"`
 }
 
 type OrderDTO struct {
-	ID     int32   `json:"id,omitempty"`
-	UserId int32   `json:"user_id"`
-	ItemId int32   `json:"item_id"`
-	Item   ItemDTO `json:"item"`
+	ID        int32          `json:"id,omitempty"`
+	UserId    int32          `json:"user_id"`
+	ItemId    int32          `json:"item_id"`
+	Item      ItemDTO        `json:"item"`
+	Status    string         `json:"status"`
+	Lines     []OrderLineDTO `json:"lines"`
+	Total     int32          `json:"total"`
+	Address   *AddressDTO    `json:"address,omitempty"`
+	Shipments []ShipmentDTO  `json:"shipments,omitempty"`
+}
+
+type AddressDTO struct {
+	Recipient  string `json:"recipient"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone,omitempty"`
+}
+
+type ShipmentDTO struct {
+	Carrier        string    `json:"carrier"`
+	TrackingNumber string    `json:"tracking_number"`
+	ShippedAt      time.Time `json:"shipped_at"`
+}
+
+type OrderLineDTO struct {
+	ItemId    int32   `json:"item_id"`
+	Quantity  int32   `json:"quantity"`
+	UnitPrice int32   `json:"unit_price"`
+	Item      ItemDTO `json:"item"`
 }
 
 type ItemDTO struct {
@@ -22,3 +54,168 @@ type ItemDTO struct {
 	Quantity    int32  `json:"quantity,omitempty"`
 	ImageURL    string `json:"image_url"`
 }
+
+// InvoiceDTO is the invoice sent along with the confirmation of a paid order.
+// Its PDF is loaded from the order service when the confirmation is sent.
+type InvoiceDTO struct {
+	Number   string `json:"number"`
+	Filename string `json:"filename"`
+}
+
+// CartDTO is the open cart of a user.
+type CartDTO struct {
+	Lines []OrderLineDTO `json:"lines"`
+	Total int32          `json:"total"`
+}
+
+// BackInStockDTO is the message catalogue-service sends when an item that was
+// out of stock can be ordered again.
+type BackInStockDTO struct {
+	Item        ItemDTO         `json:"item_info"`
+	Subscribers []SubscriberDTO `json:"subscribers"`
+}
+
+// SubscriberDTO is a user who asked to be told when an item is back.
+type SubscriberDTO struct {
+	UserId   int32  `json:"user_id"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	Locale   string `json:"locale,omitempty"`
+}
+
+// ReturnDTO is the return request an event of the returns workflow is about.
+type ReturnDTO struct {
+	ID           int64  `json:"id"`
+	ItemId       int32  `json:"item_id"`
+	Quantity     int32  `json:"quantity"`
+	Reason       string `json:"reason"`
+	Status       string `json:"status"`
+	Note         string `json:"note"`
+	RefundAmount int32  `json:"refund_amount"`
+}
+
+// TemplateDTO is a template stored in the database that overrides the file of
+// the same event type and locale.
+type TemplateDTO struct {
+	EventType string    `json:"event_type"`
+	Locale    string    `json:"locale"`
+	Body      string    `json:"body"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// InboxNotificationDTO is a notification in the in-app inbox of a user.
+type InboxNotificationDTO struct {
+	ID        int64      `json:"id"`
+	UserId    int32      `json:"user_id"`
+	EventId   string     `json:"event_id,omitempty"`
+	EventType string     `json:"event_type"`
+	Subject   string     `json:"subject"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+// ChannelPreferenceDTO lists the channels a user is notified on about events
+// of a type. An empty event type is the default of the user.
+type ChannelPreferenceDTO struct {
+	EventType string   `json:"event_type"`
+	Channels  []string `json:"channels"`
+}
+
+// CategoryPreferenceDTO is whether a user gets the notifications of a
+// category.
+type CategoryPreferenceDTO struct {
+	Category   string `json:"category"`
+	Subscribed bool   `json:"subscribed"`
+}
+
+// ContactsDTO is how a user can be reached besides their email.
+type ContactsDTO struct {
+	Phone      string `json:"phone,omitempty"`
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// DeliveryDTO is an attempt to notify a user about an event on a channel. A
+// retried or resent notification updates its delivery and counts the attempt.
+type DeliveryDTO struct {
+	ID                int64     `json:"id"`
+	EventId           string    `json:"event_id,omitempty"`
+	EventType         string    `json:"event_type"`
+	UserId            int32     `json:"user_id"`
+	OrderId           int32     `json:"order_id,omitempty"`
+	Channel           string    `json:"channel"`
+	Recipient         string    `json:"recipient"`
+	Template          string    `json:"template"`
+	Status            string    `json:"status"`
+	Error             string    `json:"error,omitempty"`
+	Attempts          int32     `json:"attempts"`
+	ProviderMessageId string    `json:"provider_message_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	// The rendered notification, kept to resend it.
+	Subject     string          `json:"subject"`
+	PlainBody   string          `json:"plain_body,omitempty"`
+	HTMLBody    string          `json:"html_body,omitempty"`
+	Attachments []AttachmentDTO `json:"attachments,omitempty"`
+}
+
+// AttachmentDTO is a file sent along with an email.
+type AttachmentDTO struct {
+	Filename string `json:"filename"`
+	Data     []byte `json:"data"`
+}
+
+// DeliveryFilter selects deliveries. Zero fields do not filter.
+type DeliveryFilter struct {
+	UserId   int32
+	OrderId  int32
+	Status   string
+	Channel  string
+	BeforeId int64
+	Limit    int32
+}
+
+// WebhookDTO is a webhook of a partner. The secret is only set when it is
+// created or rotated.
+type WebhookDTO struct {
+	ID             int64      `json:"id"`
+	URL            string     `json:"url"`
+	Events         []string   `json:"events"`
+	Description    string     `json:"description"`
+	Enabled        bool       `json:"enabled"`
+	DisabledReason string     `json:"disabled_reason,omitempty"`
+	FailingSince   *time.Time `json:"failing_since,omitempty"`
+	Secret         string     `json:"secret,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// WebhookDeliveryDTO is the delivery of an event to a webhook, updated with
+// the outcome of its last attempt.
+type WebhookDeliveryDTO struct {
+	ID             int64      `json:"id"`
+	WebhookId      int64      `json:"webhook_id"`
+	URL            string     `json:"url"`
+	EventId        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int32      `json:"attempts"`
+	ResponseStatus int32      `json:"response_status,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	Payload        []byte     `json:"payload"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// WebhookDeliveryFilter selects webhook deliveries. Zero fields do not
+// filter.
+type WebhookDeliveryFilter struct {
+	WebhookId int64
+	EventType string
+	Status    string
+	BeforeId  int64
+	Limit     int32
+}
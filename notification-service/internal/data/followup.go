@@ -0,0 +1,120 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"notification-service/internal/channel"
+	"notification-service/internal/data/dto"
+)
+
+// FollowUpStorage reads what the follow-up notifications need from the
+// other services: the open carts, the new items of the catalogue and the
+// users the digest goes to.
+type FollowUpStorage struct {
+	DB *sql.DB
+}
+
+// HasOpenCart reports whether the user has an open cart with items in it.
+func (fs *FollowUpStorage) HasOpenCart(ctx context.Context, userId int32) (bool, error) {
+	const op = "data.HasOpenCart"
+
+	var open bool
+	err := fs.DB.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM order_service.carts c
+				JOIN order_service.cart_lines l ON l.cart_id = c.id
+				WHERE c.user_id = $1 AND c.status = 'open'
+			)`, userId).Scan(&open)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return open, nil
+}
+
+// GetLatestItemId returns the id of the newest item of the catalogue, zero if
+// it has none.
+func (fs *FollowUpStorage) GetLatestItemId(ctx context.Context) (int32, error) {
+	const op = "data.GetLatestItemId"
+
+	var id int32
+	err := fs.DB.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM catalogue.item_info`).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// GetNewItems returns up to limit items added to the catalogue after the item
+// afterId, oldest first. Item ids grow with every item added.
+func (fs *FollowUpStorage) GetNewItems(ctx context.Context, afterId int32, limit int) ([]dto.ItemDTO, error) {
+	const op = "data.GetNewItems"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := fs.DB.QueryContext(ctx, `
+			SELECT id, name, price, description, COALESCE(image_url, '')
+			FROM catalogue.item_info
+			WHERE id > $1
+			ORDER BY id
+			LIMIT $2`, afterId, limit)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var items []dto.ItemDTO
+	for rows.Next() {
+		var i dto.ItemDTO
+		if err = rows.Scan(&i.ID, &i.Name, &i.Price, &i.Description, &i.ImageURL); err != nil {
+			return nil, fail(err)
+		}
+		items = append(items, i)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return items, nil
+}
+
+// GetDigestRecipients returns up to limit activated users after the user
+// afterId, by id, leaving out those unsubscribed from marketing.
+func (fs *FollowUpStorage) GetDigestRecipients(ctx context.Context, afterId int32, limit int) ([]dto.UserDTO, error) {
+	const op = "data.GetDigestRecipients"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := fs.DB.QueryContext(ctx, `
+			SELECT u.id, COALESCE(u.username, ''), u.email
+			FROM auth.users u
+			WHERE u.id > $1 AND u.activated
+			  AND NOT EXISTS (
+				SELECT 1 FROM notification.category_preferences p
+				WHERE p.user_id = u.id AND p.category = $3 AND NOT p.subscribed
+			  )
+			ORDER BY u.id
+			LIMIT $2`, afterId, limit, channel.CategoryMarketing)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var users []dto.UserDTO
+	for rows.Next() {
+		var u dto.UserDTO
+		if err = rows.Scan(&u.Id, &u.Username, &u.Email); err != nil {
+			return nil, fail(err)
+		}
+		users = append(users, u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return users, nil
+}
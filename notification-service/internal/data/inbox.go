@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"notification-service/internal/data/dto"
+)
+
+type InboxStorage struct {
+	DB *sql.DB
+}
+
+// SaveNotification puts a notification in the inbox of its user and returns
+// its id. A notification of an event the user already has is not stored
+// again; the id of the one stored is returned.
+func (is *InboxStorage) SaveNotification(ctx context.Context, n dto.InboxNotificationDTO) (int64, error) {
+	const op = "data.SaveNotification"
+
+	var id int64
+	err := is.DB.QueryRowContext(ctx, `
+			INSERT INTO notification.inbox (user_id, event_id, event_type, subject, body)
+			VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+			ON CONFLICT (user_id, event_id) DO UPDATE SET event_type = inbox.event_type
+			RETURNING id`,
+		n.UserId, n.EventId, n.EventType, n.Subject, n.Body).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// GetNotifications returns up to limit notifications of a user older than
+// beforeId, newest first. A zero beforeId starts at the newest.
+func (is *InboxStorage) GetNotifications(ctx context.Context, userId int32, unreadOnly bool, beforeId int64, limit int32) ([]*dto.InboxNotificationDTO, error) {
+	const op = "data.GetNotifications"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := is.DB.QueryContext(ctx, `
+			SELECT id, user_id, COALESCE(event_id, ''), event_type, subject, body, created_at, read_at
+			FROM notification.inbox
+			WHERE user_id = $1
+			  AND (NOT $2 OR read_at IS NULL)
+			  AND ($3::bigint = 0 OR id < $3)
+			ORDER BY id DESC
+			LIMIT $4`, userId, unreadOnly, beforeId, limit)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var notifications []*dto.InboxNotificationDTO
+	for rows.Next() {
+		var n dto.InboxNotificationDTO
+		var readAt sql.NullTime
+		err = rows.Scan(&n.ID, &n.UserId, &n.EventId, &n.EventType, &n.Subject, &n.Body, &n.CreatedAt, &readAt)
+		if err != nil {
+			return nil, fail(err)
+		}
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, &n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return notifications, nil
+}
+
+// CountUnread returns how many notifications of a user are unread.
+func (is *InboxStorage) CountUnread(ctx context.Context, userId int32) (int32, error) {
+	const op = "data.CountUnread"
+
+	var count int32
+	err := is.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM notification.inbox
+			WHERE user_id = $1 AND read_at IS NULL`, userId).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return count, nil
+}
+
+// MarkRead marks the unread notifications of a user with the given ids, or
+// all of them, as read and returns how many it marked. Ids of other users are
+// ignored.
+func (is *InboxStorage) MarkRead(ctx context.Context, userId int32, ids []int64, all bool) (int32, error) {
+	const op = "data.MarkRead"
+
+	res, err := is.DB.ExecContext(ctx, `
+			UPDATE notification.inbox SET read_at = now()
+			WHERE user_id = $1 AND read_at IS NULL AND ($2 OR id = ANY($3))`,
+		userId, all, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	marked, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return int32(marked), nil
+}
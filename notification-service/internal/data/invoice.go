@@ -0,0 +1,36 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+// InvoiceStorage reads the invoices the order service stored when it issued
+// them, so that their PDFs do not travel with the events.
+type InvoiceStorage struct {
+	DB *sql.DB
+}
+
+// GetInvoicePDF returns the PDF of the invoice of the order. It is empty for
+// invoices issued before their PDF was stored.
+func (is *InvoiceStorage) GetInvoicePDF(ctx context.Context, orderId int32) ([]byte, error) {
+	const op = "data.GetInvoicePDF"
+
+	var pdf []byte
+	err := is.DB.QueryRowContext(ctx, `
+			SELECT pdf FROM order_service.invoices WHERE order_id = $1`, orderId).Scan(&pdf)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("%s: %w", op, ErrInvoiceNotFound)
+		default:
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return pdf, nil
+}
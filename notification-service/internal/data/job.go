@@ -0,0 +1,160 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"notification-service/internal/scheduler"
+	"time"
+)
+
+type JobStorage struct {
+	DB *sql.DB
+}
+
+const jobColumns = `id, kind, dedupe_key, COALESCE(user_id, 0), payload, cron, run_at, attempts, last_error, lease_token`
+
+// SaveJob stores a job, or moves the pending job of its kind and key to its
+// run time and payload. Moving a job that is running clears its lease, so
+// that the outcome of that run is dropped and the job runs at its new time.
+func (js *JobStorage) SaveJob(ctx context.Context, j *scheduler.Job) error {
+	const op = "data.SaveJob"
+
+	err := js.DB.QueryRowContext(ctx, `
+			INSERT INTO notification.scheduled_jobs (kind, dedupe_key, user_id, payload, cron, run_at)
+			VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6)
+			ON CONFLICT (kind, dedupe_key) WHERE status = 'pending'
+			DO UPDATE SET user_id = EXCLUDED.user_id, payload = EXCLUDED.payload, cron = EXCLUDED.cron,
+				run_at = EXCLUDED.run_at, attempts = 0, last_error = '', locked_until = NULL, lease_token = '',
+				updated_at = now()
+			RETURNING id`,
+		j.Kind, j.Key, j.UserId, []byte(j.Payload), j.Cron, j.RunAt).Scan(&j.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// EnsureJob stores a job unless one of its kind and key is pending. A pending
+// job only takes the schedule and run time of j if its schedule changed, which
+// drops the outcome of a run in progress like SaveJob does.
+func (js *JobStorage) EnsureJob(ctx context.Context, j *scheduler.Job) error {
+	const op = "data.EnsureJob"
+
+	err := js.DB.QueryRowContext(ctx, `
+			INSERT INTO notification.scheduled_jobs (kind, dedupe_key, user_id, payload, cron, run_at)
+			VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6)
+			ON CONFLICT (kind, dedupe_key) WHERE status = 'pending'
+			DO UPDATE SET cron = EXCLUDED.cron,
+				run_at = CASE WHEN scheduled_jobs.cron = EXCLUDED.cron THEN scheduled_jobs.run_at ELSE EXCLUDED.run_at END,
+				locked_until = CASE WHEN scheduled_jobs.cron = EXCLUDED.cron THEN scheduled_jobs.locked_until END,
+				lease_token = CASE WHEN scheduled_jobs.cron = EXCLUDED.cron THEN scheduled_jobs.lease_token ELSE '' END,
+				updated_at = now()
+			RETURNING id`,
+		j.Kind, j.Key, j.UserId, []byte(j.Payload), j.Cron, j.RunAt).Scan(&j.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// CancelJobs cancels the pending job of kind and key and reports whether
+// there was one.
+func (js *JobStorage) CancelJobs(ctx context.Context, kind, key string) (bool, error) {
+	const op = "data.CancelJobs"
+
+	res, err := js.DB.ExecContext(ctx, `
+			UPDATE notification.scheduled_jobs
+			SET status = 'cancelled', updated_at = now()
+			WHERE kind = $1 AND dedupe_key = $2 AND status = 'pending'`, kind, key)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return affected > 0, nil
+}
+
+// ClaimJob locks the job that has been due the longest for lease under a new
+// lease token and counts the attempt. It returns nil when no job is due. The
+// row is locked with SKIP LOCKED, so several instances can claim jobs side by
+// side without running a job twice.
+func (js *JobStorage) ClaimJob(ctx context.Context, lease time.Duration) (*scheduler.Job, error) {
+	const op = "data.ClaimJob"
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var j scheduler.Job
+	var payload []byte
+	err := js.DB.QueryRowContext(ctx, `
+			UPDATE notification.scheduled_jobs
+			SET locked_until = now() + make_interval(secs => $1), lease_token = $2, attempts = attempts + 1,
+				updated_at = now()
+			WHERE id = (
+				SELECT id FROM notification.scheduled_jobs
+				WHERE status = 'pending' AND run_at <= now()
+				  AND (locked_until IS NULL OR locked_until < now())
+				ORDER BY run_at
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING `+jobColumns, lease.Seconds(), hex.EncodeToString(token),
+	).Scan(&j.ID, &j.Kind, &j.Key, &j.UserId, &payload, &j.Cron, &j.RunAt, &j.Attempts, &j.LastError, &j.Lease)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+	j.Payload = payload
+
+	return &j, nil
+}
+
+// RescheduleJob releases a pending job to run again at its run time. A job
+// cancelled, moved or claimed again while it ran is left alone.
+func (js *JobStorage) RescheduleJob(ctx context.Context, j *scheduler.Job) error {
+	const op = "data.RescheduleJob"
+
+	_, err := js.DB.ExecContext(ctx, `
+			UPDATE notification.scheduled_jobs
+			SET run_at = $3, payload = $4, attempts = $5, last_error = $6, locked_until = NULL, lease_token = '',
+				updated_at = now()
+			WHERE id = $1 AND lease_token = $2 AND status = 'pending'`,
+		j.ID, j.Lease, j.RunAt, []byte(j.Payload), j.Attempts, j.LastError)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// FinishJob sets the final status of a pending job. A job cancelled, moved or
+// claimed again while it ran is left alone.
+func (js *JobStorage) FinishJob(ctx context.Context, j *scheduler.Job, status, lastError string) error {
+	const op = "data.FinishJob"
+
+	_, err := js.DB.ExecContext(ctx, `
+			UPDATE notification.scheduled_jobs
+			SET status = $3, last_error = $4, locked_until = NULL, lease_token = '', updated_at = now()
+			WHERE id = $1 AND lease_token = $2 AND status = 'pending'`, j.ID, j.Lease, status, lastError)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
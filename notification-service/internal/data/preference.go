@@ -0,0 +1,216 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"notification-service/internal/channel"
+	"notification-service/internal/data/dto"
+)
+
+type PreferenceStorage struct {
+	DB *sql.DB
+}
+
+// GetChannels returns the channels a user is notified on about events of
+// eventType: the preference for the type, else the default of the user, else
+// channel.Default.
+func (ps *PreferenceStorage) GetChannels(ctx context.Context, userId int32, eventType string) ([]string, error) {
+	const op = "data.GetChannels"
+
+	var channels []string
+	err := ps.DB.QueryRowContext(ctx, `
+			SELECT channels FROM notification.channel_preferences
+			WHERE user_id = $1 AND event_type IN ($2, '')
+			ORDER BY event_type DESC
+			LIMIT 1`, userId, eventType).Scan(pq.Array(&channels))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return channel.Default, nil
+		}
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return channels, nil
+}
+
+// GetPreferences returns the preferences a user set.
+func (ps *PreferenceStorage) GetPreferences(ctx context.Context, userId int32) ([]*dto.ChannelPreferenceDTO, error) {
+	const op = "data.GetPreferences"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := ps.DB.QueryContext(ctx, `
+			SELECT event_type, channels FROM notification.channel_preferences
+			WHERE user_id = $1
+			ORDER BY event_type`, userId)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var preferences []*dto.ChannelPreferenceDTO
+	for rows.Next() {
+		var p dto.ChannelPreferenceDTO
+		if err = rows.Scan(&p.EventType, pq.Array(&p.Channels)); err != nil {
+			return nil, fail(err)
+		}
+		preferences = append(preferences, &p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return preferences, nil
+}
+
+// GetContacts returns the contacts of a user, empty if they set none.
+func (ps *PreferenceStorage) GetContacts(ctx context.Context, userId int32) (*dto.ContactsDTO, error) {
+	const op = "data.GetContacts"
+
+	var c dto.ContactsDTO
+	err := ps.DB.QueryRowContext(ctx, `
+			SELECT phone, webhook_url FROM notification.contacts
+			WHERE user_id = $1`, userId).Scan(&c.Phone, &c.WebhookURL)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return &c, nil
+}
+
+// UpdatePreferences replaces the preferences and contacts of a user.
+func (ps *PreferenceStorage) UpdatePreferences(ctx context.Context, userId int32, preferences []*dto.ChannelPreferenceDTO, contacts *dto.ContactsDTO) error {
+	const op = "data.UpdatePreferences"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := ps.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM notification.channel_preferences WHERE user_id = $1`, userId)
+	if err != nil {
+		return fail(err)
+	}
+
+	for _, p := range preferences {
+		_, err = tx.ExecContext(ctx, `
+				INSERT INTO notification.channel_preferences (user_id, event_type, channels)
+				VALUES ($1, $2, $3)`, userId, p.EventType, pq.Array(p.Channels))
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+			INSERT INTO notification.contacts (user_id, phone, webhook_url)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id)
+			DO UPDATE SET phone = EXCLUDED.phone, webhook_url = EXCLUDED.webhook_url, updated_at = now()`,
+		userId, contacts.Phone, contacts.WebhookURL)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// IsSubscribed reports whether a user gets the notifications of category.
+// Users are subscribed to the categories they set no preference for.
+func (ps *PreferenceStorage) IsSubscribed(ctx context.Context, userId int32, category string) (bool, error) {
+	const op = "data.IsSubscribed"
+
+	subscribed := true
+	err := ps.DB.QueryRowContext(ctx, `
+			SELECT subscribed FROM notification.category_preferences
+			WHERE user_id = $1 AND category = $2`, userId, category).Scan(&subscribed)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return subscribed, nil
+}
+
+// GetCategories returns the preference of a user for every category.
+func (ps *PreferenceStorage) GetCategories(ctx context.Context, userId int32) ([]*dto.CategoryPreferenceDTO, error) {
+	const op = "data.GetCategories"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := ps.DB.QueryContext(ctx, `
+			SELECT category, subscribed FROM notification.category_preferences
+			WHERE user_id = $1`, userId)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	set := map[string]bool{}
+	for rows.Next() {
+		var category string
+		var subscribed bool
+		if err = rows.Scan(&category, &subscribed); err != nil {
+			return nil, fail(err)
+		}
+		set[category] = subscribed
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	preferences := make([]*dto.CategoryPreferenceDTO, 0, len(channel.Categories))
+	for _, category := range channel.Categories {
+		subscribed, ok := set[category]
+		preferences = append(preferences, &dto.CategoryPreferenceDTO{
+			Category:   category,
+			Subscribed: subscribed || !ok,
+		})
+	}
+
+	return preferences, nil
+}
+
+// UpdateCategories sets the preferences of a user for the given categories
+// and leaves the others as they are.
+func (ps *PreferenceStorage) UpdateCategories(ctx context.Context, userId int32, preferences []*dto.CategoryPreferenceDTO) error {
+	const op = "data.UpdateCategories"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := ps.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback()
+
+	for _, p := range preferences {
+		_, err = tx.ExecContext(ctx, `
+				INSERT INTO notification.category_preferences (user_id, category, subscribed)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (user_id, category)
+				DO UPDATE SET subscribed = EXCLUDED.subscribed, updated_at = now()`,
+			userId, p.Category, p.Subscribed)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/lib/pq"
+)
+
+func NewTemplateStorage(dsn string) (*TemplateStorage, error) {
+	const op = "data.NewTemplateStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &TemplateStorage{DB: db}, nil
+}
+
+func NewInboxStorage(dsn string) (*InboxStorage, error) {
+	const op = "data.NewInboxStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &InboxStorage{DB: db}, nil
+}
+
+func NewPreferenceStorage(dsn string) (*PreferenceStorage, error) {
+	const op = "data.NewPreferenceStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &PreferenceStorage{DB: db}, nil
+}
+
+func NewDeliveryStorage(dsn string) (*DeliveryStorage, error) {
+	const op = "data.NewDeliveryStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &DeliveryStorage{DB: db}, nil
+}
+
+func NewJobStorage(dsn string) (*JobStorage, error) {
+	const op = "data.NewJobStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &JobStorage{DB: db}, nil
+}
+
+func NewFollowUpStorage(dsn string) (*FollowUpStorage, error) {
+	const op = "data.NewFollowUpStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &FollowUpStorage{DB: db}, nil
+}
+
+func NewWebhookStorage(dsn string) (*WebhookStorage, error) {
+	const op = "data.NewWebhookStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &WebhookStorage{DB: db}, nil
+}
+
+func NewInvoiceStorage(dsn string) (*InvoiceStorage, error) {
+	const op = "data.NewInvoiceStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &InvoiceStorage{DB: db}, nil
+}
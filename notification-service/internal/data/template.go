@@ -0,0 +1,43 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"notification-service/internal/data/dto"
+)
+
+type TemplateStorage struct {
+	DB *sql.DB
+}
+
+// GetTemplates returns the templates that override the files.
+func (ts *TemplateStorage) GetTemplates(ctx context.Context) ([]*dto.TemplateDTO, error) {
+	const op = "data.GetTemplates"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := ts.DB.QueryContext(ctx, `
+			SELECT event_type, locale, body, updated_at
+			FROM notification.templates
+			ORDER BY event_type, locale`)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var templates []*dto.TemplateDTO
+	for rows.Next() {
+		var t dto.TemplateDTO
+		if err = rows.Scan(&t.EventType, &t.Locale, &t.Body, &t.UpdatedAt); err != nil {
+			return nil, fail(err)
+		}
+		templates = append(templates, &t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return templates, nil
+}
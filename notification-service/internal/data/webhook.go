@@ -0,0 +1,390 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"notification-service/internal/data/dto"
+	"notification-service/internal/partner"
+	"time"
+)
+
+type WebhookStorage struct {
+	DB *sql.DB
+}
+
+const webhookColumns = `id, url, events, description, enabled, disabled_reason, failing_since, created_at, updated_at`
+
+func webhookFields(w *dto.WebhookDTO) []any {
+	return []any{
+		&w.ID, &w.URL, pq.Array(&w.Events), &w.Description, &w.Enabled, &w.DisabledReason, &w.FailingSince,
+		&w.CreatedAt, &w.UpdatedAt,
+	}
+}
+
+// CreateWebhook stores a webhook with its secret and sets its id and
+// timestamps.
+func (ws *WebhookStorage) CreateWebhook(ctx context.Context, w *dto.WebhookDTO) error {
+	const op = "data.CreateWebhook"
+
+	err := ws.DB.QueryRowContext(ctx, `
+			INSERT INTO notification.webhooks (url, events, secret, description)
+			VALUES ($1, $2, $3, $4)
+			RETURNING `+webhookColumns,
+		w.URL, pq.Array(w.Events), w.Secret, w.Description).Scan(webhookFields(w)...)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// GetWebhooks returns every webhook, oldest first, without its secret.
+func (ws *WebhookStorage) GetWebhooks(ctx context.Context) ([]*dto.WebhookDTO, error) {
+	const op = "data.GetWebhooks"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := ws.DB.QueryContext(ctx, `
+			SELECT `+webhookColumns+`
+			FROM notification.webhooks
+			ORDER BY id`)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var webhooks []*dto.WebhookDTO
+	for rows.Next() {
+		var w dto.WebhookDTO
+		if err = rows.Scan(webhookFields(&w)...); err != nil {
+			return nil, fail(err)
+		}
+		webhooks = append(webhooks, &w)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return webhooks, nil
+}
+
+// UpdateWebhook replaces the URL, events, description and state of a webhook
+// and reads it back. Enabling a webhook clears its failures.
+func (ws *WebhookStorage) UpdateWebhook(ctx context.Context, w *dto.WebhookDTO) error {
+	const op = "data.UpdateWebhook"
+
+	err := ws.DB.QueryRowContext(ctx, `
+			UPDATE notification.webhooks
+			SET url = $2, events = $3, description = $4, enabled = $5,
+				disabled_reason = CASE WHEN $5 THEN '' ELSE disabled_reason END,
+				failing_since = CASE WHEN $5 THEN NULL ELSE failing_since END,
+				updated_at = now()
+			WHERE id = $1
+			RETURNING `+webhookColumns,
+		w.ID, w.URL, pq.Array(w.Events), w.Description, w.Enabled).Scan(webhookFields(w)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return partner.ErrWebhookNotFound
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// SetWebhookSecret replaces the secret of a webhook.
+func (ws *WebhookStorage) SetWebhookSecret(ctx context.Context, id int64, secret string) error {
+	const op = "data.SetWebhookSecret"
+
+	res, err := ws.DB.ExecContext(ctx, `
+			UPDATE notification.webhooks
+			SET secret = $2, updated_at = now()
+			WHERE id = $1`, id, secret)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return webhookAffected(op, res)
+}
+
+// DeleteWebhook deletes a webhook along with its deliveries.
+func (ws *WebhookStorage) DeleteWebhook(ctx context.Context, id int64) error {
+	const op = "data.DeleteWebhook"
+
+	res, err := ws.DB.ExecContext(ctx, `DELETE FROM notification.webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return webhookAffected(op, res)
+}
+
+// DisableWebhook disables a webhook with reason.
+func (ws *WebhookStorage) DisableWebhook(ctx context.Context, id int64, reason string) error {
+	const op = "data.DisableWebhook"
+
+	_, err := ws.DB.ExecContext(ctx, `
+			UPDATE notification.webhooks
+			SET enabled = FALSE, disabled_reason = $2, updated_at = now()
+			WHERE id = $1 AND enabled`, id, reason)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+func webhookAffected(op string, res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if affected == 0 {
+		return partner.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// EnqueueWebhookDeliveries stores a pending delivery of an event for every
+// enabled webhook subscribed to its type. The unique webhook and event keeps
+// a redelivered event from being stored twice.
+func (ws *WebhookStorage) EnqueueWebhookDeliveries(ctx context.Context, eventId, eventType string, payload []byte) error {
+	const op = "data.EnqueueWebhookDeliveries"
+
+	_, err := ws.DB.ExecContext(ctx, `
+			INSERT INTO notification.webhook_deliveries (webhook_id, event_id, event_type, payload)
+			SELECT id, $1, $2, $3
+			FROM notification.webhooks
+			WHERE enabled AND $2 = ANY(events)
+			ON CONFLICT (webhook_id, event_id) DO NOTHING`, eventId, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// ClaimWebhookDelivery locks the delivery of an enabled webhook that has been
+// due the longest for lease under a new lease token and counts the attempt.
+// It returns nil when no delivery is due. The row is locked with SKIP LOCKED,
+// so several instances can post deliveries side by side without posting one
+// twice.
+func (ws *WebhookStorage) ClaimWebhookDelivery(ctx context.Context, lease time.Duration) (*partner.Attempt, error) {
+	const op = "data.ClaimWebhookDelivery"
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var a partner.Attempt
+	err := ws.DB.QueryRowContext(ctx, `
+			UPDATE notification.webhook_deliveries d
+			SET locked_until = now() + make_interval(secs => $1), lease_token = $2, attempts = d.attempts + 1,
+				updated_at = now()
+			FROM notification.webhooks w
+			WHERE w.id = d.webhook_id AND d.id = (
+				SELECT pd.id FROM notification.webhook_deliveries pd
+				JOIN notification.webhooks pw ON pw.id = pd.webhook_id
+				WHERE pd.status = 'pending' AND pw.enabled AND pd.next_attempt_at <= now()
+				  AND (pd.locked_until IS NULL OR pd.locked_until < now())
+				ORDER BY pd.next_attempt_at
+				LIMIT 1
+				FOR UPDATE OF pd SKIP LOCKED
+			)
+			RETURNING d.id, d.webhook_id, w.url, w.secret, d.event_id, d.event_type, d.payload, d.attempts, d.lease_token`,
+		lease.Seconds(), hex.EncodeToString(token),
+	).Scan(&a.ID, &a.WebhookId, &a.URL, &a.Secret, &a.EventId, &a.EventType, &a.Payload, &a.Attempts, &a.Lease)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return &a, nil
+}
+
+// RecordWebhookSuccess marks a delivery sent and clears the failures of its
+// webhook. It fails with partner.ErrLeaseLost if the delivery is no longer
+// claimed under a.Lease.
+func (ws *WebhookStorage) RecordWebhookSuccess(ctx context.Context, a *partner.Attempt, responseStatus int) error {
+	const op = "data.RecordWebhookSuccess"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := ws.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+			UPDATE notification.webhook_deliveries
+			SET status = 'sent', response_status = $3, error = '', delivered_at = now(), locked_until = NULL,
+				lease_token = '', updated_at = now()
+			WHERE id = $1 AND lease_token = $2 AND status = 'pending'`, a.ID, a.Lease, responseStatus)
+	if err != nil {
+		return fail(err)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return fmt.Errorf("%s: %w", op, partner.ErrLeaseLost)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+			UPDATE notification.webhooks
+			SET failing_since = NULL
+			WHERE id = $1 AND failing_since IS NOT NULL`, a.WebhookId)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// RecordWebhookFailure records a failed attempt and returns since when its
+// webhook has been failing. The delivery stays pending until nextAttemptAt,
+// or fails if it is nil. It fails with partner.ErrLeaseLost if the delivery
+// is no longer claimed under a.Lease.
+func (ws *WebhookStorage) RecordWebhookFailure(ctx context.Context, a *partner.Attempt, responseStatus int, errMsg string, nextAttemptAt *time.Time) (time.Time, error) {
+	const op = "data.RecordWebhookFailure"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := ws.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, fail(err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+			UPDATE notification.webhook_deliveries
+			SET status = CASE WHEN $5::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+				next_attempt_at = COALESCE($5, next_attempt_at),
+				response_status = $3, error = $4, locked_until = NULL, lease_token = '', updated_at = now()
+			WHERE id = $1 AND lease_token = $2 AND status = 'pending'`, a.ID, a.Lease, responseStatus, errMsg, nextAttemptAt)
+	if err != nil {
+		return time.Time{}, fail(err)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return time.Time{}, fmt.Errorf("%s: %w", op, partner.ErrLeaseLost)
+	}
+
+	var failingSince time.Time
+	err = tx.QueryRowContext(ctx, `
+			UPDATE notification.webhooks
+			SET failing_since = COALESCE(failing_since, now())
+			WHERE id = $1
+			RETURNING failing_since`, a.WebhookId).Scan(&failingSince)
+	if err != nil {
+		return time.Time{}, fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return time.Time{}, fail(err)
+	}
+
+	return failingSince, nil
+}
+
+// GetWebhookDeliveries returns the deliveries matching f, newest first.
+func (ws *WebhookStorage) GetWebhookDeliveries(ctx context.Context, f dto.WebhookDeliveryFilter) ([]*dto.WebhookDeliveryDTO, error) {
+	const op = "data.GetWebhookDeliveries"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := ws.DB.QueryContext(ctx, `
+			SELECT `+webhookDeliveryColumns+`
+			FROM notification.webhook_deliveries d
+			JOIN notification.webhooks w ON w.id = d.webhook_id
+			WHERE ($1::bigint = 0 OR d.webhook_id = $1)
+			  AND ($2 = '' OR d.event_type = $2)
+			  AND ($3 = '' OR d.status = $3)
+			  AND ($4::bigint = 0 OR d.id < $4)
+			ORDER BY d.id DESC
+			LIMIT $5`, f.WebhookId, f.EventType, f.Status, f.BeforeId, f.Limit)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var deliveries []*dto.WebhookDeliveryDTO
+	for rows.Next() {
+		var d dto.WebhookDeliveryDTO
+		if err = rows.Scan(webhookDeliveryFields(&d)...); err != nil {
+			return nil, fail(err)
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return deliveries, nil
+}
+
+// RedeliverWebhookDelivery makes a failed delivery pending again with no
+// attempts counted and returns it.
+func (ws *WebhookStorage) RedeliverWebhookDelivery(ctx context.Context, id int64) (*dto.WebhookDeliveryDTO, error) {
+	const op = "data.RedeliverWebhookDelivery"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	var d dto.WebhookDeliveryDTO
+	err := ws.DB.QueryRowContext(ctx, `
+			WITH d AS (
+				UPDATE notification.webhook_deliveries
+				SET status = 'pending', attempts = 0, next_attempt_at = now(), locked_until = NULL, updated_at = now()
+				WHERE id = $1 AND status = 'failed'
+				RETURNING *
+			)
+			SELECT `+webhookDeliveryColumns+`
+			FROM d
+			JOIN notification.webhooks w ON w.id = d.webhook_id`, id).Scan(webhookDeliveryFields(&d)...)
+	if err == nil {
+		return &d, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fail(err)
+	}
+
+	var exists bool
+	err = ws.DB.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM notification.webhook_deliveries WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return nil, fail(err)
+	}
+	if exists {
+		return nil, partner.ErrNotFailed
+	}
+
+	return nil, partner.ErrDeliveryNotFound
+}
+
+const webhookDeliveryColumns = `d.id, d.webhook_id, w.url, d.event_id, d.event_type, d.status, d.attempts,
+				d.response_status, d.error, d.payload,
+				CASE WHEN d.status = 'pending' THEN d.next_attempt_at END, d.delivered_at, d.created_at, d.updated_at`
+
+func webhookDeliveryFields(d *dto.WebhookDeliveryDTO) []any {
+	return []any{
+		&d.ID, &d.WebhookId, &d.URL, &d.EventId, &d.EventType, &d.Status, &d.Attempts,
+		&d.ResponseStatus, &d.Error, &d.Payload, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt,
+	}
+}
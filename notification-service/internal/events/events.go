@@ -0,0 +1,260 @@
+// Package events decodes the events the notification service consumes. Events
+// come wrapped in the envelope shared by all services; events published before
+// the envelope existed are plain JSON and decoded as version 0.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	eventsp "github.com/sntabq/proto-gen/gen/go/events"
+	"google.golang.org/protobuf/proto"
+	"notification-service/internal/data/dto"
+	"time"
+)
+
+// ContentType is the content type of an enveloped event.
+const ContentType = "application/x-protobuf"
+
+var ErrInvalidEvent = errors.New("invalid event")
+
+// Event is an event decoded from any of the supported schema versions. Order
+// and User are set for the events of orders and returns, User and Cart for
+// the events of carts, and BackInStock for the events of items.
+type Event struct {
+	ID            string
+	Type          string
+	Version       int32
+	OccurredAt    time.Time
+	Producer      string
+	CorrelationId string
+
+	User        dto.UserDTO
+	Order       dto.OrderDTO
+	Invoice     *dto.InvoiceDTO
+	Return      *dto.ReturnDTO
+	Cart        *dto.CartDTO
+	BackInStock *dto.BackInStockDTO
+}
+
+// decoders decode the payload of every supported version of the envelope.
+var decoders = map[int32]func(*Event, *eventsp.Envelope) error{
+	1: decodeV1,
+}
+
+// Decode decodes a message of type eventType with the given content type and
+// body. Messages that can not be decoded fail with ErrInvalidEvent.
+func Decode(contentType, eventType string, body []byte) (*Event, error) {
+	if contentType != ContentType {
+		return decodeLegacy(eventType, body)
+	}
+
+	var envelope eventsp.Envelope
+	if err := proto.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if err := validate(&envelope); err != nil {
+		return nil, err
+	}
+
+	decode, ok := decoders[envelope.GetVersion()]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidEvent, envelope.GetVersion())
+	}
+
+	event := &Event{
+		ID:            envelope.GetEventId(),
+		Type:          envelope.GetType(),
+		Version:       envelope.GetVersion(),
+		OccurredAt:    envelope.GetOccurredAt().AsTime(),
+		Producer:      envelope.GetProducer(),
+		CorrelationId: envelope.GetCorrelationId(),
+	}
+	if err := decode(event, &envelope); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func validate(e *eventsp.Envelope) error {
+	switch {
+	case e.GetEventId() == "":
+		return fmt.Errorf("%w: event_id is required", ErrInvalidEvent)
+	case e.GetType() == "":
+		return fmt.Errorf("%w: type is required", ErrInvalidEvent)
+	case e.GetOccurredAt() == nil:
+		return fmt.Errorf("%w: occurred_at is required", ErrInvalidEvent)
+	case e.GetProducer() == "":
+		return fmt.Errorf("%w: producer is required", ErrInvalidEvent)
+	case e.GetPayload() == nil:
+		return fmt.Errorf("%w: payload is required", ErrInvalidEvent)
+	}
+
+	return nil
+}
+
+// decodeV1 decodes the OrderEvent, CartEvent and BackInStockEvent payloads.
+func decodeV1(event *Event, envelope *eventsp.Envelope) error {
+	payload, err := envelope.GetPayload().UnmarshalNew()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+
+	switch p := payload.(type) {
+	case *eventsp.OrderEvent:
+		if p.GetOrder() == nil || p.GetUser().GetEmail() == "" {
+			return fmt.Errorf("%w: order and user email are required", ErrInvalidEvent)
+		}
+
+		event.User = dto.UserDTO{
+			Id:       p.GetUser().GetId(),
+			Username: p.GetUser().GetUsername(),
+			Email:    p.GetUser().GetEmail(),
+			Locale:   p.GetUser().GetLocale(),
+		}
+		event.Order = toOrderDTO(p.GetOrder())
+		if inv := p.GetInvoice(); inv != nil {
+			event.Invoice = &dto.InvoiceDTO{
+				Number:   inv.GetNumber(),
+				Filename: inv.GetFilename(),
+			}
+		}
+		if r := p.GetReturn(); r != nil {
+			event.Return = &dto.ReturnDTO{
+				ID:           r.GetId(),
+				ItemId:       r.GetItemId(),
+				Quantity:     r.GetQuantity(),
+				Reason:       r.GetReason(),
+				Status:       r.GetStatus(),
+				Note:         r.GetNote(),
+				RefundAmount: r.GetRefundAmount(),
+			}
+		}
+	case *eventsp.CartEvent:
+		if p.GetUser().GetId() == 0 || p.GetUser().GetEmail() == "" {
+			return fmt.Errorf("%w: user id and email are required", ErrInvalidEvent)
+		}
+
+		event.User = dto.UserDTO{
+			Id:       p.GetUser().GetId(),
+			Username: p.GetUser().GetUsername(),
+			Email:    p.GetUser().GetEmail(),
+			Locale:   p.GetUser().GetLocale(),
+		}
+		event.Cart = &dto.CartDTO{Total: p.GetTotal()}
+		for _, l := range p.GetLines() {
+			event.Cart.Lines = append(event.Cart.Lines, dto.OrderLineDTO{
+				ItemId:    l.GetItem().GetId(),
+				Quantity:  l.GetQuantity(),
+				UnitPrice: l.GetUnitPrice(),
+				Item:      toItemDTO(l.GetItem()),
+			})
+		}
+	case *eventsp.BackInStockEvent:
+		if p.GetItem() == nil {
+			return fmt.Errorf("%w: item is required", ErrInvalidEvent)
+		}
+
+		event.BackInStock = &dto.BackInStockDTO{Item: toItemDTO(p.GetItem())}
+		for _, s := range p.GetSubscribers() {
+			event.BackInStock.Subscribers = append(event.BackInStock.Subscribers, dto.SubscriberDTO{
+				UserId:   s.GetUserId(),
+				Email:    s.GetEmail(),
+				Username: s.GetUsername(),
+				Locale:   s.GetLocale(),
+			})
+		}
+	default:
+		return fmt.Errorf("%w: unexpected payload %s", ErrInvalidEvent, envelope.GetPayload().GetTypeUrl())
+	}
+
+	return nil
+}
+
+func toOrderDTO(o *eventsp.Order) dto.OrderDTO {
+	order := dto.OrderDTO{
+		ID:     o.GetId(),
+		UserId: o.GetUserId(),
+		Status: o.GetStatus(),
+		Total:  o.GetTotal(),
+	}
+
+	for _, l := range o.GetLines() {
+		order.Lines = append(order.Lines, dto.OrderLineDTO{
+			ItemId:    l.GetItem().GetId(),
+			Quantity:  l.GetQuantity(),
+			UnitPrice: l.GetUnitPrice(),
+			Item:      toItemDTO(l.GetItem()),
+		})
+	}
+
+	if a := o.GetAddress(); a != nil {
+		order.Address = &dto.AddressDTO{
+			Recipient:  a.GetRecipient(),
+			Line1:      a.GetLine1(),
+			Line2:      a.GetLine2(),
+			City:       a.GetCity(),
+			Region:     a.GetRegion(),
+			PostalCode: a.GetPostalCode(),
+			Country:    a.GetCountry(),
+			Phone:      a.GetPhone(),
+		}
+	}
+
+	for _, s := range o.GetShipments() {
+		order.Shipments = append(order.Shipments, dto.ShipmentDTO{
+			Carrier:        s.GetCarrier(),
+			TrackingNumber: s.GetTrackingNumber(),
+			ShippedAt:      s.GetShippedAt().AsTime(),
+		})
+	}
+
+	return order
+}
+
+func toItemDTO(i *eventsp.Item) dto.ItemDTO {
+	return dto.ItemDTO{
+		ID:          i.GetId(),
+		Name:        i.GetName(),
+		Price:       i.GetPrice(),
+		Description: i.GetDescription(),
+		ImageURL:    i.GetImageUrl(),
+	}
+}
+
+// decodeLegacy decodes the JSON messages published before events had an
+// envelope.
+func decodeLegacy(eventType string, body []byte) (*Event, error) {
+	event := &Event{Type: eventType}
+
+	if eventType == "item.back_in_stock" {
+		var backInStock dto.BackInStockDTO
+		if err := json.Unmarshal(body, &backInStock); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+		event.BackInStock = &backInStock
+
+		return event, nil
+	}
+
+	var data struct {
+		User    *dto.UserDTO    `json:"user_info"`
+		Order   *dto.OrderDTO   `json:"order_info"`
+		Invoice *dto.InvoiceDTO `json:"invoice"`
+		Return  *dto.ReturnDTO  `json:"return_info"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if data.User == nil || data.Order == nil {
+		return nil, fmt.Errorf("%w: user_info and order_info are required", ErrInvalidEvent)
+	}
+
+	event.User = *data.User
+	event.Order = *data.Order
+	event.Invoice = data.Invoice
+	event.Return = data.Return
+
+	return event, nil
+}
@@ -0,0 +1,315 @@
+// Package followup sends the notifications that go out some time after the
+// event they follow: a reminder of a cart left behind, a request to review a
+// delivered order, and the weekly digest of new items. They are scheduled as
+// jobs when their event is consumed and cancelled when they no longer apply,
+// such as when the cart is checked out.
+package followup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"notification-service/internal/channel"
+	"notification-service/internal/data/dto"
+	"notification-service/internal/events"
+	"notification-service/internal/scheduler"
+	"notification-service/internal/templates"
+	"time"
+)
+
+// Kinds of the follow-up jobs. They are also the event types of their
+// templates.
+const (
+	AbandonedCart = "cart.abandoned"
+	ReviewRequest = "order.review_request"
+	WeeklyDigest  = "digest.weekly"
+)
+
+// digestKey is the key of the only digest job.
+const digestKey = "weekly"
+
+const (
+	// digestItems caps the items of a digest.
+	digestItems = 12
+	// digestPage is how many recipients of the digest are read at once.
+	digestPage = 500
+)
+
+// follows are the event types that schedule or cancel follow-ups.
+var follows = map[string]bool{
+	"cart.updated":     true,
+	"order.created":    true,
+	"order.delivered":  true,
+	"return.requested": true,
+}
+
+// Dispatcher sends notifications on the channels of their users.
+type Dispatcher interface {
+	Send(ctx context.Context, n *channel.Notification) error
+}
+
+// Links signs unsubscribe links.
+type Links interface {
+	Link(userId int32, category string) string
+}
+
+// Store reads the state of the other services the follow-ups depend on.
+type Store interface {
+	HasOpenCart(ctx context.Context, userId int32) (bool, error)
+	GetLatestItemId(ctx context.Context) (int32, error)
+	GetNewItems(ctx context.Context, afterId int32, limit int) ([]dto.ItemDTO, error)
+	GetDigestRecipients(ctx context.Context, afterId int32, limit int) ([]dto.UserDTO, error)
+}
+
+type FollowUps struct {
+	log                *slog.Logger
+	jobs               *scheduler.Scheduler
+	dispatcher         Dispatcher
+	templates          *templates.Registry
+	links              Links
+	store              Store
+	abandonedCartDelay time.Duration
+	reviewRequestDelay time.Duration
+	digestSchedule     string
+}
+
+func New(
+	log *slog.Logger,
+	jobs *scheduler.Scheduler,
+	dispatcher Dispatcher,
+	templates *templates.Registry,
+	links Links,
+	store Store,
+	abandonedCartDelay time.Duration,
+	reviewRequestDelay time.Duration,
+	digestSchedule string,
+) *FollowUps {
+	return &FollowUps{
+		log:                log,
+		jobs:               jobs,
+		dispatcher:         dispatcher,
+		templates:          templates,
+		links:              links,
+		store:              store,
+		abandonedCartDelay: abandonedCartDelay,
+		reviewRequestDelay: reviewRequestDelay,
+		digestSchedule:     digestSchedule,
+	}
+}
+
+// cartPayload is the payload of an abandoned cart reminder.
+type cartPayload struct {
+	User dto.UserDTO `json:"user"`
+	Cart dto.CartDTO `json:"cart"`
+}
+
+// orderPayload is the payload of a review request.
+type orderPayload struct {
+	User  dto.UserDTO  `json:"user"`
+	Order dto.OrderDTO `json:"order"`
+}
+
+// digestPayload is the payload of the digest. It holds the newest item of the
+// last digest sent.
+type digestPayload struct {
+	LastItemId int32 `json:"last_item_id"`
+}
+
+// Register sets the handlers of the follow-up jobs and schedules the digest.
+// The first digest has the items added after it was scheduled. An empty
+// digest schedule cancels the digest.
+func (f *FollowUps) Register(ctx context.Context) error {
+	const op = "followup.Register"
+
+	f.jobs.Handle(AbandonedCart, f.remindAbandonedCart)
+	f.jobs.Handle(ReviewRequest, f.requestReview)
+	f.jobs.Handle(WeeklyDigest, f.sendDigest)
+
+	if f.digestSchedule == "" {
+		if err := f.jobs.Cancel(ctx, WeeklyDigest, digestKey); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	}
+
+	latest, err := f.store.GetLatestItemId(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = f.jobs.Cron(ctx, WeeklyDigest, digestKey, f.digestSchedule, digestPayload{LastItemId: latest})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Follows reports whether events of eventType schedule or cancel follow-ups.
+func Follows(eventType string) bool {
+	return follows[eventType]
+}
+
+// Schedule schedules or cancels the follow-ups of an event of eventType. A
+// changed cart moves its reminder to a day after the change and an emptied or
+// checked out cart cancels it. A delivered order gets a review request a week
+// later, unless a return is requested before.
+func (f *FollowUps) Schedule(ctx context.Context, eventType string, event *events.Event) error {
+	if event.User.Id == 0 {
+		return nil
+	}
+
+	occurredAt := event.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	switch eventType {
+	case "cart.updated":
+		if event.Cart == nil || len(event.Cart.Lines) == 0 {
+			return f.jobs.Cancel(ctx, AbandonedCart, userKey(event.User.Id))
+		}
+		return f.jobs.At(ctx, AbandonedCart, userKey(event.User.Id), event.User.Id,
+			occurredAt.Add(f.abandonedCartDelay), cartPayload{User: event.User, Cart: *event.Cart})
+	case "order.created":
+		return f.jobs.Cancel(ctx, AbandonedCart, userKey(event.User.Id))
+	case "order.delivered":
+		return f.jobs.At(ctx, ReviewRequest, orderKey(event.Order.ID), event.User.Id,
+			occurredAt.Add(f.reviewRequestDelay), orderPayload{User: event.User, Order: event.Order})
+	case "return.requested":
+		return f.jobs.Cancel(ctx, ReviewRequest, orderKey(event.Order.ID))
+	}
+
+	return nil
+}
+
+// remindAbandonedCart reminds a user of their cart, unless it was checked
+// out or emptied since the reminder was scheduled.
+func (f *FollowUps) remindAbandonedCart(ctx context.Context, j *scheduler.Job) error {
+	var p cartPayload
+	if err := json.Unmarshal(j.Payload, &p); err != nil {
+		return err
+	}
+
+	open, err := f.store.HasOpenCart(ctx, p.User.Id)
+	if err != nil {
+		return err
+	}
+	if !open {
+		f.log.Debug("cart is no longer open", slog.Int("user id", int(p.User.Id)))
+		return nil
+	}
+
+	return f.notify(ctx, j.EventId(), AbandonedCart, p.User, 0, map[string]any{
+		"username": p.User.Username,
+		"lines":    p.Cart.Lines,
+		"total":    p.Cart.Total,
+	})
+}
+
+// requestReview asks a user to review the items of a delivered order.
+func (f *FollowUps) requestReview(ctx context.Context, j *scheduler.Job) error {
+	var p orderPayload
+	if err := json.Unmarshal(j.Payload, &p); err != nil {
+		return err
+	}
+
+	return f.notify(ctx, j.EventId(), ReviewRequest, p.User, p.Order.ID, map[string]any{
+		"username": p.User.Username,
+		"order_id": p.Order.ID,
+		"lines":    p.Order.Lines,
+	})
+}
+
+// sendDigest sends the items added since the last digest to every user
+// subscribed to marketing. Users notified before a failure are not notified
+// again when the digest is retried, as the notifications of a digest share an
+// event id.
+func (f *FollowUps) sendDigest(ctx context.Context, j *scheduler.Job) error {
+	var p digestPayload
+	if err := json.Unmarshal(j.Payload, &p); err != nil {
+		return err
+	}
+
+	items, err := f.store.GetNewItems(ctx, p.LastItemId, digestItems)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		f.log.Debug("no new items for the digest")
+		return nil
+	}
+
+	lastItemId := items[len(items)-1].ID
+	eventId := fmt.Sprintf("%s-%d", WeeklyDigest, lastItemId)
+
+	var errs []error
+	var afterId int32
+	for {
+		users, err := f.store.GetDigestRecipients(ctx, afterId, digestPage)
+		if err != nil {
+			return err
+		}
+
+		for _, u := range users {
+			err = f.notify(ctx, eventId, WeeklyDigest, u, 0, map[string]any{
+				"username": u.Username,
+				"items":    items,
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("user %d: %w", u.Id, err))
+			}
+		}
+
+		if len(users) < digestPage {
+			break
+		}
+		afterId = users[len(users)-1].Id
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	p.LastItemId = lastItemId
+	j.Payload, err = json.Marshal(p)
+
+	return err
+}
+
+// notify renders the template of eventType for user with data and sends it
+// with a link to unsubscribe from its category.
+func (f *FollowUps) notify(ctx context.Context, eventId, eventType string, user dto.UserDTO, orderId int32, data map[string]any) error {
+	unsubscribeURL := f.links.Link(user.Id, channel.CategoryOf(eventType))
+	data["unsubscribe_url"] = unsubscribeURL
+
+	tmpl, err := f.templates.Get(eventType, user.Locale)
+	if err != nil {
+		return err
+	}
+
+	msg, err := tmpl.Render(data)
+	if err != nil {
+		return err
+	}
+
+	return f.dispatcher.Send(ctx, &channel.Notification{
+		EventId:        eventId,
+		EventType:      eventType,
+		UserId:         user.Id,
+		OrderId:        orderId,
+		Template:       tmpl.EventType + "." + tmpl.Locale,
+		Email:          user.Email,
+		UnsubscribeURL: unsubscribeURL,
+		Message:        msg,
+	})
+}
+
+func userKey(userId int32) string {
+	return fmt.Sprintf("user:%d", userId)
+}
+
+func orderKey(orderId int32) string {
+	return fmt.Sprintf("order:%d", orderId)
+}
@@ -0,0 +1,97 @@
+package deliveryGrpc
+
+import (
+	"context"
+	"errors"
+	notificationp "github.com/sntabq/proto-gen/gen/go/notification"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"notification-service/internal/channel"
+	"notification-service/internal/data/dto"
+)
+
+type Deliveries interface {
+	ListDeliveries(ctx context.Context, f dto.DeliveryFilter) ([]*dto.DeliveryDTO, int64, error)
+	ResendDelivery(ctx context.Context, id int64) (*dto.DeliveryDTO, error)
+}
+
+type deliveryService struct {
+	notificationp.UnimplementedDeliveryServiceServer
+	deliveries Deliveries
+}
+
+func Register(gRPCServer *grpc.Server, deliveries Deliveries) {
+	notificationp.RegisterDeliveryServiceServer(gRPCServer, &deliveryService{deliveries: deliveries})
+}
+
+func (ds *deliveryService) ListDeliveries(ctx context.Context, req *notificationp.ListDeliveriesRequest) (*notificationp.ListDeliveriesResponse, error) {
+	deliveries, next, err := ds.deliveries.ListDeliveries(ctx, dto.DeliveryFilter{
+		UserId:   req.GetUserId(),
+		OrderId:  req.GetOrderId(),
+		Status:   req.GetStatus(),
+		Channel:  req.GetChannel(),
+		BeforeId: req.GetBeforeId(),
+		Limit:    req.GetLimit(),
+	})
+	if err != nil {
+		return nil, statusError(err, "failed to list deliveries")
+	}
+
+	resp := &notificationp.ListDeliveriesResponse{
+		Deliveries:   make([]*notificationp.Delivery, 0, len(deliveries)),
+		NextBeforeId: next,
+	}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, toProto(d))
+	}
+
+	return resp, nil
+}
+
+func (ds *deliveryService) ResendDelivery(ctx context.Context, req *notificationp.ResendDeliveryRequest) (*notificationp.ResendDeliveryResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	delivery, err := ds.deliveries.ResendDelivery(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err, "failed to resend delivery")
+	}
+
+	return &notificationp.ResendDeliveryResponse{Delivery: toProto(delivery)}, nil
+}
+
+func toProto(d *dto.DeliveryDTO) *notificationp.Delivery {
+	return &notificationp.Delivery{
+		Id:                d.ID,
+		EventId:           d.EventId,
+		EventType:         d.EventType,
+		UserId:            d.UserId,
+		OrderId:           d.OrderId,
+		Channel:           d.Channel,
+		Recipient:         d.Recipient,
+		Template:          d.Template,
+		Status:            d.Status,
+		Error:             d.Error,
+		Attempts:          d.Attempts,
+		ProviderMessageId: d.ProviderMessageId,
+		Subject:           d.Subject,
+		CreatedAt:         timestamppb.New(d.CreatedAt),
+		UpdatedAt:         timestamppb.New(d.UpdatedAt),
+	}
+}
+
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, channel.ErrDeliveryNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, channel.ErrNotFailed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, channel.ErrInvalidDeliveryQuery):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
@@ -0,0 +1,81 @@
+package inboxGrpc
+
+import (
+	"context"
+	"errors"
+	notificationp "github.com/sntabq/proto-gen/gen/go/notification"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"notification-service/internal/channel"
+	"notification-service/internal/data/dto"
+)
+
+type Inbox interface {
+	ListNotifications(ctx context.Context, unreadOnly bool, beforeId int64, limit int32) ([]*dto.InboxNotificationDTO, int32, int64, error)
+	MarkRead(ctx context.Context, ids []int64, all bool) (int32, int32, error)
+}
+
+type inboxService struct {
+	notificationp.UnimplementedInboxServiceServer
+	inbox Inbox
+}
+
+func Register(gRPCServer *grpc.Server, inbox Inbox) {
+	notificationp.RegisterInboxServiceServer(gRPCServer, &inboxService{inbox: inbox})
+}
+
+func (is *inboxService) ListNotifications(ctx context.Context, req *notificationp.ListNotificationsRequest) (*notificationp.ListNotificationsResponse, error) {
+	notifications, unread, next, err := is.inbox.ListNotifications(ctx, req.GetUnreadOnly(), req.GetBeforeId(), req.GetLimit())
+	if err != nil {
+		return nil, statusError(err, "failed to list notifications")
+	}
+
+	resp := &notificationp.ListNotificationsResponse{
+		Notifications: make([]*notificationp.Notification, 0, len(notifications)),
+		UnreadCount:   unread,
+		NextBeforeId:  next,
+	}
+	for _, n := range notifications {
+		resp.Notifications = append(resp.Notifications, toProto(n))
+	}
+
+	return resp, nil
+}
+
+func (is *inboxService) MarkNotificationsRead(ctx context.Context, req *notificationp.MarkNotificationsReadRequest) (*notificationp.MarkNotificationsReadResponse, error) {
+	marked, unread, err := is.inbox.MarkRead(ctx, req.GetIds(), req.GetAll())
+	if err != nil {
+		return nil, statusError(err, "failed to mark notifications read")
+	}
+
+	return &notificationp.MarkNotificationsReadResponse{
+		Marked:      marked,
+		UnreadCount: unread,
+	}, nil
+}
+
+func toProto(n *dto.InboxNotificationDTO) *notificationp.Notification {
+	notification := &notificationp.Notification{
+		Id:        n.ID,
+		EventType: n.EventType,
+		Subject:   n.Subject,
+		Body:      n.Body,
+		CreatedAt: timestamppb.New(n.CreatedAt),
+	}
+	if n.ReadAt != nil {
+		notification.ReadAt = timestamppb.New(*n.ReadAt)
+	}
+
+	return notification
+}
+
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, channel.ErrNoUser):
+		return status.Error(codes.Unauthenticated, err.Error())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
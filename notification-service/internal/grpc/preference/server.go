@@ -0,0 +1,142 @@
+package preferenceGrpc
+
+import (
+	"context"
+	"errors"
+	notificationp "github.com/sntabq/proto-gen/gen/go/notification"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"notification-service/internal/channel"
+	"notification-service/internal/data/dto"
+	"notification-service/internal/unsubscribe"
+)
+
+type Preferences interface {
+	GetPreferences(ctx context.Context) ([]*dto.ChannelPreferenceDTO, *dto.ContactsDTO, error)
+	UpdatePreferences(ctx context.Context, preferences []*dto.ChannelPreferenceDTO, contacts *dto.ContactsDTO) error
+	GetCategories(ctx context.Context) ([]*dto.CategoryPreferenceDTO, error)
+	UpdateCategories(ctx context.Context, preferences []*dto.CategoryPreferenceDTO) ([]*dto.CategoryPreferenceDTO, error)
+	Unsubscribe(ctx context.Context, token string) (string, error)
+}
+
+type preferenceService struct {
+	notificationp.UnimplementedPreferenceServiceServer
+	preferences Preferences
+}
+
+func Register(gRPCServer *grpc.Server, preferences Preferences) {
+	notificationp.RegisterPreferenceServiceServer(gRPCServer, &preferenceService{preferences: preferences})
+}
+
+func (ps *preferenceService) GetChannelPreferences(ctx context.Context, req *notificationp.GetChannelPreferencesRequest) (*notificationp.GetChannelPreferencesResponse, error) {
+	preferences, contacts, err := ps.preferences.GetPreferences(ctx)
+	if err != nil {
+		return nil, statusError(err, "failed to get preferences")
+	}
+
+	return &notificationp.GetChannelPreferencesResponse{
+		Preferences:     toProto(preferences),
+		Contacts:        &notificationp.Contacts{Phone: contacts.Phone, WebhookUrl: contacts.WebhookURL},
+		DefaultChannels: channel.Default,
+	}, nil
+}
+
+func (ps *preferenceService) UpdateChannelPreferences(ctx context.Context, req *notificationp.UpdateChannelPreferencesRequest) (*notificationp.UpdateChannelPreferencesResponse, error) {
+	preferences := make([]*dto.ChannelPreferenceDTO, 0, len(req.GetPreferences()))
+	for _, p := range req.GetPreferences() {
+		preferences = append(preferences, &dto.ChannelPreferenceDTO{
+			EventType: p.GetEventType(),
+			Channels:  p.GetChannels(),
+		})
+	}
+	contacts := &dto.ContactsDTO{
+		Phone:      req.GetContacts().GetPhone(),
+		WebhookURL: req.GetContacts().GetWebhookUrl(),
+	}
+
+	if err := ps.preferences.UpdatePreferences(ctx, preferences, contacts); err != nil {
+		return nil, statusError(err, "failed to update preferences")
+	}
+
+	return &notificationp.UpdateChannelPreferencesResponse{
+		Preferences: toProto(preferences),
+		Contacts:    &notificationp.Contacts{Phone: contacts.Phone, WebhookUrl: contacts.WebhookURL},
+	}, nil
+}
+
+func (ps *preferenceService) GetCategoryPreferences(ctx context.Context, req *notificationp.GetCategoryPreferencesRequest) (*notificationp.GetCategoryPreferencesResponse, error) {
+	preferences, err := ps.preferences.GetCategories(ctx)
+	if err != nil {
+		return nil, statusError(err, "failed to get category preferences")
+	}
+
+	return &notificationp.GetCategoryPreferencesResponse{Preferences: categoriesToProto(preferences)}, nil
+}
+
+func (ps *preferenceService) UpdateCategoryPreferences(ctx context.Context, req *notificationp.UpdateCategoryPreferencesRequest) (*notificationp.UpdateCategoryPreferencesResponse, error) {
+	preferences := make([]*dto.CategoryPreferenceDTO, 0, len(req.GetPreferences()))
+	for _, p := range req.GetPreferences() {
+		preferences = append(preferences, &dto.CategoryPreferenceDTO{
+			Category:   p.GetCategory(),
+			Subscribed: p.GetSubscribed(),
+		})
+	}
+
+	updated, err := ps.preferences.UpdateCategories(ctx, preferences)
+	if err != nil {
+		return nil, statusError(err, "failed to update category preferences")
+	}
+
+	return &notificationp.UpdateCategoryPreferencesResponse{Preferences: categoriesToProto(updated)}, nil
+}
+
+func (ps *preferenceService) Unsubscribe(ctx context.Context, req *notificationp.UnsubscribeRequest) (*notificationp.UnsubscribeResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	category, err := ps.preferences.Unsubscribe(ctx, req.GetToken())
+	if err != nil {
+		return nil, statusError(err, "failed to unsubscribe")
+	}
+
+	return &notificationp.UnsubscribeResponse{Category: category}, nil
+}
+
+func categoriesToProto(preferences []*dto.CategoryPreferenceDTO) []*notificationp.CategoryPreference {
+	list := make([]*notificationp.CategoryPreference, 0, len(preferences))
+	for _, p := range preferences {
+		list = append(list, &notificationp.CategoryPreference{
+			Category:   p.Category,
+			Subscribed: p.Subscribed,
+		})
+	}
+
+	return list
+}
+
+func toProto(preferences []*dto.ChannelPreferenceDTO) []*notificationp.ChannelPreference {
+	list := make([]*notificationp.ChannelPreference, 0, len(preferences))
+	for _, p := range preferences {
+		list = append(list, &notificationp.ChannelPreference{
+			EventType: p.EventType,
+			Channels:  p.Channels,
+		})
+	}
+
+	return list
+}
+
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, channel.ErrNoUser):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, unsubscribe.ErrInvalidToken):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, channel.ErrInvalidPreferences):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
@@ -0,0 +1,93 @@
+package templateGrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	notificationp "github.com/sntabq/proto-gen/gen/go/notification"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"notification-service/internal/templates"
+)
+
+type Templates interface {
+	Get(eventType, locale string) (*templates.Template, error)
+	List(eventType, locale string) []*templates.Template
+}
+
+type templateService struct {
+	notificationp.UnimplementedTemplateServiceServer
+	templates Templates
+}
+
+func Register(gRPCServer *grpc.Server, templates Templates) {
+	notificationp.RegisterTemplateServiceServer(gRPCServer, &templateService{templates: templates})
+}
+
+func (ts *templateService) ListTemplates(ctx context.Context, req *notificationp.ListTemplatesRequest) (*notificationp.ListTemplatesResponse, error) {
+	list := ts.templates.List(req.GetEventType(), req.GetLocale())
+
+	resp := &notificationp.ListTemplatesResponse{Templates: make([]*notificationp.Template, 0, len(list))}
+	for _, t := range list {
+		resp.Templates = append(resp.Templates, toProto(t))
+	}
+
+	return resp, nil
+}
+
+// PreviewTemplate renders a template without sending it. The data uses the
+// variable and field names of the templates, for example
+// {"username": "Jane", "lines": [{"Quantity": 1, "Item": {"Name": "Mug"}}]}.
+func (ts *templateService) PreviewTemplate(ctx context.Context, req *notificationp.PreviewTemplateRequest) (*notificationp.PreviewTemplateResponse, error) {
+	if req.GetEventType() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event_type is required")
+	}
+
+	t, err := ts.templates.Get(req.GetEventType(), req.GetLocale())
+	if err != nil {
+		return nil, statusError(err, "failed to get template")
+	}
+
+	data := templates.Sample(t.EventType)
+	if req.GetDataJson() != "" {
+		data = map[string]any{}
+		if err = json.Unmarshal([]byte(req.GetDataJson()), &data); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "data_json is not a JSON object: %v", err)
+		}
+	}
+
+	msg, err := t.Render(data)
+	if err != nil {
+		return nil, statusError(err, "failed to render template")
+	}
+
+	return &notificationp.PreviewTemplateResponse{
+		Template:  toProto(t),
+		Subject:   msg.Subject,
+		PlainBody: msg.PlainBody,
+		HtmlBody:  msg.HTMLBody,
+	}, nil
+}
+
+func toProto(t *templates.Template) *notificationp.Template {
+	return &notificationp.Template{
+		EventType:         t.EventType,
+		Locale:            t.Locale,
+		Source:            t.Source,
+		RequiredVariables: t.Required,
+	}
+}
+
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, templates.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, templates.ErrMissingVariables):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		// Executing a template fails when the data does not fit it, for
+		// example a field that does not exist.
+		return status.Errorf(codes.InvalidArgument, "%s: %v", msg, err)
+	}
+}
@@ -0,0 +1,191 @@
+package webhookGrpc
+
+import (
+	"context"
+	"errors"
+	notificationp "github.com/sntabq/proto-gen/gen/go/notification"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"notification-service/internal/data/dto"
+	"notification-service/internal/partner"
+	"time"
+)
+
+type Webhooks interface {
+	CreateWebhook(ctx context.Context, url string, events []string, description string) (*dto.WebhookDTO, error)
+	ListWebhooks(ctx context.Context) ([]*dto.WebhookDTO, error)
+	UpdateWebhook(ctx context.Context, webhook *dto.WebhookDTO) (*dto.WebhookDTO, error)
+	RotateWebhookSecret(ctx context.Context, id int64) (string, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	ListWebhookDeliveries(ctx context.Context, f dto.WebhookDeliveryFilter) ([]*dto.WebhookDeliveryDTO, int64, error)
+	RedeliverWebhookDelivery(ctx context.Context, id int64) (*dto.WebhookDeliveryDTO, error)
+}
+
+type webhookService struct {
+	notificationp.UnimplementedWebhookServiceServer
+	webhooks Webhooks
+}
+
+func Register(gRPCServer *grpc.Server, webhooks Webhooks) {
+	notificationp.RegisterWebhookServiceServer(gRPCServer, &webhookService{webhooks: webhooks})
+}
+
+func (ws *webhookService) CreateWebhook(ctx context.Context, req *notificationp.CreateWebhookRequest) (*notificationp.CreateWebhookResponse, error) {
+	webhook, err := ws.webhooks.CreateWebhook(ctx, req.GetUrl(), req.GetEvents(), req.GetDescription())
+	if err != nil {
+		return nil, statusError(err, "failed to create webhook")
+	}
+
+	return &notificationp.CreateWebhookResponse{Webhook: toProto(webhook), Secret: webhook.Secret}, nil
+}
+
+func (ws *webhookService) ListWebhooks(ctx context.Context, _ *notificationp.ListWebhooksRequest) (*notificationp.ListWebhooksResponse, error) {
+	webhooks, err := ws.webhooks.ListWebhooks(ctx)
+	if err != nil {
+		return nil, statusError(err, "failed to list webhooks")
+	}
+
+	resp := &notificationp.ListWebhooksResponse{Webhooks: make([]*notificationp.Webhook, 0, len(webhooks))}
+	for _, w := range webhooks {
+		resp.Webhooks = append(resp.Webhooks, toProto(w))
+	}
+
+	return resp, nil
+}
+
+func (ws *webhookService) UpdateWebhook(ctx context.Context, req *notificationp.UpdateWebhookRequest) (*notificationp.UpdateWebhookResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	webhook, err := ws.webhooks.UpdateWebhook(ctx, &dto.WebhookDTO{
+		ID:          req.GetId(),
+		URL:         req.GetUrl(),
+		Events:      req.GetEvents(),
+		Description: req.GetDescription(),
+		Enabled:     req.GetEnabled(),
+	})
+	if err != nil {
+		return nil, statusError(err, "failed to update webhook")
+	}
+
+	return &notificationp.UpdateWebhookResponse{Webhook: toProto(webhook)}, nil
+}
+
+func (ws *webhookService) RotateWebhookSecret(ctx context.Context, req *notificationp.RotateWebhookSecretRequest) (*notificationp.RotateWebhookSecretResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	secret, err := ws.webhooks.RotateWebhookSecret(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err, "failed to rotate webhook secret")
+	}
+
+	return &notificationp.RotateWebhookSecretResponse{Secret: secret}, nil
+}
+
+func (ws *webhookService) DeleteWebhook(ctx context.Context, req *notificationp.DeleteWebhookRequest) (*notificationp.DeleteWebhookResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	if err := ws.webhooks.DeleteWebhook(ctx, req.GetId()); err != nil {
+		return nil, statusError(err, "failed to delete webhook")
+	}
+
+	return &notificationp.DeleteWebhookResponse{}, nil
+}
+
+func (ws *webhookService) ListWebhookDeliveries(ctx context.Context, req *notificationp.ListWebhookDeliveriesRequest) (*notificationp.ListWebhookDeliveriesResponse, error) {
+	deliveries, next, err := ws.webhooks.ListWebhookDeliveries(ctx, dto.WebhookDeliveryFilter{
+		WebhookId: req.GetWebhookId(),
+		EventType: req.GetEventType(),
+		Status:    req.GetStatus(),
+		BeforeId:  req.GetBeforeId(),
+		Limit:     req.GetLimit(),
+	})
+	if err != nil {
+		return nil, statusError(err, "failed to list webhook deliveries")
+	}
+
+	resp := &notificationp.ListWebhookDeliveriesResponse{
+		Deliveries:   make([]*notificationp.WebhookDelivery, 0, len(deliveries)),
+		NextBeforeId: next,
+	}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, deliveryToProto(d))
+	}
+
+	return resp, nil
+}
+
+func (ws *webhookService) RedeliverWebhookDelivery(ctx context.Context, req *notificationp.RedeliverWebhookDeliveryRequest) (*notificationp.RedeliverWebhookDeliveryResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	delivery, err := ws.webhooks.RedeliverWebhookDelivery(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err, "failed to redeliver webhook delivery")
+	}
+
+	return &notificationp.RedeliverWebhookDeliveryResponse{Delivery: deliveryToProto(delivery)}, nil
+}
+
+func toProto(w *dto.WebhookDTO) *notificationp.Webhook {
+	return &notificationp.Webhook{
+		Id:             w.ID,
+		Url:            w.URL,
+		Events:         w.Events,
+		Description:    w.Description,
+		Enabled:        w.Enabled,
+		DisabledReason: w.DisabledReason,
+		FailingSince:   timestamp(w.FailingSince),
+		CreatedAt:      timestamppb.New(w.CreatedAt),
+		UpdatedAt:      timestamppb.New(w.UpdatedAt),
+	}
+}
+
+func deliveryToProto(d *dto.WebhookDeliveryDTO) *notificationp.WebhookDelivery {
+	return &notificationp.WebhookDelivery{
+		Id:             d.ID,
+		WebhookId:      d.WebhookId,
+		Url:            d.URL,
+		EventId:        d.EventId,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		Error:          d.Error,
+		Payload:        string(d.Payload),
+		NextAttemptAt:  timestamp(d.NextAttemptAt),
+		DeliveredAt:    timestamp(d.DeliveredAt),
+		CreatedAt:      timestamppb.New(d.CreatedAt),
+		UpdatedAt:      timestamppb.New(d.UpdatedAt),
+	}
+}
+
+// timestamp converts an optional time, leaving the field unset if it is nil.
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
+
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, partner.ErrWebhookNotFound), errors.Is(err, partner.ErrDeliveryNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, partner.ErrNotFailed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, partner.ErrInvalidWebhook), errors.Is(err, partner.ErrInvalidDeliveryQuery):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
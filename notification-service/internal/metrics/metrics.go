@@ -0,0 +1,103 @@
+// Package metrics exposes the throughput and lag of the notification
+// pipeline with expvar. They are served as JSON at /debug/vars along with the
+// memory statistics of the runtime.
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// Received counts the messages taken off the queue.
+	Received = expvar.NewInt("messages_received")
+	// Settled counts the handled messages by outcome: acked, retried,
+	// dead_lettered, rejected and requeued.
+	Settled = expvar.NewMap("messages_settled")
+	// InFlight is the number of messages the workers are handling.
+	InFlight = expvar.NewInt("messages_in_flight")
+	// QueueDepth is the number of messages waiting in the queue when it was
+	// last checked.
+	QueueDepth = expvar.NewInt("queue_depth")
+	// QueueLag is how long the last message took from being published to
+	// being taken off the queue, in milliseconds.
+	QueueLag = expvar.NewInt("queue_lag_ms")
+	// Notifications counts the notifications by channel and status, like
+	// email.sent.
+	Notifications = expvar.NewMap("notifications")
+)
+
+// throughput is the rate of settled messages over the last minute.
+var throughput = newMeter(60)
+
+func init() {
+	expvar.Publish("messages_per_second", expvar.Func(func() any {
+		return throughput.rate()
+	}))
+}
+
+// Settle counts a handled message with its outcome.
+func Settle(outcome string) {
+	Settled.Add(outcome, 1)
+	throughput.mark()
+}
+
+// Notification counts a notification sent on channel with status.
+func Notification(channel, status string) {
+	Notifications.Add(channel+"."+status, 1)
+}
+
+// Serve serves the metrics on addr until it fails.
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	return http.ListenAndServe(addr, mux)
+}
+
+// meter counts events in one bucket per second over a sliding window.
+type meter struct {
+	mu      sync.Mutex
+	buckets []int64
+	last    int64
+}
+
+func newMeter(seconds int) *meter {
+	return &meter{buckets: make([]int64, seconds)}
+}
+
+func (m *meter) mark() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().Unix()
+	m.advance(now)
+	m.buckets[now%int64(len(m.buckets))]++
+}
+
+// rate returns the events per second over the window.
+func (m *meter) rate() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.advance(time.Now().Unix())
+	var sum int64
+	for _, n := range m.buckets {
+		sum += n
+	}
+
+	return float64(sum) / float64(len(m.buckets))
+}
+
+// advance clears the buckets of the seconds since the last event.
+func (m *meter) advance(now int64) {
+	n := int64(len(m.buckets))
+	for s := max(m.last+1, now-n+1); s <= now; s++ {
+		m.buckets[s%n] = 0
+	}
+	if now > m.last {
+		m.last = now
+	}
+}
@@ -0,0 +1,87 @@
+// Package outbound guards the requests the service posts to URLs chosen by
+// users and partners, so that a webhook cannot be pointed at the service
+// itself or at the network it runs in.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInsecureURL     = errors.New("url must be an absolute https url")
+	ErrInternalAddress = errors.New("url must not point at an internal address")
+)
+
+// ValidateURL checks that rawURL is an absolute https URL whose host resolves
+// to public addresses only.
+func ValidateURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return ErrInsecureURL
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return checkIP(ip)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: %s does not resolve", ErrInternalAddress, host)
+	}
+	for _, addr := range addrs {
+		if err = checkIP(addr.IP); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewClient returns a client that only connects to public addresses and does
+// not follow redirects. The address is checked when the connection is made,
+// after the host was resolved, so a host that resolved to a public address
+// when its URL was validated cannot be moved to an internal one later.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			return checkIP(net.ParseIP(host))
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkIP rejects loopback, private, link-local, multicast and unspecified
+// addresses.
+func checkIP(ip net.IP) error {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return ErrInternalAddress
+	}
+
+	return nil
+}
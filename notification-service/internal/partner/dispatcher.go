@@ -0,0 +1,290 @@
+package partner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"notification-service/internal/data/dto"
+	"notification-service/internal/events"
+	"notification-service/internal/metrics"
+	"notification-service/internal/outbound"
+	"notification-service/sl"
+	"strconv"
+	"time"
+)
+
+// metricsChannel is the channel deliveries are counted under.
+const metricsChannel = "partner_webhook"
+
+// Attempt is a claimed delivery along with the webhook it is posted to.
+type Attempt struct {
+	ID        int64
+	WebhookId int64
+	URL       string
+	Secret    string
+	EventId   string
+	EventType string
+	Payload   []byte
+	// Attempts counts the attempts made, this one included.
+	Attempts int
+	// Lease is the token of the claim the attempt is made under.
+	Lease string
+}
+
+// Store keeps the webhooks and their deliveries.
+type Store interface {
+	// EnqueueWebhookDeliveries stores a pending delivery of an event for
+	// every enabled webhook subscribed to eventType, unless the event was
+	// stored for it before.
+	EnqueueWebhookDeliveries(ctx context.Context, eventId, eventType string, payload []byte) error
+	// ClaimWebhookDelivery locks the longest due delivery of an enabled
+	// webhook for lease, counts the attempt and returns it, or nil if no
+	// delivery is due.
+	ClaimWebhookDelivery(ctx context.Context, lease time.Duration) (*Attempt, error)
+	// RecordWebhookSuccess marks a delivery sent and clears the failures of
+	// its webhook. It fails with ErrLeaseLost if the delivery is no longer
+	// claimed under a.Lease.
+	RecordWebhookSuccess(ctx context.Context, a *Attempt, responseStatus int) error
+	// RecordWebhookFailure records a failed attempt and returns since when
+	// the webhook has been failing. The delivery is attempted again at
+	// nextAttemptAt, or failed if it is nil. It fails with ErrLeaseLost if
+	// the delivery is no longer claimed under a.Lease.
+	RecordWebhookFailure(ctx context.Context, a *Attempt, responseStatus int, errMsg string, nextAttemptAt *time.Time) (time.Time, error)
+	// DisableWebhook disables a webhook with reason.
+	DisableWebhook(ctx context.Context, id int64, reason string) error
+}
+
+// Payload is the body posted to webhooks.
+type Payload struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       PayloadData `json:"data"`
+}
+
+type PayloadData struct {
+	Order dto.OrderDTO `json:"order"`
+}
+
+// Dispatcher stores the events partners subscribed to as deliveries and posts
+// them to their webhooks. Failed attempts are retried after a growing delay;
+// a webhook that has failed for longer than disableAfter is disabled. Webhooks
+// on internal addresses are refused and redirects are not followed.
+type Dispatcher struct {
+	log            *slog.Logger
+	store          Store
+	client         *http.Client
+	interval       time.Duration
+	batch          int
+	lease          time.Duration
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	maxRetryDelay  time.Duration
+	disableAfter   time.Duration
+}
+
+func NewDispatcher(
+	log *slog.Logger,
+	store Store,
+	timeout time.Duration,
+	interval time.Duration,
+	batch int,
+	lease time.Duration,
+	maxAttempts int,
+	retryBaseDelay time.Duration,
+	maxRetryDelay time.Duration,
+	disableAfter time.Duration,
+) *Dispatcher {
+	return &Dispatcher{
+		log:            log,
+		store:          store,
+		client:         outbound.NewClient(timeout),
+		interval:       interval,
+		batch:          batch,
+		lease:          lease,
+		maxAttempts:    maxAttempts,
+		retryBaseDelay: retryBaseDelay,
+		maxRetryDelay:  maxRetryDelay,
+		disableAfter:   disableAfter,
+	}
+}
+
+// Enqueue stores an event of eventType for the webhooks subscribed to it.
+// Events of other types and events without an id, which could not be told
+// apart from their duplicates, are not posted.
+func (d *Dispatcher) Enqueue(ctx context.Context, eventType string, event *events.Event) error {
+	const op = "partner.Enqueue"
+
+	if !Publishes(eventType) || event.ID == "" {
+		return nil
+	}
+
+	occurredAt := event.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	payload, err := json.Marshal(Payload{
+		ID:         event.ID,
+		Type:       eventType,
+		OccurredAt: occurredAt.UTC(),
+		Data:       PayloadData{Order: event.Order},
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = d.store.EnqueueWebhookDeliveries(ctx, event.ID, eventType, payload); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Run posts the due deliveries every interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	const op = "partner.Run"
+	log := d.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := d.postDue(ctx)
+			if err != nil {
+				log.Error("failed to claim webhook deliveries", sl.Err(err))
+			}
+			// A full batch means more deliveries may be due.
+			if err != nil || n < d.batch || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// postDue posts up to a batch of due deliveries and returns how many it
+// posted. Deliveries are claimed one at a time, right before they are posted,
+// so that no delivery waits out its lease behind the others.
+func (d *Dispatcher) postDue(ctx context.Context) (int, error) {
+	n := 0
+	for ; n < d.batch && ctx.Err() == nil; n++ {
+		a, err := d.store.ClaimWebhookDelivery(ctx, d.lease)
+		if err != nil || a == nil {
+			return n, err
+		}
+
+		d.deliver(ctx, a)
+	}
+
+	return n, nil
+}
+
+// deliver posts a claimed delivery and records the outcome.
+func (d *Dispatcher) deliver(ctx context.Context, a *Attempt) {
+	log := d.log.With(
+		slog.Int64("delivery id", a.ID),
+		slog.Int64("webhook id", a.WebhookId),
+		slog.String("event id", a.EventId),
+	)
+
+	responseStatus, err := d.post(ctx, a)
+	if err == nil {
+		log.Debug("webhook delivered")
+		metrics.Notification(metricsChannel, StatusSent)
+		if err = d.store.RecordWebhookSuccess(ctx, a, responseStatus); err != nil {
+			d.recordFailed(log, err)
+		}
+		return
+	}
+
+	var next *time.Time
+	if a.Attempts < d.maxAttempts {
+		at := time.Now().Add(d.backoff(a.Attempts))
+		next = &at
+		log.Warn("retrying webhook delivery", sl.Err(err), slog.Int("attempt", a.Attempts), slog.Time("next attempt", at))
+	} else {
+		log.Error("webhook delivery failed", sl.Err(err), slog.Int("attempts", a.Attempts))
+		metrics.Notification(metricsChannel, StatusFailed)
+	}
+
+	failingSince, recordErr := d.store.RecordWebhookFailure(ctx, a, responseStatus, err.Error(), next)
+	if recordErr != nil {
+		d.recordFailed(log, recordErr)
+		return
+	}
+
+	if time.Since(failingSince) < d.disableAfter {
+		return
+	}
+
+	reason := fmt.Sprintf("every delivery failed since %s", failingSince.UTC().Format(time.RFC3339))
+	if err = d.store.DisableWebhook(ctx, a.WebhookId, reason); err != nil {
+		log.Error("failed to disable webhook", sl.Err(err))
+		return
+	}
+	log.Error("webhook disabled", slog.String("reason", reason))
+}
+
+// recordFailed logs that the outcome of an attempt could not be recorded. An
+// attempt that outlived its lease is expected to be recorded by the instance
+// that claimed the delivery again.
+func (d *Dispatcher) recordFailed(log *slog.Logger, err error) {
+	if errors.Is(err, ErrLeaseLost) {
+		log.Warn("webhook delivery was claimed again while it was posted", sl.Err(err))
+		return
+	}
+
+	log.Error("failed to record webhook delivery", sl.Err(err))
+}
+
+// post posts a delivery signed with the secret of its webhook and returns the
+// status the webhook answered with. Any status but 2xx fails.
+func (d *Dispatcher) post(ctx context.Context, a *Attempt) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(a.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderId, a.EventId)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(a.Secret, timestamp, a.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// Drain a little of the body so that the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook answered %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the attempt after attempt, doubling from
+// retryBaseDelay up to maxRetryDelay.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.retryBaseDelay
+	for i := 1; i < attempt && delay < d.maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, d.maxRetryDelay)
+}
@@ -0,0 +1,157 @@
+package partner
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestDeliverSignsPosts posts a delivery to a receiver that verifies it the
+// way partners are told to.
+func TestDeliverSignsPosts(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"id":"order-42-paid","type":"order.paid"}`)
+
+	var verifyErr error
+	var id string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = Verify(secret, r.Header, body, time.Now())
+		id = r.Header.Get(HeaderId)
+	}))
+	defer receiver.Close()
+
+	store := &attemptStore{}
+	d := newTestDispatcher(store, 3, time.Hour)
+	d.client = receiver.Client()
+	d.deliver(context.Background(), &Attempt{
+		ID: 1, WebhookId: 1, URL: receiver.URL, Secret: secret,
+		EventId: "order-42-paid", EventType: "order.paid", Payload: payload, Attempts: 1,
+	})
+
+	if verifyErr != nil {
+		t.Errorf("receiver rejected the delivery: %v", verifyErr)
+	}
+	if id != "order-42-paid" {
+		t.Errorf("%s = %q, want the event id", HeaderId, id)
+	}
+	if store.sent != 1 || len(store.failures) != 0 {
+		t.Errorf("recorded %d sent and %d failed attempts, want one sent", store.sent, len(store.failures))
+	}
+}
+
+// TestDeliverRetriesAndDisables fails deliveries to a broken receiver until
+// they run out of attempts and the webhook has failed long enough to be
+// disabled.
+func TestDeliverRetriesAndDisables(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := &attemptStore{failingSince: time.Now()}
+	d := newTestDispatcher(store, 2, time.Hour)
+	d.client = receiver.Client()
+	attempt := &Attempt{ID: 1, WebhookId: 7, URL: receiver.URL, Secret: "s", EventId: "e", Payload: []byte("{}")}
+
+	attempt.Attempts = 1
+	d.deliver(context.Background(), attempt)
+	attempt.Attempts = 2
+	d.deliver(context.Background(), attempt)
+
+	if len(store.failures) != 2 {
+		t.Fatalf("recorded %d failures, want 2", len(store.failures))
+	}
+	if f := store.failures[0]; f.next == nil || f.status != http.StatusInternalServerError {
+		t.Errorf("first failure %+v, want a retry after a 500", f)
+	}
+	if f := store.failures[1]; f.next != nil {
+		t.Errorf("last failure retries at %v, want the delivery failed", f.next)
+	}
+	if store.disabled != 0 {
+		t.Errorf("webhook %d disabled after failing for a moment, want it enabled", store.disabled)
+	}
+
+	store.failingSince = time.Now().Add(-2 * time.Hour)
+	d.deliver(context.Background(), attempt)
+	if store.disabled != 7 {
+		t.Errorf("disabled webhook %d, want 7", store.disabled)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := newTestDispatcher(nil, 12, time.Hour)
+	d.retryBaseDelay, d.maxRetryDelay = 30*time.Second, 6*time.Hour
+
+	for attempt, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		12: 6 * time.Hour,
+		70: 6 * time.Hour,
+	} {
+		if got := d.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func newTestDispatcher(store Store, maxAttempts int, disableAfter time.Duration) *Dispatcher {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewDispatcher(log, store, time.Second, time.Minute, 10, time.Minute, maxAttempts, time.Second, time.Minute, disableAfter)
+}
+
+type failure struct {
+	status int
+	next   *time.Time
+}
+
+// attemptStore records the outcomes of attempts. Its webhook has been failing
+// since failingSince.
+type attemptStore struct {
+	mu           sync.Mutex
+	failingSince time.Time
+	sent         int
+	failures     []failure
+	disabled     int64
+}
+
+func (s *attemptStore) EnqueueWebhookDeliveries(context.Context, string, string, []byte) error {
+	return nil
+}
+
+func (s *attemptStore) ClaimWebhookDelivery(context.Context, time.Duration) (*Attempt, error) {
+	return nil, nil
+}
+
+func (s *attemptStore) RecordWebhookSuccess(context.Context, *Attempt, int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent++
+
+	return nil
+}
+
+func (s *attemptStore) RecordWebhookFailure(_ context.Context, _ *Attempt, status int, _ string, next *time.Time) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, failure{status: status, next: next})
+
+	return s.failingSince, nil
+}
+
+func (s *attemptStore) DisableWebhook(_ context.Context, id int64, _ string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.disabled = id
+
+	return nil
+}
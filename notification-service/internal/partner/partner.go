@@ -0,0 +1,94 @@
+// Package partner posts the events of orders to the webhooks of partners,
+// such as merchants and integrators. An event is stored as a delivery for
+// every webhook subscribed to its type when it is consumed, and the
+// dispatcher posts the pending deliveries, signed with the secret of their
+// webhook, until they succeed or run out of attempts. A webhook that keeps
+// failing is disabled.
+package partner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"notification-service/internal/outbound"
+	"slices"
+)
+
+// Statuses of a delivery.
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// secretPrefix marks the secrets of webhooks, so that they are recognized
+// when they leak.
+const secretPrefix = "whsec_"
+
+// Events are the event types partners can subscribe to.
+var Events = []string{"order.created", "order.paid", "order.shipped", "order.delivered"}
+
+var (
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrNotFailed            = errors.New("only failed deliveries can be redelivered")
+	ErrInvalidWebhook       = errors.New("invalid webhook")
+	ErrInvalidDeliveryQuery = errors.New("invalid webhook delivery query")
+	// ErrLeaseLost is returned when the outcome of an attempt is recorded
+	// after its delivery was claimed again.
+	ErrLeaseLost = errors.New("webhook delivery is no longer claimed")
+)
+
+// Publishes reports whether events of eventType are posted to webhooks.
+func Publishes(eventType string) bool {
+	return slices.Contains(Events, eventType)
+}
+
+// ValidateWebhook checks that rawURL is an absolute https URL of a public
+// host and that events lists known event types, each once.
+func ValidateWebhook(ctx context.Context, rawURL string, events []string) error {
+	if err := outbound.ValidateURL(ctx, rawURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+
+	if len(events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", ErrInvalidWebhook)
+	}
+	for i, e := range events {
+		if !Publishes(e) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, e)
+		}
+		if slices.Contains(events[:i], e) {
+			return fmt.Errorf("%w: event %q is listed twice", ErrInvalidWebhook, e)
+		}
+	}
+
+	return nil
+}
+
+// ValidateDeliveryQuery checks the filters of a delivery query. Empty values
+// do not filter.
+func ValidateDeliveryQuery(status, eventType string) error {
+	switch status {
+	case "", StatusPending, StatusSent, StatusFailed:
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidDeliveryQuery, status)
+	}
+	if eventType != "" && !Publishes(eventType) {
+		return fmt.Errorf("%w: unknown event %q", ErrInvalidDeliveryQuery, eventType)
+	}
+
+	return nil
+}
+
+// NewSecret returns a random secret to sign the deliveries of a webhook with.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return secretPrefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,27 @@
+package partner
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateWebhook(t *testing.T) {
+	events := []string{"order.paid"}
+	for url, valid := range map[string]bool{
+		"https://93.184.216.34/hooks":    true,
+		"http://93.184.216.34/hooks":     false,
+		"https://localhost/hooks":        false,
+		"https://172.16.0.5/hooks":       false,
+		"https://169.254.169.254/latest": false,
+		"https://[::1]:8443/hooks":       false,
+	} {
+		err := ValidateWebhook(context.Background(), url, events)
+		if valid && err != nil {
+			t.Errorf("ValidateWebhook(%q) = %v, want it valid", url, err)
+		}
+		if !valid && !errors.Is(err, ErrInvalidWebhook) {
+			t.Errorf("ValidateWebhook(%q) = %v, want %v", url, err, ErrInvalidWebhook)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package partner
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of a delivery.
+const (
+	// HeaderId is the id of the event, the same on every attempt, so that
+	// receivers can drop duplicates.
+	HeaderId = "X-Webhook-Id"
+	// HeaderTimestamp is when the attempt was signed, in Unix seconds.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is the signature of the timestamp and body.
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// signatureVersion prefixes signatures, so that the scheme can change without
+// breaking receivers.
+const signatureVersion = "v1="
+
+// Tolerance is how far the timestamp of a delivery may be from the clock of
+// its receiver. Older deliveries are rejected as replays.
+const Tolerance = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("webhook timestamp out of tolerance")
+)
+
+// Sign returns the signature of a delivery of body signed at timestamp: the
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and timestamp headers of a delivery of body
+// the way receivers are expected to.
+func Verify(secret string, header http.Header, body []byte, now time.Time) error {
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	signature := header.Get(HeaderSignature)
+	if !strings.HasPrefix(signature, signatureVersion) ||
+		!hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+
+	if d := now.Sub(time.Unix(timestamp, 0)); d > Tolerance || d < -Tolerance {
+		return ErrStaleTimestamp
+	}
+
+	return nil
+}
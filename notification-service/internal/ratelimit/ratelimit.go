@@ -0,0 +1,124 @@
+// Package ratelimit keeps the notifications under the quotas of the
+// providers with token buckets, one for all messages and one per recipient
+// domain.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket that lets rate events through per second with
+// bursts of up to burst events. A nil Limiter does not limit.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// New returns a limiter of rate events per second, or nil if rate is not
+// positive. A burst below one defaults to the rate rounded up.
+func New(rate float64, burst int) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = int(math.Ceil(rate))
+	}
+
+	return &Limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until an event is allowed or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Domains limits every domain on its own. A domain gets its own rate if it
+// has one and the default rate otherwise.
+type Domains struct {
+	rate  float64
+	burst int
+	rates map[string]float64
+
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+// NewDomains returns a limiter of rate events per second and domain, with
+// the domains in rates limited to their own rate. A domain whose rate is not
+// positive is not limited.
+func NewDomains(rate float64, burst int, rates map[string]float64) *Domains {
+	normalized := make(map[string]float64, len(rates))
+	for domain, r := range rates {
+		normalized[strings.ToLower(domain)] = r
+	}
+
+	return &Domains{
+		rate:     rate,
+		burst:    burst,
+		rates:    normalized,
+		limiters: map[string]*Limiter{},
+	}
+}
+
+// Wait blocks until an event of domain is allowed or ctx is done.
+func (d *Domains) Wait(ctx context.Context, domain string) error {
+	if d == nil || domain == "" {
+		return nil
+	}
+
+	return d.limiter(strings.ToLower(domain)).Wait(ctx)
+}
+
+func (d *Domains) limiter(domain string) *Limiter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	l, ok := d.limiters[domain]
+	if !ok {
+		rate, ok := d.rates[domain]
+		if !ok {
+			rate = d.rate
+		}
+		l = New(rate, d.burst)
+		d.limiters[domain] = l
+	}
+
+	return l
+}
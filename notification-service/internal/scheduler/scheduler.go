@@ -0,0 +1,290 @@
+// Package scheduler runs jobs at a given time or on a cron schedule. Jobs are
+// kept in the database, so they survive restarts, and are claimed for a lease
+// so that a job is run by one instance at a time. A job that is not done when
+// its lease runs out, as when the instance running it crashed, is run again.
+//
+// Only one job of a kind and key is pending at a time: scheduling it again
+// moves it, and it can be cancelled by its kind and key as long as it has not
+// run.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"log/slog"
+	"notification-service/sl"
+	"time"
+)
+
+// Statuses of a job.
+const (
+	StatusPending   = "pending"
+	StatusDone      = "done"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+var (
+	ErrInvalidSchedule = errors.New("invalid cron schedule")
+	ErrUnknownKind     = errors.New("no handler for job kind")
+)
+
+// Job is a scheduled run of the handler of its kind.
+type Job struct {
+	ID   int64
+	Kind string
+	// Key tells the jobs of a kind apart, for example the user a reminder
+	// is for.
+	Key    string
+	UserId int32
+	// Payload is the JSON the handler runs with. A handler may change the
+	// payload of a recurring job for its next run.
+	Payload json.RawMessage
+	RunAt   time.Time
+	// Cron is the schedule of a recurring job, empty for a job that runs
+	// once.
+	Cron      string
+	Attempts  int
+	LastError string
+	// Lease is the token of the claim the job runs under. Its outcome is
+	// only recorded while the job still holds that claim.
+	Lease string
+}
+
+// EventId identifies the notifications of a job that runs once, so that a
+// retried job does not notify a user twice. Recurring jobs need an id of
+// their own per run.
+func (j *Job) EventId() string {
+	return fmt.Sprintf("job-%d", j.ID)
+}
+
+// Store keeps the jobs.
+type Store interface {
+	// SaveJob stores j, or updates the pending job of its kind and key,
+	// and sets its id.
+	SaveJob(ctx context.Context, j *Job) error
+	// EnsureJob stores j unless a job of its kind and key is pending. The
+	// pending job keeps its payload and only takes the schedule of j if it
+	// changed.
+	EnsureJob(ctx context.Context, j *Job) error
+	// CancelJobs cancels the pending job of kind and key and reports
+	// whether there was one.
+	CancelJobs(ctx context.Context, kind, key string) (bool, error)
+	// ClaimJob locks the longest due job for lease, counts the attempt and
+	// returns it, or nil if no job is due.
+	ClaimJob(ctx context.Context, lease time.Duration) (*Job, error)
+	// RescheduleJob releases a pending job still claimed under j.Lease to
+	// run again at its RunAt with its payload, attempts and last error.
+	RescheduleJob(ctx context.Context, j *Job) error
+	// FinishJob sets the final status of a pending job still claimed under
+	// j.Lease.
+	FinishJob(ctx context.Context, j *Job, status, lastError string) error
+}
+
+// Handler runs a job. A job whose handler fails is retried.
+type Handler func(ctx context.Context, j *Job) error
+
+type Scheduler struct {
+	log            *slog.Logger
+	store          Store
+	handlers       map[string]Handler
+	interval       time.Duration
+	batch          int
+	lease          time.Duration
+	maxAttempts    int
+	retryBaseDelay time.Duration
+}
+
+func New(
+	log *slog.Logger,
+	store Store,
+	interval time.Duration,
+	batch int,
+	lease time.Duration,
+	maxAttempts int,
+	retryBaseDelay time.Duration,
+) *Scheduler {
+	return &Scheduler{
+		log:            log,
+		store:          store,
+		handlers:       map[string]Handler{},
+		interval:       interval,
+		batch:          batch,
+		lease:          lease,
+		maxAttempts:    maxAttempts,
+		retryBaseDelay: retryBaseDelay,
+	}
+}
+
+// Handle sets the handler of the jobs of kind. Handlers are set before Run.
+func (s *Scheduler) Handle(kind string, h Handler) {
+	s.handlers[kind] = h
+}
+
+// At schedules a job of kind and key to run once at runAt with payload
+// encoded as JSON. A pending job of the same kind and key is moved to runAt
+// and takes the new payload.
+func (s *Scheduler) At(ctx context.Context, kind, key string, userId int32, runAt time.Time, payload any) error {
+	const op = "scheduler.At"
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	j := &Job{Kind: kind, Key: key, UserId: userId, Payload: body, RunAt: runAt}
+	if err = s.store.SaveJob(ctx, j); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Cron schedules a recurring job of kind and key on spec, a standard cron
+// expression such as "0 9 * * MON". payload is only used if the job is not
+// scheduled yet, so that the job keeps the payload of its last run across
+// restarts.
+func (s *Scheduler) Cron(ctx context.Context, kind, key, spec string, payload any) error {
+	const op = "scheduler.Cron"
+
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %v", op, ErrInvalidSchedule, err)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	j := &Job{Kind: kind, Key: key, Payload: body, RunAt: schedule.Next(time.Now()), Cron: spec}
+	if err = s.store.EnsureJob(ctx, j); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Cancel cancels the pending job of kind and key, if any.
+func (s *Scheduler) Cancel(ctx context.Context, kind, key string) error {
+	const op = "scheduler.Cancel"
+
+	cancelled, err := s.store.CancelJobs(ctx, kind, key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if cancelled {
+		s.log.Debug("job cancelled", slog.String("kind", kind), slog.String("key", key))
+	}
+
+	return nil
+}
+
+// Run runs the due jobs every interval until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	const op = "scheduler.Run"
+	log := s.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := s.runDue(ctx)
+			if err != nil {
+				log.Error("failed to claim jobs", sl.Err(err))
+			}
+			// A full batch means more jobs may be due.
+			if err != nil || n < s.batch || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue runs up to a batch of due jobs and returns how many it ran. Jobs are
+// claimed one at a time, right before they run, so that no job waits out its
+// lease behind the others.
+func (s *Scheduler) runDue(ctx context.Context) (int, error) {
+	n := 0
+	for ; n < s.batch && ctx.Err() == nil; n++ {
+		j, err := s.store.ClaimJob(ctx, s.lease)
+		if err != nil || j == nil {
+			return n, err
+		}
+
+		s.run(ctx, j)
+	}
+
+	return n, nil
+}
+
+// run runs a claimed job and records the outcome. A recurring job is moved to
+// its next run whether it succeeded or ran out of attempts. The handler is
+// cancelled when the lease runs out, before another instance may claim the
+// job again.
+func (s *Scheduler) run(ctx context.Context, j *Job) {
+	log := s.log.With(
+		slog.Int64("job id", j.ID),
+		slog.String("kind", j.Kind),
+		slog.String("key", j.Key),
+	)
+
+	err := ErrUnknownKind
+	if h, ok := s.handlers[j.Kind]; ok {
+		runCtx, cancel := context.WithTimeout(ctx, s.lease)
+		err = h(runCtx, j)
+		cancel()
+	}
+
+	var next time.Time
+	if j.Cron != "" {
+		schedule, parseErr := cron.ParseStandard(j.Cron)
+		if parseErr != nil {
+			log.Error("invalid schedule", sl.Err(parseErr))
+			s.finish(ctx, log, j, StatusFailed, parseErr.Error())
+			return
+		}
+		next = schedule.Next(time.Now())
+	}
+
+	switch {
+	case err == nil && j.Cron == "":
+		log.Debug("job done")
+		s.finish(ctx, log, j, StatusDone, "")
+		return
+	case err == nil:
+		log.Debug("job done", slog.Time("next run", next))
+		j.RunAt, j.Attempts, j.LastError = next, 0, ""
+	case errors.Is(err, ErrUnknownKind) || j.Attempts >= s.maxAttempts:
+		log.Error("job failed", sl.Err(err), slog.Int("attempts", j.Attempts))
+		if j.Cron == "" {
+			s.finish(ctx, log, j, StatusFailed, err.Error())
+			return
+		}
+		j.RunAt, j.Attempts, j.LastError = next, 0, err.Error()
+	default:
+		delay := s.retryBaseDelay << (j.Attempts - 1)
+		log.Warn("retrying job", sl.Err(err), slog.Int("attempt", j.Attempts), slog.Duration("delay", delay))
+		j.RunAt, j.LastError = time.Now().Add(delay), err.Error()
+	}
+
+	if err = s.store.RescheduleJob(ctx, j); err != nil {
+		log.Error("failed to reschedule job", sl.Err(err))
+	}
+}
+
+func (s *Scheduler) finish(ctx context.Context, log *slog.Logger, j *Job, status, lastError string) {
+	if err := s.store.FinishJob(ctx, j, status, lastError); err != nil {
+		log.Error("failed to finish job", sl.Err(err))
+	}
+}
@@ -0,0 +1,95 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"notification-service/internal/channel"
+	"notification-service/internal/data/dto"
+	"notification-service/sl"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+// Deliveries is the history of the notifications sent to users.
+type Deliveries struct {
+	log              *slog.Logger
+	deliveryProvider DeliveryRepo
+	resender         Resender
+}
+
+func New(log *slog.Logger, deliveryProvider DeliveryRepo, resender Resender) *Deliveries {
+	return &Deliveries{
+		log:              log,
+		deliveryProvider: deliveryProvider,
+		resender:         resender,
+	}
+}
+
+type DeliveryRepo interface {
+	GetDelivery(ctx context.Context, id int64) (*dto.DeliveryDTO, error)
+	GetDeliveries(ctx context.Context, f dto.DeliveryFilter) ([]*dto.DeliveryDTO, error)
+}
+
+type Resender interface {
+	Resend(ctx context.Context, delivery *dto.DeliveryDTO) (*dto.DeliveryDTO, error)
+}
+
+// ListDeliveries returns a page of the deliveries matching f and the id to
+// pass as BeforeId for the next page, zero on the last one.
+func (ds *Deliveries) ListDeliveries(ctx context.Context, f dto.DeliveryFilter) ([]*dto.DeliveryDTO, int64, error) {
+	const op = "Deliveries.ListDeliveries"
+	log := ds.log.With(slog.String("op", op))
+
+	if err := channel.ValidateDeliveryQuery(f.Status, f.Channel); err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case f.Limit <= 0:
+		f.Limit = defaultLimit
+	case f.Limit > maxLimit:
+		f.Limit = maxLimit
+	}
+
+	limit := f.Limit
+	f.Limit++
+	deliveries, err := ds.deliveryProvider.GetDeliveries(ctx, f)
+	if err != nil {
+		log.Warn("failed to get deliveries", sl.Err(err))
+		return nil, 0, err
+	}
+
+	var next int64
+	if len(deliveries) > int(limit) {
+		deliveries = deliveries[:limit]
+		next = deliveries[limit-1].ID
+	}
+
+	return deliveries, next, nil
+}
+
+// ResendDelivery sends a failed delivery again and returns it with the
+// outcome of the new attempt.
+func (ds *Deliveries) ResendDelivery(ctx context.Context, id int64) (*dto.DeliveryDTO, error) {
+	const op = "Deliveries.ResendDelivery"
+	log := ds.log.With(slog.String("op", op), slog.Int64("delivery id", id))
+
+	delivery, err := ds.deliveryProvider.GetDelivery(ctx, id)
+	if err != nil {
+		if !errors.Is(err, channel.ErrDeliveryNotFound) {
+			log.Warn("failed to get delivery", sl.Err(err))
+		}
+		return nil, err
+	}
+
+	delivery, err = ds.resender.Resend(ctx, delivery)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("delivery resent", slog.String("status", delivery.Status))
+
+	return delivery, nil
+}
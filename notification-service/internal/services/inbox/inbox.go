@@ -0,0 +1,104 @@
+package inbox
+
+import (
+	"context"
+	"log/slog"
+	grpcapp "notification-service/internal/app/grpc"
+	"notification-service/internal/channel"
+	"notification-service/internal/data/dto"
+	"notification-service/sl"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// Inbox is the in-app inbox of the calling user.
+type Inbox struct {
+	log           *slog.Logger
+	inboxProvider InboxRepo
+}
+
+func New(log *slog.Logger, inboxProvider InboxRepo) *Inbox {
+	return &Inbox{
+		log:           log,
+		inboxProvider: inboxProvider,
+	}
+}
+
+type InboxRepo interface {
+	GetNotifications(ctx context.Context, userId int32, unreadOnly bool, beforeId int64, limit int32) ([]*dto.InboxNotificationDTO, error)
+	CountUnread(ctx context.Context, userId int32) (int32, error)
+	MarkRead(ctx context.Context, userId int32, ids []int64, all bool) (int32, error)
+}
+
+// ListNotifications returns a page of the inbox, how many notifications are
+// unread and the id to pass as beforeId for the next page, zero on the last
+// one.
+func (i *Inbox) ListNotifications(ctx context.Context, unreadOnly bool, beforeId int64, limit int32) ([]*dto.InboxNotificationDTO, int32, int64, error) {
+	const op = "Inbox.ListNotifications"
+	log := i.log.With(slog.String("op", op))
+
+	caller, ok := grpcapp.CallerFromContext(ctx)
+	if !ok {
+		return nil, 0, 0, channel.ErrNoUser
+	}
+
+	switch {
+	case limit <= 0:
+		limit = defaultLimit
+	case limit > maxLimit:
+		limit = maxLimit
+	}
+
+	notifications, err := i.inboxProvider.GetNotifications(ctx, caller.UserId, unreadOnly, beforeId, limit+1)
+	if err != nil {
+		log.Warn("failed to get notifications", sl.Err(err))
+		return nil, 0, 0, err
+	}
+
+	var next int64
+	if len(notifications) > int(limit) {
+		notifications = notifications[:limit]
+		next = notifications[limit-1].ID
+	}
+
+	unread, err := i.inboxProvider.CountUnread(ctx, caller.UserId)
+	if err != nil {
+		log.Warn("failed to count unread notifications", sl.Err(err))
+		return nil, 0, 0, err
+	}
+
+	return notifications, unread, next, nil
+}
+
+// MarkRead marks notifications of the inbox as read and returns how many it
+// marked and how many are left unread.
+func (i *Inbox) MarkRead(ctx context.Context, ids []int64, all bool) (int32, int32, error) {
+	const op = "Inbox.MarkRead"
+	log := i.log.With(slog.String("op", op))
+
+	caller, ok := grpcapp.CallerFromContext(ctx)
+	if !ok {
+		return 0, 0, channel.ErrNoUser
+	}
+
+	var marked int32
+	if all || len(ids) > 0 {
+		var err error
+		marked, err = i.inboxProvider.MarkRead(ctx, caller.UserId, ids, all)
+		if err != nil {
+			log.Warn("failed to mark notifications read", sl.Err(err))
+			return 0, 0, err
+		}
+	}
+
+	unread, err := i.inboxProvider.CountUnread(ctx, caller.UserId)
+	if err != nil {
+		log.Warn("failed to count unread notifications", sl.Err(err))
+		return 0, 0, err
+	}
+
+	return marked, unread, nil
+}
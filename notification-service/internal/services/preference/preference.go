@@ -0,0 +1,201 @@
+package preference
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	grpcapp "notification-service/internal/app/grpc"
+	"notification-service/internal/channel"
+	"notification-service/internal/data/dto"
+	"notification-service/sl"
+	"strings"
+)
+
+// Preferences holds the categories and channels the calling user is notified
+// on.
+type Preferences struct {
+	log                *slog.Logger
+	preferenceProvider PreferenceRepo
+	tokens             TokenVerifier
+}
+
+func New(log *slog.Logger, preferenceProvider PreferenceRepo, tokens TokenVerifier) *Preferences {
+	return &Preferences{
+		log:                log,
+		preferenceProvider: preferenceProvider,
+		tokens:             tokens,
+	}
+}
+
+// TokenVerifier checks unsubscribe tokens and returns the user and category
+// they unsubscribe.
+type TokenVerifier interface {
+	Verify(token string) (int32, string, error)
+}
+
+type PreferenceRepo interface {
+	GetPreferences(ctx context.Context, userId int32) ([]*dto.ChannelPreferenceDTO, error)
+	GetContacts(ctx context.Context, userId int32) (*dto.ContactsDTO, error)
+	UpdatePreferences(ctx context.Context, userId int32, preferences []*dto.ChannelPreferenceDTO, contacts *dto.ContactsDTO) error
+	GetCategories(ctx context.Context, userId int32) ([]*dto.CategoryPreferenceDTO, error)
+	UpdateCategories(ctx context.Context, userId int32, preferences []*dto.CategoryPreferenceDTO) error
+}
+
+func (p *Preferences) GetPreferences(ctx context.Context) ([]*dto.ChannelPreferenceDTO, *dto.ContactsDTO, error) {
+	const op = "Preferences.GetPreferences"
+	log := p.log.With(slog.String("op", op))
+
+	caller, ok := grpcapp.CallerFromContext(ctx)
+	if !ok {
+		return nil, nil, channel.ErrNoUser
+	}
+
+	preferences, err := p.preferenceProvider.GetPreferences(ctx, caller.UserId)
+	if err != nil {
+		log.Warn("failed to get preferences", sl.Err(err))
+		return nil, nil, err
+	}
+
+	contacts, err := p.preferenceProvider.GetContacts(ctx, caller.UserId)
+	if err != nil {
+		log.Warn("failed to get contacts", sl.Err(err))
+		return nil, nil, err
+	}
+
+	return preferences, contacts, nil
+}
+
+// UpdatePreferences replaces the preferences and contacts of the caller. An
+// event type may only appear once, and an empty list of channels turns the
+// notifications of the type off.
+func (p *Preferences) UpdatePreferences(ctx context.Context, preferences []*dto.ChannelPreferenceDTO, contacts *dto.ContactsDTO) error {
+	const op = "Preferences.UpdatePreferences"
+	log := p.log.With(slog.String("op", op))
+
+	caller, ok := grpcapp.CallerFromContext(ctx)
+	if !ok {
+		return channel.ErrNoUser
+	}
+
+	seen := make(map[string]bool, len(preferences))
+	for _, pref := range preferences {
+		pref.EventType = strings.TrimSpace(pref.EventType)
+		if seen[pref.EventType] {
+			return fmt.Errorf("%w: event type %q appears twice", channel.ErrInvalidPreferences, pref.EventType)
+		}
+		seen[pref.EventType] = true
+
+		if err := channel.ValidateChannels(pref.Channels); err != nil {
+			return err
+		}
+		pref.Channels = unique(pref.Channels)
+	}
+
+	contacts.Phone = strings.TrimSpace(contacts.Phone)
+	contacts.WebhookURL = strings.TrimSpace(contacts.WebhookURL)
+	if err := channel.ValidateContacts(ctx, contacts.Phone, contacts.WebhookURL); err != nil {
+		return err
+	}
+
+	if err := p.preferenceProvider.UpdatePreferences(ctx, caller.UserId, preferences, contacts); err != nil {
+		log.Warn("failed to update preferences", sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// GetCategories returns whether the caller gets the notifications of each
+// category.
+func (p *Preferences) GetCategories(ctx context.Context) ([]*dto.CategoryPreferenceDTO, error) {
+	const op = "Preferences.GetCategories"
+	log := p.log.With(slog.String("op", op))
+
+	caller, ok := grpcapp.CallerFromContext(ctx)
+	if !ok {
+		return nil, channel.ErrNoUser
+	}
+
+	preferences, err := p.preferenceProvider.GetCategories(ctx, caller.UserId)
+	if err != nil {
+		log.Warn("failed to get category preferences", sl.Err(err))
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+// UpdateCategories sets the preferences of the caller for the given
+// categories and returns the preferences for all of them. Transactional
+// notifications can not be turned off.
+func (p *Preferences) UpdateCategories(ctx context.Context, preferences []*dto.CategoryPreferenceDTO) ([]*dto.CategoryPreferenceDTO, error) {
+	const op = "Preferences.UpdateCategories"
+	log := p.log.With(slog.String("op", op))
+
+	caller, ok := grpcapp.CallerFromContext(ctx)
+	if !ok {
+		return nil, channel.ErrNoUser
+	}
+
+	seen := make(map[string]bool, len(preferences))
+	for _, pref := range preferences {
+		if seen[pref.Category] {
+			return nil, fmt.Errorf("%w: category %q appears twice", channel.ErrInvalidPreferences, pref.Category)
+		}
+		seen[pref.Category] = true
+
+		if err := channel.ValidateCategory(pref.Category, pref.Subscribed); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.preferenceProvider.UpdateCategories(ctx, caller.UserId, preferences); err != nil {
+		log.Warn("failed to update category preferences", sl.Err(err))
+		return nil, err
+	}
+
+	return p.GetCategories(ctx)
+}
+
+// Unsubscribe unsubscribes the user of a signed token from its category and
+// returns the category. It needs no authentication, so that the link in an
+// email works without logging in.
+func (p *Preferences) Unsubscribe(ctx context.Context, token string) (string, error) {
+	const op = "Preferences.Unsubscribe"
+	log := p.log.With(slog.String("op", op))
+
+	userId, category, err := p.tokens.Verify(token)
+	if err != nil {
+		log.Warn("invalid unsubscribe token", sl.Err(err))
+		return "", err
+	}
+
+	if err = channel.ValidateCategory(category, false); err != nil {
+		return "", err
+	}
+
+	err = p.preferenceProvider.UpdateCategories(ctx, userId, []*dto.CategoryPreferenceDTO{
+		{Category: category, Subscribed: false},
+	})
+	if err != nil {
+		log.Warn("failed to unsubscribe", sl.Err(err))
+		return "", err
+	}
+
+	log.Info("user unsubscribed", slog.Int("user id", int(userId)), slog.String("category", category))
+
+	return category, nil
+}
+
+func unique(channels []string) []string {
+	seen := make(map[string]bool, len(channels))
+	out := make([]string, 0, len(channels))
+	for _, c := range channels {
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"notification-service/internal/data/dto"
+	"notification-service/internal/partner"
+	"notification-service/sl"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+// Webhooks manages the webhooks of partners and shows what was delivered to
+// them.
+type Webhooks struct {
+	log             *slog.Logger
+	webhookProvider WebhookRepo
+}
+
+func New(log *slog.Logger, webhookProvider WebhookRepo) *Webhooks {
+	return &Webhooks{
+		log:             log,
+		webhookProvider: webhookProvider,
+	}
+}
+
+type WebhookRepo interface {
+	CreateWebhook(ctx context.Context, w *dto.WebhookDTO) error
+	GetWebhooks(ctx context.Context) ([]*dto.WebhookDTO, error)
+	UpdateWebhook(ctx context.Context, w *dto.WebhookDTO) error
+	SetWebhookSecret(ctx context.Context, id int64, secret string) error
+	DeleteWebhook(ctx context.Context, id int64) error
+	GetWebhookDeliveries(ctx context.Context, f dto.WebhookDeliveryFilter) ([]*dto.WebhookDeliveryDTO, error)
+	RedeliverWebhookDelivery(ctx context.Context, id int64) (*dto.WebhookDeliveryDTO, error)
+}
+
+// CreateWebhook registers a webhook with a new secret and returns it along
+// with the secret.
+func (ws *Webhooks) CreateWebhook(ctx context.Context, url string, events []string, description string) (*dto.WebhookDTO, error) {
+	const op = "Webhooks.CreateWebhook"
+	log := ws.log.With(slog.String("op", op))
+
+	if err := partner.ValidateWebhook(ctx, url, events); err != nil {
+		return nil, err
+	}
+
+	secret, err := partner.NewSecret()
+	if err != nil {
+		log.Error("failed to generate secret", sl.Err(err))
+		return nil, err
+	}
+
+	webhook := &dto.WebhookDTO{URL: url, Events: events, Description: description, Secret: secret}
+	if err = ws.webhookProvider.CreateWebhook(ctx, webhook); err != nil {
+		log.Warn("failed to create webhook", sl.Err(err))
+		return nil, err
+	}
+	log.Info("webhook created", slog.Int64("webhook id", webhook.ID))
+
+	webhook.Secret = secret
+
+	return webhook, nil
+}
+
+func (ws *Webhooks) ListWebhooks(ctx context.Context) ([]*dto.WebhookDTO, error) {
+	const op = "Webhooks.ListWebhooks"
+	log := ws.log.With(slog.String("op", op))
+
+	webhooks, err := ws.webhookProvider.GetWebhooks(ctx)
+	if err != nil {
+		log.Warn("failed to get webhooks", sl.Err(err))
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// UpdateWebhook replaces the URL, events and description of a webhook and
+// enables or disables it.
+func (ws *Webhooks) UpdateWebhook(ctx context.Context, webhook *dto.WebhookDTO) (*dto.WebhookDTO, error) {
+	const op = "Webhooks.UpdateWebhook"
+	log := ws.log.With(slog.String("op", op), slog.Int64("webhook id", webhook.ID))
+
+	if err := partner.ValidateWebhook(ctx, webhook.URL, webhook.Events); err != nil {
+		return nil, err
+	}
+
+	if err := ws.webhookProvider.UpdateWebhook(ctx, webhook); err != nil {
+		if !errors.Is(err, partner.ErrWebhookNotFound) {
+			log.Warn("failed to update webhook", sl.Err(err))
+		}
+		return nil, err
+	}
+	log.Info("webhook updated", slog.Bool("enabled", webhook.Enabled))
+
+	return webhook, nil
+}
+
+// RotateWebhookSecret replaces the secret of a webhook and returns the new
+// one.
+func (ws *Webhooks) RotateWebhookSecret(ctx context.Context, id int64) (string, error) {
+	const op = "Webhooks.RotateWebhookSecret"
+	log := ws.log.With(slog.String("op", op), slog.Int64("webhook id", id))
+
+	secret, err := partner.NewSecret()
+	if err != nil {
+		log.Error("failed to generate secret", sl.Err(err))
+		return "", err
+	}
+
+	if err = ws.webhookProvider.SetWebhookSecret(ctx, id, secret); err != nil {
+		if !errors.Is(err, partner.ErrWebhookNotFound) {
+			log.Warn("failed to set webhook secret", sl.Err(err))
+		}
+		return "", err
+	}
+	log.Info("webhook secret rotated")
+
+	return secret, nil
+}
+
+func (ws *Webhooks) DeleteWebhook(ctx context.Context, id int64) error {
+	const op = "Webhooks.DeleteWebhook"
+	log := ws.log.With(slog.String("op", op), slog.Int64("webhook id", id))
+
+	if err := ws.webhookProvider.DeleteWebhook(ctx, id); err != nil {
+		if !errors.Is(err, partner.ErrWebhookNotFound) {
+			log.Warn("failed to delete webhook", sl.Err(err))
+		}
+		return err
+	}
+	log.Info("webhook deleted")
+
+	return nil
+}
+
+// ListWebhookDeliveries returns a page of the deliveries matching f and the
+// id to pass as BeforeId for the next page, zero on the last one.
+func (ws *Webhooks) ListWebhookDeliveries(ctx context.Context, f dto.WebhookDeliveryFilter) ([]*dto.WebhookDeliveryDTO, int64, error) {
+	const op = "Webhooks.ListWebhookDeliveries"
+	log := ws.log.With(slog.String("op", op))
+
+	if err := partner.ValidateDeliveryQuery(f.Status, f.EventType); err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case f.Limit <= 0:
+		f.Limit = defaultLimit
+	case f.Limit > maxLimit:
+		f.Limit = maxLimit
+	}
+
+	limit := f.Limit
+	f.Limit++
+	deliveries, err := ws.webhookProvider.GetWebhookDeliveries(ctx, f)
+	if err != nil {
+		log.Warn("failed to get webhook deliveries", sl.Err(err))
+		return nil, 0, err
+	}
+
+	var next int64
+	if len(deliveries) > int(limit) {
+		deliveries = deliveries[:limit]
+		next = deliveries[limit-1].ID
+	}
+
+	return deliveries, next, nil
+}
+
+// RedeliverWebhookDelivery makes a failed delivery pending again. It is
+// posted by the next run of the dispatcher.
+func (ws *Webhooks) RedeliverWebhookDelivery(ctx context.Context, id int64) (*dto.WebhookDeliveryDTO, error) {
+	const op = "Webhooks.RedeliverWebhookDelivery"
+	log := ws.log.With(slog.String("op", op), slog.Int64("delivery id", id))
+
+	delivery, err := ws.webhookProvider.RedeliverWebhookDelivery(ctx, id)
+	if err != nil {
+		if !errors.Is(err, partner.ErrDeliveryNotFound) && !errors.Is(err, partner.ErrNotFailed) {
+			log.Warn("failed to redeliver webhook delivery", sl.Err(err))
+		}
+		return nil, err
+	}
+	log.Info("webhook delivery queued again")
+
+	return delivery, nil
+}
@@ -0,0 +1,81 @@
+package templates
+
+import (
+	"notification-service/internal/data/dto"
+	"strings"
+	"time"
+)
+
+const sampleUnsubscribeURL = "https://example.com/v1/unsubscribe?token=sample"
+
+// Sample returns data to preview the templates of eventType with. It has the
+// variables the consumer passes for events of that type.
+func Sample(eventType string) map[string]any {
+	item := dto.ItemDTO{
+		ID:       1,
+		Name:     "Sample item",
+		Price:    1999,
+		ImageURL: "https://example.com/item.png",
+	}
+	lines := []dto.OrderLineDTO{
+		{ItemId: item.ID, Quantity: 2, UnitPrice: item.Price, Item: item},
+	}
+
+	data := map[string]any{
+		"username": "Jane",
+	}
+
+	switch eventType {
+	case "item.back_in_stock":
+		data["item"] = item
+		data["unsubscribe_url"] = sampleUnsubscribeURL
+		return data
+	case "digest.weekly":
+		data["items"] = []dto.ItemDTO{item}
+		data["unsubscribe_url"] = sampleUnsubscribeURL
+		return data
+	case "cart.abandoned":
+		data["lines"] = lines
+		data["total"] = int32(3998)
+		data["unsubscribe_url"] = sampleUnsubscribeURL
+		return data
+	case "order.review_request":
+		data["order_id"] = int32(1001)
+		data["lines"] = lines
+		data["unsubscribe_url"] = sampleUnsubscribeURL
+		return data
+	}
+
+	data["order_id"] = int32(1001)
+	data["lines"] = lines
+	data["total"] = int32(3998)
+	data["address"] = &dto.AddressDTO{
+		Recipient:  "Jane Doe",
+		Line1:      "1 Sample street",
+		City:       "Astana",
+		PostalCode: "010000",
+		Country:    "KZ",
+	}
+	data["invoice_number"] = "INV-2024-001001"
+
+	switch {
+	case eventType == "order.shipped" || eventType == "order.delivered":
+		data["shipment"] = dto.ShipmentDTO{
+			Carrier:        "DHL",
+			TrackingNumber: "SAMPLE123",
+			ShippedAt:      time.Now(),
+		}
+	case strings.HasPrefix(eventType, "return."):
+		data["return"] = dto.ReturnDTO{
+			ID:           1,
+			ItemId:       item.ID,
+			Quantity:     1,
+			Reason:       "Does not fit",
+			Status:       strings.TrimPrefix(eventType, "return."),
+			RefundAmount: item.Price,
+		}
+		data["return_item"] = item.Name
+	}
+
+	return data
+}
@@ -0,0 +1,327 @@
+// Package templates keeps the templates of the notifications by event type and
+// locale. The templates embedded in the binary can be overridden by the files
+// of a directory and by the templates stored in the database, in that order.
+//
+// A template is a set of named templates: "subject", "plainBody" and
+// "htmlBody" render the message, and "required" lists the variables the
+// template needs, separated by spaces. Files are named <event type>.<locale>.tmpl,
+// for example order.created.en.tmpl.
+package templates
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"notification-service/internal/data/dto"
+	"notification-service/sl"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed "files"
+var embedded embed.FS
+
+// DefaultLocale is used when there is no template in the locale of a user.
+const DefaultLocale = "en"
+
+const (
+	SourceEmbedded  = "embedded"
+	SourceDirectory = "directory"
+	SourceDatabase  = "database"
+)
+
+var (
+	ErrNotFound         = errors.New("template not found")
+	ErrInvalidTemplate  = errors.New("invalid template")
+	ErrMissingVariables = errors.New("missing template variables")
+)
+
+// Key identifies a template.
+type Key struct {
+	EventType string
+	Locale    string
+}
+
+// Template is a parsed template of an event type and locale.
+type Template struct {
+	EventType string
+	Locale    string
+	// Source is where the template was loaded from.
+	Source string
+	// Required are the variables the template can not be rendered without.
+	Required []string
+
+	tmpl *template.Template
+}
+
+// Message is a rendered template.
+type Message struct {
+	Subject   string
+	PlainBody string
+	HTMLBody  string
+}
+
+// Store holds the templates that override the files.
+type Store interface {
+	GetTemplates(ctx context.Context) ([]*dto.TemplateDTO, error)
+}
+
+type Registry struct {
+	log   *slog.Logger
+	dir   string
+	store Store
+
+	mu        sync.RWMutex
+	templates map[Key]*Template
+}
+
+// New returns a registry of the embedded templates overridden by the files in
+// dir and the templates in store. dir and store are optional. The registry is
+// empty until it is loaded.
+func New(log *slog.Logger, dir string, store Store) *Registry {
+	return &Registry{
+		log:       log,
+		dir:       dir,
+		store:     store,
+		templates: map[Key]*Template{},
+	}
+}
+
+// Load loads the templates from all sources. If any template is invalid, the
+// templates loaded before are kept.
+func (r *Registry) Load(ctx context.Context) error {
+	const op = "templates.Load"
+
+	templates := map[Key]*Template{}
+
+	files, err := fs.Sub(embedded, "files")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = loadFS(templates, files, SourceEmbedded); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if r.dir != "" {
+		if err = loadFS(templates, os.DirFS(r.dir), SourceDirectory); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if r.store != nil {
+		stored, err := r.store.GetTemplates(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, s := range stored {
+			t, err := parse(s.EventType, s.Locale, SourceDatabase, s.Body)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			templates[Key{EventType: t.EventType, Locale: t.Locale}] = t
+		}
+	}
+
+	r.mu.Lock()
+	r.templates = templates
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Run reloads the templates every interval until ctx is cancelled, so that
+// changed overrides are picked up without a restart.
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	const op = "templates.Run"
+	log := r.log.With(slog.String("op", op))
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Load(ctx); err != nil {
+				log.Error("failed to reload templates", sl.Err(err))
+			}
+		}
+	}
+}
+
+// Get returns the template of eventType in locale. It falls back to the
+// language of a regional locale and then to DefaultLocale.
+func (r *Registry) Get(eventType, locale string) (*Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, l := range fallbacks(locale) {
+		if t, ok := r.templates[Key{EventType: eventType, Locale: l}]; ok {
+			return t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, eventType)
+}
+
+// List returns the templates of eventType in locale sorted by event type and
+// locale. Empty arguments do not filter.
+func (r *Registry) List(eventType, locale string) []*Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*Template, 0, len(r.templates))
+	for k, t := range r.templates {
+		if (eventType == "" || k.EventType == eventType) && (locale == "" || k.Locale == locale) {
+			list = append(list, t)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].EventType != list[j].EventType {
+			return list[i].EventType < list[j].EventType
+		}
+		return list[i].Locale < list[j].Locale
+	})
+
+	return list
+}
+
+// Validate checks that data has every variable the template requires. A
+// variable set to nil counts as missing.
+func (t *Template) Validate(data map[string]any) error {
+	var missing []string
+	for _, name := range t.Required {
+		v, ok := data[name]
+		if !ok || v == nil {
+			missing = append(missing, name)
+			continue
+		}
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s.%s needs %s", ErrMissingVariables, t.EventType, t.Locale, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// Render validates data and renders the template with it.
+func (t *Template) Render(data map[string]any) (*Message, error) {
+	if err := t.Validate(data); err != nil {
+		return nil, err
+	}
+
+	var msg Message
+	for name, out := range map[string]*string{
+		"subject":   &msg.Subject,
+		"plainBody": &msg.PlainBody,
+		"htmlBody":  &msg.HTMLBody,
+	} {
+		buf := new(bytes.Buffer)
+		if err := t.tmpl.ExecuteTemplate(buf, name, data); err != nil {
+			return nil, err
+		}
+		*out = buf.String()
+	}
+
+	return &msg, nil
+}
+
+// loadFS parses the templates in the root of fsys into templates.
+func loadFS(templates map[Key]*Template, fsys fs.FS, source string) error {
+	names, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		eventType, locale, ok := splitName(name)
+		if !ok {
+			return fmt.Errorf("%w: %s is not named <event type>.<locale>.tmpl", ErrInvalidTemplate, name)
+		}
+
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		t, err := parse(eventType, locale, source, string(body))
+		if err != nil {
+			return err
+		}
+		templates[Key{EventType: eventType, Locale: locale}] = t
+	}
+
+	return nil
+}
+
+func parse(eventType, locale, source, body string) (*Template, error) {
+	tmpl, err := template.New(eventType).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s.%s: %v", ErrInvalidTemplate, eventType, locale, err)
+	}
+
+	for _, name := range []string{"subject", "plainBody", "htmlBody"} {
+		if tmpl.Lookup(name) == nil {
+			return nil, fmt.Errorf("%w: %s.%s does not define %q", ErrInvalidTemplate, eventType, locale, name)
+		}
+	}
+
+	t := &Template{EventType: eventType, Locale: locale, Source: source, tmpl: tmpl}
+	if tmpl.Lookup("required") != nil {
+		buf := new(bytes.Buffer)
+		if err = tmpl.ExecuteTemplate(buf, "required", nil); err != nil {
+			return nil, fmt.Errorf("%w: %s.%s: %v", ErrInvalidTemplate, eventType, locale, err)
+		}
+		t.Required = strings.Fields(buf.String())
+	}
+
+	return t, nil
+}
+
+// splitName splits a file name like order.created.en.tmpl into its event type
+// and locale.
+func splitName(name string) (string, string, bool) {
+	base := strings.TrimSuffix(filepath.Base(name), ".tmpl")
+	i := strings.LastIndex(base, ".")
+	if i <= 0 || i == len(base)-1 {
+		return "", "", false
+	}
+
+	return base[:i], base[i+1:], true
+}
+
+// fallbacks returns the locales to look a template up in, most specific
+// first: pt-BR, pt, en.
+func fallbacks(locale string) []string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+
+	var locales []string
+	if locale != "" {
+		locales = append(locales, locale)
+		if i := strings.Index(locale, "-"); i > 0 {
+			locales = append(locales, locale[:i])
+		}
+	}
+
+	return append(locales, DefaultLocale)
+}
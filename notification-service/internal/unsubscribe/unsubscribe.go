@@ -0,0 +1,67 @@
+// Package unsubscribe signs the links users unsubscribe from a category of
+// notifications with. A link carries the user and the category and an
+// HMAC-SHA256 signature of both, so it works without logging in and can not
+// be forged for another user.
+package unsubscribe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidToken = errors.New("invalid unsubscribe token")
+
+type Signer struct {
+	secret  []byte
+	baseURL string
+}
+
+// New returns a signer of links to baseURL, the page that unsubscribes users.
+func New(secret, baseURL string) *Signer {
+	return &Signer{secret: []byte(secret), baseURL: baseURL}
+}
+
+// Token returns the token that unsubscribes the user from category. It has
+// the form <user id>.<category>.<signature>.
+func (s *Signer) Token(userId int32, category string) string {
+	payload := strconv.Itoa(int(userId)) + "." + category
+
+	return payload + "." + s.sign(payload)
+}
+
+// Link returns the link that unsubscribes the user from category.
+func (s *Signer) Link(userId int32, category string) string {
+	return s.baseURL + "?token=" + url.QueryEscape(s.Token(userId, category))
+}
+
+// Verify checks the signature of token and returns the user and category it
+// unsubscribes.
+func (s *Signer) Verify(token string) (int32, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, "", ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(parts[0]+"."+parts[1]))) {
+		return 0, "", ErrInvalidToken
+	}
+
+	userId, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil || userId <= 0 {
+		return 0, "", ErrInvalidToken
+	}
+
+	return int32(userId), parts[1], nil
+}
+
+func (s *Signer) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -2,59 +2,62 @@ package mailer
 
 import (
 	"bytes"
-	"embed"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"github.com/go-mail/mail/v2"
-	"html/template"
 	"log"
 	"log/slog"
+	"notification-service/internal/templates"
+	"strings"
 	"time"
 )
 
-//go:embed "templates"
-var templateFS embed.FS
+// Attachment is a file sent along with an email.
+type Attachment struct {
+	Filename string
+	Data     []byte
+}
 
+// Mailer sends emails over a pool of SMTP connections that are kept open
+// between emails.
 type Mailer struct {
-	dialer *mail.Dialer
 	sender string
+	pool   *pool
 }
 
-func New(host string, port int, username, password, sender string) Mailer {
+// New returns a mailer that keeps up to poolSize connections open and closes
+// those idle for longer than idleTimeout. Dialing, and every read and write on
+// a connection, time out after timeout.
+func New(
+	host string,
+	port int,
+	username, password, sender string,
+	poolSize int,
+	timeout, idleTimeout time.Duration,
+) Mailer {
 	dialer := mail.NewDialer(host, port, username, password)
-	dialer.Timeout = 20 * time.Second
+	dialer.Timeout = timeout
+
 	return Mailer{
-		dialer: dialer,
 		sender: sender,
+		pool:   newPool(dialer, poolSize, idleTimeout),
 	}
 }
 
-func (m Mailer) Send(recipient, templateFile string, data any, logger *slog.Logger) error {
-	// Use the ParseFS() method to parse the required templates file from the embedded
-	// file system.
-	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
-	if err != nil {
-		return err
-	}
-	// Execute the named templates "subject", passing in the dynamic data and storing the
-	// result in a bytes.Buffer variable.
-	subject := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(subject, "subject", data)
-	if err != nil {
-		return err
-	}
-	// Follow the same pattern to execute the "plainBody" templates and store the result
-	// in the plainBody variable.
-	plainBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(plainBody, "plainBody", data)
-	if err != nil {
-		return err
-	}
-	// And likewise with the "htmlBody" templates.
-	htmlBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
+// Close closes the idle connections.
+func (m Mailer) Close() {
+	m.pool.close()
+}
+
+// Send emails a rendered template to recipient with the extra headers and
+// returns the Message-ID of the email.
+func (m Mailer) Send(recipient string, message *templates.Message, headers map[string]string, logger *slog.Logger, attachments ...Attachment) (string, error) {
+	messageId, err := m.messageId()
 	if err != nil {
-		return err
+		return "", err
 	}
+
 	// Use the mail.NewMessage() function to initialize a new mail.Message instance.
 	// Then we use the SetHeader() method to set the email recipient, sender and subject
 	// headers, the SetBody() method to set the plain-text body, and the AddAlternative()
@@ -63,19 +66,40 @@ func (m Mailer) Send(recipient, templateFile string, data any, logger *slog.Logg
 	msg := mail.NewMessage()
 	msg.SetHeader("To", recipient)
 	msg.SetHeader("From", m.sender)
-	msg.SetHeader("Subject", subject.String())
-	msg.SetBody("text/plain", plainBody.String())
-	msg.AddAlternative("text/html", htmlBody.String())
-	// Call the DialAndSend() method on the dialer, passing in the message to send. This
-	// opens a connection to the SMTP server, sends the message, then closes the
-	// connection. If there is a timeout, it will return a "dial tcp: i/o timeout"
-	// error.
-	err = m.dialer.DialAndSend(msg)
+	msg.SetHeader("Message-ID", messageId)
+	msg.SetHeader("Subject", message.Subject)
+	for k, v := range headers {
+		msg.SetHeader(k, v)
+	}
+	msg.SetBody("text/plain", message.PlainBody)
+	msg.AddAlternative("text/html", message.HTMLBody)
+	for _, a := range attachments {
+		msg.AttachReader(a.Filename, bytes.NewReader(a.Data))
+	}
+	// Send the message over a pooled connection. A connection is dialed when
+	// none is idle, and it is discarded if sending fails so that the next
+	// email does not reuse it in a broken state.
+	err = m.pool.send(msg)
 	if err != nil {
-		log.Printf("failed to dial and send %v", err)
-		return err
+		log.Printf("failed to send %v", err)
+		return "", err
 	}
 
 	logger.Info(fmt.Sprintf("mail sent to %s", recipient))
-	return nil
+	return messageId, nil
+}
+
+// messageId returns a new Message-ID in the domain of the sender.
+func (m Mailer) messageId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if i := strings.LastIndex(m.sender, "@"); i >= 0 {
+		domain = m.sender[i+1:]
+	}
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(id), domain), nil
 }
@@ -0,0 +1,121 @@
+package mailer
+
+import (
+	"github.com/go-mail/mail/v2"
+	"sync"
+	"time"
+)
+
+// conn is an SMTP connection and when it last sent an email.
+type conn struct {
+	mail.SendCloser
+	used time.Time
+}
+
+// pool keeps SMTP connections open between emails. At most size emails are
+// sent at once, each on its own connection, so there are never more than
+// size connections open.
+type pool struct {
+	dialer      *mail.Dialer
+	idleTimeout time.Duration
+	slots       chan struct{}
+
+	mu   sync.Mutex
+	idle []*conn
+}
+
+func newPool(dialer *mail.Dialer, size int, idleTimeout time.Duration) *pool {
+	if size < 1 {
+		size = 1
+	}
+
+	return &pool{
+		dialer:      dialer,
+		idleTimeout: idleTimeout,
+		slots:       make(chan struct{}, size),
+	}
+}
+
+// send sends msg on an idle connection, or on a new one if none is idle. A
+// send that fails on an idle connection, which the server may have closed in
+// the meantime, is tried once more on a new one. It blocks while all
+// connections are busy.
+func (p *pool) send(msg *mail.Message) error {
+	p.slots <- struct{}{}
+	defer func() { <-p.slots }()
+
+	c, reused, err := p.get()
+	if err != nil {
+		return err
+	}
+
+	err = mail.Send(c, msg)
+	if err != nil && reused {
+		_ = c.Close()
+		c, err = p.dial()
+		if err != nil {
+			return err
+		}
+		err = mail.Send(c, msg)
+	}
+	if err != nil {
+		_ = c.Close()
+		return err
+	}
+
+	c.used = time.Now()
+	p.mu.Lock()
+	p.idle = append(p.idle, c)
+	p.mu.Unlock()
+
+	return nil
+}
+
+// get returns the most recently used idle connection, reporting that it was
+// reused, and closes those idle for too long. It dials a new connection when
+// none is idle.
+func (p *pool) get() (*conn, bool, error) {
+	p.mu.Lock()
+	var c *conn
+	for len(p.idle) > 0 {
+		c = p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if p.idleTimeout <= 0 || time.Since(c.used) < p.idleTimeout {
+			break
+		}
+		_ = c.Close()
+		c = nil
+	}
+	p.mu.Unlock()
+
+	if c != nil {
+		return c, true, nil
+	}
+
+	c, err := p.dial()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return c, false, nil
+}
+
+func (p *pool) dial() (*conn, error) {
+	s, err := p.dialer.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{SendCloser: s}, nil
+}
+
+// close closes the idle connections.
+func (p *pool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, c := range p.idle {
+		_ = c.Close()
+	}
+	p.idle = nil
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification.templates;
+DROP SCHEMA IF EXISTS notification;
@@ -0,0 +1,11 @@
+CREATE SCHEMA IF NOT EXISTS notification;
+
+-- Templates that override the files embedded in notification-service. The body
+-- has the same format as the files.
+CREATE TABLE notification.templates(
+    event_type TEXT NOT NULL,
+    locale TEXT NOT NULL,
+    body TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (event_type, locale)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notification.contacts;
+DROP TABLE IF EXISTS notification.channel_preferences;
+DROP TABLE IF EXISTS notification.inbox;
@@ -0,0 +1,32 @@
+-- In-app notifications. event_id is NULL for events published without one,
+-- otherwise a retried event is only stored once per user.
+CREATE TABLE notification.inbox(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    user_id BIGINT NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    event_id TEXT,
+    event_type TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    read_at TIMESTAMPTZ,
+    UNIQUE (user_id, event_id)
+);
+
+CREATE INDEX inbox_user_id_id_idx ON notification.inbox(user_id, id DESC);
+CREATE INDEX inbox_unread_idx ON notification.inbox(user_id) WHERE read_at IS NULL;
+
+-- The channels a user is notified on per event type. The empty event type is
+-- the default of the user.
+CREATE TABLE notification.channel_preferences(
+    user_id BIGINT NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    channels TEXT[] NOT NULL,
+    PRIMARY KEY (user_id, event_type)
+);
+
+CREATE TABLE notification.contacts(
+    user_id BIGINT PRIMARY KEY REFERENCES auth.users(id) ON DELETE CASCADE,
+    phone TEXT NOT NULL DEFAULT '',
+    webhook_url TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,27 @@
+-- Every attempt to notify a user, one row per event, user and channel. A
+-- retried or resent notification updates its row and counts the attempt.
+CREATE TABLE notification.deliveries(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    event_id TEXT,
+    event_type TEXT NOT NULL,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    order_id BIGINT NOT NULL DEFAULT 0,
+    channel TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    template TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL CHECK (status IN ('sent', 'failed', 'skipped')),
+    error TEXT NOT NULL DEFAULT '',
+    attempts INT NOT NULL DEFAULT 1,
+    provider_message_id TEXT NOT NULL DEFAULT '',
+    subject TEXT NOT NULL,
+    plain_body TEXT NOT NULL DEFAULT '',
+    html_body TEXT NOT NULL DEFAULT '',
+    attachments JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (event_id, user_id, channel)
+);
+
+CREATE INDEX deliveries_user_id_id_idx ON notification.deliveries(user_id, id DESC);
+CREATE INDEX deliveries_order_id_id_idx ON notification.deliveries(order_id, id DESC) WHERE order_id <> 0;
+CREATE INDEX deliveries_failed_idx ON notification.deliveries(id DESC) WHERE status = 'failed';
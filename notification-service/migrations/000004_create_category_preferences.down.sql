@@ -0,0 +1 @@
+DROP TABLE IF EXISTS notification.category_preferences;
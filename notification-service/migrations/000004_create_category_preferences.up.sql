@@ -0,0 +1,9 @@
+-- Whether a user gets the notifications of a category. Users without a row
+-- for a category are subscribed to it.
+CREATE TABLE notification.category_preferences(
+    user_id BIGINT NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    category TEXT NOT NULL,
+    subscribed BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, category)
+);
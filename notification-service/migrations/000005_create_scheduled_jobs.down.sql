@@ -0,0 +1 @@
+DROP TABLE IF EXISTS notification.scheduled_jobs;
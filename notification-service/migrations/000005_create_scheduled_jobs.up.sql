@@ -0,0 +1,21 @@
+-- Jobs of the scheduler. A job runs once at run_at, or on its cron schedule
+-- when it has one. Only one job of a kind and dedupe key is pending at a
+-- time; locked_until is set while an instance runs the job.
+CREATE TABLE notification.scheduled_jobs(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    kind TEXT NOT NULL,
+    dedupe_key TEXT NOT NULL,
+    user_id BIGINT REFERENCES auth.users(id) ON DELETE CASCADE,
+    payload JSONB NOT NULL DEFAULT '{}',
+    cron TEXT NOT NULL DEFAULT '',
+    run_at TIMESTAMPTZ NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    locked_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX scheduled_jobs_pending_key ON notification.scheduled_jobs(kind, dedupe_key) WHERE status = 'pending';
+CREATE INDEX scheduled_jobs_due_idx ON notification.scheduled_jobs(run_at) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification.webhook_deliveries;
+DROP TABLE IF EXISTS notification.webhooks;
@@ -0,0 +1,39 @@
+-- Webhooks of partners and the events they subscribed to. A webhook whose
+-- deliveries keep failing is disabled; failing_since is when its current run
+-- of failed attempts began.
+CREATE TABLE notification.webhooks(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    secret TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    disabled_reason TEXT NOT NULL DEFAULT '',
+    failing_since TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Deliveries of events to webhooks, one row per webhook and event. Pending
+-- deliveries are attempted at next_attempt_at; locked_until is set while an
+-- instance posts the event.
+CREATE TABLE notification.webhook_deliveries(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    webhook_id BIGINT NOT NULL REFERENCES notification.webhooks(id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    response_status INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX webhook_deliveries_webhook_id_id_idx ON notification.webhook_deliveries(webhook_id, id DESC);
+CREATE INDEX webhook_deliveries_due_idx ON notification.webhook_deliveries(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1 @@
+ALTER TABLE notification.scheduled_jobs DROP COLUMN IF EXISTS lease_token;
@@ -0,0 +1,4 @@
+-- lease_token is set when an instance claims a job. The instance only records
+-- the outcome of its run while the token is unchanged, so that a job moved by
+-- SaveJob, or claimed again after its lease ran out, is not overwritten.
+ALTER TABLE notification.scheduled_jobs ADD COLUMN lease_token TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE notification.webhook_deliveries DROP COLUMN IF EXISTS lease_token;
@@ -0,0 +1,4 @@
+-- lease_token is set when an instance claims a delivery. The instance only
+-- records the outcome of its attempt while the token is unchanged, so that a
+-- delivery claimed again after its lease ran out is not recorded twice.
+ALTER TABLE notification.webhook_deliveries ADD COLUMN lease_token TEXT NOT NULL DEFAULT '';
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	auth "github.com/sntabq/proto-gen/gen/go/auth"
+	cataloguep "github.com/sntabq/proto-gen/gen/go/catalogue"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"log"
 	"log/slog"
 	"order-service/config"
@@ -24,19 +27,37 @@ const (
 func main() {
 	cfg := config.LoadConfig()
 	log := setupLogger(cfg.Env)
-	application := app.New(log, cfg.GRPC.Port, cfg.StoragePath, cfg.TokenTtl)
+	application := app.New(log, cfg)
 
 	ConnectToSsoService()
+	ConnectToCatalogueService(cfg.ServiceToken)
 	go func() {
 		application.GRPCServer.MustRun()
 	}()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	go application.OutboxRelay.Run(ctx)
+	go application.Watch.Run(ctx)
+	go application.Sagas.RunPending(ctx, cfg.Saga.ResumeInterval)
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 
 	<-stop
 
-	application.GRPCServer.Stop()
+	// A graceful stop stops accepting calls and then waits for the open
+	// streams, so the hub ends the watch streams while it runs. Once closed,
+	// the hub also ends the watch streams accepted until the server stopped
+	// accepting.
+	stopped := make(chan struct{})
+	go func() {
+		application.GRPCServer.Stop()
+		close(stopped)
+	}()
+	application.Watch.Close()
+	<-stopped
+	cancel()
+	_ = application.Broker.Close()
 	log.Info("Catalogue service gracefully stopped")
 }
 
@@ -78,4 +99,21 @@ func ConnectToSsoService() {
 	}
 	grpcapp.AuthServiceClient = auth.NewAuthClient(conn)
 	grpcapp.UserInfoServiceClient = auth.NewUserInfoClient(conn)
+	grpcapp.AddressBookClient = auth.NewAddressBookClient(conn)
+}
+
+// ConnectToCatalogueService connects to the catalogue service, sending
+// serviceToken with every call so that the stock of items may be changed.
+func ConnectToCatalogueService(serviceToken string) {
+	conn, err := grpc.NewClient("0.0.0.0:44045",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			ctx = metadata.AppendToOutgoingContext(ctx, "x-service-token", serviceToken)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+	)
+	if err != nil {
+		log.Fatalf("failed to connect to catalogue service: %v", err)
+	}
+	grpcapp.StockServiceClient = cataloguep.NewStockServiceClient(conn)
 }
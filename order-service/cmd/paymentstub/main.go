@@ -0,0 +1,173 @@
+// Command paymentstub is a stand-in payment provider for local runs of the
+// webhook payment provider. Authorizations and voids settle right away;
+// captures and refunds answer "pending" and settle a moment later through a
+// signed webhook sent to the gateway.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"order-service/internal/payment"
+	"sync"
+	"time"
+)
+
+type stub struct {
+	webhookURL   string
+	secret       string
+	declineAbove int32
+
+	mu       sync.Mutex
+	seq      int
+	payments map[string]int32
+	// refs maps the idempotency keys of authorizations to their payments.
+	refs map[string]string
+}
+
+type request struct {
+	OrderId int32  `json:"order_id"`
+	Ref     string `json:"ref"`
+	Amount  int32  `json:"amount"`
+}
+
+func main() {
+	addr := flag.String("addr", ":8090", "address to listen on")
+	webhookURL := flag.String("webhook-url", "http://localhost:8080/webhooks/payments", "where to send webhooks")
+	secret := flag.String("secret", "local-webhook-secret", "secret used to sign webhooks")
+	declineAbove := flag.Int("decline-above", 0, "decline authorizations above this amount, 0 accepts all")
+	flag.Parse()
+
+	s := &stub{
+		webhookURL:   *webhookURL,
+		secret:       *secret,
+		declineAbove: int32(*declineAbove),
+		payments:     make(map[string]int32),
+		refs:         make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/capture", s.settleLater(payment.EventCaptured))
+	mux.HandleFunc("/refund", s.settleLater(payment.EventRefunded))
+	mux.HandleFunc("/void", s.void)
+
+	log.Printf("payment stub listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func (s *stub) authorize(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.declineAbove > 0 && req.Amount > s.declineAbove {
+		http.Error(w, "declined", http.StatusPaymentRequired)
+		return
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+
+	s.mu.Lock()
+	ref, ok := s.refs[key]
+	if !ok || key == "" {
+		s.seq++
+		ref = fmt.Sprintf("stub_%d_%d", req.OrderId, s.seq)
+		s.payments[ref] = req.Amount
+		if key != "" {
+			s.refs[key] = ref
+		}
+	}
+	s.mu.Unlock()
+
+	writeResult(w, ref, payment.StatusAuthorized)
+}
+
+func (s *stub) void(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.known(req.Ref) {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeResult(w, req.Ref, payment.StatusVoided)
+}
+
+// settleLater answers pending and sends eventType for the payment shortly
+// after.
+func (s *stub) settleLater(eventType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !s.known(req.Ref) {
+			http.NotFound(w, r)
+			return
+		}
+
+		s.mu.Lock()
+		s.seq++
+		event := payment.Event{
+			ID:     fmt.Sprintf("evt_%d", s.seq),
+			Type:   eventType,
+			Ref:    req.Ref,
+			Amount: req.Amount,
+		}
+		s.mu.Unlock()
+
+		go func() {
+			time.Sleep(time.Second)
+			s.send(event)
+		}()
+
+		writeResult(w, req.Ref, payment.StatusPending)
+	}
+}
+
+func (s *stub) send(event payment.Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("failed to marshal event: %v", err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("failed to create webhook request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Payment-Signature", payment.Sign(s.secret, body))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("failed to send %s webhook: %v", event.Type, err)
+		return
+	}
+	resp.Body.Close()
+
+	log.Printf("sent %s webhook for %s: %s", event.Type, event.Ref, resp.Status)
+}
+
+func (s *stub) known(ref string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.payments[ref]
+	return ok
+}
+
+func writeResult(w http.ResponseWriter, ref string, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment.Result{Ref: ref, Status: status})
+}
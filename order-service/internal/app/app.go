@@ -2,32 +2,102 @@ package app
 
 import (
 	"log/slog"
+	"order-service/config"
 	grpcapp "order-service/internal/app/grpc"
 	"order-service/internal/data"
+	paymentp "order-service/internal/payment"
+	"order-service/internal/services/cart"
+	"order-service/internal/services/coupon"
 	"order-service/internal/services/order"
-	"time"
+	"order-service/internal/services/payment"
+	"order-service/internal/services/report"
+	"order-service/internal/services/rma"
+	"order-service/internal/services/saga"
+	"order-service/internal/watch"
+	"shared/broker"
+	"shared/outbox"
 )
 
 type App struct {
-	GRPCServer *grpcapp.App
+	GRPCServer  *grpcapp.App
+	OutboxRelay *outbox.Relay
+	Broker      broker.Publisher
+	Sagas       *saga.Orchestrator
+	Watch       *watch.Hub
 }
 
 func New(
 	log *slog.Logger,
-	grpcPort int,
-	dsn string,
-	tokenTTL time.Duration,
+	cfg *config.Config,
 ) *App {
+	dsn := cfg.StoragePath
+
 	storage, err := data.New(dsn)
 	if err != nil {
 		panic(err)
 	}
 
-	orderService := order.New(log, storage, tokenTTL)
+	cartStorage, err := data.NewCartStorage(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	outboxStorage, err := data.NewOutboxStorage(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	sagaStorage, err := data.NewSagaStorage(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	paymentStorage, err := data.NewPaymentStorage(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	couponStorage, err := data.NewCouponStorage(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	returnStorage, err := data.NewReturnStorage(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	reportStorage, err := data.NewReportStorage(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	var provider paymentp.Provider
+	switch cfg.Payment.Provider {
+	case "webhook":
+		provider = paymentp.NewWebhookProvider(cfg.Payment.URL)
+	default:
+		provider = paymentp.NewFakeProvider()
+	}
+
+	publisher := broker.NewRabbitMQ(cfg.RabbitMQ.URL)
+
+	paymentService := payment.New(log, paymentStorage, storage, provider, cfg.Payment.WebhookSecret)
+	sagas := saga.New(log, sagaStorage, storage, paymentService, cfg.Saga.Lease)
+	hub := watch.New(log, dsn)
+	orderService := order.New(log, storage, sagas, hub, cfg.TokenTtl, cfg.IdempotencyTtl, cfg.Checkout.ShippingFee)
+	cartService := cart.New(log, cartStorage, couponStorage, cfg.Checkout.ShippingFee)
+	couponService := coupon.New(log, couponStorage)
+	returnService := rma.New(log, returnStorage, storage, paymentService)
+	reportService := report.New(log, reportStorage)
 
-	grpcApp := grpcapp.New(log, orderService, grpcPort)
+	grpcApp := grpcapp.New(log, orderService, cartService, orderService, paymentService, couponService, returnService, reportService, cfg.GRPC.Port)
 
 	return &App{
-		GRPCServer: grpcApp,
+		GRPCServer:  grpcApp,
+		OutboxRelay: outbox.New(log, outboxStorage, publisher, cfg.Outbox.Interval, cfg.Outbox.BatchSize, cfg.Outbox.Lease),
+		Broker:      publisher,
+		Sagas:       sagas,
+		Watch:       hub,
 	}
 }
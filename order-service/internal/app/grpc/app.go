@@ -5,6 +5,7 @@ import (
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
 	auth "github.com/sntabq/proto-gen/gen/go/auth"
+	cataloguep "github.com/sntabq/proto-gen/gen/go/catalogue"
 	orderp "github.com/sntabq/proto-gen/gen/go/order"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -13,7 +14,12 @@ import (
 	"log"
 	"log/slog"
 	"net"
+	cartGrpc "order-service/internal/grpc/cart"
+	couponGrpc "order-service/internal/grpc/coupon"
 	orderGrpc "order-service/internal/grpc/order"
+	paymentGrpc "order-service/internal/grpc/payment"
+	reportGrpc "order-service/internal/grpc/report"
+	rmaGrpc "order-service/internal/grpc/rma"
 )
 
 type App struct {
@@ -25,10 +31,18 @@ type App struct {
 var AuthServiceClient auth.AuthClient
 var OrderServiceClient orderp.OrderServiceClient
 var UserInfoServiceClient auth.UserInfoClient
+var AddressBookClient auth.AddressBookClient
+var StockServiceClient cataloguep.StockServiceClient
 
 func New(
 	log *slog.Logger,
 	catalogueService orderGrpc.OrderService,
+	cartService cartGrpc.CartService,
+	checkouter cartGrpc.Checkouter,
+	payments paymentGrpc.Payments,
+	coupons couponGrpc.Coupons,
+	returns rmaGrpc.Returns,
+	reports reportGrpc.Reports,
 	port int,
 ) *App {
 	loggingOpts := []logging.Option{
@@ -53,9 +67,22 @@ func New(
 		InterceptorCreateOrder,
 		AdminInterceptorGetAllOrders,
 		InterceptorGetOrdersOfUser,
+		AdminInterceptor,
+		InterceptorCancelOrder,
+		InterceptorGetOrder,
+		InterceptorCart,
+		InterceptorReturns,
+	), grpc.ChainStreamInterceptor(
+		recovery.StreamServerInterceptor(recoveryOpts...),
+		StreamInterceptor,
 	))
 
 	orderGrpc.Register(gRPCServer, catalogueService)
+	cartGrpc.Register(gRPCServer, cartService, checkouter)
+	paymentGrpc.Register(gRPCServer, payments)
+	couponGrpc.Register(gRPCServer, coupons)
+	rmaGrpc.Register(gRPCServer, returns)
+	reportGrpc.Register(gRPCServer, reports)
 
 	return &App{
 		log:        log,
@@ -96,6 +123,7 @@ func ConnectToSsoService() {
 	}
 	AuthServiceClient = auth.NewAuthClient(conn)
 	UserInfoServiceClient = auth.NewUserInfoClient(conn)
+	AddressBookClient = auth.NewAddressBookClient(conn)
 }
 
 func (a *App) Stop() {
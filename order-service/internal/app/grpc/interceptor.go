@@ -12,6 +12,7 @@ import (
 	"google.golang.org/grpc/status"
 	"log"
 	"log/slog"
+	"slices"
 	"strings"
 )
 
@@ -128,3 +129,238 @@ func InterceptorLogger(l *slog.Logger) logging.Logger {
 		l.Log(ctx, slog.Level(lvl), msg, fields...)
 	})
 }
+
+type callerKey struct{}
+
+// Caller is the authenticated user on whose behalf a request is served.
+type Caller struct {
+	UserId  int32
+	IsAdmin bool
+}
+
+// WithCaller returns a copy of ctx carrying the caller.
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller stored by the auth interceptors.
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+// adminMethods are the methods only admins may call. Entries ending in a slash
+// cover every method of a service.
+var adminMethods = []string{
+	"/order.OrderService/UpdateOrderStatus",
+	"/order.OrderService/CreateShipment",
+	"/order.OrderService/ListSagas",
+	"/order.PaymentService/RefundPayment",
+	"/order.CouponService/",
+	"/order.ReportService/",
+}
+
+// AdminInterceptor restricts adminMethods to admins.
+func AdminInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !isAdminMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	isAdmin, err := isAdmin(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin {
+		return nil, status.Errorf(codes.PermissionDenied, "permission failed")
+	}
+
+	return handler(WithCaller(ctx, Caller{UserId: user.Id, IsAdmin: true}), req)
+}
+
+func isAdminMethod(method string) bool {
+	for _, m := range adminMethods {
+		if method == m || strings.HasSuffix(m, "/") && strings.HasPrefix(method, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InterceptorCancelOrder authenticates the caller of CancelOrder. Whether the
+// caller owns the order is checked by the order service.
+func InterceptorCancelOrder(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if info.FullMethod != "/order.OrderService/CancelOrder" {
+		return handler(ctx, req)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	isAdmin, err := isAdmin(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(WithCaller(ctx, Caller{UserId: user.Id, IsAdmin: isAdmin}), req)
+}
+
+// InterceptorGetOrder authenticates the caller of GetOrder. Whether the caller
+// owns the order is checked by the order service.
+func InterceptorGetOrder(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if info.FullMethod != "/order.OrderService/GetOrder" {
+		return handler(ctx, req)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	isAdmin, err := isAdmin(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(WithCaller(ctx, Caller{UserId: user.Id, IsAdmin: isAdmin}), req)
+}
+
+// callerStream is a server stream whose context carries the caller.
+type callerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *callerStream) Context() context.Context {
+	return s.ctx
+}
+
+// streamMethods are the streaming methods whose callers are authenticated.
+// Whether the caller owns the order is checked by the order service; the
+// orders watched by WatchMyOrders are those of the caller.
+var streamMethods = []string{
+	"/order.OrderService/GetInvoice",
+	"/order.OrderService/WatchOrder",
+	"/order.OrderService/WatchMyOrders",
+}
+
+// StreamInterceptor authenticates the callers of streamMethods.
+func StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !slices.Contains(streamMethods, info.FullMethod) {
+		return handler(srv, ss)
+	}
+
+	ss, err := authenticateStream(ss)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+// authenticateStream returns the stream with the caller in its context.
+func authenticateStream(ss grpc.ServerStream) (grpc.ServerStream, error) {
+	ctx := ss.Context()
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	isAdmin, err := isAdmin(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &callerStream{ServerStream: ss, ctx: WithCaller(ctx, Caller{UserId: user.Id, IsAdmin: isAdmin})}, nil
+}
+
+// adminReturnMethods are the methods of ReturnService only admins may call.
+var adminReturnMethods = map[string]bool{
+	"/order.ReturnService/ApproveReturn": true,
+	"/order.ReturnService/RejectReturn":  true,
+	"/order.ReturnService/ReceiveReturn": true,
+	"/order.ReturnService/RefundReturn":  true,
+}
+
+// InterceptorReturns authenticates the callers of ReturnService and restricts
+// deciding on, receiving and refunding returns to admins. Whether the caller
+// owns a return is checked by the returns service.
+func InterceptorReturns(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, "/order.ReturnService/") {
+		return handler(ctx, req)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	isAdmin, err := isAdmin(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && adminReturnMethods[info.FullMethod] {
+		return nil, status.Errorf(codes.PermissionDenied, "permission failed")
+	}
+
+	return handler(WithCaller(ctx, Caller{UserId: user.Id, IsAdmin: isAdmin}), req)
+}
+
+func userFromContext(ctx context.Context) (*authp.User, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		log.Printf("failed to get metadata from context")
+	}
+	tkn, found := md["authorization"]
+	if !found || len(tkn) == 0 || tkn[0] == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "authentication is required")
+	}
+
+	userInfo, err := UserInfoServiceClient.GetUserInfo(ctx, &authp.GetUserInfoRequest{Token: tkn[0]})
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "unknown user"):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid user")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to get user info from sso service")
+		}
+	}
+
+	return userInfo.User, nil
+}
+
+func isAdmin(ctx context.Context, userId int32) (bool, error) {
+	isAdminResponse, err := AuthServiceClient.IsAdmin(ctx, &authp.IsAdminRequest{UserId: int64(userId)})
+	if err != nil {
+		log.Printf("permissions fail %v", err)
+		return false, status.Errorf(codes.Internal, "permission failed")
+	}
+
+	return isAdminResponse.IsAdmin, nil
+}
+
+// InterceptorCart makes sure users only work with their own cart.
+func InterceptorCart(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, "/order.CartService/") {
+		return handler(ctx, req)
+	}
+
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cartRequest, ok := req.(interface{ GetUserId() int32 })
+	if !ok || cartRequest.GetUserId() != user.Id {
+		return nil, status.Errorf(codes.PermissionDenied, "you can not use the cart of others")
+	}
+
+	return handler(WithCaller(ctx, Caller{UserId: user.Id}), req)
+}
@@ -0,0 +1,191 @@
+// Package coupon prices orders with discount codes. It only computes; looking
+// coupons up and redeeming them is left to the caller, which is expected to
+// hold a lock on the coupon while it checks and redeems it.
+package coupon
+
+import (
+	"errors"
+	"fmt"
+	"order-service/internal/data/dto"
+	"order-service/internal/data/models"
+	"strings"
+	"time"
+)
+
+const (
+	KindPercentage   = "percentage"
+	KindFixed        = "fixed"
+	KindFreeShipping = "free_shipping"
+	KindBuyXGetY     = "buy_x_get_y"
+	KindCategory     = "category"
+)
+
+// ErrRejected is wrapped by all the reasons a coupon can not be redeemed.
+var ErrRejected = errors.New("coupon rejected")
+
+var (
+	ErrCouponNotFound      = fmt.Errorf("%w: coupon not found", ErrRejected)
+	ErrCouponInactive      = fmt.Errorf("%w: coupon is not active", ErrRejected)
+	ErrCouponNotStarted    = fmt.Errorf("%w: coupon is not valid yet", ErrRejected)
+	ErrCouponExpired       = fmt.Errorf("%w: coupon has expired", ErrRejected)
+	ErrCouponExhausted     = fmt.Errorf("%w: coupon has been used up", ErrRejected)
+	ErrCouponLimitReached  = fmt.Errorf("%w: coupon has already been used the maximum number of times", ErrRejected)
+	ErrMinimumNotMet       = fmt.Errorf("%w: order total is below the minimum of the coupon", ErrRejected)
+	ErrCouponNotApplicable = fmt.Errorf("%w: coupon does not apply to this order", ErrRejected)
+)
+
+var ErrInvalidCoupon = errors.New("invalid coupon")
+
+// Line is a line of the order being priced.
+type Line struct {
+	ItemId    int32
+	Category  string
+	Quantity  int32
+	UnitPrice int32
+}
+
+// Quote is the price of an order after discounts.
+type Quote struct {
+	Subtotal    int32
+	ShippingFee int32
+	Discount    int32
+	Total       int32
+	Discounts   []dto.DiscountDTO
+}
+
+// NormalizeCode returns the form codes are stored and looked up in.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks that the coupon is well formed before it is saved.
+func Validate(c *models.Coupon) error {
+	c.Code = NormalizeCode(c.Code)
+	c.Category = strings.TrimSpace(c.Category)
+
+	switch {
+	case c.Code == "" || len(c.Code) > 64:
+		return fmt.Errorf("%w: code is required and must be at most 64 characters", ErrInvalidCoupon)
+	case len(c.Description) > 255:
+		return fmt.Errorf("%w: description must be at most 255 characters", ErrInvalidCoupon)
+	case c.Value < 0 || c.MinOrderValue < 0 || c.MaxUses < 0 || c.MaxUsesPerUser < 0:
+		return fmt.Errorf("%w: values must not be negative", ErrInvalidCoupon)
+	case c.StartsAt != nil && c.EndsAt != nil && !c.EndsAt.After(*c.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidCoupon)
+	}
+
+	switch c.Kind {
+	case KindPercentage:
+		if c.Value < 1 || c.Value > 100 {
+			return fmt.Errorf("%w: percentage must be between 1 and 100", ErrInvalidCoupon)
+		}
+	case KindFixed:
+		if c.Value < 1 {
+			return fmt.Errorf("%w: amount must be positive", ErrInvalidCoupon)
+		}
+	case KindFreeShipping:
+	case KindBuyXGetY:
+		if c.ItemId == 0 || c.BuyQuantity < 1 || c.GetQuantity < 1 {
+			return fmt.Errorf("%w: item_id, buy_quantity and get_quantity are required", ErrInvalidCoupon)
+		}
+	case KindCategory:
+		if c.Category == "" || len(c.Category) > 64 {
+			return fmt.Errorf("%w: category is required and must be at most 64 characters", ErrInvalidCoupon)
+		}
+		if c.Value < 1 || c.Value > 100 {
+			return fmt.Errorf("%w: percentage must be between 1 and 100", ErrInvalidCoupon)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidCoupon, c.Kind)
+	}
+
+	return nil
+}
+
+// Check reports whether the coupon may be redeemed at now by a user who has
+// redeemed it usedByUser times before, for an order worth subtotal.
+func Check(c *models.Coupon, usedByUser int32, subtotal int32, now time.Time) error {
+	switch {
+	case !c.Active:
+		return ErrCouponInactive
+	case c.StartsAt != nil && now.Before(*c.StartsAt):
+		return ErrCouponNotStarted
+	case c.EndsAt != nil && !now.Before(*c.EndsAt):
+		return ErrCouponExpired
+	case c.MaxUses > 0 && c.Uses >= c.MaxUses:
+		return ErrCouponExhausted
+	case c.MaxUsesPerUser > 0 && usedByUser >= c.MaxUsesPerUser:
+		return ErrCouponLimitReached
+	case subtotal < c.MinOrderValue:
+		return fmt.Errorf("%w: %d < %d", ErrMinimumNotMet, subtotal, c.MinOrderValue)
+	}
+
+	return nil
+}
+
+// Price computes the quote of the lines with shippingFee and, when c is not
+// nil, the discounts of the coupon. The coupon has to pass Check first.
+func Price(c *models.Coupon, lines []Line, shippingFee int32) (*Quote, error) {
+	q := &Quote{ShippingFee: shippingFee}
+	for _, l := range lines {
+		q.Subtotal += l.Quantity * l.UnitPrice
+	}
+
+	if c != nil {
+		q.Discounts = discounts(c, lines, q.Subtotal, shippingFee)
+		if len(q.Discounts) == 0 {
+			return nil, ErrCouponNotApplicable
+		}
+	}
+
+	// Discounts never make the order cost less than nothing.
+	left := q.Subtotal + q.ShippingFee
+	for i := range q.Discounts {
+		q.Discounts[i].Amount = min(q.Discounts[i].Amount, left)
+		left -= q.Discounts[i].Amount
+		q.Discount += q.Discounts[i].Amount
+	}
+	q.Total = q.Subtotal + q.ShippingFee - q.Discount
+
+	return q, nil
+}
+
+func discounts(c *models.Coupon, lines []Line, subtotal int32, shippingFee int32) []dto.DiscountDTO {
+	var result []dto.DiscountDTO
+	add := func(description string, itemId int32, amount int32) {
+		if amount > 0 {
+			result = append(result, dto.DiscountDTO{
+				CouponId:    c.ID,
+				Description: description,
+				ItemId:      itemId,
+				Amount:      amount,
+			})
+		}
+	}
+
+	switch c.Kind {
+	case KindPercentage:
+		add(fmt.Sprintf("%s: %d%% off", c.Code, c.Value), 0, subtotal*c.Value/100)
+	case KindFixed:
+		add(fmt.Sprintf("%s: %d off", c.Code, c.Value), 0, min(c.Value, subtotal))
+	case KindFreeShipping:
+		add(fmt.Sprintf("%s: free shipping", c.Code), 0, shippingFee)
+	case KindBuyXGetY:
+		for _, l := range lines {
+			if l.ItemId != c.ItemId {
+				continue
+			}
+			free := l.Quantity / (c.BuyQuantity + c.GetQuantity) * c.GetQuantity
+			add(fmt.Sprintf("%s: buy %d get %d free", c.Code, c.BuyQuantity, c.GetQuantity), l.ItemId, free*l.UnitPrice)
+		}
+	case KindCategory:
+		for _, l := range lines {
+			if !strings.EqualFold(l.Category, c.Category) {
+				continue
+			}
+			add(fmt.Sprintf("%s: %d%% off %s", c.Code, c.Value, c.Category), l.ItemId, l.Quantity*l.UnitPrice*c.Value/100)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,96 @@
+package coupon
+
+import (
+	"errors"
+	"order-service/internal/data/models"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+
+	for _, tt := range []struct {
+		name       string
+		coupon     models.Coupon
+		usedByUser int32
+		subtotal   int32
+		want       error
+	}{
+		{"no limits", models.Coupon{Active: true}, 0, 0, nil},
+		{"inactive", models.Coupon{}, 0, 1000, ErrCouponInactive},
+
+		{"within window", models.Coupon{Active: true, StartsAt: at(-time.Hour), EndsAt: at(time.Hour)}, 0, 1000, nil},
+		{"starts now", models.Coupon{Active: true, StartsAt: at(0)}, 0, 1000, nil},
+		{"not started", models.Coupon{Active: true, StartsAt: at(time.Second)}, 0, 1000, ErrCouponNotStarted},
+		{"ends later", models.Coupon{Active: true, EndsAt: at(time.Second)}, 0, 1000, nil},
+		{"ends now", models.Coupon{Active: true, EndsAt: at(0)}, 0, 1000, ErrCouponExpired},
+		{"expired", models.Coupon{Active: true, StartsAt: at(-2 * time.Hour), EndsAt: at(-time.Hour)}, 0, 1000, ErrCouponExpired},
+
+		{"uses left", models.Coupon{Active: true, MaxUses: 10, Uses: 9}, 0, 1000, nil},
+		{"used up", models.Coupon{Active: true, MaxUses: 10, Uses: 10}, 0, 1000, ErrCouponExhausted},
+		{"unlimited uses", models.Coupon{Active: true, Uses: 1000}, 0, 1000, nil},
+		{"user uses left", models.Coupon{Active: true, MaxUsesPerUser: 2}, 1, 1000, nil},
+		{"user limit reached", models.Coupon{Active: true, MaxUsesPerUser: 2}, 2, 1000, ErrCouponLimitReached},
+		{"unlimited user uses", models.Coupon{Active: true}, 50, 1000, nil},
+		{"used up before user limit", models.Coupon{Active: true, MaxUses: 1, Uses: 1, MaxUsesPerUser: 1}, 1, 1000, ErrCouponExhausted},
+
+		{"minimum met", models.Coupon{Active: true, MinOrderValue: 1000}, 0, 1000, nil},
+		{"minimum not met", models.Coupon{Active: true, MinOrderValue: 1000}, 0, 999, ErrMinimumNotMet},
+	} {
+		err := Check(&tt.coupon, tt.usedByUser, tt.subtotal, now)
+		if tt.want == nil && err != nil {
+			t.Errorf("%s: Check() = %v, want nil", tt.name, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: Check() = %v, want %v", tt.name, err, tt.want)
+		}
+		if err != nil && !errors.Is(err, ErrRejected) {
+			t.Errorf("%s: Check() = %v, want it to wrap %v", tt.name, err, ErrRejected)
+		}
+	}
+}
+
+func TestPrice(t *testing.T) {
+	lines := []Line{
+		{ItemId: 1, Category: "Books", Quantity: 5, UnitPrice: 200},
+		{ItemId: 2, Category: "Games", Quantity: 1, UnitPrice: 1000},
+	}
+
+	for _, tt := range []struct {
+		name     string
+		coupon   *models.Coupon
+		shipping int32
+		discount int32
+		want     error
+	}{
+		{"no coupon", nil, 500, 0, nil},
+		{"percentage", &models.Coupon{Kind: KindPercentage, Value: 10}, 500, 200, nil},
+		{"fixed", &models.Coupon{Kind: KindFixed, Value: 300}, 500, 300, nil},
+		{"fixed above subtotal", &models.Coupon{Kind: KindFixed, Value: 5000}, 500, 2000, nil},
+		{"free shipping", &models.Coupon{Kind: KindFreeShipping}, 500, 500, nil},
+		{"free shipping without fee", &models.Coupon{Kind: KindFreeShipping}, 0, 0, ErrCouponNotApplicable},
+		{"buy 2 get 1", &models.Coupon{Kind: KindBuyXGetY, ItemId: 1, BuyQuantity: 2, GetQuantity: 1}, 500, 200, nil},
+		{"buy x get y of other item", &models.Coupon{Kind: KindBuyXGetY, ItemId: 3, BuyQuantity: 1, GetQuantity: 1}, 500, 0, ErrCouponNotApplicable},
+		{"buy x get y too few", &models.Coupon{Kind: KindBuyXGetY, ItemId: 2, BuyQuantity: 1, GetQuantity: 1}, 500, 0, ErrCouponNotApplicable},
+		{"category", &models.Coupon{Kind: KindCategory, Category: "books", Value: 50}, 500, 500, nil},
+		{"category not ordered", &models.Coupon{Kind: KindCategory, Category: "Toys", Value: 50}, 500, 0, ErrCouponNotApplicable},
+	} {
+		q, err := Price(tt.coupon, lines, tt.shipping)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Price() = %v, want %v", tt.name, err, tt.want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if q.Subtotal != 2000 || q.Discount != tt.discount || q.Total != 2000+tt.shipping-tt.discount {
+			t.Errorf("%s: Price() = subtotal %d, discount %d, total %d, want 2000, %d, %d",
+				tt.name, q.Subtotal, q.Discount, q.Total, tt.discount, 2000+tt.shipping-tt.discount)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"order-service/internal/data/dto"
+	"order-service/internal/data/models"
+)
+
+type CartStorage struct {
+	DB *sql.DB
+}
+
+var (
+	ErrCartEmpty    = errors.New("cart is empty")
+	ErrOutOfStock   = errors.New("not enough items in stock")
+	ErrPriceChanged = errors.New("prices of items in cart have changed")
+)
+
+// openCartQuery returns the id of the open cart of the user, creating the cart
+// on first use.
+const openCartQuery = `
+			INSERT INTO order_service.carts (user_id)
+			VALUES ($1)
+			ON CONFLICT (user_id) WHERE status = 'open'
+			DO UPDATE SET updated_at = NOW()
+			RETURNING id`
+
+func (cs *CartStorage) GetCart(ctx context.Context, userId int) (*dto.CartDTO, error) {
+	const op = "data.GetCart"
+
+	cart, err := getCart(ctx, cs.DB, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return cart, nil
+}
+
+// getCart returns the open cart of the user, empty if the user has none.
+func getCart(ctx context.Context, q querier, userId int) (*dto.CartDTO, error) {
+	cart := &dto.CartDTO{UserId: int32(userId)}
+	err := q.QueryRowContext(ctx, `
+			SELECT id FROM order_service.carts
+			WHERE user_id = $1 AND status = 'open'`, userId).Scan(&cart.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return cart, nil
+		default:
+			return nil, err
+		}
+	}
+
+	query := `
+			SELECT l.item_id, l.quantity, l.unit_price, ` + itemColumns + `
+			FROM order_service.cart_lines l
+			JOIN catalogue.item_info i ON i.id = l.item_id
+			WHERE l.cart_id = $1
+			ORDER BY l.added_at, l.item_id`
+	rows, err := q.QueryContext(ctx, query, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line dto.CartLineDTO
+		err := rows.Scan(
+			&line.ItemId,
+			&line.Quantity,
+			&line.UnitPrice,
+			&line.Item.ID,
+			&line.Item.Name,
+			&line.Item.Price,
+			&line.Item.Description,
+			&line.Item.Quantity,
+			&line.Item.ImageURL,
+			&line.Item.Category,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		cart.Lines = append(cart.Lines, line)
+		cart.Total += line.Quantity * line.UnitPrice
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// SaveCartLine puts the item into the open cart of the user priced at the
+// current catalogue price. When the item is already in the cart its quantity
+// is increased by quantity if add is set and replaced otherwise.
+func (cs *CartStorage) SaveCartLine(ctx context.Context, userId int, itemId int, quantity int, add bool) error {
+	const op = "data.SaveCartLine"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	onConflict := `quantity = EXCLUDED.quantity`
+	if add {
+		onConflict = `quantity = order_service.cart_lines.quantity + EXCLUDED.quantity`
+	}
+	query := `
+			INSERT INTO order_service.cart_lines (cart_id, item_id, quantity, unit_price)
+			SELECT $1, id, $3, price FROM catalogue.item_info
+			WHERE id = $2
+			ON CONFLICT (cart_id, item_id)
+			DO UPDATE SET ` + onConflict + `, unit_price = EXCLUDED.unit_price`
+
+	tx, err := cs.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback()
+
+	var cartId int64
+	if err = tx.QueryRowContext(ctx, openCartQuery, userId).Scan(&cartId); err != nil {
+		return fail(err)
+	}
+
+	res, err := tx.ExecContext(ctx, query, cartId, itemId, quantity)
+	if err != nil {
+		return fail(err)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrRecordNotFound
+	}
+
+	if err = insertCartEvent(ctx, tx, userId); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (cs *CartStorage) DeleteCartLine(ctx context.Context, userId int, itemId int) error {
+	const op = "data.DeleteCartLine"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+	query := `
+			DELETE FROM order_service.cart_lines l
+			USING order_service.carts c
+			WHERE l.cart_id = c.id AND c.user_id = $1 AND c.status = 'open' AND l.item_id = $2`
+
+	tx, err := cs.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, query, userId, itemId)
+	if err != nil {
+		return fail(err)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrRecordNotFound
+	}
+
+	if err = insertCartEvent(ctx, tx, userId); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// Checkout turns the open cart of the user of order into that order in a
+// single transaction. The items of the cart are share-locked while their stock
+// and prices are re-validated, and the coupon of the order, if any, is
+// redeemed. When a price has changed since the item was added the cart is
+// repriced and ErrPriceChanged is returned so that the customer can confirm
+// the new total.
+func (os *OrderStorage) Checkout(ctx context.Context, order *dto.OrderDTO, user *dto.UserDTO) (*dto.OrderDTO, error) {
+	const op = "data.Checkout"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := os.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer tx.Rollback()
+
+	var cartId int64
+	err = tx.QueryRowContext(ctx, `
+			SELECT id FROM order_service.carts
+			WHERE user_id = $1 AND status = 'open'
+			FOR UPDATE`, order.UserId).Scan(&cartId)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrCartEmpty
+		default:
+			return nil, fail(err)
+		}
+	}
+
+	query := `
+			SELECT l.item_id, l.quantity, l.unit_price, ` + itemColumns + `
+			FROM order_service.cart_lines l
+			JOIN catalogue.item_info i ON i.id = l.item_id
+			WHERE l.cart_id = $1
+			ORDER BY l.item_id
+			FOR SHARE OF i`
+	rows, err := tx.QueryContext(ctx, query, cartId)
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	for rows.Next() {
+		var line dto.OrderLineDTO
+		err := rows.Scan(
+			&line.ItemId,
+			&line.Quantity,
+			&line.UnitPrice,
+			&line.Item.ID,
+			&line.Item.Name,
+			&line.Item.Price,
+			&line.Item.Description,
+			&line.Item.Quantity,
+			&line.Item.ImageURL,
+			&line.Item.Category,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, fail(err)
+		}
+
+		order.Lines = append(order.Lines, line)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	if len(order.Lines) == 0 {
+		return nil, ErrCartEmpty
+	}
+
+	priceChanged := false
+	for i, line := range order.Lines {
+		if line.Item.Quantity < line.Quantity {
+			return nil, fmt.Errorf("%w: item %d", ErrOutOfStock, line.ItemId)
+		}
+		if line.UnitPrice != line.Item.Price {
+			priceChanged = true
+			order.Lines[i].UnitPrice = line.Item.Price
+		}
+	}
+
+	if priceChanged {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE order_service.cart_lines l
+			SET unit_price = i.price
+			FROM catalogue.item_info i
+			WHERE l.cart_id = $1 AND i.id = l.item_id`, cartId)
+		if err != nil {
+			return nil, fail(err)
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, fail(err)
+		}
+
+		return nil, ErrPriceChanged
+	}
+
+	if err = priceOrder(ctx, tx, order); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+			INSERT INTO order_service.orders (user_id, status, total)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at, updated_at`,
+		order.UserId, order.Status, order.Total,
+	).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	for _, line := range order.Lines {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO order_service.order_lines (order_id, item_id, quantity, unit_price)
+			VALUES ($1, $2, $3, $4)`,
+			order.ID, line.ItemId, line.Quantity, line.UnitPrice,
+		)
+		if err != nil {
+			return nil, fail(err)
+		}
+	}
+
+	err = insertStatusChange(ctx, tx, &models.OrderStatusChange{
+		OrderId:   order.ID,
+		ToStatus:  order.Status,
+		ChangedBy: order.UserId,
+	})
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	if err = savePricing(ctx, tx, order); err != nil {
+		return nil, fail(err)
+	}
+
+	if order.Address != nil {
+		if err = insertOrderAddress(ctx, tx, order.ID, order.Address); err != nil {
+			return nil, fail(err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+			UPDATE order_service.carts
+			SET status = 'checked_out', order_id = $1, updated_at = NOW()
+			WHERE id = $2`, order.ID, cartId)
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	if err = insertSaga(ctx, tx, order.ID, &models.SagaData{User: user}); err != nil {
+		return nil, fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fail(err)
+	}
+
+	return order, nil
+}
+
+// ReopenCart gives the user of order back the cart that was checked out into
+// it, once its checkout has been rolled back. When the user has opened a new
+// cart since, the lines of the checked out cart are added to it instead.
+// Reopening a cart twice, or for an order placed without a cart, does nothing.
+func (os *OrderStorage) ReopenCart(ctx context.Context, orderId int32) error {
+	const op = "data.ReopenCart"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := os.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback()
+
+	var cartId, userId int64
+	err = tx.QueryRowContext(ctx, `
+			SELECT id, user_id FROM order_service.carts
+			WHERE order_id = $1 AND status = 'checked_out'
+			FOR UPDATE`, orderId).Scan(&cartId, &userId)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil
+		default:
+			return fail(err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `
+			UPDATE order_service.carts
+			SET status = 'open', order_id = NULL, updated_at = NOW()
+			WHERE id = $1 AND NOT EXISTS (
+				SELECT 1 FROM order_service.carts WHERE user_id = $2 AND status = 'open'
+			)`, cartId, userId)
+	if err != nil {
+		return fail(err)
+	}
+	reopened, err := res.RowsAffected()
+	if err != nil {
+		return fail(err)
+	}
+	if reopened == 0 {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO order_service.cart_lines (cart_id, item_id, quantity, unit_price)
+			SELECT c.id, l.item_id, l.quantity, l.unit_price
+			FROM order_service.cart_lines l, order_service.carts c
+			WHERE l.cart_id = $1 AND c.user_id = $2 AND c.status = 'open'
+			ON CONFLICT (cart_id, item_id) DO NOTHING`, cartId, userId)
+		if err != nil {
+			return fail(err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE order_service.carts
+			SET status = 'abandoned', updated_at = NOW()
+			WHERE id = $1`, cartId)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,374 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"order-service/internal/coupon"
+	"order-service/internal/data/dto"
+	"order-service/internal/data/models"
+	"time"
+)
+
+type CouponStorage struct {
+	DB *sql.DB
+}
+
+var ErrCouponExists = errors.New("coupon with this code already exists")
+
+const couponColumns = `id, code, description, kind, value, category, COALESCE(item_id, 0), buy_quantity, get_quantity,
+			min_order_value, max_uses, max_uses_per_user, uses, starts_at, ends_at, active, created_at, updated_at`
+
+func scanCoupon(row interface{ Scan(...any) error }) (*models.Coupon, error) {
+	var c models.Coupon
+	err := row.Scan(
+		&c.ID,
+		&c.Code,
+		&c.Description,
+		&c.Kind,
+		&c.Value,
+		&c.Category,
+		&c.ItemId,
+		&c.BuyQuantity,
+		&c.GetQuantity,
+		&c.MinOrderValue,
+		&c.MaxUses,
+		&c.MaxUsesPerUser,
+		&c.Uses,
+		&c.StartsAt,
+		&c.EndsAt,
+		&c.Active,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (cs *CouponStorage) SaveCoupon(ctx context.Context, c *models.Coupon) (*models.Coupon, error) {
+	const op = "data.SaveCoupon"
+	query := `
+			INSERT INTO order_service.coupons (code, description, kind, value, category, item_id, buy_quantity, get_quantity,
+				min_order_value, max_uses, max_uses_per_user, starts_at, ends_at, active)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), $7, $8, $9, $10, $11, $12, $13, $14)
+			RETURNING ` + couponColumns
+
+	saved, err := scanCoupon(cs.DB.QueryRowContext(ctx, query,
+		c.Code,
+		c.Description,
+		c.Kind,
+		c.Value,
+		c.Category,
+		c.ItemId,
+		c.BuyQuantity,
+		c.GetQuantity,
+		c.MinOrderValue,
+		c.MaxUses,
+		c.MaxUsesPerUser,
+		c.StartsAt,
+		c.EndsAt,
+		c.Active,
+	))
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23505":
+			return nil, ErrCouponExists
+		default:
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return saved, nil
+}
+
+// UpdateCoupon replaces the terms of the coupon. Its code and usage count are
+// kept.
+func (cs *CouponStorage) UpdateCoupon(ctx context.Context, c *models.Coupon) (*models.Coupon, error) {
+	const op = "data.UpdateCoupon"
+	query := `
+			UPDATE order_service.coupons
+			SET description = $2, kind = $3, value = $4, category = $5, item_id = NULLIF($6, 0), buy_quantity = $7,
+				get_quantity = $8, min_order_value = $9, max_uses = $10, max_uses_per_user = $11, starts_at = $12,
+				ends_at = $13, active = $14, updated_at = NOW()
+			WHERE id = $1
+			RETURNING ` + couponColumns
+
+	updated, err := scanCoupon(cs.DB.QueryRowContext(ctx, query,
+		c.ID,
+		c.Description,
+		c.Kind,
+		c.Value,
+		c.Category,
+		c.ItemId,
+		c.BuyQuantity,
+		c.GetQuantity,
+		c.MinOrderValue,
+		c.MaxUses,
+		c.MaxUsesPerUser,
+		c.StartsAt,
+		c.EndsAt,
+		c.Active,
+	))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, coupon.ErrCouponNotFound
+		default:
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return updated, nil
+}
+
+func (cs *CouponStorage) GetCoupon(ctx context.Context, id int64) (*models.Coupon, error) {
+	const op = "data.GetCoupon"
+
+	c, err := scanCoupon(cs.DB.QueryRowContext(ctx, `
+			SELECT `+couponColumns+` FROM order_service.coupons WHERE id = $1`, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, coupon.ErrCouponNotFound
+		default:
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return c, nil
+}
+
+func (cs *CouponStorage) ListCoupons(ctx context.Context) ([]*models.Coupon, error) {
+	const op = "data.ListCoupons"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := cs.DB.QueryContext(ctx, `
+			SELECT `+couponColumns+` FROM order_service.coupons ORDER BY id`)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var coupons []*models.Coupon
+	for rows.Next() {
+		c, err := scanCoupon(rows)
+		if err != nil {
+			return nil, fail(err)
+		}
+
+		coupons = append(coupons, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return coupons, nil
+}
+
+// DeleteCoupon removes the coupon. Orders that used it keep their discounts.
+func (cs *CouponStorage) DeleteCoupon(ctx context.Context, id int64) error {
+	const op = "data.DeleteCoupon"
+
+	res, err := cs.DB.ExecContext(ctx, `DELETE FROM order_service.coupons WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return coupon.ErrCouponNotFound
+	}
+
+	return nil
+}
+
+// GetCouponForUser returns the coupon with code and how many times the user
+// has redeemed it. The result is only a preview; redemption re-checks both
+// under a lock.
+func (cs *CouponStorage) GetCouponForUser(ctx context.Context, code string, userId int32) (*models.Coupon, int32, error) {
+	const op = "data.GetCouponForUser"
+
+	c, err := scanCoupon(cs.DB.QueryRowContext(ctx, `
+			SELECT `+couponColumns+` FROM order_service.coupons WHERE code = $1`, code))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, 0, coupon.ErrCouponNotFound
+		default:
+			return nil, 0, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	var used int32
+	err = cs.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM order_service.coupon_redemptions
+			WHERE coupon_id = $1 AND user_id = $2`, c.ID, userId).Scan(&used)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return c, used, nil
+}
+
+// priceOrder fills the subtotal, discounts and total of the order from its
+// lines and shipping fee. When the order carries a coupon code the coupon row
+// is locked until tx ends, so concurrent checkouts redeem it one at a time and
+// its usage limits hold.
+func priceOrder(ctx context.Context, tx *sql.Tx, order *dto.OrderDTO) error {
+	lines := make([]coupon.Line, 0, len(order.Lines))
+	for _, l := range order.Lines {
+		lines = append(lines, coupon.Line{
+			ItemId:    l.ItemId,
+			Category:  l.Item.Category,
+			Quantity:  l.Quantity,
+			UnitPrice: l.UnitPrice,
+		})
+	}
+
+	var c *models.Coupon
+	if order.CouponCode != "" {
+		var err error
+		c, err = scanCoupon(tx.QueryRowContext(ctx, `
+			SELECT `+couponColumns+` FROM order_service.coupons WHERE code = $1 FOR UPDATE`, order.CouponCode))
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return coupon.ErrCouponNotFound
+			default:
+				return err
+			}
+		}
+
+		var used int32
+		err = tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM order_service.coupon_redemptions
+			WHERE coupon_id = $1 AND user_id = $2`, c.ID, order.UserId).Scan(&used)
+		if err != nil {
+			return err
+		}
+
+		var subtotal int32
+		for _, l := range lines {
+			subtotal += l.Quantity * l.UnitPrice
+		}
+		if err = coupon.Check(c, used, subtotal, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	quote, err := coupon.Price(c, lines, order.ShippingFee)
+	if err != nil {
+		return err
+	}
+
+	order.Subtotal = quote.Subtotal
+	order.ShippingFee = quote.ShippingFee
+	order.Discount = quote.Discount
+	order.Discounts = quote.Discounts
+	order.Total = quote.Total
+
+	return nil
+}
+
+// savePricing stores the pricing of a saved order, its discounts and the
+// redemption of its coupon.
+func savePricing(ctx context.Context, tx *sql.Tx, order *dto.OrderDTO) error {
+	_, err := tx.ExecContext(ctx, `
+			UPDATE order_service.orders
+			SET subtotal = $2, shipping_fee = $3, discount = $4, coupon_code = $5, total = $6
+			WHERE id = $1`,
+		order.ID, order.Subtotal, order.ShippingFee, order.Discount, order.CouponCode, order.Total)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range order.Discounts {
+		if d.Amount == 0 {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO order_service.order_discounts (order_id, coupon_id, description, item_id, amount)
+			VALUES ($1, $2, $3, NULLIF($4, 0), $5)`,
+			order.ID, d.CouponId, d.Description, d.ItemId, d.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	if order.CouponCode == "" || len(order.Discounts) == 0 {
+		return nil
+	}
+
+	couponId := order.Discounts[0].CouponId
+	_, err = tx.ExecContext(ctx, `
+			INSERT INTO order_service.coupon_redemptions (coupon_id, user_id, order_id)
+			VALUES ($1, $2, $3)`, couponId, order.UserId, order.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+			UPDATE order_service.coupons SET uses = uses + 1 WHERE id = $1`, couponId)
+
+	return err
+}
+
+// releaseCoupon gives the coupon redeemed by a cancelled order back to its
+// user.
+func releaseCoupon(ctx context.Context, tx *sql.Tx, orderId int32) error {
+	_, err := tx.ExecContext(ctx, `
+			WITH released AS (
+				DELETE FROM order_service.coupon_redemptions
+				WHERE order_id = $1
+				RETURNING coupon_id
+			)
+			UPDATE order_service.coupons c
+			SET uses = GREATEST(c.uses - 1, 0)
+			FROM released r
+			WHERE c.id = r.coupon_id`, orderId)
+
+	return err
+}
+
+// attachDiscounts loads the discounts of the given orders.
+func attachDiscounts(ctx context.Context, q querier, orders []*dto.OrderDTO) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byId := make(map[int32]*dto.OrderDTO, len(orders))
+	ids := make([]int64, 0, len(orders))
+	for _, order := range orders {
+		byId[order.ID] = order
+		ids = append(ids, int64(order.ID))
+	}
+
+	rows, err := q.QueryContext(ctx, `
+			SELECT order_id, COALESCE(coupon_id, 0), description, COALESCE(item_id, 0), amount
+			FROM order_service.order_discounts
+			WHERE order_id = ANY($1)
+			ORDER BY id`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderId int32
+		var d dto.DiscountDTO
+		if err := rows.Scan(&orderId, &d.CouponId, &d.Description, &d.ItemId, &d.Amount); err != nil {
+			return err
+		}
+
+		byId[orderId].Discounts = append(byId[orderId].Discounts, d)
+	}
+
+	return rows.Err()
+}
@@ -1,10 +1,83 @@
 package dto
 
+import "time"
+
 type OrderDTO struct {
-	ID     int32   `json:"id,omitempty"`
-	UserId int32   `json:"user_id"`
-	ItemId int32   `json:"item_id"`
-	Item   ItemDTO `json:"item"`
+	ID        int32          `json:"id,omitempty"`
+	UserId    int32          `json:"user_id"`
+	ItemId    int32          `json:"item_id"`
+	Item      ItemDTO        `json:"item"`
+	Status    string         `json:"status"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Lines     []OrderLineDTO `json:"lines"`
+	Total     int32          `json:"total"`
+	Address   *AddressDTO    `json:"address,omitempty"`
+	Shipments []ShipmentDTO  `json:"shipments,omitempty"`
+
+	Subtotal    int32         `json:"subtotal"`
+	ShippingFee int32         `json:"shipping_fee"`
+	Discount    int32         `json:"discount"`
+	CouponCode  string        `json:"coupon_code,omitempty"`
+	Discounts   []DiscountDTO `json:"discounts,omitempty"`
+
+	Invoice *InvoiceDTO `json:"invoice,omitempty"`
+}
+
+// InvoiceDTO is the invoice issued for an order once it was paid.
+type InvoiceDTO struct {
+	Number   string    `json:"number"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// DiscountDTO is one discount applied to an order. ItemId is set when the
+// discount applies to a single line.
+type DiscountDTO struct {
+	CouponId    int64  `json:"coupon_id"`
+	Description string `json:"description"`
+	ItemId      int32  `json:"item_id,omitempty"`
+	Amount      int32  `json:"amount"`
+}
+
+// AddressDTO is the shipping address an order was placed with.
+type AddressDTO struct {
+	Recipient  string `json:"recipient"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone,omitempty"`
+}
+
+type ShipmentDTO struct {
+	ID             int64     `json:"id"`
+	OrderId        int32     `json:"order_id"`
+	Carrier        string    `json:"carrier"`
+	TrackingNumber string    `json:"tracking_number"`
+	ShippedAt      time.Time `json:"shipped_at"`
+}
+
+type OrderLineDTO struct {
+	ItemId    int32   `json:"item_id"`
+	Quantity  int32   `json:"quantity"`
+	UnitPrice int32   `json:"unit_price"`
+	Item      ItemDTO `json:"item"`
+}
+
+type CartDTO struct {
+	ID     int64         `json:"id,omitempty"`
+	UserId int32         `json:"user_id"`
+	Lines  []CartLineDTO `json:"lines"`
+	Total  int32         `json:"total"`
+}
+
+type CartLineDTO struct {
+	ItemId    int32   `json:"item_id"`
+	Quantity  int32   `json:"quantity"`
+	UnitPrice int32   `json:"unit_price"`
+	Item      ItemDTO `json:"item"`
 }
 
 type ItemDTO struct {
@@ -14,4 +87,12 @@ type ItemDTO struct {
 	Description string `json:"description,omitempty"`
 	Quantity    int32  `json:"quantity,omitempty"`
 	ImageURL    string `json:"image_url"`
+	Category    string `json:"category,omitempty"`
+}
+
+type UserDTO struct {
+	Id       int32  `json:"id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Role     string `json:"role,omitempty"`
 }
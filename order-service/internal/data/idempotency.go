@@ -0,0 +1,129 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"order-service/internal/data/models"
+	"time"
+)
+
+var (
+	ErrRequestInFlight = errors.New("request with the same idempotency key is in progress")
+	ErrKeyReused       = errors.New("idempotency key was used for a different request")
+)
+
+// ClaimIdempotencyKey reserves the key of the user for a new request with the
+// body hashed to requestHash. It returns the claim, holding a new token, when
+// the key was free (or expired) and is now held by the caller, and the stored
+// key with its response when an earlier request with it already completed.
+// It fails with ErrRequestInFlight when that request is still running, and
+// with ErrKeyReused when the key was used for a different request.
+//
+// A claim without a response is taken over after a minute, so a crashed
+// request does not block retries until the key expires. Taking it over
+// replaces its token, and a response is only saved with the token of the
+// claim, so the request that held it can no longer save its response and
+// rolls back along with its order. A request saving its response while the
+// claim is taken over holds the row, and the takeover waits for it and then
+// finds the response.
+func (os *OrderStorage) ClaimIdempotencyKey(ctx context.Context, userId int32, key string, requestHash string, ttl time.Duration) (*models.IdempotencyKey, error) {
+	const op = "data.ClaimIdempotencyKey"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+	claimQuery := `
+			INSERT INTO order_service.idempotency_keys (user_id, key, request_hash, claim_token, expires_at)
+			VALUES ($1, $2, $3, $4, NOW() + $5 * INTERVAL '1 second')
+			ON CONFLICT (user_id, key) DO UPDATE
+			SET order_id = NULL, response = NULL, request_hash = EXCLUDED.request_hash,
+				claim_token = EXCLUDED.claim_token, created_at = NOW(), expires_at = EXCLUDED.expires_at
+			WHERE order_service.idempotency_keys.expires_at < NOW()
+				OR (order_service.idempotency_keys.response IS NULL
+					AND order_service.idempotency_keys.request_hash = EXCLUDED.request_hash
+					AND order_service.idempotency_keys.created_at < NOW() - INTERVAL '1 minute')
+			RETURNING expires_at`
+
+	token, err := claimToken()
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	claim := models.IdempotencyKey{UserId: userId, Key: key, RequestHash: requestHash, Token: token}
+	err = os.DB.QueryRowContext(ctx, claimQuery, userId, key, requestHash, token, int64(ttl.Seconds())).Scan(&claim.ExpiresAt)
+	if err == nil {
+		return &claim, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fail(err)
+	}
+
+	stored := models.IdempotencyKey{UserId: userId, Key: key}
+	err = os.DB.QueryRowContext(ctx, `
+			SELECT request_hash, response, expires_at FROM order_service.idempotency_keys
+			WHERE user_id = $1 AND key = $2`, userId, key).Scan(&stored.RequestHash, &stored.Response, &stored.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// The claim was released between the two queries.
+			return nil, ErrRequestInFlight
+		default:
+			return nil, fail(err)
+		}
+	}
+	if stored.RequestHash != requestHash {
+		return nil, ErrKeyReused
+	}
+	if stored.Response == nil {
+		return nil, ErrRequestInFlight
+	}
+
+	return &stored, nil
+}
+
+// ReleaseIdempotencyKey drops a claim whose request failed so that the client
+// can retry with the same key. A claim that was taken over is left alone.
+func (os *OrderStorage) ReleaseIdempotencyKey(ctx context.Context, claim *models.IdempotencyKey) error {
+	const op = "data.ReleaseIdempotencyKey"
+	query := `
+			DELETE FROM order_service.idempotency_keys
+			WHERE user_id = $1 AND key = $2 AND claim_token = $3 AND response IS NULL`
+
+	if _, err := os.DB.ExecContext(ctx, query, claim.UserId, claim.Key, claim.Token); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// saveIdempotentResponse saves the response of a claim in tx. It fails with
+// ErrRequestInFlight when the claim was taken over, so that tx is rolled
+// back.
+func saveIdempotentResponse(ctx context.Context, tx *sql.Tx, claim *models.IdempotencyKey, orderId int32, response []byte) error {
+	query := `
+			UPDATE order_service.idempotency_keys
+			SET order_id = $4, response = $5
+			WHERE user_id = $1 AND key = $2 AND claim_token = $3 AND response IS NULL`
+	res, err := tx.ExecContext(ctx, query, claim.UserId, claim.Key, claim.Token, orderId, response)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrRequestInFlight
+	}
+
+	return nil
+}
+
+// claimToken returns a random token identifying a claim.
+func claimToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
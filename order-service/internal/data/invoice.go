@@ -0,0 +1,90 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"order-service/internal/data/dto"
+	"order-service/internal/invoice"
+	"time"
+)
+
+// issueInvoice numbers the invoice of a paid order and stores its PDF,
+// rendered from the order as it is now. The counter of the year stays locked
+// until tx ends, and a rolled back transaction takes its number with it, so
+// the numbers of a year have no gaps. Orders that already have an invoice keep
+// it.
+func issueInvoice(ctx context.Context, tx *sql.Tx, orderId int32) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM order_service.invoices WHERE order_id = $1)`, orderId).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	issuedAt := time.Now().UTC().Truncate(time.Second)
+	year := issuedAt.Year()
+	var number int
+	err = tx.QueryRowContext(ctx, `
+			INSERT INTO order_service.invoice_sequences (year, last_number)
+			VALUES ($1, 1)
+			ON CONFLICT (year) DO UPDATE SET last_number = order_service.invoice_sequences.last_number + 1
+			RETURNING last_number`, year).Scan(&number)
+	if err != nil {
+		return err
+	}
+
+	order, err := loadOrder(ctx, tx, orderId)
+	if err != nil {
+		return err
+	}
+
+	pdf, err := invoice.Render(order, &dto.InvoiceDTO{Number: invoice.Number(year, number), IssuedAt: issuedAt})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+			INSERT INTO order_service.invoices (order_id, year, number, issued_at, pdf)
+			VALUES ($1, $2, $3, $4, $5)`, orderId, year, number, issuedAt, pdf)
+
+	return err
+}
+
+// GetInvoicePDF returns the PDF the invoice of the order was issued with. It
+// is empty for invoices issued before their PDF was stored.
+func (os *OrderStorage) GetInvoicePDF(ctx context.Context, orderId int32) ([]byte, error) {
+	const op = "data.GetInvoicePDF"
+
+	var pdf []byte
+	err := os.DB.QueryRowContext(ctx, `
+			SELECT pdf FROM order_service.invoices WHERE order_id = $1`, orderId).Scan(&pdf)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("%s: %w", op, ErrRecordNotFound)
+		default:
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return pdf, nil
+}
+
+// attachInvoice loads the invoice of the order, if it has one.
+func attachInvoice(ctx context.Context, q querier, order *dto.OrderDTO) error {
+	var year, number int
+	var issuedAt time.Time
+	err := q.QueryRowContext(ctx, `
+			SELECT year, number, issued_at FROM order_service.invoices WHERE order_id = $1`, order.ID).Scan(&year, &number, &issuedAt)
+	switch {
+	case err == nil:
+		order.Invoice = &dto.InvoiceDTO{Number: invoice.Number(year, number), IssuedAt: issuedAt}
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	default:
+		return err
+	}
+}
@@ -1,7 +1,170 @@
 package models
 
+import (
+	"order-service/internal/data/dto"
+	"time"
+)
+
 type Order struct {
-	ID     int32 `json:"id,omitempty"`
-	UserId int32 `json:"user_id"`
-	ItemId int32 `json:"item_id"`
+	ID        int32     `json:"id,omitempty"`
+	UserId    int32     `json:"user_id"`
+	ItemId    int32     `json:"item_id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Total     int32     `json:"total"`
+}
+
+type OrderStatusChange struct {
+	ID         int64     `json:"id,omitempty"`
+	OrderId    int32     `json:"order_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  int32     `json:"changed_by"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IdempotencyKey is a key sent by a client to make a request safe to retry.
+// Response stays empty while the first request with the key is in flight.
+// Token identifies the claim of the request holding the key, and RequestHash
+// the body of that request.
+type IdempotencyKey struct {
+	UserId      int32     `json:"user_id"`
+	Key         string    `json:"key"`
+	RequestHash string    `json:"request_hash"`
+	Token       string    `json:"-"`
+	Response    []byte    `json:"response"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Saga tracks the checkout of an order across services. Step is the step the
+// saga executes next, or compensates next while compensating.
+type Saga struct {
+	ID        int64     `json:"id"`
+	OrderId   int32     `json:"order_id"`
+	Status    string    `json:"status"`
+	Step      string    `json:"step"`
+	Data      SagaData  `json:"data"`
+	Attempts  int32     `json:"attempts"`
+	LastError string    `json:"last_error"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SagaData is the state a saga carries from one step to the next.
+type SagaData struct {
+	User      *dto.UserDTO `json:"user"`
+	PaymentId string       `json:"payment_id,omitempty"`
+}
+
+// Payment is the payment of an order at a provider. RefundingAmount is the sum
+// of the refunds being made and of those the provider has not settled yet.
+type Payment struct {
+	ID              int64     `json:"id"`
+	OrderId         int32     `json:"order_id"`
+	Provider        string    `json:"provider"`
+	ProviderRef     string    `json:"provider_ref"`
+	Status          string    `json:"status"`
+	Amount          int32     `json:"amount"`
+	RefundedAmount  int32     `json:"refunded_amount"`
+	RefundingAmount int32     `json:"refunding_amount"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Coupon is a discount code. Value is a percentage for percentage and category
+// coupons and an amount for fixed ones. Zero limits are unlimited.
+type Coupon struct {
+	ID             int64      `json:"id"`
+	Code           string     `json:"code"`
+	Description    string     `json:"description"`
+	Kind           string     `json:"kind"`
+	Value          int32      `json:"value"`
+	Category       string     `json:"category"`
+	ItemId         int32      `json:"item_id"`
+	BuyQuantity    int32      `json:"buy_quantity"`
+	GetQuantity    int32      `json:"get_quantity"`
+	MinOrderValue  int32      `json:"min_order_value"`
+	MaxUses        int32      `json:"max_uses"`
+	MaxUsesPerUser int32      `json:"max_uses_per_user"`
+	Uses           int32      `json:"uses"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	Active         bool       `json:"active"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Return is the request of a customer to send back items of a delivered
+// order line.
+type Return struct {
+	ID           int64                `json:"id"`
+	OrderId      int32                `json:"order_id"`
+	UserId       int32                `json:"user_id"`
+	ItemId       int32                `json:"item_id"`
+	Quantity     int32                `json:"quantity"`
+	Reason       string               `json:"reason"`
+	PhotoURLs    []string             `json:"photo_urls"`
+	Status       string               `json:"status"`
+	Note         string               `json:"note"`
+	Restocked    bool                 `json:"restocked"`
+	RefundAmount int32                `json:"refund_amount"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+	History      []ReturnStatusChange `json:"history,omitempty"`
+}
+
+type ReturnStatusChange struct {
+	ID         int64     `json:"id,omitempty"`
+	ReturnId   int64     `json:"return_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  int32     `json:"changed_by"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SalesPeriod is the sales of a day, week or month.
+type SalesPeriod struct {
+	PeriodStart       time.Time `json:"period_start"`
+	Orders            int32     `json:"orders"`
+	Units             int32     `json:"units"`
+	Revenue           int64     `json:"revenue"`
+	AverageOrderValue int64     `json:"average_order_value"`
+}
+
+// SalesReport is the sales of a range broken down by period, with the totals
+// of the range.
+type SalesReport struct {
+	Periods           []SalesPeriod `json:"periods"`
+	Orders            int32         `json:"orders"`
+	Revenue           int64         `json:"revenue"`
+	AverageOrderValue int64         `json:"average_order_value"`
+}
+
+type TopItem struct {
+	ItemId   int32  `json:"item_id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Units    int32  `json:"units"`
+	Orders   int32  `json:"orders"`
+	Revenue  int64  `json:"revenue"`
+}
+
+// CustomerReport splits the customers who ordered in a range into those who
+// ordered for the first time and those who had ordered before.
+type CustomerReport struct {
+	NewCustomers       int32 `json:"new_customers"`
+	ReturningCustomers int32 `json:"returning_customers"`
+	NewRevenue         int64 `json:"new_revenue"`
+	ReturningRevenue   int64 `json:"returning_revenue"`
+}
+
+// ConversionReport is how many of the carts created in a range were checked
+// out.
+type ConversionReport struct {
+	Carts          int32   `json:"carts"`
+	CheckedOut     int32   `json:"checked_out"`
+	ConversionRate float64 `json:"conversion_rate"`
 }
@@ -3,12 +3,16 @@ package data
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/lib/pq"
 	"order-service/internal/data/dto"
 	"order-service/internal/data/models"
+	"order-service/internal/search"
+	"order-service/internal/services/order/lifecycle"
 	"strings"
+	"time"
 )
 
 type OrderStorage struct {
@@ -17,19 +21,40 @@ type OrderStorage struct {
 
 var (
 	ErrRecordNotFound = errors.New("record (row, entry) not found")
+	ErrStatusConflict = errors.New("order status was changed concurrently")
 )
 
-func (os *OrderStorage) SaveOrder(ctx context.Context, orderDTO *dto.OrderDTO) (*dto.OrderDTO, error) {
+const (
+	orderColumns = `o.id, o.user_id, COALESCE(o.item_id, 0), o.status, o.created_at, o.updated_at, o.total`
+	itemColumns  = `i.id, i.name, i.price, i.description, i.quantity, i.image_url, i.category`
+
+	// orderDTOColumns adds the pricing of the order to orderColumns.
+	orderDTOColumns = orderColumns + `, o.subtotal, o.shipping_fee, o.discount, o.coupon_code`
+)
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SaveOrder stores a single item order, and its shipping address if any,
+// together with the checkout saga that will confirm it on behalf of user.
+// When claim is set the resulting order is saved as the response of its key
+// in the same transaction, so a retried request can be answered without a
+// second order.
+func (os *OrderStorage) SaveOrder(ctx context.Context, orderDTO *dto.OrderDTO, claim *models.IdempotencyKey, user *dto.UserDTO) (*dto.OrderDTO, error) {
 	const op = "data.SaveOrder"
 	fail := func(e error) error {
 		return fmt.Errorf("%s: %v", op, e)
 	}
-	insertItemQuery := `INSERT INTO order_service.orders (user_id, item_id)
-            VALUES ($1, $2)
-            RETURNING id`
+	insertItemQuery := `INSERT INTO order_service.orders (user_id, item_id, status)
+            VALUES ($1, $2, $3)
+            RETURNING id, created_at, updated_at`
 	args := []interface{}{
 		orderDTO.UserId,
 		orderDTO.ItemId,
+		orderDTO.Status,
 	}
 
 	tx, err := os.DB.BeginTx(ctx, nil)
@@ -38,7 +63,7 @@ func (os *OrderStorage) SaveOrder(ctx context.Context, orderDTO *dto.OrderDTO) (
 	}
 	defer tx.Rollback()
 
-	err = os.DB.QueryRowContext(ctx, insertItemQuery, args...).Scan(&orderDTO.ID)
+	err = tx.QueryRowContext(ctx, insertItemQuery, args...).Scan(&orderDTO.ID, &orderDTO.CreatedAt, &orderDTO.UpdatedAt)
 	if err != nil || orderDTO.ID == 0 {
 		var pqErr *pq.Error
 		switch {
@@ -53,21 +78,62 @@ func (os *OrderStorage) SaveOrder(ctx context.Context, orderDTO *dto.OrderDTO) (
 		}
 	}
 
-	if err = tx.Commit(); err != nil {
+	// A single item order is an order with one line priced at the current
+	// catalogue price.
+	insertLineQuery := `
+			INSERT INTO order_service.order_lines (order_id, item_id, quantity, unit_price)
+			SELECT $1, id, 1, price FROM catalogue.item_info
+			WHERE id = $2
+			RETURNING unit_price`
+	err = tx.QueryRowContext(ctx, insertLineQuery, orderDTO.ID, orderDTO.ItemId).Scan(&orderDTO.Total)
+	if err != nil {
 		return nil, fail(err)
 	}
 
-	getOrderItemQuery := `
-				SELECT * FROM catalogue.item_info
-				WHERE id = $1`
-	err = os.DB.QueryRowContext(ctx, getOrderItemQuery, orderDTO.ItemId).Scan(
-		&orderDTO.Item.ID,
-		&orderDTO.Item.Name,
-		&orderDTO.Item.Price,
-		&orderDTO.Item.Description,
-		&orderDTO.Item.Quantity,
-		&orderDTO.Item.ImageURL,
-	)
+	err = insertStatusChange(ctx, tx, &models.OrderStatusChange{
+		OrderId:   orderDTO.ID,
+		ToStatus:  orderDTO.Status,
+		ChangedBy: orderDTO.UserId,
+	})
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	if err = attachLines(ctx, tx, []*dto.OrderDTO{orderDTO}); err != nil {
+		return nil, fail(err)
+	}
+
+	if err = priceOrder(ctx, tx, orderDTO); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = savePricing(ctx, tx, orderDTO); err != nil {
+		return nil, fail(err)
+	}
+
+	if orderDTO.Address != nil {
+		if err = insertOrderAddress(ctx, tx, orderDTO.ID, orderDTO.Address); err != nil {
+			return nil, fail(err)
+		}
+	}
+
+	if err = insertSaga(ctx, tx, orderDTO.ID, &models.SagaData{User: user}); err != nil {
+		return nil, fail(err)
+	}
+
+	if claim != nil {
+		response, err := json.Marshal(orderDTO)
+		if err != nil {
+			return nil, fail(err)
+		}
+		err = saveIdempotentResponse(ctx, tx, claim, orderDTO.ID, response)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fail(err)
+	}
 
 	return orderDTO, nil
 }
@@ -78,18 +144,22 @@ func (os *OrderStorage) GetOrderById(ctx context.Context, id int) (*models.Order
 		return fmt.Errorf("%s: %v", op, e)
 	}
 	var order models.Order
-	query := `SELECT * FROM order_service.orders
+	query := `SELECT ` + orderColumns + ` FROM order_service.orders o
 			WHERE id = $1`
 	err := os.DB.QueryRowContext(ctx, query, id).Scan(
 		&order.ID,
 		&order.UserId,
 		&order.ItemId,
+		&order.Status,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&order.Total,
 	)
 
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return nil, fail(ErrRecordNotFound)
+			return nil, fmt.Errorf("%s: %w", op, ErrRecordNotFound)
 		default:
 			return nil, fail(err)
 		}
@@ -97,120 +167,353 @@ func (os *OrderStorage) GetOrderById(ctx context.Context, id int) (*models.Order
 	return &order, nil
 }
 
-func (os *OrderStorage) GetAllOrders(ctx context.Context) ([]*dto.OrderDTO, error) {
-	const op = "data.GetAllOrders"
+// orderSortKeys are the columns orders are sorted on by each sorting of a
+// search.
+var orderSortKeys = map[string]string{
+	search.SortCreatedAt: "o.created_at",
+	search.SortTotal:     "o.total",
+	search.SortId:        "o.id",
+}
+
+// SearchOrders returns a page of the orders matching q, which must be
+// normalized, and the cursor of the next page, empty on the last page.
+func (os *OrderStorage) SearchOrders(ctx context.Context, q search.Query) ([]*dto.OrderDTO, string, error) {
+	const op = "data.SearchOrders"
 	fail := func(e error) error {
-		return fmt.Errorf("%s, %v", op, e)
+		return fmt.Errorf("%s: %v", op, e)
 	}
+
+	key := orderSortKeys[q.SortBy]
+	direction, cmp := "ASC", ">"
+	if q.Descending {
+		direction, cmp = "DESC", "<"
+	}
+
+	var from, to *time.Time
+	if !q.From.IsZero() {
+		from = &q.From
+	}
+	if !q.To.IsZero() {
+		to = &q.To
+	}
+
+	// The key of the cursor always has the type of the sort column, even
+	// without a cursor, so that the statement can be prepared.
+	var afterKey any
+	var afterId int32
+	switch q.SortBy {
+	case search.SortCreatedAt:
+		afterKey = time.Time{}
+	default:
+		afterKey = int32(0)
+	}
+	if q.After != nil {
+		afterId = q.After.Id
+		switch q.SortBy {
+		case search.SortCreatedAt:
+			afterKey = q.After.CreatedAt
+		case search.SortTotal:
+			afterKey = q.After.Total
+		default:
+			afterKey = q.After.Id
+		}
+	}
+
+	// Text matches a prefix of the order number or a whole invoice number,
+	// formatted as invoice.Number does.
 	query := `
-				SELECT *
-				FROM order_service.orders
-				LEFT JOIN catalogue.item_info iu
-				ON orders.item_id = iu.id;`
-	rows, err := os.DB.QueryContext(ctx, query)
+			SELECT ` + orderDTOColumns + `
+			FROM order_service.orders o
+			WHERE ($1 = '' OR o.status = $1)
+			  AND ($2::timestamptz IS NULL OR o.created_at >= $2)
+			  AND ($3::timestamptz IS NULL OR o.created_at < $3)
+			  AND ($4 = 0 OR o.user_id = $4)
+			  AND ($5 = '' OR EXISTS (
+				SELECT 1 FROM auth.users u
+				WHERE u.id = o.user_id AND strpos(LOWER(u.email), LOWER($5)) > 0
+			  ))
+			  AND ($6 = 0 OR EXISTS (
+				SELECT 1 FROM order_service.order_lines l
+				WHERE l.order_id = o.id AND l.item_id = $6
+			  ))
+			  AND ($7 = 0 OR o.total >= $7)
+			  AND ($8 = 0 OR o.total <= $8)
+			  AND ($9 = '' OR starts_with(o.id::text, LTRIM($9, '#')) OR EXISTS (
+				SELECT 1 FROM order_service.invoices inv
+				WHERE inv.order_id = o.id
+				  AND 'INV-' || inv.year || '-' || LPAD(inv.number::text, 6, '0') = UPPER($9)
+			  ))
+			  AND (NOT $10 OR (` + key + `, o.id) ` + cmp + ` ($11, $12))
+			ORDER BY ` + key + ` ` + direction + `, o.id ` + direction + `
+			LIMIT $13`
+	rows, err := os.DB.QueryContext(ctx, query,
+		q.Status,
+		from,
+		to,
+		q.UserId,
+		q.UserEmail,
+		q.ItemId,
+		q.MinTotal,
+		q.MaxTotal,
+		q.Text,
+		q.After != nil,
+		afterKey,
+		afterId,
+		q.Limit+1,
+	)
 	if err != nil {
-		return nil, fail(err)
+		return nil, "", fail(err)
+	}
+
+	orders, err := scanOrderDTOs(rows)
+	if err != nil {
+		return nil, "", fail(err)
+	}
+
+	var next string
+	if len(orders) > int(q.Limit) {
+		orders = orders[:q.Limit]
+		last := orders[len(orders)-1]
+		next = q.NextCursor(last.ID, last.CreatedAt, last.Total)
+	}
+
+	if err = attachLines(ctx, os.DB, orders); err != nil {
+		return nil, "", fail(err)
 	}
 
-	var orderDTOS []*dto.OrderDTO
+	if err = attachDiscounts(ctx, os.DB, orders); err != nil {
+		return nil, "", fail(err)
+	}
+
+	return orders, next, nil
+}
+
+func (os *OrderStorage) GetOrderLines(ctx context.Context, orderId int32) ([]dto.OrderLineDTO, error) {
+	const op = "data.GetOrderLines"
+
+	order := &dto.OrderDTO{ID: orderId}
+	if err := attachLines(ctx, os.DB, []*dto.OrderDTO{order}); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return order.Lines, nil
+}
+
+func scanOrderDTOs(rows *sql.Rows) ([]*dto.OrderDTO, error) {
+	defer rows.Close()
+
+	var orders []*dto.OrderDTO
 	for rows.Next() {
-		var orderDTO dto.OrderDTO
+		var order dto.OrderDTO
 		err := rows.Scan(
-			&orderDTO.ID,
-			&orderDTO.UserId,
-			&orderDTO.ItemId,
-			&orderDTO.Item.ID,
-			&orderDTO.Item.Name,
-			&orderDTO.Item.Price,
-			&orderDTO.Item.Description,
-			&orderDTO.Item.Quantity,
-			&orderDTO.Item.ImageURL,
+			&order.ID,
+			&order.UserId,
+			&order.ItemId,
+			&order.Status,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+			&order.Total,
+			&order.Subtotal,
+			&order.ShippingFee,
+			&order.Discount,
+			&order.CouponCode,
 		)
 		if err != nil {
-			return nil, fail(err)
+			return nil, err
 		}
 
-		orderDTOS = append(orderDTOS, &orderDTO)
+		orders = append(orders, &order)
 	}
 
-	return orderDTOS, nil
+	return orders, rows.Err()
 }
 
-func (os *OrderStorage) GetOrdersByUserId(ctx context.Context, userId int) ([]*dto.OrderDTO, error) {
-	const op = "data.GetOrdersByUserId"
-	fail := func(e error) error {
-		return fmt.Errorf("%s: %v", op, e)
+// attachLines loads the lines of the given orders together with their items.
+// Single item orders also get their Item filled from the only line.
+func attachLines(ctx context.Context, q querier, orders []*dto.OrderDTO) error {
+	if len(orders) == 0 {
+		return nil
 	}
 
-	query := `
-			SELECT o.*,
-			       i.*
-			FROM order_service.orders o
-			INNER JOIN catalogue.item_info i
-			ON o.item_id = i.id
-			WHERE user_id = $1
-`
-	stmt, err := os.DB.PrepareContext(ctx, query)
-	if err != nil {
-		return nil, fail(err)
+	byId := make(map[int32]*dto.OrderDTO, len(orders))
+	ids := make([]int64, 0, len(orders))
+	for _, order := range orders {
+		byId[order.ID] = order
+		ids = append(ids, int64(order.ID))
 	}
 
-	rows, err := stmt.QueryContext(ctx, userId)
+	query := `
+			SELECT l.order_id, l.item_id, l.quantity, l.unit_price, ` + itemColumns + `
+			FROM order_service.order_lines l
+			JOIN catalogue.item_info i ON i.id = l.item_id
+			WHERE l.order_id = ANY($1)
+			ORDER BY l.id`
+	rows, err := q.QueryContext(ctx, query, pq.Array(ids))
 	if err != nil {
-		return nil, fail(err)
+		return err
 	}
+	defer rows.Close()
 
-	var orders []*dto.OrderDTO
 	for rows.Next() {
-		var order dto.OrderDTO
+		var orderId int32
+		var line dto.OrderLineDTO
 		err := rows.Scan(
-			&order.ID,
-			&order.UserId,
-			&order.ItemId,
-			&order.Item.ID,
-			&order.Item.Name,
-			&order.Item.Price,
-			&order.Item.Description,
-			&order.Item.Quantity,
-			&order.Item.ImageURL,
+			&orderId,
+			&line.ItemId,
+			&line.Quantity,
+			&line.UnitPrice,
+			&line.Item.ID,
+			&line.Item.Name,
+			&line.Item.Price,
+			&line.Item.Description,
+			&line.Item.Quantity,
+			&line.Item.ImageURL,
+			&line.Item.Category,
 		)
 		if err != nil {
-			return nil, fail(err)
+			return err
 		}
 
-		orders = append(orders, &order)
+		order := byId[orderId]
+		order.Lines = append(order.Lines, line)
+		if order.ItemId == line.ItemId {
+			order.Item = line.Item
+		}
 	}
 
-	return orders, nil
+	return rows.Err()
 }
 
-func (os *OrderStorage) DeleteOrderById(ctx context.Context, id int) error {
-	const op = "data.DeleteOrderById"
+// UpdateOrderStatus moves the order from one status to another and records the
+// change in the status history. The update only applies while the order is
+// still in the from status, so two concurrent transitions cannot both win.
+func (os *OrderStorage) UpdateOrderStatus(ctx context.Context, change *models.OrderStatusChange) (*models.Order, error) {
+	const op = "data.UpdateOrderStatus"
 	fail := func(e error) error {
 		return fmt.Errorf("%s: %v", op, e)
 	}
-	query := `
-			DELETE FROM order_service.orders
-			WHERE id = $1`
+
 	tx, err := os.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fail(err)
+	}
 	defer tx.Rollback()
+
+	order, err := applyStatusChange(ctx, tx, change)
 	if err != nil {
-		return fail(err)
+		if errors.Is(err, ErrStatusConflict) {
+			return nil, err
+		}
+		return nil, fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fail(err)
 	}
 
-	exec, err := os.DB.Exec(query, id)
+	return order, nil
+}
+
+// applyStatusChange updates the status of the order within tx, records the
+// change and stores the event the customer is notified by, if any.
+func applyStatusChange(ctx context.Context, tx *sql.Tx, change *models.OrderStatusChange) (*models.Order, error) {
+	query := `
+			UPDATE order_service.orders o
+			SET status = $1, updated_at = NOW()
+			WHERE id = $2 AND status = $3
+			RETURNING ` + orderColumns
+
+	var order models.Order
+	err := tx.QueryRowContext(ctx, query, change.ToStatus, change.OrderId, change.FromStatus).Scan(
+		&order.ID,
+		&order.UserId,
+		&order.ItemId,
+		&order.Status,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&order.Total,
+	)
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrStatusConflict
+		default:
+			return nil, err
+		}
 	}
 
-	affected, err := exec.RowsAffected()
-	if err != nil || affected == 0 {
-		return fail(err)
+	if err = insertStatusChange(ctx, tx, change); err != nil {
+		return nil, err
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fail(err)
+	switch order.Status {
+	case lifecycle.StatusCancelled:
+		if err = releaseCoupon(ctx, tx, order.ID); err != nil {
+			return nil, err
+		}
+	case lifecycle.StatusPaid:
+		if err = issueInvoice(ctx, tx, order.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = insertStatusEvent(ctx, tx, order.ID, order.Status); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func (os *OrderStorage) GetOrderStatusHistory(ctx context.Context, orderId int) ([]*models.OrderStatusChange, error) {
+	const op = "data.GetOrderStatusHistory"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
 	}
+	query := `
+			SELECT id, order_id, COALESCE(from_status, ''), to_status, COALESCE(changed_by, 0), reason, created_at
+			FROM order_service.order_status_history
+			WHERE order_id = $1
+			ORDER BY id`
+
+	rows, err := os.DB.QueryContext(ctx, query, orderId)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var history []*models.OrderStatusChange
+	for rows.Next() {
+		var change models.OrderStatusChange
+		err := rows.Scan(
+			&change.ID,
+			&change.OrderId,
+			&change.FromStatus,
+			&change.ToStatus,
+			&change.ChangedBy,
+			&change.Reason,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			return nil, fail(err)
+		}
+
+		history = append(history, &change)
+	}
+
+	return history, rows.Err()
+}
+
+func insertStatusChange(ctx context.Context, tx *sql.Tx, change *models.OrderStatusChange) error {
+	query := `
+			INSERT INTO order_service.order_status_history (order_id, from_status, to_status, changed_by, reason)
+			VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, 0), $5)`
+	_, err := tx.ExecContext(ctx, query,
+		change.OrderId,
+		change.FromStatus,
+		change.ToStatus,
+		change.ChangedBy,
+		change.Reason,
+	)
 
-	return nil
+	return err
 }
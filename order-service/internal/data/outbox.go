@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	eventsp "github.com/sntabq/proto-gen/gen/go/events"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"order-service/internal/data/dto"
+	"order-service/internal/data/models"
+	"shared/events"
+)
+
+const (
+	EventOrderCreated   = "order.created"
+	EventOrderPaid      = "order.paid"
+	EventOrderShipped   = "order.shipped"
+	EventOrderDelivered = "order.delivered"
+
+	EventReturnRequested = "return.requested"
+	EventReturnApproved  = "return.approved"
+	EventReturnRejected  = "return.rejected"
+	EventReturnReceived  = "return.received"
+	EventReturnRefunded  = "return.refunded"
+
+	EventCartUpdated = "cart.updated"
+)
+
+// producer is the name the order service signs its events with.
+const producer = "order-service"
+
+// insertOutboxEvent stores payload wrapped in an event envelope. The events of
+// an order are correlated by the order.
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, eventType string, aggregateId int32, payload proto.Message) error {
+	return insertEvent(ctx, tx, eventType, aggregateId, fmt.Sprintf("order-%d", aggregateId), payload)
+}
+
+// insertCartEvent stores the open cart of the user as it is after the changes
+// made in tx. The events of a user's cart are correlated by the user.
+func insertCartEvent(ctx context.Context, tx *sql.Tx, userId int) error {
+	user := &eventsp.User{Id: int32(userId)}
+	err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(username, ''), email FROM auth.users
+			WHERE id = $1`, userId).Scan(&user.Username, &user.Email)
+	if err != nil {
+		return err
+	}
+
+	cart, err := getCart(ctx, tx, userId)
+	if err != nil {
+		return err
+	}
+
+	event := &eventsp.CartEvent{User: user, Total: cart.Total}
+	for _, l := range cart.Lines {
+		event.Lines = append(event.Lines, &eventsp.OrderLine{
+			Item: &eventsp.Item{
+				Id:          l.Item.ID,
+				Name:        l.Item.Name,
+				Price:       l.Item.Price,
+				Description: l.Item.Description,
+				ImageUrl:    l.Item.ImageURL,
+				Category:    l.Item.Category,
+			},
+			Quantity:  l.Quantity,
+			UnitPrice: l.UnitPrice,
+		})
+	}
+
+	return insertEvent(ctx, tx, EventCartUpdated, int32(userId), fmt.Sprintf("user-%d", userId), event)
+}
+
+func insertEvent(ctx context.Context, tx *sql.Tx, eventType string, aggregateId int32, correlationId string, payload proto.Message) error {
+	body, err := events.Marshal(producer, eventType, correlationId, payload)
+	if err != nil {
+		return err
+	}
+
+	query := `
+			INSERT INTO order_service.outbox (aggregate_id, event_type, payload)
+			VALUES ($1, $2, $3)`
+	_, err = tx.ExecContext(ctx, query, aggregateId, eventType, body)
+
+	return err
+}
+
+// orderEvent is the payload of the events of an order. The return is only set
+// for the events of the returns workflow.
+func orderEvent(user *dto.UserDTO, order *dto.OrderDTO, r *models.Return) *eventsp.OrderEvent {
+	event := &eventsp.OrderEvent{
+		User: &eventsp.User{
+			Id:       user.Id,
+			Username: user.Username,
+			Email:    user.Email,
+		},
+		Order: &eventsp.Order{
+			Id:          order.ID,
+			UserId:      order.UserId,
+			Status:      order.Status,
+			Subtotal:    order.Subtotal,
+			ShippingFee: order.ShippingFee,
+			Discount:    order.Discount,
+			Total:       order.Total,
+			CreatedAt:   timestamppb.New(order.CreatedAt),
+		},
+	}
+
+	for _, l := range order.Lines {
+		event.Order.Lines = append(event.Order.Lines, &eventsp.OrderLine{
+			Item: &eventsp.Item{
+				Id:          l.Item.ID,
+				Name:        l.Item.Name,
+				Price:       l.Item.Price,
+				Description: l.Item.Description,
+				ImageUrl:    l.Item.ImageURL,
+				Category:    l.Item.Category,
+			},
+			Quantity:  l.Quantity,
+			UnitPrice: l.UnitPrice,
+		})
+	}
+
+	if a := order.Address; a != nil {
+		event.Order.Address = &eventsp.Address{
+			Recipient:  a.Recipient,
+			Line1:      a.Line1,
+			Line2:      a.Line2,
+			City:       a.City,
+			Region:     a.Region,
+			PostalCode: a.PostalCode,
+			Country:    a.Country,
+			Phone:      a.Phone,
+		}
+	}
+
+	for _, s := range order.Shipments {
+		event.Order.Shipments = append(event.Order.Shipments, &eventsp.Shipment{
+			Carrier:        s.Carrier,
+			TrackingNumber: s.TrackingNumber,
+			ShippedAt:      timestamppb.New(s.ShippedAt),
+		})
+	}
+
+	if r != nil {
+		event.Return = &eventsp.Return{
+			Id:           r.ID,
+			ItemId:       r.ItemId,
+			Quantity:     r.Quantity,
+			Reason:       r.Reason,
+			Status:       r.Status,
+			Note:         r.Note,
+			RefundAmount: r.RefundAmount,
+		}
+	}
+
+	return event
+}
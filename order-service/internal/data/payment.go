@@ -0,0 +1,247 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"order-service/internal/data/models"
+)
+
+type PaymentStorage struct {
+	DB *sql.DB
+}
+
+const paymentColumns = `id, order_id, provider, provider_ref, status, amount, refunded_amount, refunding_amount, created_at, updated_at`
+
+func scanPayment(row interface{ Scan(...any) error }) (*models.Payment, error) {
+	var payment models.Payment
+	err := row.Scan(
+		&payment.ID,
+		&payment.OrderId,
+		&payment.Provider,
+		&payment.ProviderRef,
+		&payment.Status,
+		&payment.Amount,
+		&payment.RefundedAmount,
+		&payment.RefundingAmount,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+func (ps *PaymentStorage) SavePayment(ctx context.Context, payment *models.Payment) (*models.Payment, error) {
+	const op = "data.SavePayment"
+	query := `
+			INSERT INTO order_service.payments (order_id, provider, provider_ref, status, amount)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING ` + paymentColumns
+
+	saved, err := scanPayment(ps.DB.QueryRowContext(ctx, query,
+		payment.OrderId,
+		payment.Provider,
+		payment.ProviderRef,
+		payment.Status,
+		payment.Amount,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return saved, nil
+}
+
+func (ps *PaymentStorage) GetPaymentById(ctx context.Context, id int64) (*models.Payment, error) {
+	return ps.getPayment(ctx, "data.GetPaymentById", `WHERE id = $1`, id)
+}
+
+func (ps *PaymentStorage) GetPaymentByRef(ctx context.Context, provider string, ref string) (*models.Payment, error) {
+	return ps.getPayment(ctx, "data.GetPaymentByRef", `WHERE provider = $1 AND provider_ref = $2`, provider, ref)
+}
+
+// GetPaymentByOrderId returns the latest payment of the order.
+func (ps *PaymentStorage) GetPaymentByOrderId(ctx context.Context, orderId int32) (*models.Payment, error) {
+	return ps.getPayment(ctx, "data.GetPaymentByOrderId", `WHERE order_id = $1 ORDER BY id DESC LIMIT 1`, orderId)
+}
+
+func (ps *PaymentStorage) getPayment(ctx context.Context, op string, where string, args ...any) (*models.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM order_service.payments ` + where
+
+	payment, err := scanPayment(ps.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("%s: %w", op, ErrRecordNotFound)
+		default:
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return payment, nil
+}
+
+func (ps *PaymentStorage) UpdatePayment(ctx context.Context, payment *models.Payment) error {
+	const op = "data.UpdatePayment"
+	query := `
+			UPDATE order_service.payments
+			SET provider_ref = $2, status = $3, updated_at = NOW()
+			WHERE id = $1
+			RETURNING updated_at`
+
+	err := ps.DB.QueryRowContext(ctx, query,
+		payment.ID,
+		payment.ProviderRef,
+		payment.Status,
+	).Scan(&payment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// settlePaymentQuery moves a payment to a new status, adds refunded to its
+// refunded amount and takes settled off the refunds that were pending. A refund
+// only marks the payment as refunded once the whole amount has been given back.
+const settlePaymentQuery = `
+			UPDATE order_service.payments
+			SET refunded_amount = LEAST(amount, refunded_amount + $3),
+				refunding_amount = GREATEST(0, refunding_amount - $4),
+				status = CASE WHEN $2 = 'refunded' AND refunded_amount + $3 < amount THEN status ELSE $2 END,
+				updated_at = NOW()
+			WHERE id = $1
+			RETURNING ` + paymentColumns
+
+func (ps *PaymentStorage) SettlePayment(ctx context.Context, id int64, status string, refunded int32) (*models.Payment, error) {
+	const op = "data.SettlePayment"
+
+	payment, err := scanPayment(ps.DB.QueryRowContext(ctx, settlePaymentQuery, id, status, refunded, 0))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return payment, nil
+}
+
+// ApplyPaymentEvent records a webhook event and settles the payment in one
+// transaction. It reports false and leaves the payment alone when the event
+// was already applied, so redelivered events take effect once.
+func (ps *PaymentStorage) ApplyPaymentEvent(ctx context.Context, id int64, eventId string, eventType string, payload []byte, status string, refunded int32) (*models.Payment, bool, error) {
+	const op = "data.ApplyPaymentEvent"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+	insertEventQuery := `
+			INSERT INTO order_service.payment_events (payment_id, event_id, event_type, payload)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (event_id) DO NOTHING`
+
+	tx, err := ps.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fail(err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, insertEventQuery, id, eventId, eventType, payload)
+	if err != nil {
+		return nil, false, fail(err)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return nil, false, nil
+	}
+
+	// Refund events settle a refund that was pending.
+	payment, err := scanPayment(tx.QueryRowContext(ctx, settlePaymentQuery, id, status, refunded, refunded))
+	if err != nil {
+		return nil, false, fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, false, fail(err)
+	}
+
+	return payment, true, nil
+}
+
+// ReservePaymentRefund locks the payment and passes it to reserve, which
+// returns the amount to refund, and adds that amount to the refunding amount
+// of the payment. The reservation is committed before the provider is called,
+// so refunds of a payment see what the ones before them are giving back
+// without holding the lock during the call. Errors returned by reserve are
+// passed on and leave the payment alone.
+func (ps *PaymentStorage) ReservePaymentRefund(ctx context.Context, id int64, reserve func(*models.Payment) (int32, error)) (*models.Payment, int32, error) {
+	const op = "data.ReservePaymentRefund"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := ps.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, fail(err)
+	}
+	defer tx.Rollback()
+
+	payment, err := scanPayment(tx.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM order_service.payments WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, 0, fmt.Errorf("%s: %w", op, ErrRecordNotFound)
+		default:
+			return nil, 0, fail(err)
+		}
+	}
+
+	amount, err := reserve(payment)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	payment, err = scanPayment(tx.QueryRowContext(ctx, `
+			UPDATE order_service.payments
+			SET refunding_amount = refunding_amount + $2, updated_at = NOW()
+			WHERE id = $1
+			RETURNING `+paymentColumns, id, amount))
+	if err != nil {
+		return nil, 0, fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, 0, fail(err)
+	}
+
+	return payment, amount, nil
+}
+
+// ReleasePaymentRefund takes a reserved amount the provider did not refund
+// off the refunding amount of the payment.
+func (ps *PaymentStorage) ReleasePaymentRefund(ctx context.Context, id int64, amount int32) error {
+	const op = "data.ReleasePaymentRefund"
+
+	_, err := ps.DB.ExecContext(ctx, `
+			UPDATE order_service.payments
+			SET refunding_amount = GREATEST(0, refunding_amount - $2), updated_at = NOW()
+			WHERE id = $1`, id, amount)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// SettlePaymentRefund moves a reserved amount the provider refunded from the
+// refunding to the refunded amount of the payment.
+func (ps *PaymentStorage) SettlePaymentRefund(ctx context.Context, id int64, amount int32) (*models.Payment, error) {
+	const op = "data.SettlePaymentRefund"
+
+	payment, err := scanPayment(ps.DB.QueryRowContext(ctx, settlePaymentQuery, id, "refunded", amount, amount))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return payment, nil
+}
@@ -0,0 +1,174 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"order-service/internal/data/models"
+	"order-service/internal/report"
+)
+
+type ReportStorage struct {
+	DB *sql.DB
+}
+
+// soldInRange is the sold orders placed in [$1, $2) with their units and
+// revenue. When a category is given in $3 only the lines of that category
+// count; otherwise the revenue of an order is its total, so discounts and
+// shipping are included.
+const soldInRange = `
+			sold AS (
+				SELECT l.order_id, l.user_id, l.created_at, SUM(l.quantity) AS units,
+				       CASE WHEN $3 = '' THEN MAX(o.total) ELSE SUM(l.amount) END AS revenue
+				FROM order_service.sold_lines l
+				JOIN order_service.sold_orders o ON o.id = l.order_id
+				WHERE l.created_at >= $1 AND l.created_at < $2 AND ($3 = '' OR LOWER(l.category) = LOWER($3))
+				GROUP BY l.order_id, l.user_id, l.created_at
+			)`
+
+// GetSalesReport returns the orders, units and revenue of every day, week or
+// month of the range that had sales.
+func (rs *ReportStorage) GetSalesReport(ctx context.Context, f report.Filter, granularity string) (*models.SalesReport, error) {
+	const op = "data.GetSalesReport"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := rs.DB.QueryContext(ctx, `
+			WITH `+soldInRange+`
+			SELECT date_trunc($4, created_at) AS period, COUNT(*), COALESCE(SUM(units), 0), COALESCE(SUM(revenue), 0)
+			FROM sold
+			GROUP BY period
+			ORDER BY period`, f.From, f.To, f.Category, granularity)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var r models.SalesReport
+	for rows.Next() {
+		var p models.SalesPeriod
+		if err := rows.Scan(&p.PeriodStart, &p.Orders, &p.Units, &p.Revenue); err != nil {
+			return nil, fail(err)
+		}
+		if p.Orders > 0 {
+			p.AverageOrderValue = p.Revenue / int64(p.Orders)
+		}
+
+		r.Periods = append(r.Periods, p)
+		r.Orders += p.Orders
+		r.Revenue += p.Revenue
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	if r.Orders > 0 {
+		r.AverageOrderValue = r.Revenue / int64(r.Orders)
+	}
+
+	return &r, nil
+}
+
+// GetTopItems returns the limit items sold the most units of in the range.
+func (rs *ReportStorage) GetTopItems(ctx context.Context, f report.Filter, limit int32) ([]*models.TopItem, error) {
+	const op = "data.GetTopItems"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	rows, err := rs.DB.QueryContext(ctx, `
+			SELECT item_id, name, category, SUM(quantity), COUNT(DISTINCT order_id), SUM(amount)
+			FROM order_service.sold_lines
+			WHERE created_at >= $1 AND created_at < $2 AND ($3 = '' OR LOWER(category) = LOWER($3))
+			GROUP BY item_id, name, category
+			ORDER BY SUM(quantity) DESC, SUM(amount) DESC, item_id
+			LIMIT $4`, f.From, f.To, f.Category, limit)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var items []*models.TopItem
+	for rows.Next() {
+		var item models.TopItem
+		err := rows.Scan(
+			&item.ItemId,
+			&item.Name,
+			&item.Category,
+			&item.Units,
+			&item.Orders,
+			&item.Revenue,
+		)
+		if err != nil {
+			return nil, fail(err)
+		}
+
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return items, nil
+}
+
+// GetCustomerReport counts the customers who ordered in the range, telling
+// apart those whose first sold order ever falls in the range.
+func (rs *ReportStorage) GetCustomerReport(ctx context.Context, f report.Filter) (*models.CustomerReport, error) {
+	const op = "data.GetCustomerReport"
+
+	var r models.CustomerReport
+	err := rs.DB.QueryRowContext(ctx, `
+			WITH `+soldInRange+`,
+			customers AS (
+				SELECT user_id, SUM(revenue) AS revenue FROM sold GROUP BY user_id
+			),
+			first_orders AS (
+				SELECT user_id, MIN(created_at) AS first_at FROM order_service.sold_orders GROUP BY user_id
+			)
+			SELECT COUNT(*) FILTER (WHERE f.first_at >= $1),
+			       COUNT(*) FILTER (WHERE f.first_at < $1),
+			       COALESCE(SUM(c.revenue) FILTER (WHERE f.first_at >= $1), 0),
+			       COALESCE(SUM(c.revenue) FILTER (WHERE f.first_at < $1), 0)
+			FROM customers c
+			JOIN first_orders f ON f.user_id = c.user_id`, f.From, f.To, f.Category).Scan(
+		&r.NewCustomers,
+		&r.ReturningCustomers,
+		&r.NewRevenue,
+		&r.ReturningRevenue,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return &r, nil
+}
+
+// GetConversionReport counts the carts created in the range and how many of
+// them were checked out. With a category only carts holding an item of it
+// count.
+func (rs *ReportStorage) GetConversionReport(ctx context.Context, f report.Filter) (*models.ConversionReport, error) {
+	const op = "data.GetConversionReport"
+
+	var r models.ConversionReport
+	err := rs.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*), COUNT(*) FILTER (WHERE c.status = 'checked_out')
+			FROM order_service.carts c
+			WHERE c.created_at >= $1 AND c.created_at < $2 AND ($3 = '' OR EXISTS (
+				SELECT 1 FROM order_service.cart_lines l
+				JOIN catalogue.item_info i ON i.id = l.item_id
+				WHERE l.cart_id = c.id AND LOWER(i.category) = LOWER($3)
+			))`, f.From, f.To, f.Category).Scan(&r.Carts, &r.CheckedOut)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if r.Carts > 0 {
+		r.ConversionRate = float64(r.CheckedOut) / float64(r.Carts)
+	}
+
+	return &r, nil
+}
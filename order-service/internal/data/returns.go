@@ -0,0 +1,315 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"order-service/internal/data/models"
+	"order-service/internal/rma"
+	"order-service/internal/services/order/lifecycle"
+)
+
+type ReturnStorage struct {
+	DB *sql.DB
+}
+
+// returnEvents maps the statuses of a return to the event the customer is
+// told about them with. A refunding return is not news; the customer is told
+// once the refund went through.
+var returnEvents = map[string]string{
+	rma.StatusRequested: EventReturnRequested,
+	rma.StatusApproved:  EventReturnApproved,
+	rma.StatusRejected:  EventReturnRejected,
+	rma.StatusReceived:  EventReturnReceived,
+	rma.StatusRefunded:  EventReturnRefunded,
+}
+
+const returnColumns = `id, order_id, user_id, item_id, quantity, reason, photo_urls, status, note, restocked,
+			refund_amount, created_at, updated_at`
+
+func scanReturn(row interface{ Scan(...any) error }) (*models.Return, error) {
+	var r models.Return
+	err := row.Scan(
+		&r.ID,
+		&r.OrderId,
+		&r.UserId,
+		&r.ItemId,
+		&r.Quantity,
+		&r.Reason,
+		pq.Array(&r.PhotoURLs),
+		&r.Status,
+		&r.Note,
+		&r.Restocked,
+		&r.RefundAmount,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// OpenReturn stores the return request of a line of a delivered order. The
+// order is locked while the quantity left to return of the line is checked,
+// so concurrent requests can not return more than was bought.
+func (rs *ReturnStorage) OpenReturn(ctx context.Context, r *models.Return) (*models.Return, error) {
+	const op = "data.OpenReturn"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := rs.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `
+			SELECT status FROM order_service.orders WHERE id = $1 FOR UPDATE`, r.OrderId).Scan(&status)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, fail(err)
+		}
+	}
+	if status != lifecycle.StatusDelivered {
+		return nil, rma.ErrNotReturnable
+	}
+
+	var bought, returned int32
+	err = tx.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(quantity), 0) FROM order_service.order_lines
+			WHERE order_id = $1 AND item_id = $2`, r.OrderId, r.ItemId).Scan(&bought)
+	if err != nil {
+		return nil, fail(err)
+	}
+	err = tx.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(quantity), 0) FROM order_service.returns
+			WHERE order_id = $1 AND item_id = $2 AND status <> $3`, r.OrderId, r.ItemId, rma.StatusRejected).Scan(&returned)
+	if err != nil {
+		return nil, fail(err)
+	}
+	if bought == 0 {
+		return nil, fmt.Errorf("%w: order has no line with item %d", rma.ErrInvalidReturn, r.ItemId)
+	}
+	if returned+r.Quantity > bought {
+		return nil, fmt.Errorf("%w: %d of %d left", rma.ErrQuantityExceeded, bought-returned, bought)
+	}
+
+	opened, err := scanReturn(tx.QueryRowContext(ctx, `
+			INSERT INTO order_service.returns (order_id, user_id, item_id, quantity, reason, photo_urls, status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING `+returnColumns,
+		r.OrderId, r.UserId, r.ItemId, r.Quantity, r.Reason, pq.Array(r.PhotoURLs), rma.StatusRequested))
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	change := &models.ReturnStatusChange{ReturnId: opened.ID, ToStatus: rma.StatusRequested, ChangedBy: r.UserId}
+	if err = insertReturnChange(ctx, tx, opened, change); err != nil {
+		return nil, fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fail(err)
+	}
+
+	return opened, nil
+}
+
+// UpdateReturn moves the return to the status of the change and stores its
+// note, restocked flag and refund amount. It fails with rma.ErrStatusConflict
+// when the return is no longer in the status the change starts from.
+func (rs *ReturnStorage) UpdateReturn(ctx context.Context, r *models.Return, change *models.ReturnStatusChange) (*models.Return, error) {
+	const op = "data.UpdateReturn"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := rs.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer tx.Rollback()
+
+	updated, err := scanReturn(tx.QueryRowContext(ctx, `
+			UPDATE order_service.returns
+			SET status = $3, note = $4, restocked = $5, refund_amount = $6, updated_at = NOW()
+			WHERE id = $1 AND status = $2
+			RETURNING `+returnColumns,
+		r.ID, change.FromStatus, change.ToStatus, r.Note, r.Restocked, r.RefundAmount))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, rma.ErrStatusConflict
+		default:
+			return nil, fail(err)
+		}
+	}
+
+	if err = insertReturnChange(ctx, tx, updated, change); err != nil {
+		return nil, fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fail(err)
+	}
+
+	return updated, nil
+}
+
+func (rs *ReturnStorage) GetReturn(ctx context.Context, id int64) (*models.Return, error) {
+	const op = "data.GetReturn"
+
+	r, err := scanReturn(rs.DB.QueryRowContext(ctx, `
+			SELECT `+returnColumns+` FROM order_service.returns WHERE id = $1`, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, rma.ErrReturnNotFound
+		default:
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if err = attachReturnHistory(ctx, rs.DB, []*models.Return{r}); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return r, nil
+}
+
+// ListReturns returns the returns in status, or in any status when status is
+// empty, of the user, or of all users when userId is zero, newest first.
+func (rs *ReturnStorage) ListReturns(ctx context.Context, userId int32, status string) ([]*models.Return, error) {
+	const op = "data.ListReturns"
+
+	returns, err := queryReturns(ctx, rs.DB, `
+			SELECT `+returnColumns+` FROM order_service.returns
+			WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)
+			ORDER BY id DESC`, userId, status)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return returns, nil
+}
+
+// GetOrderReturns returns the returns of the order with their history,
+// oldest first.
+func (os *OrderStorage) GetOrderReturns(ctx context.Context, orderId int) ([]*models.Return, error) {
+	const op = "data.GetOrderReturns"
+
+	returns, err := queryReturns(ctx, os.DB, `
+			SELECT `+returnColumns+` FROM order_service.returns
+			WHERE order_id = $1
+			ORDER BY id`, orderId)
+	if err == nil {
+		err = attachReturnHistory(ctx, os.DB, returns)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return returns, nil
+}
+
+func queryReturns(ctx context.Context, q querier, query string, args ...any) ([]*models.Return, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var returns []*models.Return
+	for rows.Next() {
+		r, err := scanReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		returns = append(returns, r)
+	}
+
+	return returns, rows.Err()
+}
+
+func attachReturnHistory(ctx context.Context, q querier, returns []*models.Return) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	byId := make(map[int64]*models.Return, len(returns))
+	ids := make([]int64, 0, len(returns))
+	for _, r := range returns {
+		byId[r.ID] = r
+		ids = append(ids, r.ID)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+			SELECT id, return_id, COALESCE(from_status, ''), to_status, COALESCE(changed_by, 0), note, created_at
+			FROM order_service.return_status_history
+			WHERE return_id = ANY($1)
+			ORDER BY id`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change models.ReturnStatusChange
+		err := rows.Scan(
+			&change.ID,
+			&change.ReturnId,
+			&change.FromStatus,
+			&change.ToStatus,
+			&change.ChangedBy,
+			&change.Note,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		byId[change.ReturnId].History = append(byId[change.ReturnId].History, change)
+	}
+
+	return rows.Err()
+}
+
+// insertReturnChange records the change in the history of the return and
+// stores the event the customer is told about it with.
+func insertReturnChange(ctx context.Context, tx *sql.Tx, r *models.Return, change *models.ReturnStatusChange) error {
+	_, err := tx.ExecContext(ctx, `
+			INSERT INTO order_service.return_status_history (return_id, from_status, to_status, changed_by, note)
+			VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, 0), $5)`,
+		r.ID, change.FromStatus, change.ToStatus, change.ChangedBy, change.Note)
+	if err != nil {
+		return err
+	}
+
+	// Reverting a change, as when a refund fails, is not news to the customer.
+	eventType, ok := returnEvents[r.Status]
+	if !ok || (change.FromStatus != "" && !rma.CanTransition(change.FromStatus, change.ToStatus)) {
+		return nil
+	}
+
+	user, err := sagaUser(ctx, tx, r.OrderId)
+	if err != nil || user == nil {
+		return err
+	}
+
+	order, err := loadOrder(ctx, tx, r.OrderId)
+	if err != nil {
+		return err
+	}
+
+	return insertOutboxEvent(ctx, tx, eventType, r.OrderId, orderEvent(user, order, r))
+}
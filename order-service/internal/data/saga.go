@@ -0,0 +1,266 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	eventsp "github.com/sntabq/proto-gen/gen/go/events"
+	"order-service/internal/data/models"
+	"order-service/internal/invoice"
+	"time"
+)
+
+type SagaStorage struct {
+	DB *sql.DB
+}
+
+var ErrSagaBusy = errors.New("saga is finished or being executed elsewhere")
+
+const sagaColumns = `id, order_id, status, step, data, attempts, last_error, created_at, updated_at`
+
+func insertSaga(ctx context.Context, tx *sql.Tx, orderId int32, data *models.SagaData) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	query := `
+			INSERT INTO order_service.sagas (order_id, data)
+			VALUES ($1, $2)`
+	_, err = tx.ExecContext(ctx, query, orderId, body)
+
+	return err
+}
+
+func scanSaga(row interface{ Scan(...any) error }) (*models.Saga, error) {
+	var saga models.Saga
+	var data []byte
+	err := row.Scan(
+		&saga.ID,
+		&saga.OrderId,
+		&saga.Status,
+		&saga.Step,
+		&data,
+		&saga.Attempts,
+		&saga.LastError,
+		&saga.CreatedAt,
+		&saga.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &saga.Data); err != nil {
+		return nil, err
+	}
+
+	return &saga, nil
+}
+
+// ClaimSaga locks the unfinished saga of the order for lease, so that only one
+// instance of the service executes it at a time.
+func (ss *SagaStorage) ClaimSaga(ctx context.Context, orderId int32, lease time.Duration) (*models.Saga, error) {
+	const op = "data.ClaimSaga"
+	query := `
+			UPDATE order_service.sagas
+			SET locked_until = NOW() + $2 * INTERVAL '1 second'
+			WHERE order_id = $1
+				AND status IN ('running', 'compensating')
+				AND (locked_until IS NULL OR locked_until < NOW())
+			RETURNING ` + sagaColumns
+
+	saga, err := scanSaga(ss.DB.QueryRowContext(ctx, query, orderId, int64(lease.Seconds())))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrSagaBusy
+		default:
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return saga, nil
+}
+
+// SaveSagaState stores the progress of a saga. The lock is dropped once the
+// saga reaches a final status.
+func (ss *SagaStorage) SaveSagaState(ctx context.Context, saga *models.Saga) error {
+	const op = "data.SaveSagaState"
+	query := `
+			UPDATE order_service.sagas
+			SET status = $2, step = $3, data = $4, attempts = $5, last_error = $6, updated_at = NOW(),
+				locked_until = CASE WHEN $2 IN ('running', 'compensating') THEN locked_until END
+			WHERE id = $1
+			RETURNING updated_at`
+
+	data, err := json.Marshal(saga.Data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = ss.DB.QueryRowContext(ctx, query,
+		saga.ID,
+		saga.Status,
+		saga.Step,
+		data,
+		saga.Attempts,
+		saga.LastError,
+	).Scan(&saga.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+func (ss *SagaStorage) UnlockSaga(ctx context.Context, id int64) error {
+	const op = "data.UnlockSaga"
+
+	_, err := ss.DB.ExecContext(ctx, `UPDATE order_service.sagas SET locked_until = NULL WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// CompleteSaga finishes the saga and stores the order.created event for the
+// notification service in the same transaction. When the order was paid by
+// then, the event names the invoice and is followed by the order.paid event
+// held back while the saga ran.
+func (ss *SagaStorage) CompleteSaga(ctx context.Context, saga *models.Saga, status string) error {
+	const op = "data.CompleteSaga"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := ss.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback()
+
+	// Lock the saga before loading the order, so that a payment settled
+	// meanwhile is either seen here or announced once the saga completed.
+	_, err = tx.ExecContext(ctx, `SELECT 1 FROM order_service.sagas WHERE id = $1 FOR UPDATE`, saga.ID)
+	if err != nil {
+		return fail(err)
+	}
+
+	order, err := loadOrder(ctx, tx, saga.OrderId)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Without the user there is no one to notify.
+	if user := saga.Data.User; user != nil {
+		payload := orderEvent(user, order, nil)
+		// The PDF is not sent along; the notification service loads it
+		// by the order id.
+		if order.Invoice != nil {
+			payload.Invoice = &eventsp.Invoice{
+				Number:   order.Invoice.Number,
+				Filename: invoice.Filename(order.Invoice.Number),
+			}
+		}
+
+		err = insertOutboxEvent(ctx, tx, EventOrderCreated, order.ID, payload)
+		if err != nil {
+			return fail(err)
+		}
+
+		if order.Invoice != nil {
+			err = insertOutboxEvent(ctx, tx, EventOrderPaid, order.ID, orderEvent(user, order, nil))
+			if err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+			UPDATE order_service.sagas
+			SET status = $2, step = '', attempts = 0, last_error = '', locked_until = NULL, updated_at = NOW()
+			WHERE id = $1
+			RETURNING updated_at`, saga.ID, status).Scan(&saga.UpdatedAt)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fail(err)
+	}
+
+	saga.Status = status
+	saga.Step = ""
+	saga.Attempts = 0
+	saga.LastError = ""
+
+	return nil
+}
+
+// ListSagas returns the sagas in status, or the unfinished ones when status is
+// empty, oldest first.
+func (ss *SagaStorage) ListSagas(ctx context.Context, status string) ([]*models.Saga, error) {
+	const op = "data.ListSagas"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+	query := `
+			SELECT ` + sagaColumns + `
+			FROM order_service.sagas
+			WHERE ($1 = '' AND status IN ('running', 'compensating')) OR status = $1
+			ORDER BY updated_at, id`
+
+	rows, err := ss.DB.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var sagas []*models.Saga
+	for rows.Next() {
+		saga, err := scanSaga(rows)
+		if err != nil {
+			return nil, fail(err)
+		}
+
+		sagas = append(sagas, saga)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	return sagas, nil
+}
+
+// GetResumableSagas returns the order ids of unfinished sagas that nobody is
+// executing at the moment.
+func (ss *SagaStorage) GetResumableSagas(ctx context.Context) ([]int32, error) {
+	const op = "data.GetResumableSagas"
+	query := `
+			SELECT order_id FROM order_service.sagas
+			WHERE status IN ('running', 'compensating')
+				AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY updated_at, id`
+
+	rows, err := ss.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var orderIds []int32
+	for rows.Next() {
+		var orderId int32
+		if err := rows.Scan(&orderId); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		orderIds = append(orderIds, orderId)
+	}
+
+	return orderIds, rows.Err()
+}
@@ -0,0 +1,276 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"order-service/internal/data/dto"
+	"order-service/internal/data/models"
+	"order-service/internal/services/order/lifecycle"
+)
+
+var (
+	ErrNoShippingAddress = errors.New("order has no shipping address")
+	ErrDuplicateTracking = errors.New("shipment with this tracking number already exists")
+)
+
+// statusEvents maps the order statuses the customer or partners are told
+// about to the event published for them.
+var statusEvents = map[string]string{
+	lifecycle.StatusPaid:      EventOrderPaid,
+	lifecycle.StatusShipped:   EventOrderShipped,
+	lifecycle.StatusDelivered: EventOrderDelivered,
+}
+
+func insertOrderAddress(ctx context.Context, tx *sql.Tx, orderId int32, address *dto.AddressDTO) error {
+	query := `
+			INSERT INTO order_service.order_addresses (order_id, recipient, line1, line2, city, region, postal_code, country, phone)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := tx.ExecContext(ctx, query,
+		orderId,
+		address.Recipient,
+		address.Line1,
+		address.Line2,
+		address.City,
+		address.Region,
+		address.PostalCode,
+		address.Country,
+		address.Phone,
+	)
+
+	return err
+}
+
+// GetOrderDetails returns the order with its lines, shipping address,
+// shipments and invoice.
+func (os *OrderStorage) GetOrderDetails(ctx context.Context, id int) (*dto.OrderDTO, error) {
+	const op = "data.GetOrderDetails"
+
+	order, err := loadOrder(ctx, os.DB, int32(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("%s: %w", op, ErrRecordNotFound)
+		default:
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return order, nil
+}
+
+// CreateShipment records that the order was handed to a carrier and moves it
+// to shipped in the same transaction.
+func (os *OrderStorage) CreateShipment(ctx context.Context, shipment *dto.ShipmentDTO, change *models.OrderStatusChange) (*dto.OrderDTO, error) {
+	const op = "data.CreateShipment"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+
+	tx, err := os.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer tx.Rollback()
+
+	var hasAddress bool
+	err = tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM order_service.order_addresses WHERE order_id = $1)`,
+		shipment.OrderId).Scan(&hasAddress)
+	if err != nil {
+		return nil, fail(err)
+	}
+	if !hasAddress {
+		return nil, ErrNoShippingAddress
+	}
+
+	err = tx.QueryRowContext(ctx, `
+			INSERT INTO order_service.shipments (order_id, carrier, tracking_number, created_by)
+			VALUES ($1, $2, $3, NULLIF($4, 0))
+			RETURNING id, shipped_at`,
+		shipment.OrderId, shipment.Carrier, shipment.TrackingNumber, change.ChangedBy,
+	).Scan(&shipment.ID, &shipment.ShippedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23505":
+			return nil, ErrDuplicateTracking
+		default:
+			return nil, fail(err)
+		}
+	}
+
+	if _, err = applyStatusChange(ctx, tx, change); err != nil {
+		if errors.Is(err, ErrStatusConflict) {
+			return nil, err
+		}
+		return nil, fail(err)
+	}
+
+	order, err := loadOrder(ctx, tx, shipment.OrderId)
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fail(err)
+	}
+
+	return order, nil
+}
+
+// loadOrder reads the order with its lines, discounts, shipping address and
+// shipments.
+func loadOrder(ctx context.Context, q querier, id int32) (*dto.OrderDTO, error) {
+	var order dto.OrderDTO
+	query := `SELECT ` + orderDTOColumns + ` FROM order_service.orders o WHERE id = $1`
+	err := q.QueryRowContext(ctx, query, id).Scan(
+		&order.ID,
+		&order.UserId,
+		&order.ItemId,
+		&order.Status,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&order.Total,
+		&order.Subtotal,
+		&order.ShippingFee,
+		&order.Discount,
+		&order.CouponCode,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = attachLines(ctx, q, []*dto.OrderDTO{&order}); err != nil {
+		return nil, err
+	}
+
+	if err = attachDiscounts(ctx, q, []*dto.OrderDTO{&order}); err != nil {
+		return nil, err
+	}
+
+	var address dto.AddressDTO
+	err = q.QueryRowContext(ctx, `
+			SELECT recipient, line1, line2, city, region, postal_code, country, phone
+			FROM order_service.order_addresses
+			WHERE order_id = $1`, id).Scan(
+		&address.Recipient,
+		&address.Line1,
+		&address.Line2,
+		&address.City,
+		&address.Region,
+		&address.PostalCode,
+		&address.Country,
+		&address.Phone,
+	)
+	switch {
+	case err == nil:
+		order.Address = &address
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, `
+			SELECT id, order_id, carrier, tracking_number, shipped_at
+			FROM order_service.shipments
+			WHERE order_id = $1
+			ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shipment dto.ShipmentDTO
+		err := rows.Scan(
+			&shipment.ID,
+			&shipment.OrderId,
+			&shipment.Carrier,
+			&shipment.TrackingNumber,
+			&shipment.ShippedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		order.Shipments = append(order.Shipments, shipment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err = attachInvoice(ctx, q, &order); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// insertStatusEvent stores the event for a status change the customer or
+// partners are told about. The customer is addressed with the user snapshot
+// kept by the checkout saga; orders placed before sagas existed have none and
+// are skipped. An order paid while its checkout saga is still running is not
+// announced here: CompleteSaga announces it after order.created.
+func insertStatusEvent(ctx context.Context, tx *sql.Tx, orderId int32, status string) error {
+	eventType, ok := statusEvents[status]
+	if !ok {
+		return nil
+	}
+
+	if status == lifecycle.StatusPaid {
+		// The saga stays locked until tx ends, so CompleteSaga either ran
+		// before or sees the order paid.
+		var sagaStatus string
+		err := tx.QueryRowContext(ctx, `
+				SELECT status FROM order_service.sagas WHERE order_id = $1 FOR UPDATE`, orderId).Scan(&sagaStatus)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if sagaStatus == "running" {
+			return nil
+		}
+	}
+
+	user, err := sagaUser(ctx, tx, orderId)
+	if err != nil || user == nil {
+		return err
+	}
+
+	order, err := loadOrder(ctx, tx, orderId)
+	if err != nil {
+		return err
+	}
+
+	return insertOutboxEvent(ctx, tx, eventType, orderId, orderEvent(user, order, nil))
+}
+
+// sagaUser returns the user snapshot the checkout saga of the order kept, or
+// nil when the order was placed before sagas existed.
+func sagaUser(ctx context.Context, tx *sql.Tx, orderId int32) (*dto.UserDTO, error) {
+	var user []byte
+	err := tx.QueryRowContext(ctx, `
+			SELECT data->'user' FROM order_service.sagas WHERE order_id = $1`, orderId).Scan(&user)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	if user == nil {
+		return nil, nil
+	}
+
+	var userInfo dto.UserDTO
+	if err = json.Unmarshal(user, &userInfo); err != nil {
+		return nil, err
+	}
+
+	return &userInfo, nil
+}
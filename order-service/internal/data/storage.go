@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"shared/outbox"
 )
 
 var (
@@ -21,3 +22,82 @@ func New(dsn string) (*OrderStorage, error) {
 
 	return &OrderStorage{DB: db}, nil
 }
+
+func NewCartStorage(dsn string) (*CartStorage, error) {
+	const op = "data.NewCartStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &CartStorage{DB: db}, nil
+}
+
+// NewOutboxStorage returns the outbox the events of the order service are stored in
+// until they are published.
+func NewOutboxStorage(dsn string) (*outbox.Store, error) {
+	const op = "data.NewOutboxStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return outbox.NewStore(db, "order_service.outbox"), nil
+}
+
+func NewSagaStorage(dsn string) (*SagaStorage, error) {
+	const op = "data.NewSagaStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &SagaStorage{DB: db}, nil
+}
+
+func NewPaymentStorage(dsn string) (*PaymentStorage, error) {
+	const op = "data.NewPaymentStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &PaymentStorage{DB: db}, nil
+}
+
+func NewCouponStorage(dsn string) (*CouponStorage, error) {
+	const op = "data.NewCouponStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &CouponStorage{DB: db}, nil
+}
+
+func NewReturnStorage(dsn string) (*ReturnStorage, error) {
+	const op = "data.NewReturnStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &ReturnStorage{DB: db}, nil
+}
+
+func NewReportStorage(dsn string) (*ReportStorage, error) {
+	const op = "data.NewReportStorage"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &ReportStorage{DB: db}, nil
+}
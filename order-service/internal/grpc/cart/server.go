@@ -0,0 +1,171 @@
+package cartGrpc
+
+import (
+	"context"
+	"errors"
+	"github.com/jinzhu/copier"
+	orderp "github.com/sntabq/proto-gen/gen/go/order"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"order-service/internal/coupon"
+	"order-service/internal/data"
+	"order-service/internal/data/dto"
+	orderGrpc "order-service/internal/grpc/order"
+	"order-service/internal/services/cart"
+	"order-service/internal/services/order/lifecycle"
+	"strings"
+)
+
+type CartService interface {
+	AddToCart(ctx context.Context, userId int, itemId int, quantity int) (*dto.CartDTO, error)
+	UpdateCartLine(ctx context.Context, userId int, itemId int, quantity int) (*dto.CartDTO, error)
+	RemoveFromCart(ctx context.Context, userId int, itemId int) (*dto.CartDTO, error)
+	GetCart(ctx context.Context, userId int) (*dto.CartDTO, error)
+	ValidateCoupon(ctx context.Context, userId int, code string) (*coupon.Quote, error)
+}
+
+// Checkouter turns a cart into an order. It is implemented by the order
+// service.
+type Checkouter interface {
+	Checkout(ctx context.Context, userId int, addressId int64, couponCode string) (*dto.OrderDTO, error)
+}
+
+type cartService struct {
+	orderp.UnimplementedCartServiceServer
+	cart       CartService
+	checkouter Checkouter
+}
+
+func Register(gRPCServer *grpc.Server, cart CartService, checkouter Checkouter) {
+	orderp.RegisterCartServiceServer(gRPCServer, &cartService{cart: cart, checkouter: checkouter})
+}
+
+func (cs *cartService) AddToCart(ctx context.Context, req *orderp.AddToCartRequest) (*orderp.AddToCartResponse, error) {
+	if req.GetItemId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "item_id is required")
+	}
+
+	c, err := cs.cart.AddToCart(ctx, int(req.GetUserId()), int(req.GetItemId()), int(req.GetQuantity()))
+	if err != nil {
+		return nil, statusError(err, "failed to add item to cart")
+	}
+
+	response, err := toProtoCart(c)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to copy to response cart")
+	}
+
+	return &orderp.AddToCartResponse{Cart: response}, nil
+}
+
+func (cs *cartService) UpdateCartLine(ctx context.Context, req *orderp.UpdateCartLineRequest) (*orderp.UpdateCartLineResponse, error) {
+	if req.GetItemId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "item_id is required")
+	}
+
+	c, err := cs.cart.UpdateCartLine(ctx, int(req.GetUserId()), int(req.GetItemId()), int(req.GetQuantity()))
+	if err != nil {
+		return nil, statusError(err, "failed to update cart line")
+	}
+
+	response, err := toProtoCart(c)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to copy to response cart")
+	}
+
+	return &orderp.UpdateCartLineResponse{Cart: response}, nil
+}
+
+func (cs *cartService) RemoveFromCart(ctx context.Context, req *orderp.RemoveFromCartRequest) (*orderp.RemoveFromCartResponse, error) {
+	if req.GetItemId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "item_id is required")
+	}
+
+	c, err := cs.cart.RemoveFromCart(ctx, int(req.GetUserId()), int(req.GetItemId()))
+	if err != nil {
+		return nil, statusError(err, "failed to remove item from cart")
+	}
+
+	response, err := toProtoCart(c)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to copy to response cart")
+	}
+
+	return &orderp.RemoveFromCartResponse{Cart: response}, nil
+}
+
+func (cs *cartService) GetCart(ctx context.Context, req *orderp.GetCartRequest) (*orderp.GetCartResponse, error) {
+	c, err := cs.cart.GetCart(ctx, int(req.GetUserId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get cart")
+	}
+
+	response, err := toProtoCart(c)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to copy to response cart")
+	}
+
+	return &orderp.GetCartResponse{Cart: response}, nil
+}
+
+func (cs *cartService) Checkout(ctx context.Context, req *orderp.CheckoutRequest) (*orderp.CheckoutResponse, error) {
+	orderDTO, err := cs.checkouter.Checkout(ctx, int(req.GetUserId()), req.GetAddressId(), req.GetCouponCode())
+	if err != nil {
+		return nil, statusError(err, "failed to checkout cart")
+	}
+
+	o, err := orderGrpc.ToProtoOrder(orderDTO)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to copy to response order")
+	}
+
+	return &orderp.CheckoutResponse{Order: o}, nil
+}
+
+// ValidateCoupon prices the cart of the user with the coupon without
+// redeeming it.
+func (cs *cartService) ValidateCoupon(ctx context.Context, req *orderp.ValidateCouponRequest) (*orderp.ValidateCouponResponse, error) {
+	if strings.TrimSpace(req.GetCode()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	quote, err := cs.cart.ValidateCoupon(ctx, int(req.GetUserId()), req.GetCode())
+	if err != nil {
+		return nil, statusError(err, "failed to validate coupon")
+	}
+
+	return &orderp.ValidateCouponResponse{Quote: &orderp.Quote{
+		Subtotal:    quote.Subtotal,
+		ShippingFee: quote.ShippingFee,
+		Discount:    quote.Discount,
+		Total:       quote.Total,
+		Discounts:   orderGrpc.ToProtoDiscounts(quote.Discounts),
+	}}, nil
+}
+
+// statusError maps errors of the cart to gRPC statuses.
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "item not found")
+	case errors.Is(err, coupon.ErrCouponNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, cart.ErrInvalidQuantity), errors.Is(err, lifecycle.ErrAddressNotFound):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, data.ErrCartEmpty), errors.Is(err, data.ErrOutOfStock), errors.Is(err, data.ErrPriceChanged),
+		errors.Is(err, lifecycle.ErrCheckoutFailed), errors.Is(err, coupon.ErrRejected):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
+
+func toProtoCart(c *dto.CartDTO) (*orderp.Cart, error) {
+	var response orderp.Cart
+	if err := copier.Copy(&response, c); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
@@ -0,0 +1,169 @@
+package couponGrpc
+
+import (
+	"context"
+	"errors"
+	orderp "github.com/sntabq/proto-gen/gen/go/order"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"order-service/internal/coupon"
+	"order-service/internal/data"
+	"order-service/internal/data/models"
+	"time"
+)
+
+type Coupons interface {
+	CreateCoupon(ctx context.Context, c *models.Coupon) (*models.Coupon, error)
+	UpdateCoupon(ctx context.Context, c *models.Coupon) (*models.Coupon, error)
+	GetCoupon(ctx context.Context, id int64) (*models.Coupon, error)
+	ListCoupons(ctx context.Context) ([]*models.Coupon, error)
+	DeleteCoupon(ctx context.Context, id int64) error
+}
+
+type couponService struct {
+	orderp.UnimplementedCouponServiceServer
+	coupons Coupons
+}
+
+func Register(gRPCServer *grpc.Server, coupons Coupons) {
+	orderp.RegisterCouponServiceServer(gRPCServer, &couponService{coupons: coupons})
+}
+
+func (cs *couponService) CreateCoupon(ctx context.Context, req *orderp.CreateCouponRequest) (*orderp.CreateCouponResponse, error) {
+	if req.GetCoupon() == nil {
+		return nil, status.Error(codes.InvalidArgument, "coupon is required")
+	}
+
+	created, err := cs.coupons.CreateCoupon(ctx, fromProto(req.GetCoupon()))
+	if err != nil {
+		return nil, statusError(err, "failed to create coupon")
+	}
+
+	return &orderp.CreateCouponResponse{Coupon: toProto(created)}, nil
+}
+
+func (cs *couponService) UpdateCoupon(ctx context.Context, req *orderp.UpdateCouponRequest) (*orderp.UpdateCouponResponse, error) {
+	if req.GetCoupon().GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "coupon id is required")
+	}
+
+	updated, err := cs.coupons.UpdateCoupon(ctx, fromProto(req.GetCoupon()))
+	if err != nil {
+		return nil, statusError(err, "failed to update coupon")
+	}
+
+	return &orderp.UpdateCouponResponse{Coupon: toProto(updated)}, nil
+}
+
+func (cs *couponService) GetCoupon(ctx context.Context, req *orderp.GetCouponRequest) (*orderp.GetCouponResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	c, err := cs.coupons.GetCoupon(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err, "failed to get coupon")
+	}
+
+	return &orderp.GetCouponResponse{Coupon: toProto(c)}, nil
+}
+
+func (cs *couponService) ListCoupons(ctx context.Context, req *orderp.ListCouponsRequest) (*orderp.ListCouponsResponse, error) {
+	coupons, err := cs.coupons.ListCoupons(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list coupons")
+	}
+
+	response := make([]*orderp.Coupon, 0, len(coupons))
+	for _, c := range coupons {
+		response = append(response, toProto(c))
+	}
+
+	return &orderp.ListCouponsResponse{Coupons: response}, nil
+}
+
+func (cs *couponService) DeleteCoupon(ctx context.Context, req *orderp.DeleteCouponRequest) (*orderp.DeleteCouponResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	if err := cs.coupons.DeleteCoupon(ctx, req.GetId()); err != nil {
+		return nil, statusError(err, "failed to delete coupon")
+	}
+
+	return &orderp.DeleteCouponResponse{}, nil
+}
+
+// statusError maps errors of the coupons to gRPC statuses.
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, coupon.ErrCouponNotFound):
+		return status.Error(codes.NotFound, "coupon not found")
+	case errors.Is(err, coupon.ErrInvalidCoupon):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, data.ErrCouponExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
+
+func fromProto(c *orderp.Coupon) *models.Coupon {
+	return &models.Coupon{
+		ID:             c.GetId(),
+		Code:           c.GetCode(),
+		Description:    c.GetDescription(),
+		Kind:           c.GetKind(),
+		Value:          c.GetValue(),
+		Category:       c.GetCategory(),
+		ItemId:         c.GetItemId(),
+		BuyQuantity:    c.GetBuyQuantity(),
+		GetQuantity:    c.GetGetQuantity(),
+		MinOrderValue:  c.GetMinOrderValue(),
+		MaxUses:        c.GetMaxUses(),
+		MaxUsesPerUser: c.GetMaxUsesPerUser(),
+		StartsAt:       fromTimestamp(c.GetStartsAt()),
+		EndsAt:         fromTimestamp(c.GetEndsAt()),
+		Active:         c.GetActive(),
+	}
+}
+
+func toProto(c *models.Coupon) *orderp.Coupon {
+	return &orderp.Coupon{
+		Id:             c.ID,
+		Code:           c.Code,
+		Description:    c.Description,
+		Kind:           c.Kind,
+		Value:          c.Value,
+		Category:       c.Category,
+		ItemId:         c.ItemId,
+		BuyQuantity:    c.BuyQuantity,
+		GetQuantity:    c.GetQuantity,
+		MinOrderValue:  c.MinOrderValue,
+		MaxUses:        c.MaxUses,
+		MaxUsesPerUser: c.MaxUsesPerUser,
+		Uses:           c.Uses,
+		StartsAt:       toTimestamp(c.StartsAt),
+		EndsAt:         toTimestamp(c.EndsAt),
+		Active:         c.Active,
+		CreatedAt:      timestamppb.New(c.CreatedAt),
+		UpdatedAt:      timestamppb.New(c.UpdatedAt),
+	}
+}
+
+func fromTimestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
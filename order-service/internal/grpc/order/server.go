@@ -2,28 +2,54 @@ package orderGrpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/jinzhu/copier"
-	cataloguep "github.com/sntabq/proto-gen/gen/go/catalogue"
 	orderp "github.com/sntabq/proto-gen/gen/go/order"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"log"
+	"order-service/internal/coupon"
 	"order-service/internal/data"
 	"order-service/internal/data/dto"
 	"order-service/internal/data/models"
+	"order-service/internal/invoice"
+	"order-service/internal/search"
+	"order-service/internal/services/order/lifecycle"
 	"strconv"
 	"strings"
 )
 
 type OrderService interface {
-	CreateOrder(context.Context, *dto.OrderDTO) (*dto.OrderDTO, error)
-	ListOrders(context.Context) ([]*dto.OrderDTO, error)
-	GetOrder(context.Context, int) (*models.Order, error)
-	GetOrdersByUserId(context.Context, int) ([]*dto.OrderDTO, error)
+	CreateOrder(ctx context.Context, orderDTO *dto.OrderDTO, idempotencyKey string, addressId int64) (*dto.OrderDTO, error)
+	ListOrders(ctx context.Context, q search.Query) ([]*dto.OrderDTO, string, error)
+	GetOrder(context.Context, int) (*dto.OrderDTO, error)
+	GetOrdersByUserId(ctx context.Context, userId int, q search.Query) ([]*dto.OrderDTO, string, error)
+	GetOrderHistory(context.Context, int) ([]*models.OrderStatusChange, error)
+	GetOrderReturns(context.Context, int) ([]*models.Return, error)
+	UpdateOrderStatus(ctx context.Context, id int, status string, reason string) (*models.Order, error)
+	CancelOrder(ctx context.Context, id int, reason string) (*models.Order, error)
+	CreateShipment(ctx context.Context, id int, carrier string, trackingNumber string) (*dto.OrderDTO, error)
+	ListSagas(ctx context.Context, status string) ([]*models.Saga, error)
+	GetInvoice(ctx context.Context, id int) (*dto.InvoiceDTO, []byte, error)
+	WatchOrder(ctx context.Context, id int, send func(*models.OrderStatusChange) error) error
+	WatchMyOrders(ctx context.Context, send func(*models.OrderStatusChange) error) error
 }
 
+// maxIdempotencyKeyLength matches the size of the key column.
+const maxIdempotencyKeyLength = 255
+
+// ReasonKeyReused is the reason of the error a request reusing an idempotency
+// key for a different order fails with. The gateway answers it with 422.
+const ReasonKeyReused = "IDEMPOTENCY_KEY_REUSED"
+
+// invoiceChunkSize is the size of the parts invoices are streamed in.
+const invoiceChunkSize = 32 << 10
+
 type orderService struct {
 	orderp.UnimplementedOrderServiceServer
 	order OrderService
@@ -41,9 +67,29 @@ func (os *orderService) CreateOrder(ctx context.Context, req *orderp.CreateOrder
 		return nil, err
 	}
 
-	orderDTO, err := os.order.CreateOrder(ctx, &ord)
+	var idempotencyKey string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if keys := md.Get("idempotency-key"); len(keys) > 0 {
+			idempotencyKey = keys[0]
+		}
+	}
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return nil, status.Error(codes.InvalidArgument, "idempotency key is too long")
+	}
+
+	orderDTO, err := os.order.CreateOrder(ctx, &ord, idempotencyKey, req.GetAddressId())
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrRequestInFlight):
+			return nil, status.Error(codes.Aborted, err.Error())
+		case errors.Is(err, data.ErrKeyReused):
+			return nil, keyReusedError(err)
+		case errors.Is(err, lifecycle.ErrAddressNotFound):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, coupon.ErrCouponNotFound):
+			return nil, status.Error(codes.NotFound, err.Error())
+		case errors.Is(err, lifecycle.ErrCheckoutFailed), errors.Is(err, coupon.ErrRejected):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
 		case strings.Contains(err.Error(), data.ErrItemDoesNotExist.Error()):
 			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("item with id %d does not exist", req.Order.ItemId))
 		case strings.Contains(err.Error(), data.ErrUserDoesNotExist.Error()):
@@ -53,35 +99,30 @@ func (os *orderService) CreateOrder(ctx context.Context, req *orderp.CreateOrder
 		}
 	}
 
-	var response orderp.Order
-	err = copier.Copy(&response, orderDTO)
+	response, err := ToProtoOrder(orderDTO)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "error with copying to dto")
 	}
 
-	return &orderp.CreateOrderResponse{Order: &response}, nil
+	return &orderp.CreateOrderResponse{Order: response}, nil
 }
 
 func (os *orderService) ListOrders(ctx context.Context, req *orderp.ListOrdersRequest) (*orderp.ListOrdersResponse, error) {
-	var responseOrders []*orderp.Order
+	q := searchQuery(req.GetPage(), req.GetFrom(), req.GetTo())
+	q.Status = req.GetStatus()
+	q.UserId = req.GetUserId()
+	q.UserEmail = req.GetUserEmail()
+	q.ItemId = req.GetItemId()
+	q.MinTotal = req.GetMinTotal()
+	q.MaxTotal = req.GetMaxTotal()
+	q.Text = req.GetQuery()
 
-	orders, err := os.order.ListOrders(ctx)
+	orders, next, err := os.order.ListOrders(ctx, q)
 	if err != nil {
-		return nil, err
-	}
-
-	for _, item := range orders {
-		var ord orderp.Order
-		err := copier.Copy(&ord, &item)
-		if err != nil {
-			log.Fatalf("failed to copy %v", err)
-			return nil, err
-		}
-
-		responseOrders = append(responseOrders, &ord)
+		return nil, statusError(err, "failed to list orders")
 	}
 
-	return &orderp.ListOrdersResponse{Orders: responseOrders}, nil
+	return toProtoOrderList(orders, next)
 }
 
 func (os *orderService) GetOrder(ctx context.Context, req *orderp.GetOrderRequest) (*orderp.GetOrderResponse, error) {
@@ -92,15 +133,35 @@ func (os *orderService) GetOrder(ctx context.Context, req *orderp.GetOrderReques
 
 	order, err := os.order.GetOrder(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, statusError(err, "failed to get order")
 	}
 
-	orderResponse := &orderp.Order{
-		Id:     order.ID,
-		ItemId: order.ItemId,
-		UserId: order.UserId,
+	history, err := os.order.GetOrderHistory(ctx, id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get order history")
+	}
+
+	var historyResponse []*orderp.OrderStatusChange
+	for _, change := range history {
+		historyResponse = append(historyResponse, toProtoStatusChange(change))
 	}
-	return &orderp.GetOrderResponse{Order: orderResponse}, nil
+
+	returns, err := os.order.GetOrderReturns(ctx, id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get order returns")
+	}
+
+	var returnsResponse []*orderp.Return
+	for _, r := range returns {
+		returnsResponse = append(returnsResponse, ToProtoReturn(r))
+	}
+
+	response, err := ToProtoOrder(order)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to copy to response orderp")
+	}
+
+	return &orderp.GetOrderResponse{Order: response, History: historyResponse, Returns: returnsResponse}, nil
 }
 
 func (os *orderService) GetOrderByUserId(ctx context.Context, req *orderp.GetOrdersByUserId) (*orderp.ListOrdersResponse, error) {
@@ -108,27 +169,345 @@ func (os *orderService) GetOrderByUserId(ctx context.Context, req *orderp.GetOrd
 	if userId == 0 {
 		return nil, status.Error(codes.InvalidArgument, "user_id is required")
 	}
-	ordersByUserId, err := os.order.GetOrdersByUserId(ctx, int(userId))
+
+	q := searchQuery(req.GetPage(), req.GetFrom(), req.GetTo())
+	q.Status = req.GetStatus()
+
+	orders, next, err := os.order.GetOrdersByUserId(ctx, int(userId), q)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get orders of user")
+		return nil, statusError(err, "failed to get orders of user")
+	}
+
+	return toProtoOrderList(orders, next)
+}
+
+// searchQuery starts an order search with the paging and date range of a
+// request.
+func searchQuery(page *orderp.OrderPage, from, to *timestamppb.Timestamp) search.Query {
+	q := search.Query{
+		SortBy:     page.GetSortBy(),
+		Descending: page.GetDescending(),
+		Limit:      page.GetLimit(),
+		Cursor:     page.GetCursor(),
+	}
+	if from != nil {
+		q.From = from.AsTime()
+	}
+	if to != nil {
+		q.To = to.AsTime()
 	}
 
-	var ordersResponse []*orderp.Order
-	for _, order := range ordersByUserId {
-		var o orderp.Order
-		err := copier.Copy(&o, order)
+	return q
+}
+
+func toProtoOrderList(orders []*dto.OrderDTO, next string) (*orderp.ListOrdersResponse, error) {
+	response := make([]*orderp.Order, 0, len(orders))
+	for _, order := range orders {
+		o, err := ToProtoOrder(order)
 		if err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to copy to response orderp")
+			return nil, status.Error(codes.Internal, "failed to copy to response orderp")
+		}
+
+		response = append(response, o)
+	}
+
+	return &orderp.ListOrdersResponse{Orders: response, NextCursor: next}, nil
+}
+
+func (os *orderService) UpdateOrderStatus(ctx context.Context, req *orderp.UpdateOrderStatusRequest) (*orderp.UpdateOrderStatusResponse, error) {
+	if req.GetOrderId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+	if req.GetStatus() == "" {
+		return nil, status.Error(codes.InvalidArgument, "status is required")
+	}
+
+	updated, err := os.order.UpdateOrderStatus(ctx, int(req.GetOrderId()), req.GetStatus(), req.GetReason())
+	if err != nil {
+		return nil, statusError(err, "failed to update order status")
+	}
+
+	return &orderp.UpdateOrderStatusResponse{Order: modelToProto(updated)}, nil
+}
+
+func (os *orderService) CancelOrder(ctx context.Context, req *orderp.CancelOrderRequest) (*orderp.CancelOrderResponse, error) {
+	if req.GetOrderId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+
+	cancelled, err := os.order.CancelOrder(ctx, int(req.GetOrderId()), req.GetReason())
+	if err != nil {
+		return nil, statusError(err, "failed to cancel order")
+	}
+
+	return &orderp.CancelOrderResponse{Order: modelToProto(cancelled)}, nil
+}
+
+func (os *orderService) CreateShipment(ctx context.Context, req *orderp.CreateShipmentRequest) (*orderp.CreateShipmentResponse, error) {
+	if req.GetOrderId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+	carrier := strings.TrimSpace(req.GetCarrier())
+	if carrier == "" || len(carrier) > 64 {
+		return nil, status.Error(codes.InvalidArgument, "carrier is required and must be at most 64 characters")
+	}
+	trackingNumber := strings.TrimSpace(req.GetTrackingNumber())
+	if trackingNumber == "" || len(trackingNumber) > 128 {
+		return nil, status.Error(codes.InvalidArgument, "tracking_number is required and must be at most 128 characters")
+	}
+
+	shipped, err := os.order.CreateShipment(ctx, int(req.GetOrderId()), carrier, trackingNumber)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoShippingAddress):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		case errors.Is(err, data.ErrDuplicateTracking):
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		default:
+			return nil, statusError(err, "failed to create shipment")
 		}
+	}
 
-		ordersResponse = append(ordersResponse, &o)
+	response, err := ToProtoOrder(shipped)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to copy to response orderp")
 	}
 
-	var items []*cataloguep.Item
-	items = append(items, &cataloguep.Item{Id: 1})
-	items = append(items, &cataloguep.Item{Id: 2})
-	items = append(items, &cataloguep.Item{Id: 3})
-	items = append(items, &cataloguep.Item{Id: 4})
+	return &orderp.CreateShipmentResponse{Order: response}, nil
+}
 
-	return &orderp.ListOrdersResponse{Orders: ordersResponse}, nil
+func (os *orderService) ListSagas(ctx context.Context, req *orderp.ListSagasRequest) (*orderp.ListSagasResponse, error) {
+	sagas, err := os.order.ListSagas(ctx, req.GetStatus())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list sagas")
+	}
+
+	var responseSagas []*orderp.Saga
+	for _, s := range sagas {
+		responseSagas = append(responseSagas, &orderp.Saga{
+			Id:        s.ID,
+			OrderId:   s.OrderId,
+			Status:    s.Status,
+			Step:      s.Step,
+			Attempts:  s.Attempts,
+			LastError: s.LastError,
+			CreatedAt: timestamppb.New(s.CreatedAt),
+			UpdatedAt: timestamppb.New(s.UpdatedAt),
+		})
+	}
+
+	return &orderp.ListSagasResponse{Sagas: responseSagas}, nil
+}
+
+func (os *orderService) GetInvoice(req *orderp.GetInvoiceRequest, stream orderp.OrderService_GetInvoiceServer) error {
+	if req.GetOrderId() == 0 {
+		return status.Error(codes.InvalidArgument, "order_id is required")
+	}
+
+	inv, pdf, err := os.order.GetInvoice(stream.Context(), int(req.GetOrderId()))
+	if err != nil {
+		if errors.Is(err, lifecycle.ErrInvoiceNotIssued) {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return statusError(err, "failed to get invoice")
+	}
+
+	for offset := 0; offset < len(pdf); offset += invoiceChunkSize {
+		chunk := &orderp.InvoiceChunk{Data: pdf[offset:min(offset+invoiceChunkSize, len(pdf))]}
+		if offset == 0 {
+			chunk.Filename = invoice.Filename(inv.Number)
+			chunk.ContentType = "application/pdf"
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (os *orderService) WatchOrder(req *orderp.WatchOrderRequest, stream orderp.OrderService_WatchOrderServer) error {
+	if req.GetOrderId() == 0 {
+		return status.Error(codes.InvalidArgument, "order_id is required")
+	}
+
+	err := os.order.WatchOrder(stream.Context(), int(req.GetOrderId()), func(change *models.OrderStatusChange) error {
+		return stream.Send(toProtoStatusChange(change))
+	})
+	if err != nil {
+		return watchError(err)
+	}
+
+	return nil
+}
+
+func (os *orderService) WatchMyOrders(req *orderp.WatchMyOrdersRequest, stream orderp.OrderService_WatchMyOrdersServer) error {
+	// Changes may be far apart; the headers tell the client that it was
+	// let in before the first one.
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	err := os.order.WatchMyOrders(stream.Context(), func(change *models.OrderStatusChange) error {
+		return stream.Send(toProtoStatusChange(change))
+	})
+	if err != nil {
+		return watchError(err)
+	}
+
+	return nil
+}
+
+// watchError maps the errors of a watch. Errors of sending are passed on as
+// they are.
+func watchError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, lifecycle.ErrWatchInterrupted) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	return statusError(err, "failed to watch orders")
+}
+
+func toProtoStatusChange(change *models.OrderStatusChange) *orderp.OrderStatusChange {
+	return &orderp.OrderStatusChange{
+		OrderId:    change.OrderId,
+		FromStatus: change.FromStatus,
+		ToStatus:   change.ToStatus,
+		ChangedBy:  change.ChangedBy,
+		Reason:     change.Reason,
+		CreatedAt:  timestamppb.New(change.CreatedAt),
+	}
+}
+
+// keyReusedError returns err as an invalid argument marked with
+// ReasonKeyReused.
+func keyReusedError(err error) error {
+	st, detailsErr := status.New(codes.InvalidArgument, err.Error()).WithDetails(&errdetails.ErrorInfo{
+		Reason: ReasonKeyReused,
+		Domain: "order-service",
+	})
+	if detailsErr != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return st.Err()
+}
+
+// statusError maps errors of the order lifecycle to gRPC statuses.
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "order not found")
+	case errors.Is(err, lifecycle.ErrUnknownStatus),
+		errors.Is(err, search.ErrInvalidQuery):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, lifecycle.ErrNotOrderOwner):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, lifecycle.ErrInvalidTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, data.ErrStatusConflict):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
+
+// ToProtoOrder converts an order with its lines, shipping address, discounts
+// and shipments to its proto message.
+func ToProtoOrder(orderDTO *dto.OrderDTO) (*orderp.Order, error) {
+	var o orderp.Order
+	if err := copier.Copy(&o, orderDTO); err != nil {
+		return nil, err
+	}
+	o.CreatedAt = timestamppb.New(orderDTO.CreatedAt)
+	o.UpdatedAt = timestamppb.New(orderDTO.UpdatedAt)
+
+	if a := orderDTO.Address; a != nil {
+		o.ShippingAddress = &orderp.Address{
+			Recipient:  a.Recipient,
+			Line1:      a.Line1,
+			Line2:      a.Line2,
+			City:       a.City,
+			Region:     a.Region,
+			PostalCode: a.PostalCode,
+			Country:    a.Country,
+			Phone:      a.Phone,
+		}
+	}
+
+	o.Discounts = ToProtoDiscounts(orderDTO.Discounts)
+	if orderDTO.Invoice != nil {
+		o.InvoiceNumber = orderDTO.Invoice.Number
+	}
+
+	o.Shipments = nil
+	for _, s := range orderDTO.Shipments {
+		o.Shipments = append(o.Shipments, &orderp.Shipment{
+			Id:             s.ID,
+			OrderId:        s.OrderId,
+			Carrier:        s.Carrier,
+			TrackingNumber: s.TrackingNumber,
+			ShippedAt:      timestamppb.New(s.ShippedAt),
+		})
+	}
+
+	return &o, nil
+}
+
+// ToProtoReturn converts a return with its history to its proto message.
+func ToProtoReturn(r *models.Return) *orderp.Return {
+	response := &orderp.Return{
+		Id:           r.ID,
+		OrderId:      r.OrderId,
+		UserId:       r.UserId,
+		ItemId:       r.ItemId,
+		Quantity:     r.Quantity,
+		Reason:       r.Reason,
+		PhotoUrls:    r.PhotoURLs,
+		Status:       r.Status,
+		Note:         r.Note,
+		Restocked:    r.Restocked,
+		RefundAmount: r.RefundAmount,
+		CreatedAt:    timestamppb.New(r.CreatedAt),
+		UpdatedAt:    timestamppb.New(r.UpdatedAt),
+	}
+	for _, change := range r.History {
+		response.History = append(response.History, &orderp.ReturnStatusChange{
+			FromStatus: change.FromStatus,
+			ToStatus:   change.ToStatus,
+			ChangedBy:  change.ChangedBy,
+			Note:       change.Note,
+			CreatedAt:  timestamppb.New(change.CreatedAt),
+		})
+	}
+
+	return response
+}
+
+func ToProtoDiscounts(discounts []dto.DiscountDTO) []*orderp.Discount {
+	var result []*orderp.Discount
+	for _, d := range discounts {
+		result = append(result, &orderp.Discount{
+			CouponId:    d.CouponId,
+			Description: d.Description,
+			ItemId:      d.ItemId,
+			Amount:      d.Amount,
+		})
+	}
+
+	return result
+}
+
+func modelToProto(order *models.Order) *orderp.Order {
+	return &orderp.Order{
+		Id:        order.ID,
+		ItemId:    order.ItemId,
+		UserId:    order.UserId,
+		Status:    order.Status,
+		CreatedAt: timestamppb.New(order.CreatedAt),
+		UpdatedAt: timestamppb.New(order.UpdatedAt),
+	}
 }
@@ -0,0 +1,80 @@
+package paymentGrpc
+
+import (
+	"context"
+	"errors"
+	orderp "github.com/sntabq/proto-gen/gen/go/order"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"order-service/internal/data"
+	"order-service/internal/data/models"
+	"order-service/internal/payment"
+)
+
+type Payments interface {
+	Refund(ctx context.Context, orderId int32, amount int32) (*models.Payment, error)
+	HandleWebhook(ctx context.Context, payload []byte, signature string) error
+}
+
+type paymentService struct {
+	orderp.UnimplementedPaymentServiceServer
+	payments Payments
+}
+
+func Register(gRPCServer *grpc.Server, payments Payments) {
+	orderp.RegisterPaymentServiceServer(gRPCServer, &paymentService{payments: payments})
+}
+
+func (ps *paymentService) RefundPayment(ctx context.Context, req *orderp.RefundPaymentRequest) (*orderp.RefundPaymentResponse, error) {
+	if req.GetOrderId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+	if req.GetAmount() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "amount must not be negative")
+	}
+
+	refunded, err := ps.payments.Refund(ctx, req.GetOrderId(), req.GetAmount())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return nil, status.Error(codes.NotFound, "payment not found")
+		case errors.Is(err, payment.ErrNotRefundable), errors.Is(err, payment.ErrInvalidAmount):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		default:
+			return nil, status.Error(codes.Internal, "failed to refund payment")
+		}
+	}
+
+	return &orderp.RefundPaymentResponse{Payment: toProto(refunded)}, nil
+}
+
+func (ps *paymentService) HandlePaymentWebhook(ctx context.Context, req *orderp.HandlePaymentWebhookRequest) (*orderp.HandlePaymentWebhookResponse, error) {
+	err := ps.payments.HandleWebhook(ctx, req.GetPayload(), req.GetSignature())
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrInvalidSignature):
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		case errors.Is(err, data.ErrRecordNotFound):
+			return nil, status.Error(codes.NotFound, "payment not found")
+		default:
+			return nil, status.Error(codes.Internal, "failed to handle payment webhook")
+		}
+	}
+
+	return &orderp.HandlePaymentWebhookResponse{}, nil
+}
+
+func toProto(p *models.Payment) *orderp.Payment {
+	return &orderp.Payment{
+		Id:             p.ID,
+		OrderId:        p.OrderId,
+		Provider:       p.Provider,
+		Status:         p.Status,
+		Amount:         p.Amount,
+		RefundedAmount: p.RefundedAmount,
+		CreatedAt:      timestamppb.New(p.CreatedAt),
+		UpdatedAt:      timestamppb.New(p.UpdatedAt),
+	}
+}
@@ -0,0 +1,125 @@
+package reportGrpc
+
+import (
+	"context"
+	"errors"
+	orderp "github.com/sntabq/proto-gen/gen/go/order"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"order-service/internal/data/models"
+	"order-service/internal/report"
+)
+
+type Reports interface {
+	GetSalesReport(ctx context.Context, f report.Filter, granularity string) (*models.SalesReport, error)
+	GetTopItems(ctx context.Context, f report.Filter, limit int32) ([]*models.TopItem, error)
+	GetCustomerReport(ctx context.Context, f report.Filter) (*models.CustomerReport, error)
+	GetConversionReport(ctx context.Context, f report.Filter) (*models.ConversionReport, error)
+}
+
+type reportService struct {
+	orderp.UnimplementedReportServiceServer
+	reports Reports
+}
+
+func Register(gRPCServer *grpc.Server, reports Reports) {
+	orderp.RegisterReportServiceServer(gRPCServer, &reportService{reports: reports})
+}
+
+func (rs *reportService) GetSalesReport(ctx context.Context, req *orderp.GetSalesReportRequest) (*orderp.GetSalesReportResponse, error) {
+	sales, err := rs.reports.GetSalesReport(ctx, toFilter(req.GetFilter()), req.GetGranularity())
+	if err != nil {
+		return nil, statusError(err, "failed to get sales report")
+	}
+
+	periods := make([]*orderp.SalesPeriod, 0, len(sales.Periods))
+	for _, p := range sales.Periods {
+		periods = append(periods, &orderp.SalesPeriod{
+			PeriodStart:       timestamppb.New(p.PeriodStart),
+			Orders:            p.Orders,
+			Units:             p.Units,
+			Revenue:           p.Revenue,
+			AverageOrderValue: p.AverageOrderValue,
+		})
+	}
+
+	return &orderp.GetSalesReportResponse{
+		Periods:           periods,
+		Orders:            sales.Orders,
+		Revenue:           sales.Revenue,
+		AverageOrderValue: sales.AverageOrderValue,
+	}, nil
+}
+
+func (rs *reportService) GetTopItems(ctx context.Context, req *orderp.GetTopItemsRequest) (*orderp.GetTopItemsResponse, error) {
+	items, err := rs.reports.GetTopItems(ctx, toFilter(req.GetFilter()), req.GetLimit())
+	if err != nil {
+		return nil, statusError(err, "failed to get top items")
+	}
+
+	response := make([]*orderp.TopItem, 0, len(items))
+	for _, item := range items {
+		response = append(response, &orderp.TopItem{
+			ItemId:   item.ItemId,
+			Name:     item.Name,
+			Category: item.Category,
+			Units:    item.Units,
+			Orders:   item.Orders,
+			Revenue:  item.Revenue,
+		})
+	}
+
+	return &orderp.GetTopItemsResponse{Items: response}, nil
+}
+
+func (rs *reportService) GetCustomerReport(ctx context.Context, req *orderp.GetCustomerReportRequest) (*orderp.GetCustomerReportResponse, error) {
+	customers, err := rs.reports.GetCustomerReport(ctx, toFilter(req.GetFilter()))
+	if err != nil {
+		return nil, statusError(err, "failed to get customer report")
+	}
+
+	return &orderp.GetCustomerReportResponse{
+		NewCustomers:       customers.NewCustomers,
+		ReturningCustomers: customers.ReturningCustomers,
+		NewRevenue:         customers.NewRevenue,
+		ReturningRevenue:   customers.ReturningRevenue,
+	}, nil
+}
+
+func (rs *reportService) GetConversionReport(ctx context.Context, req *orderp.GetConversionReportRequest) (*orderp.GetConversionReportResponse, error) {
+	conversion, err := rs.reports.GetConversionReport(ctx, toFilter(req.GetFilter()))
+	if err != nil {
+		return nil, statusError(err, "failed to get conversion report")
+	}
+
+	return &orderp.GetConversionReportResponse{
+		Carts:          conversion.Carts,
+		CheckedOut:     conversion.CheckedOut,
+		ConversionRate: conversion.ConversionRate,
+	}, nil
+}
+
+// toFilter converts the filter of a request, leaving unset bounds zero.
+func toFilter(f *orderp.ReportFilter) report.Filter {
+	filter := report.Filter{Category: f.GetCategory()}
+	if f.GetFrom() != nil {
+		filter.From = f.GetFrom().AsTime()
+	}
+	if f.GetTo() != nil {
+		filter.To = f.GetTo().AsTime()
+	}
+
+	return filter
+}
+
+// statusError maps errors of the reports to gRPC statuses.
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, report.ErrInvalidFilter):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
@@ -0,0 +1,157 @@
+package rmaGrpc
+
+import (
+	"context"
+	"errors"
+	orderp "github.com/sntabq/proto-gen/gen/go/order"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"order-service/internal/data"
+	"order-service/internal/data/models"
+	orderGrpc "order-service/internal/grpc/order"
+	"order-service/internal/payment"
+	"order-service/internal/rma"
+	"order-service/internal/services/order/lifecycle"
+)
+
+type Returns interface {
+	OpenReturn(ctx context.Context, r *models.Return) (*models.Return, error)
+	GetReturn(ctx context.Context, id int64) (*models.Return, error)
+	ListReturns(ctx context.Context, status string) ([]*models.Return, error)
+	ApproveReturn(ctx context.Context, id int64, note string) (*models.Return, error)
+	RejectReturn(ctx context.Context, id int64, note string) (*models.Return, error)
+	ReceiveReturn(ctx context.Context, id int64, restock bool, note string) (*models.Return, error)
+	RefundReturn(ctx context.Context, id int64, amount int32, note string) (*models.Return, error)
+}
+
+type returnService struct {
+	orderp.UnimplementedReturnServiceServer
+	returns Returns
+}
+
+func Register(gRPCServer *grpc.Server, returns Returns) {
+	orderp.RegisterReturnServiceServer(gRPCServer, &returnService{returns: returns})
+}
+
+func (rs *returnService) OpenReturn(ctx context.Context, req *orderp.OpenReturnRequest) (*orderp.OpenReturnResponse, error) {
+	if req.GetOrderId() == 0 || req.GetItemId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order_id and item_id are required")
+	}
+
+	opened, err := rs.returns.OpenReturn(ctx, &models.Return{
+		OrderId:   req.GetOrderId(),
+		ItemId:    req.GetItemId(),
+		Quantity:  req.GetQuantity(),
+		Reason:    req.GetReason(),
+		PhotoURLs: req.GetPhotoUrls(),
+	})
+	if err != nil {
+		return nil, statusError(err, "failed to open return")
+	}
+
+	return &orderp.OpenReturnResponse{Return: orderGrpc.ToProtoReturn(opened)}, nil
+}
+
+func (rs *returnService) GetReturn(ctx context.Context, req *orderp.GetReturnRequest) (*orderp.GetReturnResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	r, err := rs.returns.GetReturn(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err, "failed to get return")
+	}
+
+	return &orderp.GetReturnResponse{Return: orderGrpc.ToProtoReturn(r)}, nil
+}
+
+func (rs *returnService) ListReturns(ctx context.Context, req *orderp.ListReturnsRequest) (*orderp.ListReturnsResponse, error) {
+	returns, err := rs.returns.ListReturns(ctx, req.GetStatus())
+	if err != nil {
+		return nil, statusError(err, "failed to list returns")
+	}
+
+	response := make([]*orderp.Return, 0, len(returns))
+	for _, r := range returns {
+		response = append(response, orderGrpc.ToProtoReturn(r))
+	}
+
+	return &orderp.ListReturnsResponse{Returns: response}, nil
+}
+
+func (rs *returnService) ApproveReturn(ctx context.Context, req *orderp.ApproveReturnRequest) (*orderp.ApproveReturnResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	approved, err := rs.returns.ApproveReturn(ctx, req.GetId(), req.GetNote())
+	if err != nil {
+		return nil, statusError(err, "failed to approve return")
+	}
+
+	return &orderp.ApproveReturnResponse{Return: orderGrpc.ToProtoReturn(approved)}, nil
+}
+
+func (rs *returnService) RejectReturn(ctx context.Context, req *orderp.RejectReturnRequest) (*orderp.RejectReturnResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	rejected, err := rs.returns.RejectReturn(ctx, req.GetId(), req.GetNote())
+	if err != nil {
+		return nil, statusError(err, "failed to reject return")
+	}
+
+	return &orderp.RejectReturnResponse{Return: orderGrpc.ToProtoReturn(rejected)}, nil
+}
+
+func (rs *returnService) ReceiveReturn(ctx context.Context, req *orderp.ReceiveReturnRequest) (*orderp.ReceiveReturnResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	received, err := rs.returns.ReceiveReturn(ctx, req.GetId(), req.GetRestock(), req.GetNote())
+	if err != nil {
+		return nil, statusError(err, "failed to receive return")
+	}
+
+	return &orderp.ReceiveReturnResponse{Return: orderGrpc.ToProtoReturn(received)}, nil
+}
+
+func (rs *returnService) RefundReturn(ctx context.Context, req *orderp.RefundReturnRequest) (*orderp.RefundReturnResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	refunded, err := rs.returns.RefundReturn(ctx, req.GetId(), req.GetAmount(), req.GetNote())
+	if err != nil {
+		return nil, statusError(err, "failed to refund return")
+	}
+
+	return &orderp.RefundReturnResponse{Return: orderGrpc.ToProtoReturn(refunded)}, nil
+}
+
+// statusError maps errors of the returns to gRPC statuses.
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, rma.ErrReturnNotFound):
+		return status.Error(codes.NotFound, "return not found")
+	case errors.Is(err, data.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "order not found")
+	case errors.Is(err, lifecycle.ErrNotOrderOwner):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, rma.ErrInvalidReturn):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, rma.ErrNotReturnable),
+		errors.Is(err, rma.ErrQuantityExceeded),
+		errors.Is(err, rma.ErrInvalidTransition),
+		errors.Is(err, payment.ErrNotRefundable),
+		errors.Is(err, payment.ErrInvalidAmount):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, rma.ErrStatusConflict):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
@@ -0,0 +1,123 @@
+// Package invoice numbers invoices and renders them as PDF documents.
+package invoice
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"github.com/go-fonts/dejavu/dejavusansmono"
+	"github.com/go-fonts/dejavu/dejavusansmonobold"
+	"github.com/signintech/gopdf"
+	"order-service/internal/data/dto"
+	"strings"
+	"text/template"
+)
+
+//go:embed "templates"
+var templateFS embed.FS
+
+var tmpl = template.Must(template.New("invoice.tmpl").Funcs(template.FuncMap{
+	"amount": func(l dto.OrderLineDTO) int32 { return l.Quantity * l.UnitPrice },
+	"neg":    func(v int32) int32 { return -v },
+}).ParseFS(templateFS, "templates/invoice.tmpl"))
+
+// The invoices are set in DejaVu Sans Mono, which keeps the columns of the
+// template aligned and covers the Latin and Cyrillic alphabets the names and
+// addresses are written in. It is embedded, so every reader shows it.
+const (
+	fontFamily = "DejaVuSansMono"
+	lineHeight = 14
+	margin     = 50
+)
+
+// Number formats the number of the n-th invoice issued in year.
+func Number(year int, n int) string {
+	return fmt.Sprintf("INV-%d-%06d", year, n)
+}
+
+// Filename is the name the PDF of the invoice is served and attached as.
+func Filename(number string) string {
+	return "invoice-" + number + ".pdf"
+}
+
+// Render returns the PDF of the invoice of the order.
+func Render(order *dto.OrderDTO, inv *dto.InvoiceDTO) ([]byte, error) {
+	var text bytes.Buffer
+	err := tmpl.Execute(&text, map[string]any{
+		"Number":   inv.Number,
+		"IssuedAt": inv.IssuedAt,
+		"Order":    order,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invoice.Render: %w", err)
+	}
+
+	pdf, err := newPDF()
+	if err != nil {
+		return nil, fmt.Errorf("invoice.Render: %w", err)
+	}
+	for _, l := range strings.Split(strings.TrimRight(text.String(), "\n"), "\n") {
+		line, heading := strings.CutPrefix(l, "# ")
+		if heading {
+			err = pdf.SetFont(fontFamily, "B", 14)
+			pdf.Br(6)
+		} else {
+			err = pdf.SetFont(fontFamily, "", 10)
+			line = strings.ReplaceAll(l, "\t", "    ")
+		}
+		if err == nil {
+			err = writeLine(pdf, line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invoice.Render: %w", err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err = pdf.Write(&out); err != nil {
+		return nil, fmt.Errorf("invoice.Render: %w", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+func newPDF() (*gopdf.GoPdf, error) {
+	pdf := &gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4, Unit: gopdf.UnitPT})
+	pdf.SetMargins(margin, margin+10, margin, margin)
+	err := pdf.AddTTFFontData(fontFamily, dejavusansmono.TTF)
+	if err != nil {
+		return nil, err
+	}
+	err = pdf.AddTTFFontDataWithOption(fontFamily, dejavusansmonobold.TTF, gopdf.TtfOption{Style: gopdf.Bold})
+	if err != nil {
+		return nil, err
+	}
+	pdf.AddPage()
+
+	return pdf, nil
+}
+
+// writeLine writes text on a line of its own, starting a new page when the
+// line does not fit on the current one. It fails on characters the font has
+// no glyph for rather than leaving them out of the invoice.
+func writeLine(pdf *gopdf.GoPdf, text string) error {
+	for _, r := range text {
+		ok, err := pdf.IsCurrFontContainGlyph(r)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no glyph for %q in %q", r, text)
+		}
+	}
+	if pdf.GetY()+lineHeight > gopdf.PageSizeA4.H-margin {
+		pdf.AddPage()
+	}
+	if err := pdf.Cell(nil, text); err != nil {
+		return err
+	}
+	pdf.Br(lineHeight)
+
+	return nil
+}
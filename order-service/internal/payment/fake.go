@@ -0,0 +1,119 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FakeProvider settles every call immediately and keeps payments in memory.
+// It is meant for local runs and tests.
+type FakeProvider struct {
+	// DeclineAbove makes authorizations of larger amounts fail. Zero accepts
+	// any amount.
+	DeclineAbove int32
+
+	mu       sync.Mutex
+	seq      int
+	payments map[string]*fakePayment
+	// refs maps the idempotency keys of authorizations to their payments.
+	refs map[string]string
+}
+
+type fakePayment struct {
+	amount   int32
+	captured int32
+	refunded int32
+	status   string
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{payments: make(map[string]*fakePayment), refs: make(map[string]string)}
+}
+
+func (f *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (f *FakeProvider) Authorize(_ context.Context, orderId int32, amount int32, idempotencyKey string) (*Result, error) {
+	if f.DeclineAbove > 0 && amount > f.DeclineAbove {
+		return nil, ErrDeclined
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if ref, ok := f.refs[idempotencyKey]; ok && idempotencyKey != "" {
+		return &Result{Ref: ref, Status: f.payments[ref].status}, nil
+	}
+
+	f.seq++
+	ref := fmt.Sprintf("fake_%d_%d", orderId, f.seq)
+	f.payments[ref] = &fakePayment{amount: amount, status: StatusAuthorized}
+	if idempotencyKey != "" {
+		f.refs[idempotencyKey] = ref
+	}
+
+	return &Result{Ref: ref, Status: StatusAuthorized}, nil
+}
+
+func (f *FakeProvider) Capture(_ context.Context, ref string, amount int32) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.payments[ref]
+	if !ok {
+		return nil, ErrUnknownPayment
+	}
+	if p.status != StatusAuthorized && p.status != StatusCaptured {
+		return nil, fmt.Errorf("cannot capture %s payment", p.status)
+	}
+	if amount > p.amount {
+		return nil, fmt.Errorf("cannot capture more than authorized")
+	}
+
+	p.captured = amount
+	p.status = StatusCaptured
+
+	return &Result{Ref: ref, Status: StatusCaptured}, nil
+}
+
+func (f *FakeProvider) Refund(_ context.Context, ref string, amount int32) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.payments[ref]
+	if !ok {
+		return nil, ErrUnknownPayment
+	}
+	if p.status != StatusCaptured {
+		return nil, fmt.Errorf("cannot refund %s payment", p.status)
+	}
+	if p.refunded+amount > p.captured {
+		return nil, fmt.Errorf("cannot refund more than captured")
+	}
+
+	p.refunded += amount
+	if p.refunded == p.captured {
+		p.status = StatusRefunded
+	}
+
+	return &Result{Ref: ref, Status: StatusRefunded}, nil
+}
+
+func (f *FakeProvider) Void(_ context.Context, ref string) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.payments[ref]
+	if !ok {
+		return nil, ErrUnknownPayment
+	}
+	if p.status != StatusAuthorized && p.status != StatusVoided {
+		return nil, fmt.Errorf("cannot void %s payment", p.status)
+	}
+
+	p.status = StatusVoided
+
+	return &Result{Ref: ref, Status: StatusVoided}, nil
+}
@@ -0,0 +1,99 @@
+// Package payment defines the interface to payment providers, the providers
+// shipped with the service and the webhook events they send.
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	StatusPending    = "pending"
+	StatusAuthorized = "authorized"
+	StatusCaptured   = "captured"
+	StatusRefunded   = "refunded"
+	StatusVoided     = "voided"
+	StatusFailed     = "failed"
+	// StatusCapturePending is the status of a payment whose capture the
+	// provider settles later. It becomes captured when the payment.captured
+	// webhook arrives.
+	StatusCapturePending = "capture_pending"
+)
+
+const (
+	EventCaptured = "payment.captured"
+	EventRefunded = "payment.refunded"
+	EventVoided   = "payment.voided"
+	EventFailed   = "payment.failed"
+)
+
+var (
+	ErrDeclined         = errors.New("payment was declined")
+	ErrUnknownPayment   = errors.New("payment is unknown to the provider")
+	ErrInvalidSignature = errors.New("webhook signature is invalid")
+	ErrNotRefundable    = errors.New("only captured payments can be refunded")
+	ErrInvalidAmount    = errors.New("refund amount exceeds what is left to refund")
+	ErrSettling         = errors.New("payment capture has not settled yet")
+)
+
+// Provider is a payment service provider. Calls may finish synchronously or
+// report StatusPending, in which case the outcome arrives later as a webhook
+// event. Authorizations with the same idempotencyKey return the authorization
+// made by the first one, so that a retried authorization does not reserve the
+// amount twice.
+type Provider interface {
+	Name() string
+	Authorize(ctx context.Context, orderId int32, amount int32, idempotencyKey string) (*Result, error)
+	Capture(ctx context.Context, ref string, amount int32) (*Result, error)
+	Refund(ctx context.Context, ref string, amount int32) (*Result, error)
+	Void(ctx context.Context, ref string) (*Result, error)
+}
+
+// Result is the answer of a provider to a call. Ref identifies the payment at
+// the provider.
+type Result struct {
+	Ref    string `json:"ref"`
+	Status string `json:"status"`
+}
+
+// Event is a webhook notification about a payment. ID is unique per event and
+// is used to drop redelivered events.
+type Event struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Ref    string `json:"ref"`
+	Amount int32  `json:"amount"`
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of payload.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseEvent verifies the signature of a webhook payload and decodes it.
+func ParseEvent(secret string, payload []byte, signature string) (*Event, error) {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || secret == "" {
+		return nil, ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
@@ -0,0 +1,93 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookProvider talks to a provider over a small JSON API and learns about
+// captures and refunds that settle later through signed webhooks. The
+// paymentstub command implements the API for local runs.
+type WebhookProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewWebhookProvider(baseURL string) *WebhookProvider {
+	return &WebhookProvider{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type webhookRequest struct {
+	OrderId int32  `json:"order_id,omitempty"`
+	Ref     string `json:"ref,omitempty"`
+	Amount  int32  `json:"amount,omitempty"`
+}
+
+func (w *WebhookProvider) Name() string {
+	return "webhook"
+}
+
+func (w *WebhookProvider) Authorize(ctx context.Context, orderId int32, amount int32, idempotencyKey string) (*Result, error) {
+	return w.call(ctx, "/authorize", idempotencyKey, webhookRequest{OrderId: orderId, Amount: amount})
+}
+
+func (w *WebhookProvider) Capture(ctx context.Context, ref string, amount int32) (*Result, error) {
+	return w.call(ctx, "/capture", "", webhookRequest{Ref: ref, Amount: amount})
+}
+
+func (w *WebhookProvider) Refund(ctx context.Context, ref string, amount int32) (*Result, error) {
+	return w.call(ctx, "/refund", "", webhookRequest{Ref: ref, Amount: amount})
+}
+
+func (w *WebhookProvider) Void(ctx context.Context, ref string) (*Result, error) {
+	return w.call(ctx, "/void", "", webhookRequest{Ref: ref})
+}
+
+// call posts body to path, with idempotencyKey in the Idempotency-Key header
+// when it is set.
+func (w *WebhookProvider) call(ctx context.Context, path string, idempotencyKey string, body webhookRequest) (*Result, error) {
+	const op = "payment.WebhookProvider.call"
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPaymentRequired:
+		return nil, ErrDeclined
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrUnknownPayment
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("%s: provider answered %s", op, resp.Status)
+	}
+
+	var result Result
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &result, nil
+}
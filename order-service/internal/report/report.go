@@ -0,0 +1,74 @@
+// Package report holds the filters of the sales reports.
+package report
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+const (
+	// defaultRange is the period reported on when the filter has no start.
+	defaultRange = 30 * 24 * time.Hour
+
+	defaultTopItems = 10
+	maxTopItems     = 100
+)
+
+var ErrInvalidFilter = errors.New("invalid report filter")
+
+// Filter restricts a report to the orders placed in [From, To) and, when
+// Category is set, to the lines of items in that category.
+type Filter struct {
+	From     time.Time
+	To       time.Time
+	Category string
+}
+
+// Normalize fills the bounds the filter leaves open, ending the range at now
+// and starting it 30 days before its end, and checks it.
+func (f *Filter) Normalize(now time.Time) error {
+	f.Category = strings.TrimSpace(f.Category)
+	if f.To.IsZero() {
+		f.To = now
+	}
+	if f.From.IsZero() {
+		f.From = f.To.Add(-defaultRange)
+	}
+	if !f.From.Before(f.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidFilter)
+	}
+
+	return nil
+}
+
+// Granularity returns the granularity of a sales report, day by default.
+func Granularity(g string) (string, error) {
+	switch g {
+	case "":
+		return GranularityDay, nil
+	case GranularityDay, GranularityWeek, GranularityMonth:
+		return g, nil
+	default:
+		return "", fmt.Errorf("%w: unknown granularity %q", ErrInvalidFilter, g)
+	}
+}
+
+// Limit returns how many top items to report, 10 by default.
+func Limit(limit int32) (int32, error) {
+	switch {
+	case limit == 0:
+		return defaultTopItems, nil
+	case limit < 0 || limit > maxTopItems:
+		return 0, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, maxTopItems)
+	default:
+		return limit, nil
+	}
+}
@@ -0,0 +1,100 @@
+// Package rma describes the states of a return request and the transitions
+// allowed between them.
+package rma
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	StatusRequested = "requested"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusReceived  = "received"
+	StatusRefunding = "refunding"
+	StatusRefunded  = "refunded"
+)
+
+const (
+	maxReasonLength = 1000
+	maxNoteLength   = 1000
+	maxPhotos       = 5
+	maxPhotoURL     = 512
+)
+
+var (
+	ErrReturnNotFound    = errors.New("return not found")
+	ErrInvalidReturn     = errors.New("invalid return")
+	ErrNotReturnable     = errors.New("only delivered orders can be returned")
+	ErrQuantityExceeded  = errors.New("quantity exceeds what is left to return of the line")
+	ErrInvalidTransition = errors.New("return status transition is not allowed")
+	ErrStatusConflict    = errors.New("return status was changed concurrently")
+)
+
+// transitions lists, for every status, the statuses a return may move to
+// next. Rejected and refunded are terminal. A return is refunding while its
+// payment is refunded; when that fails it is moved back to received, which is
+// not a transition.
+var transitions = map[string][]string{
+	StatusRequested: {StatusApproved, StatusRejected},
+	StatusApproved:  {StatusReceived},
+	StatusReceived:  {StatusRefunding},
+	StatusRefunding: {StatusRefunded},
+	StatusRejected:  {},
+	StatusRefunded:  {},
+}
+
+// IsKnown reports whether status is one of the return statuses.
+func IsKnown(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// CanTransition reports whether a return in status from may move to status to.
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate checks the request of a customer and normalizes it.
+func Validate(quantity int32, reason string, photoURLs []string) (string, []string, error) {
+	reason = strings.TrimSpace(reason)
+	switch {
+	case quantity <= 0:
+		return "", nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidReturn)
+	case reason == "" || len(reason) > maxReasonLength:
+		return "", nil, fmt.Errorf("%w: reason is required and must be at most %d characters", ErrInvalidReturn, maxReasonLength)
+	case len(photoURLs) > maxPhotos:
+		return "", nil, fmt.Errorf("%w: at most %d photos", ErrInvalidReturn, maxPhotos)
+	}
+
+	photos := make([]string, 0, len(photoURLs))
+	for _, p := range photoURLs {
+		p = strings.TrimSpace(p)
+		u, err := url.Parse(p)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(p) > maxPhotoURL {
+			return "", nil, fmt.Errorf("%w: photo %q is not an http(s) url", ErrInvalidReturn, p)
+		}
+		photos = append(photos, p)
+	}
+
+	return reason, photos, nil
+}
+
+// ValidateNote checks the note an admin leaves on a decision.
+func ValidateNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if len(note) > maxNoteLength {
+		return "", fmt.Errorf("%w: note must be at most %d characters", ErrInvalidReturn, maxNoteLength)
+	}
+
+	return note, nil
+}
@@ -0,0 +1,131 @@
+// Package search holds the filters, sorting and cursors of order searches.
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order-service/internal/services/order/lifecycle"
+	"strings"
+	"time"
+)
+
+const (
+	SortCreatedAt = "created_at"
+	SortTotal     = "total"
+	SortId        = "id"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+var ErrInvalidQuery = errors.New("invalid order search")
+
+// Query is a search for orders. Zero fields do not filter. Orders are sorted by
+// SortBy, newest or largest first when Descending is set, and ties are broken
+// by id in the same direction.
+type Query struct {
+	Status    string
+	From      time.Time // Inclusive.
+	To        time.Time // Exclusive.
+	UserId    int32
+	UserEmail string
+	ItemId    int32
+	MinTotal  int32
+	MaxTotal  int32
+	// Text is matched against the order number and the invoice number.
+	Text string
+
+	SortBy     string
+	Descending bool
+	Limit      int32
+	Cursor     string
+
+	// After is the decoded Cursor, the last order of the previous page.
+	After *Cursor
+}
+
+// Cursor points at the last order of a page by the key it was sorted on.
+type Cursor struct {
+	SortBy     string    `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	CreatedAt  time.Time `json:"c,omitempty"`
+	Total      int32     `json:"t,omitempty"`
+	Id         int32     `json:"i"`
+}
+
+// Normalize applies the defaults of the query, checks it and decodes its
+// cursor. A cursor is only valid with the sorting it was issued for.
+func (q *Query) Normalize() error {
+	q.UserEmail = strings.TrimSpace(q.UserEmail)
+	q.Text = strings.TrimSpace(q.Text)
+
+	if q.Status != "" && !lifecycle.IsKnown(q.Status) {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidQuery, q.Status)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+	if q.MinTotal < 0 || q.MaxTotal < 0 || (q.MaxTotal > 0 && q.MinTotal > q.MaxTotal) {
+		return fmt.Errorf("%w: invalid total range", ErrInvalidQuery)
+	}
+
+	switch q.SortBy {
+	case "":
+		q.SortBy = SortCreatedAt
+	case SortCreatedAt, SortTotal, SortId:
+	default:
+		return fmt.Errorf("%w: unknown sort %q", ErrInvalidQuery, q.SortBy)
+	}
+
+	switch {
+	case q.Limit == 0:
+		q.Limit = defaultLimit
+	case q.Limit < 0 || q.Limit > maxLimit:
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, maxLimit)
+	}
+
+	q.After = nil
+	if q.Cursor != "" {
+		after, err := decodeCursor(q.Cursor)
+		if err != nil || after.SortBy != q.SortBy || after.Descending != q.Descending {
+			return fmt.Errorf("%w: invalid cursor", ErrInvalidQuery)
+		}
+		q.After = after
+	}
+
+	return nil
+}
+
+// NextCursor returns the cursor of the page that follows the order with id,
+// createdAt and total.
+func (q *Query) NextCursor(id int32, createdAt time.Time, total int32) string {
+	c := Cursor{SortBy: q.SortBy, Descending: q.Descending, Id: id}
+	switch q.SortBy {
+	case SortCreatedAt:
+		c.CreatedAt = createdAt
+	case SortTotal:
+		c.Total = total
+	}
+
+	b, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Cursor
+	if err = json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
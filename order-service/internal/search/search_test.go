@@ -0,0 +1,60 @@
+package search
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 12, 30, 15, 123456789, time.UTC)
+
+	for _, tt := range []struct {
+		sortBy     string
+		descending bool
+		want       Cursor
+	}{
+		{SortCreatedAt, false, Cursor{SortBy: SortCreatedAt, CreatedAt: createdAt, Id: 42}},
+		{SortCreatedAt, true, Cursor{SortBy: SortCreatedAt, Descending: true, CreatedAt: createdAt, Id: 42}},
+		{SortTotal, false, Cursor{SortBy: SortTotal, Total: 1999, Id: 42}},
+		{SortTotal, true, Cursor{SortBy: SortTotal, Descending: true, Total: 1999, Id: 42}},
+		{SortId, false, Cursor{SortBy: SortId, Id: 42}},
+		{SortId, true, Cursor{SortBy: SortId, Descending: true, Id: 42}},
+	} {
+		first := Query{SortBy: tt.sortBy, Descending: tt.descending}
+		if err := first.Normalize(); err != nil {
+			t.Fatalf("Normalize(%s) = %v", tt.sortBy, err)
+		}
+
+		next := Query{SortBy: tt.sortBy, Descending: tt.descending, Cursor: first.NextCursor(42, createdAt, 1999)}
+		if err := next.Normalize(); err != nil {
+			t.Errorf("Normalize(%s, descending %v) with its own cursor = %v", tt.sortBy, tt.descending, err)
+			continue
+		}
+		if next.After == nil || !next.After.CreatedAt.Equal(tt.want.CreatedAt) {
+			t.Errorf("cursor of %s, descending %v = %+v, want %+v", tt.sortBy, tt.descending, next.After, tt.want)
+			continue
+		}
+		got := *next.After
+		got.CreatedAt = tt.want.CreatedAt
+		if got != tt.want {
+			t.Errorf("cursor of %s, descending %v = %+v, want %+v", tt.sortBy, tt.descending, got, tt.want)
+		}
+	}
+}
+
+func TestCursorRejected(t *testing.T) {
+	issued := Query{SortBy: SortTotal, Descending: true}
+	cursor := issued.NextCursor(42, time.Now(), 1999)
+
+	for name, q := range map[string]Query{
+		"other sort":      {SortBy: SortCreatedAt, Descending: true, Cursor: cursor},
+		"other direction": {SortBy: SortTotal, Cursor: cursor},
+		"not base64":      {SortBy: SortTotal, Descending: true, Cursor: "not a cursor!"},
+		"not json":        {SortBy: SortTotal, Descending: true, Cursor: "bm90IGpzb24"},
+	} {
+		if err := q.Normalize(); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%s: Normalize() = %v, want %v", name, err, ErrInvalidQuery)
+		}
+	}
+}
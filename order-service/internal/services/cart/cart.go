@@ -0,0 +1,164 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"order-service/internal/coupon"
+	"order-service/internal/data"
+	"order-service/internal/data/dto"
+	"order-service/internal/data/models"
+	"order-service/internal/sl"
+	"time"
+)
+
+// maxLineQuantity caps a single cart line so that a typo cannot reserve the
+// whole stock of an item.
+const maxLineQuantity = 99
+
+var ErrInvalidQuantity = errors.New("quantity must be between 1 and 99")
+
+type Cart struct {
+	log            *slog.Logger
+	cartProvider   CartRepo
+	couponProvider CouponRepo
+	shippingFee    int32
+}
+
+func New(
+	log *slog.Logger,
+	cartProvider CartRepo,
+	couponProvider CouponRepo,
+	shippingFee int32,
+) *Cart {
+	return &Cart{
+		log:            log,
+		cartProvider:   cartProvider,
+		couponProvider: couponProvider,
+		shippingFee:    shippingFee,
+	}
+}
+
+type CouponRepo interface {
+	GetCouponForUser(ctx context.Context, code string, userId int32) (*models.Coupon, int32, error)
+}
+
+type CartRepo interface {
+	GetCart(ctx context.Context, userId int) (*dto.CartDTO, error)
+	SaveCartLine(ctx context.Context, userId int, itemId int, quantity int, add bool) error
+	DeleteCartLine(ctx context.Context, userId int, itemId int) error
+}
+
+func (c *Cart) AddToCart(ctx context.Context, userId int, itemId int, quantity int) (*dto.CartDTO, error) {
+	return c.saveLine(ctx, "Cart.AddToCart", userId, itemId, quantity, true)
+}
+
+// UpdateCartLine sets the quantity of an item in the cart. A zero quantity
+// removes the item.
+func (c *Cart) UpdateCartLine(ctx context.Context, userId int, itemId int, quantity int) (*dto.CartDTO, error) {
+	if quantity == 0 {
+		return c.RemoveFromCart(ctx, userId, itemId)
+	}
+
+	return c.saveLine(ctx, "Cart.UpdateCartLine", userId, itemId, quantity, false)
+}
+
+func (c *Cart) saveLine(ctx context.Context, op string, userId int, itemId int, quantity int, add bool) (*dto.CartDTO, error) {
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Int("user id", userId),
+		slog.Int("item id", itemId),
+	)
+
+	log.Info("attempting to save cart line")
+
+	if quantity < 1 || quantity > maxLineQuantity {
+		return nil, ErrInvalidQuantity
+	}
+
+	if err := c.cartProvider.SaveCartLine(ctx, userId, itemId, quantity, add); err != nil {
+		log.Warn("failed to save cart line", sl.Err(err))
+		return nil, err
+	}
+
+	return c.GetCart(ctx, userId)
+}
+
+func (c *Cart) RemoveFromCart(ctx context.Context, userId int, itemId int) (*dto.CartDTO, error) {
+	const op = "Cart.RemoveFromCart"
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Int("user id", userId),
+		slog.Int("item id", itemId),
+	)
+
+	log.Info("attempting to remove item from cart")
+
+	if err := c.cartProvider.DeleteCartLine(ctx, userId, itemId); err != nil {
+		log.Warn("failed to remove cart line", sl.Err(err))
+		return nil, err
+	}
+
+	return c.GetCart(ctx, userId)
+}
+
+func (c *Cart) GetCart(ctx context.Context, userId int) (*dto.CartDTO, error) {
+	const op = "Cart.GetCart"
+
+	cart, err := c.cartProvider.GetCart(ctx, userId)
+	if err != nil {
+		c.log.Warn("failed to get cart", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// ValidateCoupon prices the cart of the user with the coupon without
+// redeeming it. Checkout checks the coupon again, so the quote may no longer
+// hold by then.
+func (c *Cart) ValidateCoupon(ctx context.Context, userId int, code string) (*coupon.Quote, error) {
+	const op = "Cart.ValidateCoupon"
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Int("user id", userId),
+	)
+
+	log.Info("attempting to validate coupon")
+
+	code = coupon.NormalizeCode(code)
+	if code == "" {
+		return nil, coupon.ErrCouponNotFound
+	}
+
+	cart, err := c.cartProvider.GetCart(ctx, userId)
+	if err != nil {
+		log.Warn("failed to get cart", sl.Err(err))
+		return nil, err
+	}
+	if len(cart.Lines) == 0 {
+		return nil, data.ErrCartEmpty
+	}
+
+	found, used, err := c.couponProvider.GetCouponForUser(ctx, code, int32(userId))
+	if err != nil {
+		log.Warn("failed to get coupon", sl.Err(err))
+		return nil, err
+	}
+
+	lines := make([]coupon.Line, 0, len(cart.Lines))
+	for _, l := range cart.Lines {
+		lines = append(lines, coupon.Line{
+			ItemId:    l.ItemId,
+			Category:  l.Item.Category,
+			Quantity:  l.Quantity,
+			UnitPrice: l.UnitPrice,
+		})
+	}
+
+	if err = coupon.Check(found, used, cart.Total, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return coupon.Price(found, lines, c.shippingFee)
+}
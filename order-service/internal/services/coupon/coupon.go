@@ -0,0 +1,112 @@
+package coupon
+
+import (
+	"context"
+	"log/slog"
+	"order-service/internal/coupon"
+	"order-service/internal/data/models"
+	"order-service/internal/sl"
+)
+
+// Coupons lets admins manage discount codes.
+type Coupons struct {
+	log            *slog.Logger
+	couponProvider CouponRepo
+}
+
+func New(
+	log *slog.Logger,
+	couponProvider CouponRepo,
+) *Coupons {
+	return &Coupons{
+		log:            log,
+		couponProvider: couponProvider,
+	}
+}
+
+type CouponRepo interface {
+	SaveCoupon(ctx context.Context, c *models.Coupon) (*models.Coupon, error)
+	UpdateCoupon(ctx context.Context, c *models.Coupon) (*models.Coupon, error)
+	GetCoupon(ctx context.Context, id int64) (*models.Coupon, error)
+	ListCoupons(ctx context.Context) ([]*models.Coupon, error)
+	DeleteCoupon(ctx context.Context, id int64) error
+}
+
+func (cs *Coupons) CreateCoupon(ctx context.Context, c *models.Coupon) (*models.Coupon, error) {
+	const op = "Coupons.CreateCoupon"
+	log := cs.log.With(
+		slog.String("op", op),
+		slog.String("code", c.Code),
+	)
+
+	log.Info("attempting to create coupon")
+
+	if err := coupon.Validate(c); err != nil {
+		return nil, err
+	}
+
+	saved, err := cs.couponProvider.SaveCoupon(ctx, c)
+	if err != nil {
+		log.Warn("failed to save coupon", sl.Err(err))
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+func (cs *Coupons) UpdateCoupon(ctx context.Context, c *models.Coupon) (*models.Coupon, error) {
+	const op = "Coupons.UpdateCoupon"
+	log := cs.log.With(
+		slog.String("op", op),
+		slog.Int64("coupon id", c.ID),
+	)
+
+	log.Info("attempting to update coupon")
+
+	existing, err := cs.couponProvider.GetCoupon(ctx, c.ID)
+	if err != nil {
+		log.Warn("failed to get coupon", sl.Err(err))
+		return nil, err
+	}
+
+	// The code of a coupon can not change, as orders refer to it.
+	c.Code = existing.Code
+	if err := coupon.Validate(c); err != nil {
+		return nil, err
+	}
+
+	updated, err := cs.couponProvider.UpdateCoupon(ctx, c)
+	if err != nil {
+		log.Warn("failed to update coupon", sl.Err(err))
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (cs *Coupons) GetCoupon(ctx context.Context, id int64) (*models.Coupon, error) {
+	return cs.couponProvider.GetCoupon(ctx, id)
+}
+
+func (cs *Coupons) ListCoupons(ctx context.Context) ([]*models.Coupon, error) {
+	const op = "Coupons.ListCoupons"
+
+	coupons, err := cs.couponProvider.ListCoupons(ctx)
+	if err != nil {
+		cs.log.Warn("failed to list coupons", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return coupons, nil
+}
+
+func (cs *Coupons) DeleteCoupon(ctx context.Context, id int64) error {
+	const op = "Coupons.DeleteCoupon"
+
+	if err := cs.couponProvider.DeleteCoupon(ctx, id); err != nil {
+		cs.log.Warn("failed to delete coupon", slog.String("op", op), sl.Err(err))
+		return err
+	}
+
+	return nil
+}
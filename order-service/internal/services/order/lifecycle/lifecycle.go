@@ -0,0 +1,54 @@
+// Package lifecycle describes the states of an order and the transitions
+// allowed between them.
+package lifecycle
+
+import "errors"
+
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+	StatusPaid      = "paid"
+	StatusShipped   = "shipped"
+	StatusDelivered = "delivered"
+	StatusCancelled = "cancelled"
+	StatusRefunded  = "refunded"
+)
+
+var (
+	ErrUnknownStatus     = errors.New("unknown order status")
+	ErrInvalidTransition = errors.New("order status transition is not allowed")
+	ErrNotOrderOwner     = errors.New("order belongs to another user")
+	ErrCheckoutFailed    = errors.New("checkout failed and the order was cancelled")
+	ErrAddressNotFound   = errors.New("shipping address not found")
+	ErrInvoiceNotIssued  = errors.New("order has not been invoiced yet")
+	ErrWatchInterrupted  = errors.New("watch was interrupted and may have missed changes")
+)
+
+// transitions lists, for every status, the statuses an order may move to
+// next. Cancelled and refunded are terminal.
+var transitions = map[string][]string{
+	StatusPending:   {StatusConfirmed, StatusCancelled},
+	StatusConfirmed: {StatusPaid, StatusCancelled},
+	StatusPaid:      {StatusShipped, StatusRefunded},
+	StatusShipped:   {StatusDelivered},
+	StatusDelivered: {StatusRefunded},
+	StatusCancelled: {},
+	StatusRefunded:  {},
+}
+
+// IsKnown reports whether status is one of the order statuses.
+func IsKnown(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// CanTransition reports whether an order in status from may move to status to.
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
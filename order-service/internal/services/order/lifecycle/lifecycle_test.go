@@ -0,0 +1,56 @@
+package lifecycle
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	for _, tt := range []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusPending, StatusConfirmed, true},
+		{StatusPending, StatusCancelled, true},
+		{StatusConfirmed, StatusPaid, true},
+		{StatusConfirmed, StatusCancelled, true},
+		{StatusPaid, StatusShipped, true},
+		{StatusPaid, StatusRefunded, true},
+		{StatusShipped, StatusDelivered, true},
+		{StatusDelivered, StatusRefunded, true},
+
+		// Steps can not be skipped.
+		{StatusPending, StatusPaid, false},
+		{StatusPending, StatusShipped, false},
+		{StatusConfirmed, StatusShipped, false},
+		{StatusPaid, StatusDelivered, false},
+		// Orders never go back.
+		{StatusConfirmed, StatusPending, false},
+		{StatusPaid, StatusConfirmed, false},
+		{StatusShipped, StatusPaid, false},
+		{StatusDelivered, StatusShipped, false},
+		// Paid orders are refunded rather than cancelled, and shipped ones
+		// only once delivered.
+		{StatusPaid, StatusCancelled, false},
+		{StatusShipped, StatusCancelled, false},
+		{StatusShipped, StatusRefunded, false},
+		{StatusDelivered, StatusCancelled, false},
+		// Cancelled and refunded are terminal.
+		{StatusCancelled, StatusPending, false},
+		{StatusCancelled, StatusConfirmed, false},
+		{StatusCancelled, StatusRefunded, false},
+		{StatusRefunded, StatusPaid, false},
+		{StatusRefunded, StatusDelivered, false},
+		{StatusRefunded, StatusCancelled, false},
+		// A status is not a transition to itself.
+		{StatusPending, StatusPending, false},
+		{StatusPaid, StatusPaid, false},
+		{StatusRefunded, StatusRefunded, false},
+		// Unknown statuses go nowhere and can not be reached.
+		{"lost", StatusPending, false},
+		{StatusPending, "lost", false},
+		{"", StatusConfirmed, false},
+		{StatusPending, "", false},
+	} {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	grpcapp "order-service/internal/app/grpc"
+	"order-service/internal/data/dto"
+	"order-service/internal/data/models"
+	"order-service/internal/services/order/lifecycle"
+	"order-service/internal/sl"
+)
+
+func (o *Order) UpdateOrderStatus(ctx context.Context, id int, status string, reason string) (*models.Order, error) {
+	const op = "Order.UpdateOrderStatus"
+	log := o.log.With(
+		slog.String("op", op),
+		slog.Int("order id", id),
+		slog.String("status", status),
+	)
+
+	log.Info("attempting to update order status")
+
+	if !lifecycle.IsKnown(status) {
+		return nil, lifecycle.ErrUnknownStatus
+	}
+
+	order, err := o.orderProvider.GetOrderById(ctx, id)
+	if err != nil {
+		log.Warn("failed to get order", sl.Err(err))
+		return nil, err
+	}
+
+	return o.transition(ctx, order, status, reason)
+}
+
+// CancelOrder cancels the order on behalf of its owner or an admin.
+func (o *Order) CancelOrder(ctx context.Context, id int, reason string) (*models.Order, error) {
+	const op = "Order.CancelOrder"
+	log := o.log.With(
+		slog.String("op", op),
+		slog.Int("order id", id),
+	)
+
+	log.Info("attempting to cancel order")
+
+	order, err := o.orderProvider.GetOrderById(ctx, id)
+	if err != nil {
+		log.Warn("failed to get order", sl.Err(err))
+		return nil, err
+	}
+
+	caller, ok := grpcapp.CallerFromContext(ctx)
+	if !ok || (!caller.IsAdmin && caller.UserId != order.UserId) {
+		return nil, lifecycle.ErrNotOrderOwner
+	}
+
+	return o.transition(ctx, order, lifecycle.StatusCancelled, reason)
+}
+
+// CreateShipment hands the order to a carrier and marks it as shipped.
+func (o *Order) CreateShipment(ctx context.Context, id int, carrier string, trackingNumber string) (*dto.OrderDTO, error) {
+	const op = "Order.CreateShipment"
+	log := o.log.With(
+		slog.String("op", op),
+		slog.Int("order id", id),
+		slog.String("carrier", carrier),
+	)
+
+	log.Info("attempting to create shipment")
+
+	order, err := o.orderProvider.GetOrderById(ctx, id)
+	if err != nil {
+		log.Warn("failed to get order", sl.Err(err))
+		return nil, err
+	}
+
+	if !lifecycle.CanTransition(order.Status, lifecycle.StatusShipped) {
+		return nil, fmt.Errorf("%w: %s -> %s", lifecycle.ErrInvalidTransition, order.Status, lifecycle.StatusShipped)
+	}
+
+	var changedBy int32
+	if caller, ok := grpcapp.CallerFromContext(ctx); ok {
+		changedBy = caller.UserId
+	}
+
+	shipped, err := o.orderProvider.CreateShipment(ctx, &dto.ShipmentDTO{
+		OrderId:        order.ID,
+		Carrier:        carrier,
+		TrackingNumber: trackingNumber,
+	}, &models.OrderStatusChange{
+		OrderId:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   lifecycle.StatusShipped,
+		ChangedBy:  changedBy,
+		Reason:     fmt.Sprintf("shipped with %s, tracking number %s", carrier, trackingNumber),
+	})
+	if err != nil {
+		log.Warn("failed to create shipment", sl.Err(err))
+		return nil, err
+	}
+
+	return shipped, nil
+}
+
+func (o *Order) transition(ctx context.Context, order *models.Order, status string, reason string) (*models.Order, error) {
+	const op = "Order.transition"
+
+	if !lifecycle.CanTransition(order.Status, status) {
+		return nil, fmt.Errorf("%w: %s -> %s", lifecycle.ErrInvalidTransition, order.Status, status)
+	}
+
+	var changedBy int32
+	if caller, ok := grpcapp.CallerFromContext(ctx); ok {
+		changedBy = caller.UserId
+	}
+
+	updated, err := o.orderProvider.UpdateOrderStatus(ctx, &models.OrderStatusChange{
+		OrderId:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   status,
+		ChangedBy:  changedBy,
+		Reason:     reason,
+	})
+	if err != nil {
+		o.log.Warn("failed to update order status", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (o *Order) GetOrderHistory(ctx context.Context, id int) ([]*models.OrderStatusChange, error) {
+	const op = "Order.GetOrderHistory"
+
+	history, err := o.orderProvider.GetOrderStatusHistory(ctx, id)
+	if err != nil {
+		o.log.Warn("failed to get order status history", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// GetOrderReturns returns the return requests made for the order.
+func (o *Order) GetOrderReturns(ctx context.Context, id int) ([]*models.Return, error) {
+	const op = "Order.GetOrderReturns"
+
+	returns, err := o.orderProvider.GetOrderReturns(ctx, id)
+	if err != nil {
+		o.log.Warn("failed to get order returns", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return returns, nil
+}
@@ -0,0 +1,81 @@
+package order
+
+import (
+	"context"
+	"log/slog"
+	grpcapp "order-service/internal/app/grpc"
+	"order-service/internal/data/models"
+	"order-service/internal/services/order/lifecycle"
+)
+
+// StatusWatcher delivers the status changes of orders as they are committed.
+// The channels are closed when the watcher had to drop the subscription.
+type StatusWatcher interface {
+	WatchOrder(orderId int32) (<-chan *models.OrderStatusChange, func())
+	WatchUser(userId int32) (<-chan *models.OrderStatusChange, func())
+}
+
+// WatchOrder sends the current status of the order and then every change of
+// it until ctx is done. Only the owner of the order and admins may watch it.
+func (o *Order) WatchOrder(ctx context.Context, id int, send func(*models.OrderStatusChange) error) error {
+	const op = "Order.WatchOrder"
+	log := o.log.With(
+		slog.String("op", op),
+		slog.Int("order id", id),
+	)
+
+	log.Info("attempting to watch order")
+
+	// Subscribe first, so that no change is lost between reading the current
+	// status and watching for the next one.
+	changes, stop := o.watcher.WatchOrder(int32(id))
+	defer stop()
+
+	order, err := o.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = send(&models.OrderStatusChange{
+		OrderId:   order.ID,
+		ToStatus:  order.Status,
+		CreatedAt: order.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return forward(ctx, changes, send)
+}
+
+// WatchMyOrders sends every status change of the orders of the caller until
+// ctx is done.
+func (o *Order) WatchMyOrders(ctx context.Context, send func(*models.OrderStatusChange) error) error {
+	caller, ok := grpcapp.CallerFromContext(ctx)
+	if !ok {
+		return lifecycle.ErrNotOrderOwner
+	}
+
+	o.log.Info("attempting to watch orders of user", slog.String("op", "Order.WatchMyOrders"), slog.Int("user id", int(caller.UserId)))
+
+	changes, stop := o.watcher.WatchUser(caller.UserId)
+	defer stop()
+
+	return forward(ctx, changes, send)
+}
+
+func forward(ctx context.Context, changes <-chan *models.OrderStatusChange, send func(*models.OrderStatusChange) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case change, ok := <-changes:
+			if !ok {
+				return lifecycle.ErrWatchInterrupted
+			}
+			if err := send(change); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	grpcapp "order-service/internal/app/grpc"
+	"order-service/internal/data"
+	"order-service/internal/data/models"
+	"order-service/internal/payment"
+	"order-service/internal/services/order/lifecycle"
+	"order-service/internal/sl"
+	"strconv"
+)
+
+type Payments struct {
+	log             *slog.Logger
+	paymentProvider PaymentRepo
+	orderProvider   OrderRepo
+	provider        payment.Provider
+	webhookSecret   string
+}
+
+func New(
+	log *slog.Logger,
+	paymentProvider PaymentRepo,
+	orderProvider OrderRepo,
+	provider payment.Provider,
+	webhookSecret string,
+) *Payments {
+	return &Payments{
+		log:             log,
+		paymentProvider: paymentProvider,
+		orderProvider:   orderProvider,
+		provider:        provider,
+		webhookSecret:   webhookSecret,
+	}
+}
+
+type PaymentRepo interface {
+	SavePayment(ctx context.Context, payment *models.Payment) (*models.Payment, error)
+	GetPaymentById(ctx context.Context, id int64) (*models.Payment, error)
+	GetPaymentByRef(ctx context.Context, provider string, ref string) (*models.Payment, error)
+	GetPaymentByOrderId(ctx context.Context, orderId int32) (*models.Payment, error)
+	UpdatePayment(ctx context.Context, payment *models.Payment) error
+	SettlePayment(ctx context.Context, id int64, status string, refunded int32) (*models.Payment, error)
+	ReservePaymentRefund(ctx context.Context, id int64, reserve func(*models.Payment) (int32, error)) (*models.Payment, int32, error)
+	ReleasePaymentRefund(ctx context.Context, id int64, amount int32) error
+	SettlePaymentRefund(ctx context.Context, id int64, amount int32) (*models.Payment, error)
+	ApplyPaymentEvent(ctx context.Context, id int64, eventId string, eventType string, payload []byte, status string, refunded int32) (*models.Payment, bool, error)
+}
+
+type OrderRepo interface {
+	GetOrderById(context.Context, int) (*models.Order, error)
+	UpdateOrderStatus(context.Context, *models.OrderStatusChange) (*models.Order, error)
+}
+
+// Authorize reserves amount for the order at the provider and returns the id
+// of the payment record. The record is written before the provider is called,
+// so an authorization is never made without a trace. Authorizing an order
+// again returns its authorized payment, and an attempt that did not finish is
+// retried with the idempotency key of its record, so that retries never
+// reserve the amount twice.
+func (p *Payments) Authorize(ctx context.Context, orderId int32, amount int32) (string, error) {
+	const op = "Payments.Authorize"
+	log := p.log.With(
+		slog.String("op", op),
+		slog.Int("order id", int(orderId)),
+	)
+
+	log.Info("attempting to authorize payment")
+
+	record, err := p.paymentProvider.GetPaymentByOrderId(ctx, orderId)
+	switch {
+	case err == nil && record.Amount == amount && record.Status == payment.StatusPending:
+	case err == nil && record.Amount == amount && authorized(record.Status):
+		log.Info("payment was already authorized")
+		return strconv.FormatInt(record.ID, 10), nil
+	case err == nil, errors.Is(err, data.ErrRecordNotFound):
+		record, err = p.paymentProvider.SavePayment(ctx, &models.Payment{
+			OrderId:  orderId,
+			Provider: p.provider.Name(),
+			Status:   payment.StatusPending,
+			Amount:   amount,
+		})
+		if err != nil {
+			log.Warn("failed to save payment", sl.Err(err))
+			return "", err
+		}
+	default:
+		log.Warn("failed to get payment", sl.Err(err))
+		return "", err
+	}
+
+	result, err := p.provider.Authorize(ctx, orderId, amount, fmt.Sprintf("payment-%d", record.ID))
+	if err != nil {
+		log.Warn("failed to authorize payment", sl.Err(err))
+		if errors.Is(err, payment.ErrDeclined) {
+			record.Status = payment.StatusFailed
+			if err := p.paymentProvider.UpdatePayment(ctx, record); err != nil {
+				log.Warn("failed to update payment", sl.Err(err))
+			}
+		}
+		return "", err
+	}
+
+	record.ProviderRef = result.Ref
+	record.Status = result.Status
+	if err := p.paymentProvider.UpdatePayment(ctx, record); err != nil {
+		log.Warn("failed to update payment", sl.Err(err))
+		return "", err
+	}
+
+	return strconv.FormatInt(record.ID, 10), nil
+}
+
+// authorized reports whether a payment in status holds an authorization,
+// captured or not.
+func authorized(status string) bool {
+	switch status {
+	case payment.StatusAuthorized, payment.StatusCapturePending, payment.StatusCaptured:
+		return true
+	default:
+		return false
+	}
+}
+
+// Capture collects the authorized amount. When the provider settles the
+// capture right away the order is marked as paid; otherwise the payment waits
+// in StatusCapturePending until the payment.captured webhook arrives.
+func (p *Payments) Capture(ctx context.Context, paymentId string) error {
+	const op = "Payments.Capture"
+
+	record, err := p.getPayment(ctx, paymentId)
+	if err != nil {
+		return err
+	}
+	if record.Status == payment.StatusCaptured || record.Status == payment.StatusCapturePending {
+		return nil
+	}
+
+	result, err := p.provider.Capture(ctx, record.ProviderRef, record.Amount)
+	if err != nil {
+		p.log.Warn("failed to capture payment", slog.String("op", op), sl.Err(err))
+		return err
+	}
+
+	status := result.Status
+	if status == payment.StatusPending {
+		status = payment.StatusCapturePending
+	}
+
+	record, err = p.paymentProvider.SettlePayment(ctx, record.ID, status, 0)
+	if err != nil {
+		return err
+	}
+
+	return p.syncOrder(ctx, record, "payment captured")
+}
+
+// Void releases an authorization that was not captured.
+func (p *Payments) Void(ctx context.Context, paymentId string) error {
+	const op = "Payments.Void"
+
+	record, err := p.getPayment(ctx, paymentId)
+	if err != nil {
+		return err
+	}
+	if record.Status != payment.StatusAuthorized {
+		return nil
+	}
+
+	result, err := p.provider.Void(ctx, record.ProviderRef)
+	if err != nil {
+		p.log.Warn("failed to void payment", slog.String("op", op), sl.Err(err))
+		return err
+	}
+
+	_, err = p.paymentProvider.SettlePayment(ctx, record.ID, result.Status, 0)
+
+	return err
+}
+
+// RefundAll gives back whatever is left of a captured payment. It undoes a
+// capture when a checkout is rolled back. A capture that has not settled yet
+// fails with ErrSettling, so that the rollback is retried once it has.
+func (p *Payments) RefundAll(ctx context.Context, paymentId string) error {
+	record, err := p.getPayment(ctx, paymentId)
+	if err != nil {
+		return err
+	}
+	switch {
+	case record.Status == payment.StatusCapturePending:
+		return payment.ErrSettling
+	case record.Status != payment.StatusCaptured,
+		record.RefundedAmount+record.RefundingAmount >= record.Amount:
+		return nil
+	}
+
+	_, err = p.refund(ctx, record.ID, 0)
+
+	return err
+}
+
+// Refund refunds amount of the payment of the order, or all that is left when
+// amount is zero.
+func (p *Payments) Refund(ctx context.Context, orderId int32, amount int32) (*models.Payment, error) {
+	const op = "Payments.Refund"
+	log := p.log.With(
+		slog.String("op", op),
+		slog.Int("order id", int(orderId)),
+	)
+
+	log.Info("attempting to refund payment")
+
+	record, err := p.paymentProvider.GetPaymentByOrderId(ctx, orderId)
+	if err != nil {
+		log.Warn("failed to get payment", sl.Err(err))
+		return nil, err
+	}
+
+	return p.refund(ctx, record.ID, amount)
+}
+
+// refund refunds amount of a payment, or all that is left when amount is zero.
+// The amount is reserved as refunding before the provider is called, so that
+// refunds running at the same time cannot give back more than was captured,
+// and a refund the provider made is never lost from the books: should the
+// service stop before it is settled, it stays reserved. A refund the provider
+// did not settle right away is settled by its webhook.
+func (p *Payments) refund(ctx context.Context, id int64, amount int32) (*models.Payment, error) {
+	const op = "Payments.refund"
+	log := p.log.With(
+		slog.String("op", op),
+		slog.Int64("payment id", id),
+	)
+
+	record, amount, err := p.paymentProvider.ReservePaymentRefund(ctx, id, func(record *models.Payment) (int32, error) {
+		if record.Status != payment.StatusCaptured {
+			return 0, payment.ErrNotRefundable
+		}
+
+		left := record.Amount - record.RefundedAmount - record.RefundingAmount
+		if amount == 0 {
+			amount = left
+		}
+		if amount <= 0 || amount > left {
+			return 0, payment.ErrInvalidAmount
+		}
+
+		return amount, nil
+	})
+	if err != nil {
+		log.Warn("failed to reserve refund", sl.Err(err))
+		return nil, err
+	}
+
+	result, err := p.provider.Refund(ctx, record.ProviderRef, amount)
+	if err != nil {
+		log.Warn("failed to refund payment", sl.Err(err))
+		if releaseErr := p.paymentProvider.ReleasePaymentRefund(context.WithoutCancel(ctx), id, amount); releaseErr != nil {
+			log.Error("failed to release refund", sl.Err(releaseErr))
+		}
+		return nil, err
+	}
+	if result.Status != payment.StatusRefunded {
+		// The refund settles later through a webhook.
+		return record, nil
+	}
+
+	record, err = p.paymentProvider.SettlePaymentRefund(context.WithoutCancel(ctx), id, amount)
+	if err != nil {
+		log.Error("failed to settle refund", sl.Err(err))
+		return nil, err
+	}
+
+	return record, p.syncOrder(ctx, record, "payment refunded")
+}
+
+// HandleWebhook verifies and applies an event sent by the provider.
+func (p *Payments) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	const op = "Payments.HandleWebhook"
+	log := p.log.With(slog.String("op", op))
+
+	event, err := payment.ParseEvent(p.webhookSecret, payload, signature)
+	if err != nil {
+		log.Warn("rejected payment webhook", sl.Err(err))
+		return payment.ErrInvalidSignature
+	}
+
+	log = log.With(slog.String("event id", event.ID), slog.String("event type", event.Type))
+	log.Info("attempting to apply payment webhook")
+
+	record, err := p.paymentProvider.GetPaymentByRef(ctx, p.provider.Name(), event.Ref)
+	if err != nil {
+		log.Warn("failed to get payment", sl.Err(err))
+		return err
+	}
+
+	var status string
+	var refunded int32
+	switch event.Type {
+	case payment.EventCaptured:
+		status = payment.StatusCaptured
+	case payment.EventRefunded:
+		status, refunded = payment.StatusRefunded, event.Amount
+	case payment.EventVoided:
+		status = payment.StatusVoided
+	case payment.EventFailed:
+		status = payment.StatusFailed
+	default:
+		log.Info("ignoring unknown payment event")
+		return nil
+	}
+
+	record, applied, err := p.paymentProvider.ApplyPaymentEvent(ctx, record.ID, event.ID, event.Type, payload, status, refunded)
+	if err != nil {
+		log.Warn("failed to apply payment event", sl.Err(err))
+		return err
+	}
+	if !applied {
+		log.Info("payment event was already applied")
+		return nil
+	}
+
+	return p.syncOrder(ctx, record, "payment "+record.Status)
+}
+
+// syncOrder moves the order to the status that matches its payment. Orders
+// that already are in that status, or cannot move there, are left alone.
+func (p *Payments) syncOrder(ctx context.Context, record *models.Payment, reason string) error {
+	var to string
+	switch record.Status {
+	case payment.StatusCaptured:
+		to = lifecycle.StatusPaid
+	case payment.StatusRefunded:
+		to = lifecycle.StatusRefunded
+	case payment.StatusFailed:
+		to = lifecycle.StatusCancelled
+	default:
+		return nil
+	}
+
+	order, err := p.orderProvider.GetOrderById(ctx, int(record.OrderId))
+	if err != nil {
+		return err
+	}
+	if !lifecycle.CanTransition(order.Status, to) {
+		return nil
+	}
+
+	var changedBy int32
+	if caller, ok := grpcapp.CallerFromContext(ctx); ok {
+		changedBy = caller.UserId
+	}
+
+	_, err = p.orderProvider.UpdateOrderStatus(ctx, &models.OrderStatusChange{
+		OrderId:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   to,
+		ChangedBy:  changedBy,
+		Reason:     reason,
+	})
+
+	return err
+}
+
+func (p *Payments) getPayment(ctx context.Context, paymentId string) (*models.Payment, error) {
+	id, err := strconv.ParseInt(paymentId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment id %q", paymentId)
+	}
+
+	return p.paymentProvider.GetPaymentById(ctx, id)
+}
@@ -0,0 +1,102 @@
+package report
+
+import (
+	"context"
+	"log/slog"
+	"order-service/internal/data/models"
+	"order-service/internal/report"
+	"order-service/internal/sl"
+	"time"
+)
+
+// Reports builds the sales reports of the admin dashboard.
+type Reports struct {
+	log            *slog.Logger
+	reportProvider ReportRepo
+}
+
+func New(log *slog.Logger, reportProvider ReportRepo) *Reports {
+	return &Reports{
+		log:            log,
+		reportProvider: reportProvider,
+	}
+}
+
+type ReportRepo interface {
+	GetSalesReport(ctx context.Context, f report.Filter, granularity string) (*models.SalesReport, error)
+	GetTopItems(ctx context.Context, f report.Filter, limit int32) ([]*models.TopItem, error)
+	GetCustomerReport(ctx context.Context, f report.Filter) (*models.CustomerReport, error)
+	GetConversionReport(ctx context.Context, f report.Filter) (*models.ConversionReport, error)
+}
+
+func (r *Reports) GetSalesReport(ctx context.Context, f report.Filter, granularity string) (*models.SalesReport, error) {
+	const op = "Reports.GetSalesReport"
+
+	if err := f.Normalize(time.Now()); err != nil {
+		return nil, err
+	}
+	granularity, err := report.Granularity(granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	sales, err := r.reportProvider.GetSalesReport(ctx, f, granularity)
+	if err != nil {
+		r.log.Warn("failed to get sales report", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return sales, nil
+}
+
+func (r *Reports) GetTopItems(ctx context.Context, f report.Filter, limit int32) ([]*models.TopItem, error) {
+	const op = "Reports.GetTopItems"
+
+	if err := f.Normalize(time.Now()); err != nil {
+		return nil, err
+	}
+	limit, err := report.Limit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := r.reportProvider.GetTopItems(ctx, f, limit)
+	if err != nil {
+		r.log.Warn("failed to get top items", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (r *Reports) GetCustomerReport(ctx context.Context, f report.Filter) (*models.CustomerReport, error) {
+	const op = "Reports.GetCustomerReport"
+
+	if err := f.Normalize(time.Now()); err != nil {
+		return nil, err
+	}
+
+	customers, err := r.reportProvider.GetCustomerReport(ctx, f)
+	if err != nil {
+		r.log.Warn("failed to get customer report", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return customers, nil
+}
+
+func (r *Reports) GetConversionReport(ctx context.Context, f report.Filter) (*models.ConversionReport, error) {
+	const op = "Reports.GetConversionReport"
+
+	if err := f.Normalize(time.Now()); err != nil {
+		return nil, err
+	}
+
+	conversion, err := r.reportProvider.GetConversionReport(ctx, f)
+	if err != nil {
+		r.log.Warn("failed to get conversion report", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return conversion, nil
+}
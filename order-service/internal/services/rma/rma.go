@@ -0,0 +1,337 @@
+package rma
+
+import (
+	"context"
+	"fmt"
+	cataloguep "github.com/sntabq/proto-gen/gen/go/catalogue"
+	"log/slog"
+	grpcapp "order-service/internal/app/grpc"
+	"order-service/internal/data/dto"
+	"order-service/internal/data/models"
+	"order-service/internal/rma"
+	"order-service/internal/services/order/lifecycle"
+	"order-service/internal/sl"
+)
+
+// Returns handles return requests from opening to refund.
+type Returns struct {
+	log            *slog.Logger
+	returnProvider ReturnRepo
+	orderProvider  OrderRepo
+	refunder       Refunder
+}
+
+func New(
+	log *slog.Logger,
+	returnProvider ReturnRepo,
+	orderProvider OrderRepo,
+	refunder Refunder,
+) *Returns {
+	return &Returns{
+		log:            log,
+		returnProvider: returnProvider,
+		orderProvider:  orderProvider,
+		refunder:       refunder,
+	}
+}
+
+type ReturnRepo interface {
+	OpenReturn(ctx context.Context, r *models.Return) (*models.Return, error)
+	GetReturn(ctx context.Context, id int64) (*models.Return, error)
+	ListReturns(ctx context.Context, userId int32, status string) ([]*models.Return, error)
+	UpdateReturn(ctx context.Context, r *models.Return, change *models.ReturnStatusChange) (*models.Return, error)
+}
+
+type OrderRepo interface {
+	GetOrderDetails(ctx context.Context, id int) (*dto.OrderDTO, error)
+}
+
+// Refunder refunds part of the payment of an order.
+type Refunder interface {
+	Refund(ctx context.Context, orderId int32, amount int32) (*models.Payment, error)
+}
+
+// OpenReturn requests the return of quantity units of the item of a delivered
+// order of the caller.
+func (rs *Returns) OpenReturn(ctx context.Context, r *models.Return) (*models.Return, error) {
+	const op = "Returns.OpenReturn"
+	log := rs.log.With(
+		slog.String("op", op),
+		slog.Int("order id", int(r.OrderId)),
+		slog.Int("item id", int(r.ItemId)),
+	)
+
+	log.Info("attempting to open return")
+
+	reason, photos, err := rma.Validate(r.Quantity, r.Reason, r.PhotoURLs)
+	if err != nil {
+		return nil, err
+	}
+	r.Reason, r.PhotoURLs = reason, photos
+
+	order, err := rs.orderProvider.GetOrderDetails(ctx, int(r.OrderId))
+	if err != nil {
+		log.Warn("failed to get order", sl.Err(err))
+		return nil, err
+	}
+
+	caller, ok := grpcapp.CallerFromContext(ctx)
+	if !ok || caller.UserId != order.UserId {
+		return nil, lifecycle.ErrNotOrderOwner
+	}
+	r.UserId = order.UserId
+
+	opened, err := rs.returnProvider.OpenReturn(ctx, r)
+	if err != nil {
+		log.Warn("failed to open return", sl.Err(err))
+		return nil, err
+	}
+
+	return opened, nil
+}
+
+// GetReturn returns the return with its history to its owner or an admin.
+func (rs *Returns) GetReturn(ctx context.Context, id int64) (*models.Return, error) {
+	const op = "Returns.GetReturn"
+
+	r, err := rs.returnProvider.GetReturn(ctx, id)
+	if err != nil {
+		rs.log.Warn("failed to get return", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	caller, ok := grpcapp.CallerFromContext(ctx)
+	if !ok || (!caller.IsAdmin && caller.UserId != r.UserId) {
+		return nil, lifecycle.ErrNotOrderOwner
+	}
+
+	return r, nil
+}
+
+// ListReturns lists the returns in status of the caller, or of all users when
+// the caller is an admin.
+func (rs *Returns) ListReturns(ctx context.Context, status string) ([]*models.Return, error) {
+	const op = "Returns.ListReturns"
+
+	if status != "" && !rma.IsKnown(status) {
+		return nil, fmt.Errorf("%w: unknown status %q", rma.ErrInvalidReturn, status)
+	}
+
+	caller, ok := grpcapp.CallerFromContext(ctx)
+	if !ok {
+		return nil, lifecycle.ErrNotOrderOwner
+	}
+
+	userId := caller.UserId
+	if caller.IsAdmin {
+		userId = 0
+	}
+
+	returns, err := rs.returnProvider.ListReturns(ctx, userId, status)
+	if err != nil {
+		rs.log.Warn("failed to list returns", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return returns, nil
+}
+
+func (rs *Returns) ApproveReturn(ctx context.Context, id int64, note string) (*models.Return, error) {
+	return rs.decide(ctx, id, rma.StatusApproved, note)
+}
+
+func (rs *Returns) RejectReturn(ctx context.Context, id int64, note string) (*models.Return, error) {
+	return rs.decide(ctx, id, rma.StatusRejected, note)
+}
+
+func (rs *Returns) decide(ctx context.Context, id int64, status string, note string) (*models.Return, error) {
+	const op = "Returns.decide"
+	log := rs.log.With(
+		slog.String("op", op),
+		slog.Int64("return id", id),
+		slog.String("status", status),
+	)
+
+	log.Info("attempting to decide on return")
+
+	note, err := rma.ValidateNote(note)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := rs.returnProvider.GetReturn(ctx, id)
+	if err != nil {
+		log.Warn("failed to get return", sl.Err(err))
+		return nil, err
+	}
+
+	r.Note = note
+
+	return rs.transition(ctx, r, status, note)
+}
+
+// ReceiveReturn records that the items of an approved return arrived back and,
+// when restock is set, puts them back into stock. Restocking is idempotent at
+// the catalogue, so it is done before the return moves on and is simply
+// repeated when that fails.
+func (rs *Returns) ReceiveReturn(ctx context.Context, id int64, restock bool, note string) (*models.Return, error) {
+	const op = "Returns.ReceiveReturn"
+	log := rs.log.With(
+		slog.String("op", op),
+		slog.Int64("return id", id),
+	)
+
+	log.Info("attempting to receive return")
+
+	note, err := rma.ValidateNote(note)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := rs.returnProvider.GetReturn(ctx, id)
+	if err != nil {
+		log.Warn("failed to get return", sl.Err(err))
+		return nil, err
+	}
+
+	if !rma.CanTransition(r.Status, rma.StatusReceived) {
+		return nil, fmt.Errorf("%w: %s -> %s", rma.ErrInvalidTransition, r.Status, rma.StatusReceived)
+	}
+
+	if restock {
+		_, err = grpcapp.StockServiceClient.RestockItems(ctx, &cataloguep.RestockItemsRequest{
+			RestockId: fmt.Sprintf("return-%d", r.ID),
+			Lines:     []*cataloguep.StockLine{{ItemId: r.ItemId, Quantity: r.Quantity}},
+		})
+		if err != nil {
+			log.Error("failed to restock items", sl.Err(err))
+			return nil, err
+		}
+		r.Restocked = true
+	}
+
+	r.Note = note
+
+	return rs.transition(ctx, r, rma.StatusReceived, note)
+}
+
+// RefundReturn refunds a received return through the payment of its order.
+// When amount is zero the customer gets back what they paid for the returned
+// units, their share of the discounts and shipping included. The return is
+// moved to refunding before the payment is refunded, so that it can not be
+// refunded twice, and moved back when the refund fails. Only once the refund
+// went through is it moved to refunded and the customer told about it.
+func (rs *Returns) RefundReturn(ctx context.Context, id int64, amount int32, note string) (*models.Return, error) {
+	const op = "Returns.RefundReturn"
+	log := rs.log.With(
+		slog.String("op", op),
+		slog.Int64("return id", id),
+	)
+
+	log.Info("attempting to refund return")
+
+	note, err := rma.ValidateNote(note)
+	if err != nil {
+		return nil, err
+	}
+	if amount < 0 {
+		return nil, fmt.Errorf("%w: amount must not be negative", rma.ErrInvalidReturn)
+	}
+
+	r, err := rs.returnProvider.GetReturn(ctx, id)
+	if err != nil {
+		log.Warn("failed to get return", sl.Err(err))
+		return nil, err
+	}
+
+	if amount == 0 {
+		order, err := rs.orderProvider.GetOrderDetails(ctx, int(r.OrderId))
+		if err != nil {
+			log.Warn("failed to get order", sl.Err(err))
+			return nil, err
+		}
+		amount = refundAmount(order, r)
+	}
+
+	received := r.Status
+	r.RefundAmount, r.Note = amount, note
+	refunding, err := rs.transition(ctx, r, rma.StatusRefunding, note)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = rs.refunder.Refund(ctx, r.OrderId, amount); err != nil {
+		log.Warn("failed to refund payment", sl.Err(err))
+
+		refunding.RefundAmount = 0
+		_, revertErr := rs.returnProvider.UpdateReturn(ctx, refunding, &models.ReturnStatusChange{
+			ReturnId:   refunding.ID,
+			FromStatus: rma.StatusRefunding,
+			ToStatus:   received,
+			ChangedBy:  changedBy(ctx),
+			Note:       fmt.Sprintf("refund failed: %v", err),
+		})
+		if revertErr != nil {
+			log.Error("failed to revert return after failed refund", sl.Err(revertErr))
+		}
+
+		return nil, err
+	}
+
+	refunded, err := rs.transition(ctx, refunding, rma.StatusRefunded, note)
+	if err != nil {
+		log.Error("failed to mark refunded return", sl.Err(err))
+		return nil, err
+	}
+
+	return refunded, nil
+}
+
+func (rs *Returns) transition(ctx context.Context, r *models.Return, status string, note string) (*models.Return, error) {
+	const op = "Returns.transition"
+
+	if !rma.CanTransition(r.Status, status) {
+		return nil, fmt.Errorf("%w: %s -> %s", rma.ErrInvalidTransition, r.Status, status)
+	}
+
+	updated, err := rs.returnProvider.UpdateReturn(ctx, r, &models.ReturnStatusChange{
+		ReturnId:   r.ID,
+		FromStatus: r.Status,
+		ToStatus:   status,
+		ChangedBy:  changedBy(ctx),
+		Note:       note,
+	})
+	if err != nil {
+		rs.log.Warn("failed to update return", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func changedBy(ctx context.Context) int32 {
+	if caller, ok := grpcapp.CallerFromContext(ctx); ok {
+		return caller.UserId
+	}
+
+	return 0
+}
+
+// refundAmount is what the customer paid for the returned units: their price
+// scaled by the share of the order total left after discounts.
+func refundAmount(order *dto.OrderDTO, r *models.Return) int32 {
+	var unitPrice int32
+	for _, l := range order.Lines {
+		if l.ItemId == r.ItemId {
+			unitPrice = l.UnitPrice
+			break
+		}
+	}
+
+	gross := int64(order.Subtotal) + int64(order.ShippingFee)
+	if gross == 0 {
+		return 0
+	}
+
+	return int32(int64(r.Quantity) * int64(unitPrice) * int64(order.Total) / gross)
+}
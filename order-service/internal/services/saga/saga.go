@@ -0,0 +1,410 @@
+// Package saga runs the checkout of an order as a sequence of steps across
+// catalogue, payment and order, undoing the completed steps when one fails.
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	cataloguep "github.com/sntabq/proto-gen/gen/go/catalogue"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	grpcapp "order-service/internal/app/grpc"
+	"order-service/internal/data"
+	"order-service/internal/data/dto"
+	"order-service/internal/data/models"
+	"order-service/internal/payment"
+	"order-service/internal/services/order/lifecycle"
+	"order-service/internal/sl"
+	"time"
+)
+
+const (
+	StatusRunning      = "running"
+	StatusCompensating = "compensating"
+	StatusCompleted    = "completed"
+	StatusCompensated  = "compensated"
+	// StatusFailed marks a saga whose compensation kept failing. It needs the
+	// attention of an admin.
+	StatusFailed = "failed"
+)
+
+const (
+	StepReserveStock     = "reserve_stock"
+	StepAuthorizePayment = "authorize_payment"
+	StepConfirmOrder     = "confirm_order"
+	StepCapturePayment   = "capture_payment"
+	StepNotify           = "notify"
+)
+
+const (
+	// maxStepAttempts is how often a step is tried before the saga gives up
+	// and compensates.
+	maxStepAttempts = 5
+	// maxCompensationAttempts is how often a compensation is tried before the
+	// saga is marked as failed.
+	maxCompensationAttempts = 10
+)
+
+type SagaRepo interface {
+	ClaimSaga(ctx context.Context, orderId int32, lease time.Duration) (*models.Saga, error)
+	SaveSagaState(ctx context.Context, saga *models.Saga) error
+	UnlockSaga(ctx context.Context, id int64) error
+	CompleteSaga(ctx context.Context, saga *models.Saga, status string) error
+	ListSagas(ctx context.Context, status string) ([]*models.Saga, error)
+	GetResumableSagas(ctx context.Context) ([]int32, error)
+}
+
+type OrderRepo interface {
+	GetOrderById(context.Context, int) (*models.Order, error)
+	GetOrderLines(ctx context.Context, orderId int32) ([]dto.OrderLineDTO, error)
+	UpdateOrderStatus(context.Context, *models.OrderStatusChange) (*models.Order, error)
+	ReopenCart(ctx context.Context, orderId int32) error
+}
+
+// PaymentGateway takes the payment for an order and gives it back when the
+// checkout is rolled back.
+type PaymentGateway interface {
+	Authorize(ctx context.Context, orderId int32, amount int32) (string, error)
+	Void(ctx context.Context, paymentId string) error
+	Capture(ctx context.Context, paymentId string) error
+	RefundAll(ctx context.Context, paymentId string) error
+}
+
+type step struct {
+	name       string
+	action     func(context.Context, *models.Saga) error
+	compensate func(context.Context, *models.Saga) error
+}
+
+type Orchestrator struct {
+	log           *slog.Logger
+	sagaProvider  SagaRepo
+	orderProvider OrderRepo
+	payments      PaymentGateway
+	lease         time.Duration
+	steps         []step
+}
+
+func New(
+	log *slog.Logger,
+	sagaProvider SagaRepo,
+	orderProvider OrderRepo,
+	payments PaymentGateway,
+	lease time.Duration,
+) *Orchestrator {
+	o := &Orchestrator{
+		log:           log,
+		sagaProvider:  sagaProvider,
+		orderProvider: orderProvider,
+		payments:      payments,
+		lease:         lease,
+	}
+	o.steps = []step{
+		{name: StepReserveStock, action: o.reserveStock, compensate: o.releaseStock},
+		{name: StepAuthorizePayment, action: o.authorizePayment, compensate: o.voidPayment},
+		{name: StepConfirmOrder, action: o.confirmOrder},
+		{name: StepCapturePayment, action: o.capturePayment, compensate: o.refundPayment},
+		{name: StepNotify, action: o.notify},
+	}
+
+	return o
+}
+
+// permanentError marks a failure that retrying cannot fix, such as missing
+// stock or a declined payment.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+// classify marks errors that will not go away on retry as permanent.
+func classify(err error) error {
+	if errors.Is(err, payment.ErrDeclined) {
+		return permanentError{err: err}
+	}
+
+	switch status.Code(err) {
+	case codes.FailedPrecondition, codes.InvalidArgument, codes.NotFound, codes.PermissionDenied:
+		return permanentError{err: err}
+	default:
+		return err
+	}
+}
+
+// Run executes the saga of the order until it finishes or a step fails in a
+// way worth retrying later. In the latter case the saga stays unfinished and
+// is picked up again by RunPending.
+func (o *Orchestrator) Run(ctx context.Context, orderId int32) (*models.Saga, error) {
+	const op = "Orchestrator.Run"
+	log := o.log.With(
+		slog.String("op", op),
+		slog.Int("order id", int(orderId)),
+	)
+
+	saga, err := o.sagaProvider.ClaimSaga(ctx, orderId, o.lease)
+	if err != nil {
+		return nil, err
+	}
+
+	if saga.Step == "" && saga.Status == StatusRunning {
+		saga.Step = o.steps[0].name
+	}
+
+	for saga.Status == StatusRunning || saga.Status == StatusCompensating {
+		if err := o.advance(ctx, saga); err != nil {
+			log.Warn("saga step failed, will retry later",
+				slog.String("step", saga.Step),
+				sl.Err(err),
+			)
+			if err := o.sagaProvider.UnlockSaga(context.WithoutCancel(ctx), saga.ID); err != nil {
+				log.Warn("failed to unlock saga", sl.Err(err))
+			}
+			return saga, err
+		}
+	}
+
+	log.Info("saga finished", slog.String("status", saga.Status))
+
+	return saga, nil
+}
+
+// advance executes or compensates the current step and records the result.
+func (o *Orchestrator) advance(ctx context.Context, saga *models.Saga) error {
+	i := o.stepIndex(saga.Step)
+
+	if saga.Status == StatusCompensating {
+		if i < 0 {
+			return o.finishCompensation(ctx, saga)
+		}
+
+		if compensate := o.steps[i].compensate; compensate != nil {
+			if err := compensate(ctx, saga); err != nil {
+				saga.Attempts++
+				saga.LastError = err.Error()
+				if saga.Attempts >= maxCompensationAttempts {
+					saga.Status = StatusFailed
+					return o.sagaProvider.SaveSagaState(ctx, saga)
+				}
+				if saveErr := o.sagaProvider.SaveSagaState(ctx, saga); saveErr != nil {
+					return saveErr
+				}
+				return err
+			}
+		}
+
+		saga.Step = ""
+		if i > 0 {
+			saga.Step = o.steps[i-1].name
+		}
+		saga.Attempts = 0
+
+		return o.sagaProvider.SaveSagaState(ctx, saga)
+	}
+
+	if i < 0 {
+		return fmt.Errorf("unknown saga step %q", saga.Step)
+	}
+
+	if err := o.steps[i].action(ctx, saga); err != nil {
+		saga.Attempts++
+		saga.LastError = err.Error()
+
+		var permanent permanentError
+		if errors.As(err, &permanent) || saga.Attempts >= maxStepAttempts {
+			// Compensations are idempotent, so the failed step is undone as
+			// well in case it was partly applied.
+			saga.Status = StatusCompensating
+			saga.Attempts = 0
+			return o.sagaProvider.SaveSagaState(ctx, saga)
+		}
+		if saveErr := o.sagaProvider.SaveSagaState(ctx, saga); saveErr != nil {
+			return saveErr
+		}
+		return err
+	}
+
+	// The last step finishes the saga by itself.
+	if i == len(o.steps)-1 {
+		return nil
+	}
+
+	saga.Step = o.steps[i+1].name
+	saga.Attempts = 0
+	saga.LastError = ""
+
+	return o.sagaProvider.SaveSagaState(ctx, saga)
+}
+
+// finishCompensation cancels the order once every step has been undone and
+// gives the customer their cart back.
+func (o *Orchestrator) finishCompensation(ctx context.Context, saga *models.Saga) error {
+	order, err := o.orderProvider.GetOrderById(ctx, int(saga.OrderId))
+	if err != nil {
+		return err
+	}
+
+	if err = o.orderProvider.ReopenCart(ctx, order.ID); err != nil {
+		return err
+	}
+
+	if lifecycle.CanTransition(order.Status, lifecycle.StatusCancelled) {
+		_, err = o.orderProvider.UpdateOrderStatus(ctx, &models.OrderStatusChange{
+			OrderId:    order.ID,
+			FromStatus: order.Status,
+			ToStatus:   lifecycle.StatusCancelled,
+			Reason:     "checkout failed: " + saga.LastError,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	saga.Status = StatusCompensated
+
+	return o.sagaProvider.SaveSagaState(ctx, saga)
+}
+
+func (o *Orchestrator) stepIndex(name string) int {
+	for i, s := range o.steps {
+		if s.name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// RunPending resumes unfinished sagas every interval until ctx is cancelled,
+// starting right away so that checkouts interrupted by a restart are finished
+// or compensated.
+func (o *Orchestrator) RunPending(ctx context.Context, interval time.Duration) {
+	const op = "Orchestrator.RunPending"
+	log := o.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		orderIds, err := o.sagaProvider.GetResumableSagas(ctx)
+		if err != nil {
+			log.Warn("failed to get unfinished sagas", sl.Err(err))
+		}
+
+		for _, orderId := range orderIds {
+			if _, err := o.Run(ctx, orderId); err != nil && !errors.Is(err, data.ErrSagaBusy) {
+				log.Warn("failed to resume saga", slog.Int("order id", int(orderId)), sl.Err(err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *Orchestrator) ListSagas(ctx context.Context, status string) ([]*models.Saga, error) {
+	const op = "Orchestrator.ListSagas"
+
+	sagas, err := o.sagaProvider.ListSagas(ctx, status)
+	if err != nil {
+		o.log.Warn("failed to list sagas", slog.String("op", op), sl.Err(err))
+		return nil, err
+	}
+
+	return sagas, nil
+}
+
+func reservationId(saga *models.Saga) string {
+	return fmt.Sprintf("order-%d", saga.OrderId)
+}
+
+func (o *Orchestrator) reserveStock(ctx context.Context, saga *models.Saga) error {
+	lines, err := o.orderProvider.GetOrderLines(ctx, saga.OrderId)
+	if err != nil {
+		return err
+	}
+
+	req := &cataloguep.ReserveStockRequest{ReservationId: reservationId(saga)}
+	for _, line := range lines {
+		req.Lines = append(req.Lines, &cataloguep.StockLine{ItemId: line.ItemId, Quantity: line.Quantity})
+	}
+
+	_, err = grpcapp.StockServiceClient.ReserveStock(ctx, req)
+
+	return classify(err)
+}
+
+func (o *Orchestrator) releaseStock(ctx context.Context, saga *models.Saga) error {
+	_, err := grpcapp.StockServiceClient.ReleaseStock(ctx, &cataloguep.ReleaseStockRequest{
+		ReservationId: reservationId(saga),
+	})
+
+	return err
+}
+
+func (o *Orchestrator) authorizePayment(ctx context.Context, saga *models.Saga) error {
+	order, err := o.orderProvider.GetOrderById(ctx, int(saga.OrderId))
+	if err != nil {
+		return err
+	}
+
+	paymentId, err := o.payments.Authorize(ctx, order.ID, order.Total)
+	if err != nil {
+		return classify(err)
+	}
+	saga.Data.PaymentId = paymentId
+
+	return nil
+}
+
+func (o *Orchestrator) voidPayment(ctx context.Context, saga *models.Saga) error {
+	if saga.Data.PaymentId == "" {
+		return nil
+	}
+
+	return o.payments.Void(ctx, saga.Data.PaymentId)
+}
+
+func (o *Orchestrator) capturePayment(ctx context.Context, saga *models.Saga) error {
+	return o.payments.Capture(ctx, saga.Data.PaymentId)
+}
+
+func (o *Orchestrator) refundPayment(ctx context.Context, saga *models.Saga) error {
+	if saga.Data.PaymentId == "" {
+		return nil
+	}
+
+	return o.payments.RefundAll(ctx, saga.Data.PaymentId)
+}
+
+func (o *Orchestrator) confirmOrder(ctx context.Context, saga *models.Saga) error {
+	order, err := o.orderProvider.GetOrderById(ctx, int(saga.OrderId))
+	if err != nil {
+		return err
+	}
+	if order.Status == lifecycle.StatusConfirmed {
+		return nil
+	}
+	if !lifecycle.CanTransition(order.Status, lifecycle.StatusConfirmed) {
+		return permanentError{err: fmt.Errorf("%w: %s -> %s", lifecycle.ErrInvalidTransition, order.Status, lifecycle.StatusConfirmed)}
+	}
+
+	_, err = o.orderProvider.UpdateOrderStatus(ctx, &models.OrderStatusChange{
+		OrderId:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   lifecycle.StatusConfirmed,
+	})
+
+	return err
+}
+
+func (o *Orchestrator) notify(ctx context.Context, saga *models.Saga) error {
+	return o.sagaProvider.CompleteSaga(ctx, saga, StatusCompleted)
+}
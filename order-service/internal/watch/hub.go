@@ -0,0 +1,228 @@
+// Package watch pushes order status changes to the clients watching them.
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lib/pq"
+	"log/slog"
+	"order-service/internal/data/models"
+	"order-service/internal/sl"
+	"sync"
+	"time"
+)
+
+// channel is the Postgres channel status changes are announced on, see the
+// order_status_history_notify trigger.
+const channel = "order_status"
+
+// bufferSize is how many changes a watcher may fall behind before it is
+// dropped.
+const bufferSize = 16
+
+// pingInterval is how often an idle listener checks its connection.
+const pingInterval = 90 * time.Second
+
+// listenAttempts is how many times Run tries to listen for status changes
+// before it gives up, waiting listenBaseDelay after the first failure and
+// twice as long after each further one.
+const (
+	listenAttempts  = 5
+	listenBaseDelay = time.Second
+)
+
+type notification struct {
+	models.OrderStatusChange
+	UserId int32 `json:"user_id"`
+}
+
+type watcher struct {
+	orderId int32
+	userId  int32
+	changes chan *models.OrderStatusChange
+}
+
+// Hub fans the status changes announced by Postgres out to the watchers of
+// the orders. As changes are announced by the database, watchers see the
+// changes made by every instance of the service.
+//
+// Watchers that fall behind, and all watchers when the connection to Postgres
+// was lost, are dropped by closing their channel; they may have missed changes
+// and have to watch again. Once the hub is closed, the channels of new
+// watchers are closed right away.
+type Hub struct {
+	log *slog.Logger
+	dsn string
+
+	mu       sync.Mutex
+	watchers map[*watcher]struct{}
+	closed   bool
+}
+
+func New(log *slog.Logger, dsn string) *Hub {
+	return &Hub{
+		log:      log,
+		dsn:      dsn,
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+// Run listens for status changes until ctx is cancelled. The hub is closed
+// when Run gives up listening.
+func (h *Hub) Run(ctx context.Context) {
+	const op = "watch.Run"
+	log := h.log.With(slog.String("op", op))
+
+	listener := pq.NewListener(h.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Warn("order status listener failed", sl.Err(err))
+		}
+	})
+	defer listener.Close()
+	defer h.DropAll()
+
+	if !h.listen(ctx, listener) {
+		h.Close()
+		return
+	}
+
+	log.Info("order status hub started")
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("order status hub stopped")
+			return
+		case n := <-listener.Notify:
+			if n == nil {
+				// The connection was re-established; changes made in
+				// between were not announced to us.
+				h.DropAll()
+				continue
+			}
+			h.dispatch(n.Extra)
+		case <-ticker.C:
+			go listener.Ping()
+		}
+	}
+}
+
+// listen subscribes the listener to the status changes, retrying with a
+// growing delay. It reports false when it gave up or ctx was cancelled.
+func (h *Hub) listen(ctx context.Context, listener *pq.Listener) bool {
+	const op = "watch.listen"
+	log := h.log.With(slog.String("op", op))
+
+	delay := listenBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := listener.Listen(channel)
+		if err == nil {
+			return true
+		}
+		if attempt == listenAttempts {
+			log.Error("failed to listen for order status changes, giving up", sl.Err(err))
+			return false
+		}
+		log.Warn("failed to listen for order status changes, retrying",
+			sl.Err(err),
+			slog.Duration("delay", delay),
+		)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// WatchOrder returns the changes of the order and a function that stops
+// watching it.
+func (h *Hub) WatchOrder(orderId int32) (<-chan *models.OrderStatusChange, func()) {
+	return h.watch(&watcher{orderId: orderId})
+}
+
+// WatchUser returns the changes of all orders of the user and a function that
+// stops watching them.
+func (h *Hub) WatchUser(userId int32) (<-chan *models.OrderStatusChange, func()) {
+	return h.watch(&watcher{userId: userId})
+}
+
+func (h *Hub) watch(w *watcher) (<-chan *models.OrderStatusChange, func()) {
+	w.changes = make(chan *models.OrderStatusChange, bufferSize)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(w.changes)
+		return w.changes, func() {}
+	}
+	h.watchers[w] = struct{}{}
+	h.mu.Unlock()
+
+	return w.changes, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.drop(w)
+	}
+}
+
+func (h *Hub) dispatch(payload string) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		h.log.Warn("failed to decode order status change", slog.String("op", "watch.dispatch"), sl.Err(err))
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for w := range h.watchers {
+		if (w.orderId != 0 && w.orderId != n.OrderId) || (w.userId != 0 && w.userId != n.UserId) {
+			continue
+		}
+
+		change := n.OrderStatusChange
+		select {
+		case w.changes <- &change:
+		default:
+			h.drop(w)
+		}
+	}
+}
+
+// Close drops every watcher and the watchers that come after, which ends the
+// streams serving them. The server calls it on shutdown, as a graceful stop
+// waits for open streams.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for w := range h.watchers {
+		h.drop(w)
+	}
+}
+
+// DropAll drops every watcher, which ends the streams serving them.
+func (h *Hub) DropAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for w := range h.watchers {
+		h.drop(w)
+	}
+}
+
+// drop closes the channel of the watcher. h.mu must be held.
+func (h *Hub) drop(w *watcher) {
+	if _, ok := h.watchers[w]; !ok {
+		return
+	}
+	delete(h.watchers, w)
+	close(w.changes)
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS order_service.order_status_history;
+ALTER TABLE IF EXISTS order_service.orders
+    DROP COLUMN IF EXISTS status ,
+    DROP COLUMN IF EXISTS created_at ,
+    DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,16 @@
+ALTER TABLE order_service.orders
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending' ,
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+CREATE TABLE IF NOT EXISTS order_service.order_status_history(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    order_id BIGINT NOT NULL REFERENCES order_service.orders(id) ON DELETE CASCADE ,
+    from_status VARCHAR(20) ,
+    to_status VARCHAR(20) NOT NULL ,
+    changed_by BIGINT REFERENCES auth.users(id) ,
+    reason TEXT NOT NULL DEFAULT '' ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS order_status_history_order_id_idx ON order_service.order_status_history (order_id);
@@ -0,0 +1,5 @@
+ALTER TABLE IF EXISTS order_service.orders
+    DROP COLUMN IF EXISTS total;
+DROP TABLE IF EXISTS order_service.order_lines;
+DROP TABLE IF EXISTS order_service.cart_lines;
+DROP TABLE IF EXISTS order_service.carts;
@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS order_service.carts(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    user_id BIGINT NOT NULL REFERENCES auth.users(id) ,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' ,
+    order_id BIGINT REFERENCES order_service.orders(id) ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- A user has at most one open cart, checked out carts are kept for reporting.
+CREATE UNIQUE INDEX IF NOT EXISTS carts_user_id_open_key ON order_service.carts (user_id) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS order_service.cart_lines(
+    cart_id BIGINT NOT NULL REFERENCES order_service.carts(id) ON DELETE CASCADE ,
+    item_id INTEGER NOT NULL REFERENCES catalogue.item_info(id) ,
+    quantity INTEGER NOT NULL CHECK (quantity > 0) ,
+    unit_price INTEGER NOT NULL ,
+    added_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    PRIMARY KEY (cart_id, item_id)
+);
+
+CREATE TABLE IF NOT EXISTS order_service.order_lines(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    order_id BIGINT NOT NULL REFERENCES order_service.orders(id) ON DELETE CASCADE ,
+    item_id INTEGER NOT NULL REFERENCES catalogue.item_info(id) ,
+    quantity INTEGER NOT NULL CHECK (quantity > 0) ,
+    unit_price INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_lines_order_id_idx ON order_service.order_lines (order_id);
+
+ALTER TABLE order_service.orders
+    ALTER COLUMN item_id DROP NOT NULL ,
+    ADD COLUMN IF NOT EXISTS total INTEGER NOT NULL DEFAULT 0;
+
+-- Every order placed before carts existed becomes a single line order.
+INSERT INTO order_service.order_lines (order_id, item_id, quantity, unit_price)
+SELECT o.id, o.item_id, 1, i.price
+FROM order_service.orders o
+JOIN catalogue.item_info i ON i.id = o.item_id
+WHERE NOT EXISTS (SELECT 1 FROM order_service.order_lines l WHERE l.order_id = o.id);
+
+UPDATE order_service.orders o
+SET total = l.total
+FROM (
+    SELECT order_id, SUM(quantity * unit_price) AS total
+    FROM order_service.order_lines
+    GROUP BY order_id
+) l
+WHERE l.order_id = o.id;
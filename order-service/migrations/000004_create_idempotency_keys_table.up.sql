@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS order_service.idempotency_keys(
+    user_id BIGINT NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE ,
+    key VARCHAR(255) NOT NULL ,
+    order_id BIGINT REFERENCES order_service.orders(id) ON DELETE CASCADE ,
+    response JSONB ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    expires_at TIMESTAMP(0) WITH TIME ZONE NOT NULL ,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON order_service.idempotency_keys (expires_at);
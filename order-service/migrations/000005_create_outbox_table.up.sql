@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS order_service.outbox(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    aggregate_id BIGINT NOT NULL ,
+    event_type VARCHAR(64) NOT NULL ,
+    payload JSONB NOT NULL ,
+    attempts INTEGER NOT NULL DEFAULT 0 ,
+    last_error TEXT NOT NULL DEFAULT '' ,
+    next_attempt_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    published_at TIMESTAMP(0) WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS outbox_unpublished_idx ON order_service.outbox (next_attempt_at, id)
+    WHERE published_at IS NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS order_service.sagas(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    order_id BIGINT NOT NULL REFERENCES order_service.orders(id) ON DELETE CASCADE ,
+    status VARCHAR(20) NOT NULL DEFAULT 'running' ,
+    step VARCHAR(32) NOT NULL DEFAULT '' ,
+    data JSONB NOT NULL DEFAULT '{}' ,
+    attempts INTEGER NOT NULL DEFAULT 0 ,
+    last_error TEXT NOT NULL DEFAULT '' ,
+    locked_until TIMESTAMP(0) WITH TIME ZONE ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    CONSTRAINT sagas_order_id_key UNIQUE (order_id)
+);
+
+CREATE INDEX IF NOT EXISTS sagas_unfinished_idx ON order_service.sagas (updated_at)
+    WHERE status IN ('running', 'compensating');
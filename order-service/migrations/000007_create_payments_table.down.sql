@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS order_service.payment_events;
+DROP TABLE IF EXISTS order_service.payments;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS order_service.payments(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    order_id BIGINT NOT NULL REFERENCES order_service.orders(id) ON DELETE CASCADE ,
+    provider VARCHAR(32) NOT NULL ,
+    provider_ref VARCHAR(255) NOT NULL DEFAULT '' ,
+    status VARCHAR(20) NOT NULL ,
+    amount INTEGER NOT NULL CHECK (amount >= 0) ,
+    refunded_amount INTEGER NOT NULL DEFAULT 0 ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    CHECK (refunded_amount BETWEEN 0 AND amount)
+);
+
+CREATE INDEX IF NOT EXISTS payments_order_id_idx ON order_service.payments (order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS payments_provider_ref_key ON order_service.payments (provider, provider_ref)
+    WHERE provider_ref <> '';
+
+CREATE TABLE IF NOT EXISTS order_service.payment_events(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    payment_id BIGINT NOT NULL REFERENCES order_service.payments(id) ON DELETE CASCADE ,
+    event_id VARCHAR(255) NOT NULL ,
+    event_type VARCHAR(64) NOT NULL ,
+    payload JSONB NOT NULL ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    CONSTRAINT payment_events_event_id_key UNIQUE (event_id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS order_service.shipments;
+DROP TABLE IF EXISTS order_service.order_addresses;
@@ -0,0 +1,26 @@
+-- The shipping address of an order is a copy of the address book entry taken
+-- when the order is placed, so later edits of the address book do not change
+-- where an existing order goes.
+CREATE TABLE IF NOT EXISTS order_service.order_addresses(
+    order_id BIGINT PRIMARY KEY REFERENCES order_service.orders(id) ON DELETE CASCADE ,
+    recipient VARCHAR(255) NOT NULL ,
+    line1 VARCHAR(255) NOT NULL ,
+    line2 VARCHAR(255) NOT NULL DEFAULT '' ,
+    city VARCHAR(128) NOT NULL ,
+    region VARCHAR(128) NOT NULL DEFAULT '' ,
+    postal_code VARCHAR(32) NOT NULL ,
+    country CHAR(2) NOT NULL ,
+    phone VARCHAR(32) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS order_service.shipments(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    order_id BIGINT NOT NULL REFERENCES order_service.orders(id) ON DELETE CASCADE ,
+    carrier VARCHAR(64) NOT NULL ,
+    tracking_number VARCHAR(128) NOT NULL ,
+    created_by BIGINT ,
+    shipped_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    CONSTRAINT shipments_tracking_key UNIQUE (carrier, tracking_number)
+);
+
+CREATE INDEX IF NOT EXISTS shipments_order_id_idx ON order_service.shipments (order_id);
@@ -0,0 +1,9 @@
+ALTER TABLE order_service.orders
+    DROP COLUMN IF EXISTS subtotal ,
+    DROP COLUMN IF EXISTS discount ,
+    DROP COLUMN IF EXISTS shipping_fee ,
+    DROP COLUMN IF EXISTS coupon_code;
+
+DROP TABLE IF EXISTS order_service.order_discounts;
+DROP TABLE IF EXISTS order_service.coupon_redemptions;
+DROP TABLE IF EXISTS order_service.coupons;
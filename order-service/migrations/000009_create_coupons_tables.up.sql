@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS order_service.coupons(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    code VARCHAR(64) NOT NULL ,
+    description VARCHAR(255) NOT NULL DEFAULT '' ,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('percentage', 'fixed', 'free_shipping', 'buy_x_get_y', 'category')) ,
+    value INTEGER NOT NULL DEFAULT 0 CHECK (value >= 0) ,
+    category VARCHAR(64) NOT NULL DEFAULT '' ,
+    item_id INTEGER REFERENCES catalogue.item_info(id) ON DELETE SET NULL ,
+    buy_quantity INTEGER NOT NULL DEFAULT 0 CHECK (buy_quantity >= 0) ,
+    get_quantity INTEGER NOT NULL DEFAULT 0 CHECK (get_quantity >= 0) ,
+    min_order_value INTEGER NOT NULL DEFAULT 0 CHECK (min_order_value >= 0) ,
+    max_uses INTEGER NOT NULL DEFAULT 0 CHECK (max_uses >= 0) , -- zero is unlimited
+    max_uses_per_user INTEGER NOT NULL DEFAULT 0 CHECK (max_uses_per_user >= 0) , -- zero is unlimited
+    uses INTEGER NOT NULL DEFAULT 0 ,
+    starts_at TIMESTAMP(0) WITH TIME ZONE ,
+    ends_at TIMESTAMP(0) WITH TIME ZONE ,
+    active BOOLEAN NOT NULL DEFAULT TRUE ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    CONSTRAINT coupons_code_key UNIQUE (code)
+);
+
+CREATE TABLE IF NOT EXISTS order_service.coupon_redemptions(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    coupon_id BIGINT NOT NULL REFERENCES order_service.coupons(id) ON DELETE CASCADE ,
+    user_id BIGINT NOT NULL ,
+    order_id BIGINT NOT NULL REFERENCES order_service.orders(id) ON DELETE CASCADE ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    CONSTRAINT coupon_redemptions_order_id_key UNIQUE (coupon_id, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS coupon_redemptions_user_idx ON order_service.coupon_redemptions (coupon_id, user_id);
+
+CREATE TABLE IF NOT EXISTS order_service.order_discounts(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    order_id BIGINT NOT NULL REFERENCES order_service.orders(id) ON DELETE CASCADE ,
+    coupon_id BIGINT REFERENCES order_service.coupons(id) ON DELETE SET NULL ,
+    description VARCHAR(255) NOT NULL ,
+    item_id INTEGER ,
+    amount INTEGER NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS order_discounts_order_id_idx ON order_service.order_discounts (order_id);
+
+-- The total of an order is its subtotal plus shipping minus discounts.
+ALTER TABLE order_service.orders
+    ADD COLUMN IF NOT EXISTS subtotal INTEGER NOT NULL DEFAULT 0 ,
+    ADD COLUMN IF NOT EXISTS discount INTEGER NOT NULL DEFAULT 0 ,
+    ADD COLUMN IF NOT EXISTS shipping_fee INTEGER NOT NULL DEFAULT 0 ,
+    ADD COLUMN IF NOT EXISTS coupon_code VARCHAR(64) NOT NULL DEFAULT '';
+
+UPDATE order_service.orders SET subtotal = total;
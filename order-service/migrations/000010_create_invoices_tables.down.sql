@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS order_service.invoices;
+DROP TABLE IF EXISTS order_service.invoice_sequences;
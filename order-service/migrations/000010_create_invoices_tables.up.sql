@@ -0,0 +1,16 @@
+-- Invoice numbers are gap free within a year: the counter row is locked by the
+-- transaction that issues the invoice and rolled back together with it.
+CREATE TABLE IF NOT EXISTS order_service.invoice_sequences(
+    year INTEGER PRIMARY KEY ,
+    last_number INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS order_service.invoices(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    order_id BIGINT NOT NULL REFERENCES order_service.orders(id) ON DELETE RESTRICT ,
+    year INTEGER NOT NULL ,
+    number INTEGER NOT NULL ,
+    issued_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    CONSTRAINT invoices_order_id_key UNIQUE (order_id) ,
+    CONSTRAINT invoices_year_number_key UNIQUE (year, number)
+);
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS order_status_history_notify ON order_service.order_status_history;
+DROP FUNCTION IF EXISTS order_service.notify_order_status_change();
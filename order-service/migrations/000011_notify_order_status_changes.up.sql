@@ -0,0 +1,23 @@
+-- Every status change of an order is announced on the order_status channel,
+-- for the order watchers of all instances of the service. The notification is
+-- delivered when the transaction that made the change commits.
+CREATE OR REPLACE FUNCTION order_service.notify_order_status_change() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('order_status', json_build_object(
+        'id', NEW.id,
+        'order_id', NEW.order_id,
+        'user_id', (SELECT user_id FROM order_service.orders WHERE id = NEW.order_id),
+        'from_status', COALESCE(NEW.from_status, ''),
+        'to_status', NEW.to_status,
+        'changed_by', COALESCE(NEW.changed_by, 0),
+        'reason', left(NEW.reason, 1000), -- payloads are limited to 8000 bytes
+        'created_at', NEW.created_at
+    )::text);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER order_status_history_notify
+    AFTER INSERT ON order_service.order_status_history
+    FOR EACH ROW EXECUTE FUNCTION order_service.notify_order_status_change();
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS order_service.return_status_history;
+DROP TABLE IF EXISTS order_service.returns;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS order_service.returns(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    order_id BIGINT NOT NULL REFERENCES order_service.orders(id) ON DELETE CASCADE ,
+    user_id BIGINT NOT NULL ,
+    item_id INTEGER NOT NULL ,
+    quantity INTEGER NOT NULL CHECK (quantity > 0) ,
+    reason TEXT NOT NULL ,
+    photo_urls TEXT[] NOT NULL DEFAULT '{}' ,
+    status VARCHAR(20) NOT NULL DEFAULT 'requested' ,
+    note TEXT NOT NULL DEFAULT '' ,
+    restocked BOOLEAN NOT NULL DEFAULT FALSE ,
+    refund_amount INTEGER NOT NULL DEFAULT 0 ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW() ,
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS returns_order_id_idx ON order_service.returns (order_id);
+CREATE INDEX IF NOT EXISTS returns_user_id_idx ON order_service.returns (user_id);
+
+CREATE TABLE IF NOT EXISTS order_service.return_status_history(
+    id BIGINT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY ,
+    return_id BIGINT NOT NULL REFERENCES order_service.returns(id) ON DELETE CASCADE ,
+    from_status VARCHAR(20) ,
+    to_status VARCHAR(20) NOT NULL ,
+    changed_by BIGINT ,
+    note TEXT NOT NULL DEFAULT '' ,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS return_status_history_return_id_idx ON order_service.return_status_history (return_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS order_service.carts_created_at_idx;
+DROP INDEX IF EXISTS order_service.orders_created_at_idx;
+DROP VIEW IF EXISTS order_service.sold_lines;
+DROP VIEW IF EXISTS order_service.sold_orders;
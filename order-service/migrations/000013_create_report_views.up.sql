@@ -0,0 +1,17 @@
+-- Orders that count as sales: paid, and neither cancelled nor refunded since.
+CREATE OR REPLACE VIEW order_service.sold_orders AS
+SELECT id, user_id, total, created_at
+FROM order_service.orders
+WHERE status IN ('paid', 'shipped', 'delivered');
+
+-- The lines of sold orders with the category of their item, for the reports
+-- that break sales down by item or filter them by category.
+CREATE OR REPLACE VIEW order_service.sold_lines AS
+SELECT o.id AS order_id, o.user_id, o.created_at, l.item_id, i.name, i.category, l.quantity,
+       l.quantity * l.unit_price AS amount
+FROM order_service.sold_orders o
+JOIN order_service.order_lines l ON l.order_id = o.id
+JOIN catalogue.item_info i ON i.id = l.item_id;
+
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON order_service.orders (created_at);
+CREATE INDEX IF NOT EXISTS carts_created_at_idx ON order_service.carts (created_at);
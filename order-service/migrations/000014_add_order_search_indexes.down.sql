@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS order_service.order_lines_item_id_idx;
+DROP INDEX IF EXISTS order_service.orders_total_idx;
+DROP INDEX IF EXISTS order_service.orders_status_created_at_idx;
+DROP INDEX IF EXISTS order_service.orders_user_id_created_at_idx;
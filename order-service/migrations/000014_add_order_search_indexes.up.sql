@@ -0,0 +1,5 @@
+-- Keyset pagination of the orders of a user and of all orders by status.
+CREATE INDEX IF NOT EXISTS orders_user_id_created_at_idx ON order_service.orders (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS orders_status_created_at_idx ON order_service.orders (status, created_at, id);
+CREATE INDEX IF NOT EXISTS orders_total_idx ON order_service.orders (total, id);
+CREATE INDEX IF NOT EXISTS order_lines_item_id_idx ON order_service.order_lines (item_id);
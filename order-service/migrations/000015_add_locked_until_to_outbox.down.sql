@@ -0,0 +1 @@
+ALTER TABLE order_service.outbox DROP COLUMN IF EXISTS locked_until;
@@ -0,0 +1 @@
+ALTER TABLE order_service.outbox ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP(0) WITH TIME ZONE;
@@ -0,0 +1,3 @@
+ALTER TABLE order_service.idempotency_keys
+    DROP COLUMN IF EXISTS request_hash ,
+    DROP COLUMN IF EXISTS claim_token;
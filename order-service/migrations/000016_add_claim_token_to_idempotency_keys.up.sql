@@ -0,0 +1,3 @@
+ALTER TABLE order_service.idempotency_keys
+    ADD COLUMN IF NOT EXISTS claim_token VARCHAR(32) NOT NULL DEFAULT '' ,
+    ADD COLUMN IF NOT EXISTS request_hash VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE order_service.payments
+    DROP COLUMN IF EXISTS refunding_amount;
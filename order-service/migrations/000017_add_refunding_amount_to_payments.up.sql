@@ -0,0 +1,2 @@
+ALTER TABLE order_service.payments
+    ADD COLUMN IF NOT EXISTS refunding_amount INTEGER NOT NULL DEFAULT 0 CHECK (refunding_amount >= 0);
@@ -0,0 +1 @@
+ALTER TABLE order_service.invoices DROP COLUMN IF EXISTS pdf;
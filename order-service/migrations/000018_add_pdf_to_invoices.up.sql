@@ -0,0 +1,4 @@
+-- The PDF is rendered once, when the invoice is issued, so that it does not
+-- change with the order afterwards. Invoices issued before it was stored have
+-- an empty one.
+ALTER TABLE order_service.invoices ADD COLUMN IF NOT EXISTS pdf BYTEA NOT NULL DEFAULT '';
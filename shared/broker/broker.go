@@ -0,0 +1,69 @@
+// Package broker carries the events of the shop from the services that
+// publish them to the notification service. It hides the message broker
+// behind Publisher and Subscriber: RabbitMQ in deployments, and Memory, an
+// in-process queue, in tests. The services run apart, so they are only
+// configured with RabbitMQ.
+package broker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// KindRabbitMQ is the only kind of broker a service can be configured with.
+const KindRabbitMQ = "rabbitmq"
+
+var (
+	ErrNacked = errors.New("message was not confirmed by the broker")
+	ErrClosed = errors.New("broker is closed")
+)
+
+// Message is an event as it travels through the broker.
+type Message struct {
+	// ID identifies the event, so that consumers can drop duplicates.
+	ID            string
+	Type          string
+	ContentType   string
+	CorrelationId string
+	Timestamp     time.Time
+	Body          []byte
+}
+
+// Publisher publishes the events of the shop. A message is published once
+// Publish returns nil.
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+// Handler handles a message. A message is settled when its handler returns
+// nil; when it fails, it is retried after a growing delay and dead-lettered
+// once it has been retried too often or can never succeed.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Subscriber hands the events of the shop to a handler until ctx is
+// cancelled.
+type Subscriber interface {
+	Run(ctx context.Context, handle Handler)
+}
+
+// permanentError is an error retrying will not fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as an error retrying will not fix, such as a malformed
+// message. The message is dead-lettered right away.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
@@ -0,0 +1,210 @@
+package broker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Outcomes a delivered message is settled with.
+const (
+	OutcomeAcked        = "acked"
+	OutcomeRequeued     = "requeued"
+	OutcomeRetried      = "retried"
+	OutcomeDeadLettered = "dead_lettered"
+)
+
+// Observer is told about the messages a Memory delivers, so that they can be
+// counted.
+type Observer interface {
+	// Delivering is called before msg is handed to its handler.
+	Delivering(msg *Message)
+	// Settled is called after the handler of msg returned, with the outcome
+	// msg was settled with.
+	Settled(msg *Message, outcome string)
+}
+
+// Memory is a broker that keeps its messages in memory. It publishes and
+// subscribes within one process, so it needs no RabbitMQ, and loses the
+// messages it holds when the process stops.
+//
+// A message whose handler fails is retried after a growing delay, and is
+// dead-lettered once it has been retried maxRetries times or its handler
+// failed permanently. The dead-lettered messages are kept for inspection.
+type Memory struct {
+	workers        int
+	maxRetries     int
+	retryBaseDelay time.Duration
+	observer       Observer
+
+	mu     sync.Mutex
+	queue  []pending
+	dead   []*Message
+	ready  chan struct{}
+	closed bool
+}
+
+// pending is a queued message and how often it has been retried.
+type pending struct {
+	msg     *Message
+	retries int
+}
+
+func NewMemory(workers int, maxRetries int, retryBaseDelay time.Duration) *Memory {
+	return &Memory{
+		workers:        max(workers, 1),
+		maxRetries:     maxRetries,
+		retryBaseDelay: retryBaseDelay,
+		ready:          make(chan struct{}, 1),
+	}
+}
+
+// Observe makes the broker tell o about the messages it delivers. It must be
+// called before Run.
+func (m *Memory) Observe(o Observer) {
+	m.observer = o
+}
+
+// Publish queues a copy of msg. It fails with ErrClosed once the broker is
+// closed.
+func (m *Memory) Publish(_ context.Context, msg *Message) error {
+	c := *msg
+	c.Body = append([]byte(nil), msg.Body...)
+	if c.Timestamp.IsZero() {
+		c.Timestamp = time.Now()
+	}
+
+	if !m.push(pending{msg: &c}) {
+		return ErrClosed
+	}
+
+	return nil
+}
+
+// Close makes the broker refuse the messages published after it. The
+// messages waiting to be retried are dropped.
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+
+	return nil
+}
+
+// Run hands the queued messages to the workers until ctx is cancelled. The
+// message a worker is handling when ctx is cancelled goes back to the queue.
+func (m *Memory) Run(ctx context.Context, handle Handler) {
+	var wg sync.WaitGroup
+	for i := 0; i < m.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				p, ok := m.pop(ctx)
+				if !ok {
+					return
+				}
+				m.deliver(ctx, p, handle)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// DeadLetters returns the messages that were dead-lettered.
+func (m *Memory) DeadLetters() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*Message(nil), m.dead...)
+}
+
+// Len returns the number of queued messages, the ones waiting to be retried
+// excluded.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.queue)
+}
+
+// deliver hands a message to its handler and settles it like the RabbitMQ
+// consumer does.
+func (m *Memory) deliver(ctx context.Context, p pending, handle Handler) {
+	if m.observer != nil {
+		m.observer.Delivering(p.msg)
+	}
+
+	var outcome string
+	err := handle(ctx, p.msg)
+	switch {
+	case err == nil:
+		outcome = OutcomeAcked
+	case ctx.Err() != nil:
+		m.push(p)
+		outcome = OutcomeRequeued
+	case IsPermanent(err) || p.retries >= m.maxRetries:
+		m.mu.Lock()
+		m.dead = append(m.dead, p.msg)
+		m.mu.Unlock()
+		outcome = OutcomeDeadLettered
+	default:
+		delay := m.retryBaseDelay << p.retries
+		p.retries++
+		time.AfterFunc(delay, func() { m.push(p) })
+		outcome = OutcomeRetried
+	}
+
+	if m.observer != nil {
+		m.observer.Settled(p.msg, outcome)
+	}
+}
+
+// push queues a message and returns false if the broker is closed.
+func (m *Memory) push(p pending) bool {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return false
+	}
+	m.queue = append(m.queue, p)
+	m.mu.Unlock()
+
+	m.signal()
+
+	return true
+}
+
+// pop takes the oldest message off the queue, waiting for one to be published
+// if it is empty. It returns false once ctx is cancelled.
+func (m *Memory) pop(ctx context.Context) (pending, bool) {
+	for {
+		m.mu.Lock()
+		if len(m.queue) > 0 {
+			p := m.queue[0]
+			m.queue = m.queue[1:]
+			more := len(m.queue) > 0
+			m.mu.Unlock()
+			if more {
+				m.signal()
+			}
+			return p, true
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return pending{}, false
+		case <-m.ready:
+		}
+	}
+}
+
+// signal wakes up a worker waiting for a message.
+func (m *Memory) signal() {
+	select {
+	case m.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,138 @@
+package broker
+
+import (
+	"context"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"sync"
+	"time"
+)
+
+const (
+	// QueueName is the queue the notification service consumes the events
+	// from.
+	QueueName = "shop"
+	// DeadLetterExchange receives the messages that could not be handled,
+	// both from the consumer and from the broker when a message is rejected.
+	DeadLetterExchange = "shop.dlx"
+)
+
+// QueueArgs are the arguments the shop queue is declared with. Producers and
+// the consumer must declare it with the same arguments, or declaring it
+// fails.
+var QueueArgs = amqp.Table{"x-dead-letter-exchange": DeadLetterExchange}
+
+// confirmTimeout bounds how long the publisher waits for the broker to
+// confirm a single message.
+const confirmTimeout = 5 * time.Second
+
+// RabbitMQ publishes to the shop queue of a RabbitMQ broker and waits for the
+// broker to confirm every message. The connection is opened on the first
+// message and reopened after it is lost.
+type RabbitMQ struct {
+	url string
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func NewRabbitMQ(url string) *RabbitMQ {
+	return &RabbitMQ{url: url}
+}
+
+func (r *RabbitMQ) Publish(ctx context.Context, msg *Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.connect(); err != nil {
+		return err
+	}
+
+	confirmation, err := r.channel.PublishWithDeferredConfirmWithContext(ctx, "", QueueName, false, false, amqp.Publishing{
+		ContentType:   msg.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: msg.CorrelationId,
+		MessageId:     msg.ID,
+		Timestamp:     msg.Timestamp,
+		Type:          msg.Type,
+		Body:          msg.Body,
+	})
+	if err != nil {
+		r.close()
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
+
+	acked, err := confirmation.WaitContext(waitCtx)
+	if err != nil {
+		r.close()
+		return err
+	}
+	if !acked {
+		return ErrNacked
+	}
+
+	return nil
+}
+
+func (r *RabbitMQ) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.close()
+
+	return nil
+}
+
+// connect (re)opens the connection and a channel in confirm mode when there
+// is none or the broker closed it.
+func (r *RabbitMQ) connect() error {
+	if r.conn != nil && !r.conn.IsClosed() && r.channel != nil && !r.channel.IsClosed() {
+		return nil
+	}
+	r.close()
+
+	conn, err := amqp.Dial(r.url)
+	if err != nil {
+		return err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if err = ch.Confirm(false); err != nil {
+		conn.Close()
+		return err
+	}
+
+	_, err = ch.QueueDeclare(
+		QueueName, // name
+		true,      // durable
+		false,     // delete when unused
+		false,     // exclusive
+		false,     // no-wait
+		QueueArgs, // arguments
+	)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	r.conn = conn
+	r.channel = ch
+
+	return nil
+}
+
+func (r *RabbitMQ) close() {
+	if r.conn != nil {
+		r.conn.Close()
+	}
+	r.conn = nil
+	r.channel = nil
+}
@@ -0,0 +1,118 @@
+// Package events wraps the events the services publish in the envelope they
+// share. Envelopes are stored in the outboxes of the services in their JSON
+// form, so that the outboxes stay readable, and published in binary.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	eventsp "github.com/sntabq/proto-gen/gen/go/events"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+const (
+	// ContentType is the content type of a published envelope.
+	ContentType = "application/x-protobuf"
+	// Version is the schema version of the payloads published.
+	Version = 1
+)
+
+var ErrInvalidEvent = errors.New("invalid event")
+
+// New wraps payload in an envelope of type eventType occurring now, signed
+// with the name of its producer. Events of the same aggregate, such as an
+// order or an item, share correlationId.
+func New(producer, eventType, correlationId string, payload proto.Message) (*eventsp.Envelope, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	body, err := anypb.New(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := &eventsp.Envelope{
+		EventId:       hex.EncodeToString(id),
+		Type:          eventType,
+		Version:       Version,
+		OccurredAt:    timestamppb.New(time.Now()),
+		Producer:      producer,
+		CorrelationId: correlationId,
+		Payload:       body,
+	}
+	if err = Validate(envelope); err != nil {
+		return nil, err
+	}
+
+	return envelope, nil
+}
+
+// Marshal wraps payload in an envelope like New and returns it in the JSON
+// form the outboxes store.
+func Marshal(producer, eventType, correlationId string, payload proto.Message) ([]byte, error) {
+	envelope, err := New(producer, eventType, correlationId, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return protojson.Marshal(envelope)
+}
+
+// Validate checks that the envelope is complete and that its payload has what
+// consumers need to act on it.
+func Validate(e *eventsp.Envelope) error {
+	switch {
+	case e.GetEventId() == "":
+		return fmt.Errorf("%w: event_id is required", ErrInvalidEvent)
+	case e.GetType() == "":
+		return fmt.Errorf("%w: type is required", ErrInvalidEvent)
+	case e.GetVersion() < 1:
+		return fmt.Errorf("%w: version must be positive", ErrInvalidEvent)
+	case e.GetOccurredAt() == nil:
+		return fmt.Errorf("%w: occurred_at is required", ErrInvalidEvent)
+	case e.GetProducer() == "":
+		return fmt.Errorf("%w: producer is required", ErrInvalidEvent)
+	case e.GetPayload() == nil:
+		return fmt.Errorf("%w: payload is required", ErrInvalidEvent)
+	}
+
+	payload, err := e.GetPayload().UnmarshalNew()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+
+	switch p := payload.(type) {
+	case *eventsp.OrderEvent:
+		if p.GetOrder().GetId() == 0 {
+			return fmt.Errorf("%w: order is required", ErrInvalidEvent)
+		}
+		if p.GetUser().GetEmail() == "" {
+			return fmt.Errorf("%w: user email is required", ErrInvalidEvent)
+		}
+	case *eventsp.CartEvent:
+		if p.GetUser().GetId() == 0 || p.GetUser().GetEmail() == "" {
+			return fmt.Errorf("%w: user id and email are required", ErrInvalidEvent)
+		}
+	case *eventsp.BackInStockEvent:
+		if p.GetItem().GetId() == 0 {
+			return fmt.Errorf("%w: item is required", ErrInvalidEvent)
+		}
+		for _, s := range p.GetSubscribers() {
+			if s.GetEmail() == "" {
+				return fmt.Errorf("%w: subscriber email is required", ErrInvalidEvent)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unexpected payload %s", ErrInvalidEvent, e.GetPayload().GetTypeUrl())
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+module shared
+
+go 1.21
+
+require (
+	github.com/rabbitmq/amqp091-go v1.10.0
+	github.com/sntabq/proto-gen v0.0.0-00010101000000-000000000000
+	google.golang.org/protobuf v1.34.1
+)
+
+replace github.com/sntabq/proto-gen => ../gateway/third_party/protos
@@ -0,0 +1,169 @@
+// Package outbox moves the events a service stores in its outbox table,
+// together with the changes that caused them, to the broker.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	eventsp "github.com/sntabq/proto-gen/gen/go/events"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+	"shared/broker"
+	"shared/events"
+	"strconv"
+	"time"
+)
+
+type EventStore interface {
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*Event, error)
+	MarkPublished(ctx context.Context, event *Event) error
+	MarkFailed(ctx context.Context, event *Event, publishErr error) error
+}
+
+// Relay moves events from the outbox table to the broker. Events are
+// published at least once; the event id is sent as the message id so that
+// consumers can drop duplicates.
+//
+// A batch of events is claimed for lease and published without holding a
+// transaction. The events of the batch that could not be published before
+// the lease expired are left to be claimed again.
+type Relay struct {
+	log       *slog.Logger
+	store     EventStore
+	publisher broker.Publisher
+	interval  time.Duration
+	batchSize int
+	lease     time.Duration
+}
+
+func New(
+	log *slog.Logger,
+	store EventStore,
+	publisher broker.Publisher,
+	interval time.Duration,
+	batchSize int,
+	lease time.Duration,
+) *Relay {
+	return &Relay{
+		log:       log,
+		store:     store,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+		lease:     lease,
+	}
+}
+
+// Run publishes pending events every interval until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	const op = "outbox.Run"
+	log := r.log.With(slog.String("op", op))
+
+	log.Info("outbox relay started")
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("outbox relay stopped")
+			return
+		case <-ticker.C:
+			if err := r.relay(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Warn("failed to relay outbox events", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func (r *Relay) relay(ctx context.Context) error {
+	for {
+		claimed, err := r.relayBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if claimed < r.batchSize {
+			return nil
+		}
+	}
+}
+
+// relayBatch claims a batch of due events, publishes them and records the
+// outcomes. It returns how many events it claimed.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	events, err := r.store.ClaimPending(ctx, r.batchSize, r.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	leaseCtx, cancel := context.WithTimeout(ctx, r.lease)
+	defer cancel()
+
+	for _, event := range events {
+		if leaseCtx.Err() != nil {
+			break
+		}
+
+		if publishErr := r.publish(leaseCtx, event); publishErr != nil {
+			err = r.store.MarkFailed(ctx, event, publishErr)
+		} else {
+			err = r.store.MarkPublished(ctx, event)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(events), ctx.Err()
+}
+
+func (r *Relay) publish(ctx context.Context, event *Event) error {
+	const op = "outbox.publish"
+
+	msg, err := message(event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = r.publisher.Publish(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// message returns the message to publish for an outbox event. Events stored
+// before they were wrapped in an envelope are published as plain JSON, which
+// consumers still accept.
+func message(event *Event) (*broker.Message, error) {
+	msg := &broker.Message{
+		ID:          strconv.FormatInt(event.ID, 10),
+		Type:        event.EventType,
+		ContentType: "application/json",
+		Timestamp:   event.CreatedAt,
+		Body:        event.Payload,
+	}
+
+	var envelope eventsp.Envelope
+	if err := protojson.Unmarshal(event.Payload, &envelope); err != nil {
+		return msg, nil
+	}
+	if err := events.Validate(&envelope); err != nil {
+		return nil, err
+	}
+
+	body, err := proto.Marshal(&envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.ContentType = events.ContentType
+	msg.ID = envelope.GetEventId()
+	msg.CorrelationId = envelope.GetCorrelationId()
+	msg.Body = body
+
+	return msg, nil
+}
@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"context"
+	eventsp "github.com/sntabq/proto-gen/gen/go/events"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"log/slog"
+	"shared/broker"
+	"shared/events"
+	"testing"
+	"time"
+)
+
+// TestRelayPublishesEnvelopes relays an order.created event stored the way
+// the order service stores it and checks that it reaches the broker in the
+// form the notification service decodes.
+func TestRelayPublishesEnvelopes(t *testing.T) {
+	envelope, err := events.New("order-service", "order.created", "order-42", &eventsp.OrderEvent{
+		User:  &eventsp.User{Id: 7, Username: "Jane", Email: "jane@example.com"},
+		Order: &eventsp.Order{Id: 42, UserId: 7, Total: 2400},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := protojson.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &eventStore{pending: []*Event{
+		{ID: 1, AggregateId: 42, EventType: "order.created", Payload: payload, CreatedAt: time.Now()},
+	}}
+	memory := broker.NewMemory(1, 0, 0)
+	relay := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, memory, time.Second, 10, time.Minute)
+
+	if err = relay.relay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.pending) != 0 {
+		t.Fatalf("%d events left pending, want none", len(store.pending))
+	}
+
+	msg := receive(t, memory)
+	if msg.ID != envelope.GetEventId() || msg.Type != "order.created" || msg.CorrelationId != "order-42" {
+		t.Errorf("published %+v, want the id, type and correlation id of the envelope", msg)
+	}
+	if msg.ContentType != events.ContentType {
+		t.Errorf("content type = %q, want %q", msg.ContentType, events.ContentType)
+	}
+
+	var published eventsp.Envelope
+	if err = proto.Unmarshal(msg.Body, &published); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(&published, envelope) {
+		t.Errorf("published envelope %v, want %v", &published, envelope)
+	}
+}
+
+// receive returns the next message of b.
+func receive(t *testing.T, b *broker.Memory) *broker.Message {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan *broker.Message, 1)
+	go b.Run(ctx, func(_ context.Context, msg *broker.Message) error {
+		select {
+		case received <- msg:
+		default:
+		}
+		return nil
+	})
+
+	select {
+	case msg := <-received:
+		return msg
+	case <-ctx.Done():
+		t.Fatal("no message was published")
+		return nil
+	}
+}
+
+// eventStore hands its pending events to the relay and drops the published
+// ones.
+type eventStore struct {
+	pending []*Event
+}
+
+func (s *eventStore) ClaimPending(_ context.Context, limit int, _ time.Duration) ([]*Event, error) {
+	claimed := s.pending[:min(limit, len(s.pending))]
+	for _, event := range claimed {
+		event.Attempts++
+	}
+
+	return claimed, nil
+}
+
+func (s *eventStore) MarkPublished(_ context.Context, event *Event) error {
+	for i, e := range s.pending {
+		if e.ID == event.ID {
+			s.pending = append(s.pending[:i:i], s.pending[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (s *eventStore) MarkFailed(context.Context, *Event, error) error {
+	return nil
+}
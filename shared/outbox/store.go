@@ -0,0 +1,126 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxBackoff caps the delay between two publish attempts of an event.
+const maxBackoff = 5 * time.Minute
+
+// Event is an event stored together with the change that caused it and
+// waiting to be published.
+type Event struct {
+	ID          int64     `json:"id"`
+	AggregateId int32     `json:"aggregate_id"`
+	EventType   string    `json:"event_type"`
+	Payload     []byte    `json:"payload"`
+	Attempts    int32     `json:"attempts"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Store keeps the outbox of a service in Table, which every service creates
+// with the same columns.
+type Store struct {
+	DB    *sql.DB
+	Table string
+}
+
+func NewStore(db *sql.DB, table string) *Store {
+	return &Store{DB: db, Table: table}
+}
+
+// ClaimPending leases up to limit due events, oldest first, for lease and
+// returns them. The claim counts as an attempt and commits right away, so no
+// lock is held while the events are published; several relays can run side
+// by side, and an event whose relay died is claimed again once its lease
+// expires.
+func (s *Store) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*Event, error) {
+	const op = "outbox.ClaimPending"
+	fail := func(e error) error {
+		return fmt.Errorf("%s: %v", op, e)
+	}
+	query := fmt.Sprintf(`
+			UPDATE %[1]s
+			SET attempts = attempts + 1, locked_until = NOW() + $2 * INTERVAL '1 millisecond'
+			WHERE id IN (
+				SELECT id FROM %[1]s
+				WHERE published_at IS NULL AND next_attempt_at <= NOW()
+				AND (locked_until IS NULL OR locked_until <= NOW())
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED)
+			RETURNING id, aggregate_id, event_type, payload, attempts, created_at`, s.Table)
+
+	rows, err := s.DB.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fail(err)
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		var event Event
+		err := rows.Scan(
+			&event.ID,
+			&event.AggregateId,
+			&event.EventType,
+			&event.Payload,
+			&event.Attempts,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fail(err)
+		}
+
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+
+	return events, nil
+}
+
+// MarkPublished records that a claimed event was published.
+func (s *Store) MarkPublished(ctx context.Context, event *Event) error {
+	const op = "outbox.MarkPublished"
+	query := fmt.Sprintf(`
+			UPDATE %s
+			SET last_error = '', published_at = NOW(), locked_until = NULL
+			WHERE id = $1`, s.Table)
+
+	if _, err := s.DB.ExecContext(ctx, query, event.ID); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// MarkFailed records that publishing a claimed event failed and schedules it
+// again with exponential backoff. Nothing is recorded once the event was
+// claimed again by another relay.
+func (s *Store) MarkFailed(ctx context.Context, event *Event, publishErr error) error {
+	const op = "outbox.MarkFailed"
+	query := fmt.Sprintf(`
+			UPDATE %s
+			SET last_error = $3, next_attempt_at = NOW() + $4 * INTERVAL '1 second', locked_until = NULL
+			WHERE id = $1 AND attempts = $2 AND published_at IS NULL`, s.Table)
+
+	backoff := time.Duration(1<<min(event.Attempts-1, 16)) * time.Second
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	_, err := s.DB.ExecContext(ctx, query, event.ID, event.Attempts, publishErr.Error(), int64(backoff.Seconds()))
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}